@@ -0,0 +1,112 @@
+// Package main Spot price forecast backtest.
+//
+// The backtest application evaluates the spot price forecasts of the productinfo application offline, over spot prices
+// recorded with the --spot-price-record-file switch of the productinfo application.
+package main
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"sort"
+	"text/tabwriter"
+	"time"
+
+	"github.com/banzaicloud/productinfo/pkg/productinfo"
+	"github.com/banzaicloud/productinfo/pkg/productinfo/forecast"
+	flag "github.com/spf13/pflag"
+)
+
+const (
+	fileFlag       = "file"
+	providerFlag   = "provider"
+	regionFlag     = "region"
+	typeFlag       = "type"
+	resolutionFlag = "resolution"
+	trainFlag      = "train"
+	horizonFlag    = "horizon"
+	confidenceFlag = "confidence"
+)
+
+func main() {
+	file := flag.String(fileFlag, "", "path of the CSV file with the recorded spot prices")
+	provider := flag.String(providerFlag, "", "only evaluate series of this provider")
+	region := flag.String(regionFlag, "", "only evaluate series in this region")
+	instType := flag.String(typeFlag, "", "only evaluate series of this instance type")
+	resolution := flag.Duration(resolutionFlag, time.Hour, "the period the samples are aggregated into")
+	train := flag.Duration(trainFlag, 7*24*time.Hour, "the minimum history the models are fitted on")
+	horizon := flag.Duration(horizonFlag, 24*time.Hour, "the forecasted time window")
+	confidence := flag.Float64(confidenceFlag, 0.95, "the confidence level of the forecast intervals")
+	flag.Parse()
+
+	if *file == "" {
+		fmt.Fprintln(os.Stderr, "the --file flag is required")
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	f, err := os.Open(*file)
+	quitOnError("could not open spot price file", err)
+	samples, err := productinfo.ReadSpotPriceSamples(f)
+	f.Close()
+	quitOnError("could not read spot price samples", err)
+
+	sort.Slice(samples, func(i, j int) bool {
+		return samples[i].Time.Before(samples[j].Time)
+	})
+
+	history := productinfo.NewSpotPriceHistory(*resolution, time.Duration(math.MaxInt64))
+	for _, s := range samples {
+		if (*provider != "" && s.Provider != *provider) || (*region != "" && s.Region != *region) || (*instType != "" && s.InstanceType != *instType) {
+			continue
+		}
+		history.Add(s)
+	}
+
+	seasonLength := int(24 * time.Hour / *resolution)
+	trainSteps := int(*train / *resolution)
+	horizonSteps := int(*horizon / *resolution)
+
+	var keys []productinfo.SpotPriceSample
+	allSeries := history.AllSeries()
+	for k := range allSeries {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return fmt.Sprint(keys[i]) < fmt.Sprint(keys[j])
+	})
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "PROVIDER\tREGION\tTYPE\tZONE\tFORECASTS\tMAE\tRMSE\tMAPE\tCOVERAGE")
+	var total forecast.BacktestResult
+	for _, k := range keys {
+		res, err := forecast.Backtest(allSeries[k].Values, seasonLength, trainSteps, horizonSteps, *confidence)
+		if err != nil {
+			continue
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%d\t%.5f\t%.5f\t%.2f%%\t%.2f%%\n", k.Provider, k.Region, k.InstanceType, k.Zone,
+			res.Forecasts, res.MAE, res.RMSE, res.MAPE*100, res.Coverage*100)
+		n := float64(res.Forecasts)
+		total.MAE += res.MAE * n
+		total.RMSE += res.RMSE * res.RMSE * n
+		total.MAPE += res.MAPE * n
+		total.Coverage += res.Coverage * n
+		total.Forecasts += res.Forecasts
+	}
+	w.Flush()
+
+	if total.Forecasts == 0 {
+		fmt.Println("no series is long enough for the given training window and horizon")
+		return
+	}
+	n := float64(total.Forecasts)
+	fmt.Printf("\noverall: forecasts=%d mae=%.5f rmse=%.5f mape=%.2f%% coverage=%.2f%%\n", total.Forecasts, total.MAE/n,
+		math.Sqrt(total.RMSE/n), total.MAPE/n*100, total.Coverage/n*100)
+}
+
+func quitOnError(msg string, err error) {
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s : %s\n", msg, err.Error())
+		os.Exit(1)
+	}
+}
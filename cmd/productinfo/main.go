@@ -14,7 +14,9 @@ package main
 
 import (
 	"context"
+	"fmt"
 	"os"
+	"strings"
 	"time"
 
 	"github.com/patrickmn/go-cache"
@@ -24,10 +26,12 @@ import (
 	"github.com/banzaicloud/go-gin-prometheus"
 	"github.com/banzaicloud/productinfo/internal/app/productinfo/api"
 	"github.com/banzaicloud/productinfo/pkg/productinfo"
+	"github.com/banzaicloud/productinfo/pkg/productinfo/alert"
 	"github.com/banzaicloud/productinfo/pkg/productinfo/azure"
 	"github.com/banzaicloud/productinfo/pkg/productinfo/ec2"
 	"github.com/banzaicloud/productinfo/pkg/productinfo/gce"
 	"github.com/banzaicloud/productinfo/pkg/productinfo/oci"
+	"github.com/banzaicloud/productinfo/pkg/productinfo/sink"
 	"github.com/gin-gonic/gin"
 	"github.com/prometheus/client_golang/prometheus"
 	flag "github.com/spf13/pflag"
@@ -41,6 +45,41 @@ const (
 	prodInfRenewalIntervalFlag = "product-info-renewal-interval"
 	prometheusAddressFlag      = "prometheus-address"
 	prometheusQueryFlag        = "prometheus-query"
+	promRegionLabelFlag        = "prometheus-region-label"
+	promInstanceTypeLabelFlag  = "prometheus-instance-type-label"
+	promZoneLabelFlag          = "prometheus-zone-label"
+	remoteReadAddressFlag      = "prometheus-remote-read-address"
+	remoteReadMetricFlag       = "prometheus-remote-read-metric"
+	remoteReadMatchersFlag     = "prometheus-remote-read-matchers"
+	remoteReadLookbackFlag     = "prometheus-remote-read-lookback"
+	spotPriceFileFlag          = "spot-price-file"
+	spotPriceSourcesFlag       = "spot-price-sources"
+	spotPriceFallbackFlag      = "spot-price-fallback"
+	spotPriceProvidersFlag     = "spot-price-providers"
+	spotPriceRecordFileFlag    = "spot-price-record-file"
+	changeThresholdFlag        = "change-price-threshold"
+	changeWebhookURLFlag       = "change-webhook-url"
+	changeWebhookSecretFlag    = "change-webhook-secret"
+	changeWebhookRetriesFlag   = "change-webhook-retries"
+	changeNatsAddressFlag      = "change-nats-address"
+	changeNatsSubjectFlag      = "change-nats-subject"
+	changeKafkaRestFlag        = "change-kafka-rest-address"
+	changeKafkaTopicFlag       = "change-kafka-topic"
+	alertRulesFileFlag         = "alert-rules-file"
+	catalogMaxShrinkFlag       = "catalog-max-shrink"
+	catalogMaxPriceDeltaFlag   = "catalog-max-price-delta"
+	catalogRejectZeroPriceFlag = "catalog-reject-zero-price"
+	catalogRequiredAttrsFlag   = "catalog-required-attributes"
+	catalogRebaselineAfterFlag = "catalog-rebaseline-after"
+	ec2SavingsPlansURLFlag     = "ec2-savings-plans-url"
+	ec2OfferFilesFlag          = "ec2-offer-files"
+	azureReservationsFlag      = "azure-reservation-prices"
+	azurePricesURLFlag         = "azure-prices-url"
+	azureEnvironmentFlag       = "azure-environment"
+	azureOfferFlag             = "azure-offer"
+	azureInstanceFlag          = "azure-instance"
+	ec2InstanceFlag            = "ec2-instance"
+	gcePriceListFlag           = "gce-price-list"
 	providerFlag               = "provider"
 	helpFlag                   = "help"
 	metricsEnabledFlag         = "metrics-enabled"
@@ -69,9 +108,50 @@ func defineFlags() {
 		"price metrics via banzaicloud/spot-price-exporter. If empty, the productinfo app will use current spot prices queried directly from the AWS API.")
 	flag.String(prometheusQueryFlag, "avg_over_time(aws_spot_current_price{region=\"%s\", product_description=\"Linux/UNIX\"}[1w])",
 		"advanced configuration: change the query used to query spot price info from Prometheus.")
+	flag.String(promRegionLabelFlag, productinfo.DefaultPrometheusLabels.Region, "advanced configuration: the label of the spot price metrics holding the region")
+	flag.String(promInstanceTypeLabelFlag, productinfo.DefaultPrometheusLabels.InstanceType, "advanced configuration: the label of the spot price metrics holding the instance type")
+	flag.String(promZoneLabelFlag, productinfo.DefaultPrometheusLabels.Zone, "advanced configuration: the label of the spot price metrics holding the availability zone")
+	flag.String(remoteReadAddressFlag, "", "url of a Prometheus remote read endpoint that has spot price metrics")
+	flag.String(remoteReadMetricFlag, "aws_spot_current_price", "the name of the spot price metric queried through the remote read endpoint")
+	flag.StringSlice(remoteReadMatchersFlag, []string{"product_description=Linux/UNIX"}, "additional label matchers of the remote read query in the format <label>=<value>")
+	flag.Duration(remoteReadLookbackFlag, 7*24*time.Hour, "the time window spot price samples are averaged over when using the remote read endpoint")
+	flag.String(spotPriceFileFlag, "", "path of a JSON file with static spot prices keyed by region, instance type and zone")
+	flag.StringSlice(spotPriceSourcesFlag, []string{"prometheus"}, "the spot price sources to be queried in order. "+
+		"Supported sources: prometheus, prometheus-remote-read, static. The current prices of the provider are always used as the last resort.")
+	flag.String(spotPriceFallbackFlag, string(productinfo.SpotFallbackOnEmpty), "when to query the next spot price source: error, empty or merge")
+	flag.StringSlice(spotPriceProvidersFlag, []string{Ec2}, "the providers the spot price sources are queried for, the sources must hold the regions and instance types of these providers")
+	flag.String(spotPriceRecordFileFlag, "", "path of a CSV file the collected spot prices are appended to, used for backtesting the spot price forecasts")
+	flag.Float64(changeThresholdFlag, 0.05, "the minimum ratio of a price change to emit a catalog change event")
+	flag.StringSlice(changeWebhookURLFlag, []string{}, "urls the catalog change events are posted to")
+	flag.String(changeWebhookSecretFlag, "", "secret used to sign the catalog change webhook requests with HMAC-SHA256")
+	flag.Int(changeWebhookRetriesFlag, 3, "the number of retries of a failed catalog change webhook delivery")
+	flag.String(changeNatsAddressFlag, "", "host:port of a NATS server the catalog change events are published to")
+	flag.String(changeNatsSubjectFlag, "productinfo.changes", "the NATS subject prefix of the catalog change events")
+	flag.String(changeKafkaRestFlag, "", "url of a Kafka REST Proxy (v2 API) the catalog change events are produced through, brokers can't be addressed directly")
+	flag.String(changeKafkaTopicFlag, "productinfo-changes", "the Kafka topic of the catalog change events")
+	flag.String(alertRulesFileFlag, "", "path of the JSON file the price alert rules are persisted to, rules are kept in memory only if empty")
+	flag.Float64(catalogMaxShrinkFlag, 0.5, "the maximum ratio of the instance types or prices of a region that may disappear in a renewal, 0 disables the check")
+	flag.Float64(catalogMaxPriceDeltaFlag, 1, "the maximum relative change of an on demand price in a renewal, 0 disables the check")
+	flag.Bool(catalogRejectZeroPriceFlag, false, "reject catalogs with instance types without an on demand price")
+	flag.StringSlice(catalogRequiredAttrsFlag, []string{productinfo.Cpu, productinfo.Memory}, "the attributes every instance type of a catalog must have: cpu, memory, gpu, ntwPerf")
+	flag.Int(catalogRebaselineAfterFlag, 3, "the number of consecutive rejections with the same violations a catalog is accepted after as the new baseline, 0 keeps rejecting it")
+	flag.String(ec2SavingsPlansURLFlag, "https://pricing.us-east-1.amazonaws.com", "base url of the AWS Price List the savings plan offer files are downloaded from, savings plans are not retrieved if empty")
+	flag.String(ec2OfferFilesFlag, "", "base url of the AWS Price List, e.g. https://pricing.us-east-1.amazonaws.com, or path of a directory with <region>.json files the EC2 offer files are read from, the Price List API is queried if empty or the offer file can't be read")
+	flag.StringArray(ec2InstanceFlag, []string{}, "an additional EC2 product info provider in the format <provider>:<key>=<value>,..., "+
+		"keys: partition, profile, pricing-region, pricing-profile, currency, savings-plans-url, offer-files. "+
+		"The provider must be listed in the providers, the urls not given are taken from the ec2 flags")
 	flag.String(gceApiKeyFlag, "", "GCE API key to use for getting SKUs")
+	flag.String(gcePriceListFlag, "", "url or path of a JSON file in the format of the Google Cloud pricing calculator the GCE prices are read from, e.g. "+
+		gce.CalculatorPriceListURL+", the Cloud Billing API is queried if empty")
 	flag.StringSlice(providerFlag, []string{Ec2, Gce, Azure, Oracle}, "Providers that will be used with the productinfo application.")
 	flag.String(azureSubscriptionId, "", "Azure subscription ID to use with the APIs")
+	flag.String(azurePricesURLFlag, azure.RetailPricesURL, "url of the Azure Retail Prices API, the deprecated Rate Card API is used if empty")
+	flag.String(azureReservationsFlag, azure.RetailPricesURL, "url of the Azure Retail Prices API or path of a JSON file in its format the reservation prices are read from, reservations are not retrieved if empty")
+	flag.String(azureEnvironmentFlag, "AzurePublicCloud", "the Azure cloud: AzurePublicCloud, AzureChinaCloud, AzureUSGovernmentCloud or AzureGermanCloud")
+	flag.String(azureOfferFlag, azure.PayAsYouGoOffer, "the offer durable id of the Azure subscription the Rate Card prices are queried for")
+	flag.StringArray(azureInstanceFlag, []string{}, "an additional Azure product info provider in the format <provider>:<key>=<value>,..., "+
+		"keys: subscription-id, environment, auth-location, offer, currency, locale, region-info, prices-url, reservation-prices. "+
+		"The provider must be listed in the providers, the settings not given are taken from the azure flags")
 	flag.Bool(helpFlag, false, "print usage")
 	flag.Bool(metricsEnabledFlag, false, "internal metrics are exposed if enabled")
 	flag.String(metricsAddressFlag, ":9900", "the address where internal metrics are exposed")
@@ -109,6 +189,9 @@ func init() {
 	prometheus.MustRegister(productinfo.ScrapeDurationGauge)
 	prometheus.MustRegister(productinfo.ScrapeFailuresTotalCounter)
 	prometheus.MustRegister(productinfo.RegionFailuresTotalCounter)
+	prometheus.MustRegister(productinfo.CatalogRejectionsTotalCounter)
+	prometheus.MustRegister(productinfo.CatalogRejectedGauge)
+	prometheus.MustRegister(productinfo.QualityIssuesGauge)
 }
 
 func main() {
@@ -122,6 +205,35 @@ func main() {
 		cache.New(24*time.Hour, 24.*time.Hour), infoers())
 	quitOnError("error encountered", err)
 
+	if src := spotPriceSource(); src != nil {
+		for _, p := range viper.GetStringSlice(spotPriceProvidersFlag) {
+			prodInfo.SetSpotPriceSource(p, src)
+		}
+	}
+
+	if recordFile := viper.GetString(spotPriceRecordFileFlag); recordFile != "" {
+		f, err := os.OpenFile(recordFile, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+		quitOnError("could not open spot price record file", err)
+		defer f.Close()
+		prodInfo.SpotPriceHistory().RecordTo(f)
+	}
+
+	guardRules := productinfo.GuardRules{
+		MaxShrink:          viper.GetFloat64(catalogMaxShrinkFlag),
+		MaxPriceDelta:      viper.GetFloat64(catalogMaxPriceDeltaFlag),
+		RejectZeroOnDemand: viper.GetBool(catalogRejectZeroPriceFlag),
+		RequiredAttributes: viper.GetStringSlice(catalogRequiredAttrsFlag),
+		RebaselineAfter:    viper.GetInt(catalogRebaselineAfterFlag),
+	}
+	quitOnError("invalid catalog guard rules", guardRules.Validate())
+	prodInfo.CatalogGuard().SetRules(guardRules)
+
+	configureChangeSinks(prodInfo.Changes())
+
+	alerts, err := alert.NewManager(viper.GetString(alertRulesFileFlag), prodInfo, alert.WebhookNotifier{Retries: 3, Backoff: time.Second})
+	quitOnError("could not load alert rules", err)
+	prodInfo.AddPriceListener(alerts)
+
 	go prodInfo.Start(context.Background())
 
 	quitOnError("error encountered", err)
@@ -129,7 +241,7 @@ func main() {
 	// configure the gin validator
 	api.ConfigureValidator(viper.GetStringSlice(providerFlag), prodInfo)
 
-	routeHandler := api.NewRouteHandler(prodInfo)
+	routeHandler := api.NewRouteHandler(prodInfo, alerts)
 
 	// new default gin engine (recovery, logger middleware)
 	router := gin.Default()
@@ -151,21 +263,37 @@ func main() {
 func infoers() map[string]productinfo.ProductInfoer {
 	providers := viper.GetStringSlice(providerFlag)
 	infoers := make(map[string]productinfo.ProductInfoer, len(providers))
+	azureInstances, err := azureInstanceConfigs()
+	quitOnError("invalid azure instance", err)
+	ec2Instances, err := ec2InstanceConfigs()
+	quitOnError("invalid ec2 instance", err)
 	for _, p := range providers {
 		var infoer productinfo.ProductInfoer
 		var err error
 
 		switch p {
 		case Ec2:
-			infoer, err = ec2.NewEc2Infoer(viper.GetString(prometheusAddressFlag), viper.GetString(prometheusQueryFlag))
+			infoer, err = ec2.NewEc2Infoer(ec2.Config{
+				SavingsPlanSource: savingsPlanSource(viper.GetString(ec2SavingsPlansURLFlag)),
+				ProductSource:     productSource(viper.GetString(ec2OfferFilesFlag)),
+			})
 		case Gce:
-			infoer, err = gce.NewGceInfoer(viper.GetString(gceApiKeyFlag))
+			infoer, err = gce.NewGceInfoer(gce.Config{
+				ApiKey:          viper.GetString(gceApiKeyFlag),
+				PriceListSource: priceListSource(viper.GetString(gcePriceListFlag)),
+			})
 		case Azure:
-			infoer, err = azure.NewAzureInfoer(viper.GetString(azureSubscriptionId))
+			infoer, err = azure.NewAzureInfoer(azureConfig())
 		case Oracle:
 			infoer, err = oci.NewInfoer()
 		default:
-			log.Fatalf("provider %s is not supported", p)
+			if cfg, ok := ec2Instances[p]; ok {
+				infoer, err = ec2.NewEc2Infoer(cfg)
+			} else if cfg, ok := azureInstances[p]; ok {
+				infoer, err = azure.NewAzureInfoer(cfg)
+			} else {
+				log.Fatalf("provider %s is not supported", p)
+			}
 		}
 
 		quitOnError("could not initialize product info provider", err)
@@ -176,6 +304,231 @@ func infoers() map[string]productinfo.ProductInfoer {
 	return infoers
 }
 
+// configureChangeSinks registers the catalog change event sinks set up by the flags
+func configureChangeSinks(n *productinfo.ChangeNotifier) {
+	n.SetThreshold(viper.GetFloat64(changeThresholdFlag))
+	for _, url := range viper.GetStringSlice(changeWebhookURLFlag) {
+		n.AddSink(sink.NewWebhookSink(url, viper.GetString(changeWebhookSecretFlag), viper.GetInt(changeWebhookRetriesFlag), time.Second))
+	}
+	if addr := viper.GetString(changeNatsAddressFlag); addr != "" {
+		n.AddSink(sink.NewNatsSink(addr, viper.GetString(changeNatsSubjectFlag)))
+	}
+	if addr := viper.GetString(changeKafkaRestFlag); addr != "" {
+		n.AddSink(sink.NewKafkaSink(addr, viper.GetString(changeKafkaTopicFlag)))
+	}
+}
+
+// savingsPlanSource creates the source of the EC2 savings plan prices at the given url, or returns nil if it's empty
+func savingsPlanSource(url string) ec2.SavingsPlanSource {
+	if url != "" {
+		return ec2.NewOfferFileSavingsPlanSource(url)
+	}
+	return nil
+}
+
+// productSource creates the source of the EC2 products reading the offer files at the given location, or returns nil if
+// it's empty
+func productSource(location string) ec2.ProductSource {
+	if location != "" {
+		return ec2.NewOfferFileProductSource(location)
+	}
+	return nil
+}
+
+// reservationSource creates the source of the Azure reservation prices at the given location, or returns nil if it's empty
+func reservationSource(location string) azure.ReservationSource {
+	if location != "" {
+		return azure.NewRetailPricesReservationSource(location)
+	}
+	return nil
+}
+
+// priceListSource creates the source of the GCE prices reading the price list at the given location, or returns nil if
+// it's empty
+func priceListSource(location string) gce.PriceListSource {
+	if location != "" {
+		return gce.NewCalculatorPriceListSource(location)
+	}
+	return nil
+}
+
+// azureConfig returns the config of the Azure product info provider set by the azure flags
+func azureConfig() azure.Config {
+	return azure.Config{
+		SubscriptionId:    viper.GetString(azureSubscriptionId),
+		Environment:       viper.GetString(azureEnvironmentFlag),
+		OfferDurableId:    viper.GetString(azureOfferFlag),
+		RetailPricesURL:   viper.GetString(azurePricesURLFlag),
+		ReservationSource: reservationSource(viper.GetString(azureReservationsFlag)),
+	}
+}
+
+// parseInstanceSpec parses the provider and the settings of an additional product info provider given in the format
+// <provider>:<key>=<value>,...
+func parseInstanceSpec(spec string) (string, map[string]string, error) {
+	parts := strings.SplitN(spec, ":", 2)
+	if len(parts) != 2 || parts[0] == "" {
+		return "", nil, fmt.Errorf("%s is not in the format <provider>:<key>=<value>,...", spec)
+	}
+	settings := make(map[string]string)
+	for _, setting := range strings.Split(parts[1], ",") {
+		kv := strings.SplitN(setting, "=", 2)
+		if len(kv) != 2 {
+			return "", nil, fmt.Errorf("%s of %s is not in the format <key>=<value>", setting, parts[0])
+		}
+		settings[kv[0]] = kv[1]
+	}
+	return parts[0], settings, nil
+}
+
+// azureInstanceConfigs parses the additional Azure product info providers set by the azure-instance flags
+func azureInstanceConfigs() (map[string]azure.Config, error) {
+	specs, err := flag.CommandLine.GetStringArray(azureInstanceFlag)
+	if err != nil {
+		return nil, err
+	}
+	configs := make(map[string]azure.Config, len(specs))
+	for _, spec := range specs {
+		provider, settings, err := parseInstanceSpec(spec)
+		if err != nil {
+			return nil, err
+		}
+		cfg := azureConfig()
+		reservations := viper.GetString(azureReservationsFlag)
+		for key, value := range settings {
+			switch key {
+			case "subscription-id":
+				cfg.SubscriptionId = value
+			case "environment":
+				cfg.Environment = value
+			case "auth-location":
+				cfg.AuthLocation = value
+			case "offer":
+				cfg.OfferDurableId = value
+			case "currency":
+				cfg.Currency = value
+			case "locale":
+				cfg.Locale = value
+			case "region-info":
+				cfg.RegionInfo = value
+			case "prices-url":
+				cfg.RetailPricesURL = value
+			case "reservation-prices":
+				reservations = value
+			default:
+				return nil, fmt.Errorf("unknown setting %s of %s", key, provider)
+			}
+		}
+		cfg.ReservationSource = reservationSource(reservations)
+		configs[provider] = cfg
+	}
+	return configs, nil
+}
+
+// ec2InstanceConfigs parses the additional EC2 product info providers set by the ec2-instance flags
+func ec2InstanceConfigs() (map[string]ec2.Config, error) {
+	specs, err := flag.CommandLine.GetStringArray(ec2InstanceFlag)
+	if err != nil {
+		return nil, err
+	}
+	configs := make(map[string]ec2.Config, len(specs))
+	for _, spec := range specs {
+		provider, settings, err := parseInstanceSpec(spec)
+		if err != nil {
+			return nil, err
+		}
+		var cfg ec2.Config
+		savingsPlansURL := viper.GetString(ec2SavingsPlansURLFlag)
+		offerFiles := viper.GetString(ec2OfferFilesFlag)
+		for key, value := range settings {
+			switch key {
+			case "partition":
+				cfg.Partition = value
+			case "profile":
+				cfg.Profile = value
+			case "pricing-region":
+				cfg.PricingRegion = value
+			case "pricing-profile":
+				cfg.PricingProfile = value
+			case "currency":
+				cfg.Currency = value
+			case "savings-plans-url":
+				savingsPlansURL = value
+			case "offer-files":
+				offerFiles = value
+			default:
+				return nil, fmt.Errorf("unknown setting %s of %s", key, provider)
+			}
+		}
+		cfg.SavingsPlanSource = savingsPlanSource(savingsPlansURL)
+		cfg.ProductSource = productSource(offerFiles)
+		configs[provider] = cfg
+	}
+	return configs, nil
+}
+
+// spotPriceSource assembles the configured spot price sources into a chain, returns nil if no source is available
+func spotPriceSource() productinfo.SpotPriceSource {
+	labels := productinfo.PrometheusLabels{
+		Region:       viper.GetString(promRegionLabelFlag),
+		InstanceType: viper.GetString(promInstanceTypeLabelFlag),
+		Zone:         viper.GetString(promZoneLabelFlag),
+	}
+
+	var sources []productinfo.SpotPriceSource
+	for _, s := range viper.GetStringSlice(spotPriceSourcesFlag) {
+		switch s {
+		case "prometheus":
+			if viper.GetString(prometheusAddressFlag) == "" {
+				log.Warn("Prometheus API address is not set, skipping Prometheus spot price source.")
+				continue
+			}
+			src, err := productinfo.NewPrometheusSpotPriceSource(viper.GetString(prometheusAddressFlag), viper.GetString(prometheusQueryFlag), labels)
+			if err != nil {
+				log.WithError(err).Warn("Error creating Prometheus client, skipping Prometheus spot price source.")
+				continue
+			}
+			sources = append(sources, src)
+		case "prometheus-remote-read":
+			if viper.GetString(remoteReadAddressFlag) == "" {
+				log.Warn("Prometheus remote read address is not set, skipping remote read spot price source.")
+				continue
+			}
+			matchers, err := remoteReadMatchers(viper.GetStringSlice(remoteReadMatchersFlag))
+			quitOnError("invalid remote read matchers", err)
+			sources = append(sources, productinfo.NewPrometheusRemoteReadSpotPriceSource(viper.GetString(remoteReadAddressFlag),
+				viper.GetString(remoteReadMetricFlag), matchers, labels, viper.GetDuration(remoteReadLookbackFlag)))
+		case "static":
+			if viper.GetString(spotPriceFileFlag) == "" {
+				log.Warn("spot price file is not set, skipping static spot price source.")
+				continue
+			}
+			sources = append(sources, productinfo.NewStaticSpotPriceSource(viper.GetString(spotPriceFileFlag)))
+		default:
+			log.Fatalf("spot price source %s is not supported", s)
+		}
+	}
+	if len(sources) == 0 {
+		return nil
+	}
+	rule, err := productinfo.ParseSpotFallbackRule(viper.GetString(spotPriceFallbackFlag))
+	quitOnError("invalid spot price configuration", err)
+	return productinfo.NewSpotPriceSourceChain(rule, sources...)
+}
+
+// remoteReadMatchers parses the label matchers of the remote read query given in the format <label>=<value>
+func remoteReadMatchers(specs []string) (map[string]string, error) {
+	matchers := make(map[string]string, len(specs))
+	for _, spec := range specs {
+		kv := strings.SplitN(spec, "=", 2)
+		if len(kv) != 2 || kv[0] == "" {
+			return nil, fmt.Errorf("invalid label matcher: %s", spec)
+		}
+		matchers[kv[0]] = kv[1]
+	}
+	return matchers, nil
+}
+
 func quitOnError(msg string, err error) {
 	if err != nil {
 		log.Errorf("%s : %s", msg, err.Error())
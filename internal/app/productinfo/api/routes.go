@@ -4,8 +4,12 @@ import (
 	"fmt"
 	"net/http"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/banzaicloud/productinfo/pkg/productinfo"
+	"github.com/banzaicloud/productinfo/pkg/productinfo/alert"
 	"github.com/gin-contrib/cors"
 	"github.com/gin-contrib/static"
 	"github.com/gin-gonic/gin"
@@ -18,17 +22,21 @@ const (
 	providerParam  = "provider"
 	regionParam    = "region"
 	attributeParam = "attribute"
+	typeParam      = "type"
+	idParam        = "id"
 )
 
 // RouteHandler configures the REST API routes in the gin router
 type RouteHandler struct {
-	prod *productinfo.CachingProductInfo
+	prod   *productinfo.CachingProductInfo
+	alerts *alert.Manager
 }
 
 // NewRouteHandler creates a new RouteHandler and returns a reference to it
-func NewRouteHandler(p *productinfo.CachingProductInfo) *RouteHandler {
+func NewRouteHandler(p *productinfo.CachingProductInfo, alerts *alert.Manager) *RouteHandler {
 	return &RouteHandler{
-		prod: p,
+		prod:   p,
+		alerts: alerts,
 	}
 }
 
@@ -82,6 +90,67 @@ func (r *RouteHandler) ConfigureRoutes(router *gin.Engine) {
 		metaGroup.GET("/:provider/:region", r.getRegion).Use(ValidateRegionData(v))
 	}
 
+	priceGroup := v1.Group("/prices")
+	{
+		priceGroup.Use(ValidatePathParam(providerParam, v, "provider"))
+		priceGroup.Use(ValidateRegionData(v))
+		priceGroup.GET("/:provider/:region/:type", r.getPrice)
+	}
+
+	forecastGroup := v1.Group("/forecast")
+	{
+		forecastGroup.Use(ValidatePathParam(providerParam, v, "provider"))
+		forecastGroup.Use(ValidateRegionData(v))
+		forecastGroup.GET("/:provider/:region/:type", r.getSpotPriceForecast)
+	}
+
+	customGroup := v1.Group("/custom")
+	{
+		customGroup.Use(ValidatePathParam(providerParam, v, "provider"))
+		customGroup.Use(ValidateRegionData(v))
+		customGroup.GET("/:provider/:region", r.getCustomMachineTypePrice)
+	}
+
+	addOnGroup := v1.Group("/addons")
+	{
+		addOnGroup.Use(ValidatePathParam(providerParam, v, "provider"))
+		addOnGroup.Use(ValidateRegionData(v))
+		addOnGroup.GET("/:provider/:region", r.getAddOns)
+		addOnGroup.GET("/:provider/:region/:type", r.getAddOnPrice)
+	}
+
+	changesGroup := v1.Group("/changes")
+	{
+		changesGroup.GET("/", r.getChanges)
+	}
+
+	catalogGroup := v1.Group("/catalogs")
+	{
+		catalogGroup.GET("/status", r.getCatalogStatus)
+	}
+
+	rejectedGroup := v1.Group("/catalogs/rejected")
+	{
+		rejectedGroup.Use(ValidatePathParam(providerParam, v, "provider"))
+		rejectedGroup.Use(ValidateRegionData(v))
+		rejectedGroup.GET("/:provider/:region", r.getRejectedCatalogs)
+	}
+
+	qualityGroup := v1.Group("/quality")
+	{
+		qualityGroup.Use(ValidatePathParam(providerParam, v, "provider"))
+		qualityGroup.GET("/:provider", r.getQualityReports)
+	}
+
+	alertGroup := v1.Group("/alerts")
+	{
+		alertGroup.GET("/", r.getAlertRules)
+		alertGroup.POST("/", r.createAlertRule)
+		alertGroup.GET("/:id", r.getAlertRule)
+		alertGroup.PUT("/:id", r.updateAlertRule)
+		alertGroup.DELETE("/:id", r.deleteAlertRule)
+	}
+
 	providerGroup := v1.Group("/providers")
 	{
 		providerGroup.GET("/", r.getProviders)
@@ -96,6 +165,9 @@ func (r *RouteHandler) signalStatus(c *gin.Context) {
 // swagger:route GET /products/{provider}/{region} products getProductDetails
 //
 // Provides a list of available machine types on a given provider in a specific region.
+// The prices are the prices of the operating system given in the os query parameter, Linux by default.
+// The tenancy query parameter selects the shared (default) or dedicated instances, or the dedicated hosts.
+// If the monthlyUsage query parameter (0-100) is set, the effective monthly prices are computed for the usage.
 //
 //     Produces:
 //     - application/json
@@ -109,11 +181,29 @@ func (r *RouteHandler) signalStatus(c *gin.Context) {
 func (r *RouteHandler) getProductDetails(c *gin.Context) {
 	prov := c.Param(providerParam)
 	region := c.Param(regionParam)
+	opSys, tenancy, ok := osAndTenancy(c)
+	if !ok {
+		return
+	}
 
-	log.Infof("getting product details for provider: %s, region: %s", prov, region)
+	var usage float64
+	if monthlyUsage := c.Query("monthlyUsage"); monthlyUsage != "" {
+		var err error
+		if usage, err = strconv.ParseFloat(monthlyUsage, 64); err != nil || usage < 0 || usage > 100 {
+			c.JSON(http.StatusBadRequest, gin.H{"code": "bad_params", "message": "monthly usage must be a percentage between 0 and 100", "params": map[string]string{"monthlyUsage": monthlyUsage}})
+			return
+		}
+	}
 
-	var err error
-	if details, err := r.prod.GetProductDetails(prov, region); err == nil {
+	log.Infof("getting product details for provider: %s, region: %s, os: %s, tenancy: %s", prov, region, opSys, tenancy)
+
+	details, err := r.prod.GetProductDetailsForTenancy(prov, region, opSys, tenancy)
+	if err == nil {
+		if usage > 0 {
+			for i, d := range details {
+				details[i].EffectiveMonthlyPrice = r.prod.EffectiveMonthlyPrice(prov, region, d.Type, opSys, d.OnDemandPrice, usage/100)
+			}
+		}
 		log.Debugf("successfully retrieved product details:  %s, region: %s", prov, region)
 		c.JSON(http.StatusOK, ProductDetailsResponse{details})
 		return
@@ -122,6 +212,88 @@ func (r *RouteHandler) getProductDetails(c *gin.Context) {
 	c.JSON(http.StatusInternalServerError, gin.H{"status": http.StatusInternalServerError, "message": fmt.Sprintf("%s", err)})
 }
 
+// swagger:route GET /prices/{provider}/{region}/{type} prices getPrice
+//
+// Provides the on demand price and the spot price averaged over the availability zones of an instance type.
+// The os and tenancy query parameters select the prices like on the products route, only shared instances have spot prices.
+// The zones query parameter is a comma separated list of zones, every zone of the region by default.
+//
+//     Produces:
+//     - application/json
+//
+//     Schemes: http
+//
+//     Security:
+//
+//     Responses:
+//       200: PriceResponse
+func (r *RouteHandler) getPrice(c *gin.Context) {
+	prov := c.Param(providerParam)
+	region := c.Param(regionParam)
+	instType := c.Param(typeParam)
+	opSys, tenancy, ok := osAndTenancy(c)
+	if !ok {
+		return
+	}
+	var zones []string
+	if z := c.Query("zones"); z != "" {
+		zones = strings.Split(z, ",")
+	} else {
+		var err error
+		if zones, err = r.prod.GetZones(prov, region); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"status": http.StatusInternalServerError, "message": fmt.Sprintf("%s", err)})
+			return
+		}
+	}
+
+	log.Infof("getting price for provider: %s, region: %s, type: %s, os: %s, tenancy: %s", prov, region, instType, opSys, tenancy)
+
+	onDemand, spot, err := r.prod.GetTenancyPrice(prov, region, instType, opSys, tenancy, zones)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"status": http.StatusNotFound, "message": fmt.Sprintf("%s", err)})
+		return
+	}
+	if onDemand <= 0 && spot <= 0 {
+		c.JSON(http.StatusNotFound, gin.H{"status": http.StatusNotFound, "message": fmt.Sprintf("no %s %s price of instance type: %s", tenancy, opSys, instType)})
+		return
+	}
+	c.JSON(http.StatusOK, PriceResponse{Type: instType, Os: opSys, Tenancy: tenancy, Zones: zones, OnDemandPrice: onDemand, SpotPrice: spot})
+}
+
+// osAndTenancy returns the operating system and the tenancy query parameters, Linux and shared by default, false is
+// returned if one of them is not supported and the error response is sent
+func osAndTenancy(c *gin.Context) (string, string, bool) {
+	opSys := c.DefaultQuery("os", productinfo.Linux)
+	if !isOperatingSystem(opSys) {
+		c.JSON(http.StatusBadRequest, gin.H{"code": "bad_params", "message": "unsupported operating system", "params": map[string]string{"os": opSys}})
+		return "", "", false
+	}
+	tenancy := c.DefaultQuery("tenancy", productinfo.Shared)
+	if !isTenancy(tenancy) {
+		c.JSON(http.StatusBadRequest, gin.H{"code": "bad_params", "message": "unsupported tenancy", "params": map[string]string{"tenancy": tenancy}})
+		return "", "", false
+	}
+	return opSys, tenancy, true
+}
+
+func isOperatingSystem(opSys string) bool {
+	for _, o := range productinfo.OperatingSystems {
+		if o == opSys {
+			return true
+		}
+	}
+	return false
+}
+
+func isTenancy(tenancy string) bool {
+	for _, t := range productinfo.Tenancies {
+		if t == tenancy {
+			return true
+		}
+	}
+	return false
+}
+
 // swagger:route GET /products/{provider}/{region}/{attribute} attributes getAttributeValues
 //
 // Provides a list of available attribute values in a provider's region.
@@ -206,7 +378,7 @@ func (r *RouteHandler) getRegion(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, gin.H{"status": http.StatusInternalServerError, "message": fmt.Sprintf("%s", err)})
 		return
 	}
-	c.JSON(http.StatusOK, GetRegionResp{region, regions[region], zones})
+	c.JSON(http.StatusOK, GetRegionResp{region, regions[region], zones, r.prod.GetZoneInstanceTypes(provider, region)})
 }
 
 // swagger:route GET /providers providers getProviders
@@ -230,3 +402,415 @@ func (r *RouteHandler) getProviders(c *gin.Context) {
 	}
 	c.JSON(http.StatusOK, providers)
 }
+
+// swagger:route GET /forecast/{provider}/{region}/{type} forecast getSpotPriceForecast
+//
+// Provides the forecasted spot prices of an instance type per availability zone with confidence intervals
+//
+//     Produces:
+//     - application/json
+//
+//     Schemes: http
+//
+//     Security:
+//
+//     Responses:
+//       200: SpotPriceForecastResponse
+func (r *RouteHandler) getSpotPriceForecast(c *gin.Context) {
+	prov := c.Param(providerParam)
+	region := c.Param(regionParam)
+	instType := c.Param(typeParam)
+
+	horizon, err := time.ParseDuration(c.DefaultQuery("horizon", "24h"))
+	if err != nil || horizon <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"code": "bad_params", "message": "invalid horizon parameter", "params": map[string]string{"horizon": c.Query("horizon")}})
+		return
+	}
+	confidence, err := strconv.ParseFloat(c.DefaultQuery("confidence", "0.95"), 64)
+	if err != nil || confidence <= 0 || confidence >= 1 {
+		c.JSON(http.StatusBadRequest, gin.H{"code": "bad_params", "message": "invalid confidence parameter", "params": map[string]string{"confidence": c.Query("confidence")}})
+		return
+	}
+
+	log.Infof("getting spot price forecast for provider: %s, region: %s, type: %s", prov, region, instType)
+
+	forecasts, err := r.prod.GetSpotPriceForecast(prov, region, instType, horizon, confidence)
+	if err == productinfo.ErrNoSpotPriceHistory {
+		c.JSON(http.StatusNotFound, gin.H{"status": http.StatusNotFound,
+			"message": fmt.Sprintf("%s for provider: %s, region: %s, type: %s", err, prov, region, instType)})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"code": "bad_params", "message": fmt.Sprintf("%s", err)})
+		return
+	}
+	c.JSON(http.StatusOK, SpotPriceForecastResponse{Type: instType, Confidence: confidence, Zones: forecasts})
+}
+
+// swagger:route GET /custom/{provider}/{region} custom getCustomMachineTypePrice
+//
+// Validates a custom machine type and provides its on demand and spot prices
+//
+//     Produces:
+//     - application/json
+//
+//     Schemes: http
+//
+//     Security:
+//
+//     Responses:
+//       200: CustomMachineTypePriceResponse
+func (r *RouteHandler) getCustomMachineTypePrice(c *gin.Context) {
+	prov := c.Param(providerParam)
+	region := c.Param(regionParam)
+
+	pricer, ok := r.prod.GetCustomMachineTypePricer(prov)
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"status": http.StatusNotFound, "message": fmt.Sprintf("custom machine types are not supported by provider: %s", prov)})
+		return
+	}
+	mt := productinfo.CustomMachineType{Series: c.DefaultQuery("series", "n1")}
+	var err error
+	if mt.Cpus, err = strconv.ParseFloat(c.Query("cpus"), 64); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"code": "bad_params", "message": "invalid cpus parameter", "params": map[string]string{"cpus": c.Query("cpus")}})
+		return
+	}
+	if mt.Mem, err = strconv.ParseFloat(c.Query("memory"), 64); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"code": "bad_params", "message": "invalid memory parameter", "params": map[string]string{"memory": c.Query("memory")}})
+		return
+	}
+	if err := pricer.ValidateCustomMachineType(mt); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"code": "bad_params", "message": fmt.Sprintf("%s", err)})
+		return
+	}
+
+	log.Infof("getting custom machine type price for provider: %s, region: %s, shape: %+v", prov, region, mt)
+
+	price, err := pricer.PriceCustomMachineType(region, mt)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"status": http.StatusNotFound, "message": fmt.Sprintf("%s", err)})
+		return
+	}
+	c.JSON(http.StatusOK, CustomMachineTypePriceResponse(*price))
+}
+
+// swagger:route GET /addons/{provider}/{region} addons getAddOns
+//
+// Provides the GPUs and local SSDs that can be attached to the instances of a region
+//
+//     Produces:
+//     - application/json
+//
+//     Schemes: http
+//
+//     Security:
+//
+//     Responses:
+//       200: AddOnsResponse
+func (r *RouteHandler) getAddOns(c *gin.Context) {
+	prov := c.Param(providerParam)
+	region := c.Param(regionParam)
+
+	log.Infof("getting add-ons for provider: %s, region: %s", prov, region)
+
+	addOns, err := r.prod.GetAddOns(prov, region)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"status": http.StatusNotFound, "message": fmt.Sprintf("%s", err)})
+		return
+	}
+	c.JSON(http.StatusOK, AddOnsResponse(addOns))
+}
+
+// swagger:route GET /addons/{provider}/{region}/{type} addons getAddOnPrice
+//
+// Provides the on demand and spot prices of an instance type with GPUs and local SSDs attached in a zone
+//
+//     Produces:
+//     - application/json
+//
+//     Schemes: http
+//
+//     Security:
+//
+//     Responses:
+//       200: AddOnPriceResponse
+func (r *RouteHandler) getAddOnPrice(c *gin.Context) {
+	prov := c.Param(providerParam)
+	region := c.Param(regionParam)
+
+	req := productinfo.AddOnRequest{Type: c.Param(typeParam), Zone: c.Query("zone"), GpuModel: c.Query("gpuModel")}
+	if req.Zone == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"code": "bad_params", "message": "the zone parameter is required"})
+		return
+	}
+	var err error
+	if req.Gpus, err = strconv.Atoi(c.DefaultQuery("gpus", "0")); err != nil || req.Gpus < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"code": "bad_params", "message": "invalid gpus parameter", "params": map[string]string{"gpus": c.Query("gpus")}})
+		return
+	}
+	if req.LocalSsds, err = strconv.Atoi(c.DefaultQuery("localSsds", "0")); err != nil || req.LocalSsds < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"code": "bad_params", "message": "invalid localSsds parameter", "params": map[string]string{"localSsds": c.Query("localSsds")}})
+		return
+	}
+	if _, err := r.prod.GetAddOns(prov, region); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"status": http.StatusNotFound, "message": fmt.Sprintf("%s", err)})
+		return
+	}
+
+	log.Infof("getting add-on price for provider: %s, region: %s, request: %+v", prov, region, req)
+
+	price, err := r.prod.GetAddOnPrice(prov, region, req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"code": "bad_params", "message": fmt.Sprintf("%s", err)})
+		return
+	}
+	c.JSON(http.StatusOK, AddOnPriceResponse(*price))
+}
+
+// swagger:route GET /changes changes getChanges
+//
+// Provides the catalog change events in the order they were emitted, optionally filtered
+//
+//     Produces:
+//     - application/json
+//
+//     Schemes: http
+//
+//     Security:
+//
+//     Responses:
+//       200: ChangesResponse
+func (r *RouteHandler) getChanges(c *gin.Context) {
+	filter := productinfo.ChangeFilter{
+		Provider:     c.Query("provider"),
+		Region:       c.Query("region"),
+		InstanceType: c.Query("instanceType"),
+	}
+	for _, t := range c.QueryArray("type") {
+		filter.Types = append(filter.Types, productinfo.ChangeType(t))
+	}
+	if since := c.Query("sinceId"); since != "" {
+		id, err := strconv.ParseUint(since, 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"code": "bad_params", "message": "invalid sinceId parameter", "params": map[string]string{"sinceId": since}})
+			return
+		}
+		filter.SinceId = id
+	}
+	if since := c.Query("since"); since != "" {
+		t, err := time.Parse(time.RFC3339, since)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"code": "bad_params", "message": "invalid since parameter", "params": map[string]string{"since": since}})
+			return
+		}
+		filter.Since = t
+	}
+	if limit := c.Query("limit"); limit != "" {
+		l, err := strconv.Atoi(limit)
+		if err != nil || l < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"code": "bad_params", "message": "invalid limit parameter", "params": map[string]string{"limit": limit}})
+			return
+		}
+		filter.Limit = l
+	}
+	c.JSON(http.StatusOK, ChangesResponse(r.prod.Changes().Changes(filter)))
+}
+
+// swagger:route GET /catalogs/status catalogs getCatalogStatus
+//
+// Provides the result of the last sanity check of every catalog
+//
+//     Produces:
+//     - application/json
+//
+//     Schemes: http
+//
+//     Security:
+//
+//     Responses:
+//       200: CatalogStatusResponse
+func (r *RouteHandler) getCatalogStatus(c *gin.Context) {
+	c.JSON(http.StatusOK, CatalogStatusResponse(r.prod.CatalogGuard().Status()))
+}
+
+// swagger:route GET /catalogs/rejected/{provider}/{region} catalogs getRejectedCatalogs
+//
+// Provides the last rejected catalogs of a region with the violated rules
+//
+//     Produces:
+//     - application/json
+//
+//     Schemes: http
+//
+//     Security:
+//
+//     Responses:
+//       200: RejectedCatalogsResponse
+func (r *RouteHandler) getRejectedCatalogs(c *gin.Context) {
+	c.JSON(http.StatusOK, RejectedCatalogsResponse(r.prod.CatalogGuard().Rejected(c.Param(providerParam), c.Param(regionParam))))
+}
+
+// swagger:route GET /quality/{provider} quality getQualityReports
+//
+// Provides the data quality issues found in the last scrape of a provider per region
+//
+//     Produces:
+//     - application/json
+//
+//     Schemes: http
+//
+//     Security:
+//
+//     Responses:
+//       200: QualityReportsResponse
+func (r *RouteHandler) getQualityReports(c *gin.Context) {
+	reports := r.prod.QualityReports(c.Param(providerParam))
+	if region := c.Query("region"); region != "" {
+		filtered := make([]productinfo.QualityReport, 0)
+		for _, report := range reports {
+			if report.Region == region {
+				filtered = append(filtered, report)
+			}
+		}
+		reports = filtered
+	}
+	c.JSON(http.StatusOK, QualityReportsResponse(reports))
+}
+
+// swagger:route GET /alerts alerts getAlertRules
+//
+// Provides the price alert rules
+//
+//     Produces:
+//     - application/json
+//
+//     Schemes: http
+//
+//     Security:
+//
+//     Responses:
+//       200: AlertRulesResponse
+func (r *RouteHandler) getAlertRules(c *gin.Context) {
+	rules := r.alerts.Rules()
+	for i := range rules {
+		rules[i].Secret = ""
+	}
+	c.JSON(http.StatusOK, AlertRulesResponse(rules))
+}
+
+// swagger:route GET /alerts/{id} alerts getAlertRule
+//
+// Provides a price alert rule
+//
+//     Produces:
+//     - application/json
+//
+//     Schemes: http
+//
+//     Security:
+//
+//     Responses:
+//       200: AlertRuleResponse
+func (r *RouteHandler) getAlertRule(c *gin.Context) {
+	rule, err := r.alerts.Rule(c.Param(idParam))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"status": http.StatusNotFound, "message": fmt.Sprintf("%s", err)})
+		return
+	}
+	rule.Secret = ""
+	c.JSON(http.StatusOK, AlertRuleResponse(rule))
+}
+
+// swagger:route POST /alerts alerts createAlertRule
+//
+// Creates a price alert rule
+//
+//     Consumes:
+//     - application/json
+//
+//     Produces:
+//     - application/json
+//
+//     Schemes: http
+//
+//     Security:
+//
+//     Responses:
+//       201: AlertRuleResponse
+func (r *RouteHandler) createAlertRule(c *gin.Context) {
+	var rule alert.Rule
+	if err := c.BindJSON(&rule); err != nil {
+		return
+	}
+	if err := rule.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"code": "bad_params", "message": fmt.Sprintf("%s", err)})
+		return
+	}
+	rule, err := r.alerts.CreateRule(rule)
+	if err != nil {
+		r.alertRuleError(c, err)
+		return
+	}
+	rule.Secret = ""
+	c.JSON(http.StatusCreated, AlertRuleResponse(rule))
+}
+
+// swagger:route PUT /alerts/{id} alerts updateAlertRule
+//
+// Replaces a price alert rule, the alerts of the rule are reset
+//
+//     Consumes:
+//     - application/json
+//
+//     Produces:
+//     - application/json
+//
+//     Schemes: http
+//
+//     Security:
+//
+//     Responses:
+//       200: AlertRuleResponse
+func (r *RouteHandler) updateAlertRule(c *gin.Context) {
+	var rule alert.Rule
+	if err := c.BindJSON(&rule); err != nil {
+		return
+	}
+	if err := rule.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"code": "bad_params", "message": fmt.Sprintf("%s", err)})
+		return
+	}
+	rule, err := r.alerts.UpdateRule(c.Param(idParam), rule)
+	if err != nil {
+		r.alertRuleError(c, err)
+		return
+	}
+	rule.Secret = ""
+	c.JSON(http.StatusOK, AlertRuleResponse(rule))
+}
+
+// swagger:route DELETE /alerts/{id} alerts deleteAlertRule
+//
+// Deletes a price alert rule
+//
+//     Schemes: http
+//
+//     Security:
+//
+//     Responses:
+//       204:
+func (r *RouteHandler) deleteAlertRule(c *gin.Context) {
+	if err := r.alerts.DeleteRule(c.Param(idParam)); err != nil {
+		r.alertRuleError(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+func (r *RouteHandler) alertRuleError(c *gin.Context, err error) {
+	if err == alert.ErrRuleNotFound {
+		c.JSON(http.StatusNotFound, gin.H{"status": http.StatusNotFound, "message": fmt.Sprintf("%s", err)})
+		return
+	}
+	c.JSON(http.StatusInternalServerError, gin.H{"status": http.StatusInternalServerError, "message": fmt.Sprintf("%s", err)})
+}
@@ -0,0 +1,140 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/banzaicloud/productinfo/pkg/productinfo"
+	"github.com/gin-gonic/gin"
+	"github.com/patrickmn/go-cache"
+	"github.com/stretchr/testify/assert"
+)
+
+// testInfoer a product infoer with a single region, the products and prices are stored in the cache by the tests
+type testInfoer struct {
+	productinfo.ProductInfoer
+}
+
+func (ti *testInfoer) GetRegions() (map[string]string, error) {
+	return map[string]string{"eu-west-1": "EU (Ireland)"}, nil
+}
+
+func (ti *testInfoer) GetZones(region string) ([]string, error) {
+	return []string{"eu-west-1a", "eu-west-1b"}, nil
+}
+
+func (ti *testInfoer) HasShortLivedPriceInfo() bool {
+	return true
+}
+
+func (ti *testInfoer) GetCurrentPrices(region string) (map[string]productinfo.Price, error) {
+	return map[string]productinfo.Price{}, nil
+}
+
+func (ti *testInfoer) GetNetworkPerformanceMapper() (productinfo.NetworkPerfMapper, error) {
+	return testNetworkMapper{}, nil
+}
+
+type testNetworkMapper struct{}
+
+func (nm testNetworkMapper) MapNetworkPerf(vm productinfo.VmInfo) (string, error) {
+	return "high", nil
+}
+
+func TestRouteHandler_getPrice(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	c := cache.New(5*time.Minute, 10*time.Minute)
+	prod, err := productinfo.NewCachingProductInfo(10*time.Second, c, map[string]productinfo.ProductInfoer{"ec2": &testInfoer{}})
+	assert.Nil(t, err, "the error should be nil")
+	c.Set(fmt.Sprintf(productinfo.VmKeyTemplate, "ec2", "eu-west-1"), []productinfo.VmInfo{{
+		Type:          "m5.large",
+		OnDemandPrice: 0.096,
+		OsPrices:      map[string]productinfo.OsPrice{productinfo.Windows: {OnDemandPrice: 0.188}},
+		TenancyPrices: map[string]productinfo.TenancyPrice{productinfo.Dedicated: {OnDemandPrice: 0.106}},
+	}}, cache.NoExpiration)
+	// the short lived spot prices of EC2 are stored without on demand prices
+	c.Set(fmt.Sprintf(productinfo.SpotPriceKeyTemplate, "ec2", "eu-west-1", "m5.large"), productinfo.Price{
+		SpotPrice: productinfo.SpotPriceInfo{"eu-west-1a": 0.03, "eu-west-1b": 0.04},
+		OsPrices: map[string]productinfo.OsPrice{
+			productinfo.Windows: {SpotPrice: productinfo.SpotPriceInfo{"eu-west-1a": 0.12, "eu-west-1b": 0.1}},
+		},
+	}, cache.NoExpiration)
+
+	ConfigureValidator([]string{"ec2"}, prod)
+	router := gin.New()
+	NewRouteHandler(prod, nil).ConfigureRoutes(router)
+
+	tests := []struct {
+		name   string
+		path   string
+		status int
+		check  func(price PriceResponse)
+	}{
+		{
+			name:   "linux shared price averaged over every zone",
+			path:   "/api/v1/prices/ec2/eu-west-1/m5.large",
+			status: http.StatusOK,
+			check: func(price PriceResponse) {
+				assert.Equal(t, productinfo.Linux, price.Os)
+				assert.Equal(t, productinfo.Shared, price.Tenancy)
+				assert.Equal(t, []string{"eu-west-1a", "eu-west-1b"}, price.Zones)
+				assert.Equal(t, 0.096, price.OnDemandPrice)
+				assert.InDelta(t, 0.035, price.SpotPrice, 0.000001)
+			},
+		},
+		{
+			name:   "windows price in a zone",
+			path:   "/api/v1/prices/ec2/eu-west-1/m5.large?os=windows&zones=eu-west-1a",
+			status: http.StatusOK,
+			check: func(price PriceResponse) {
+				assert.Equal(t, 0.188, price.OnDemandPrice)
+				assert.Equal(t, 0.12, price.SpotPrice)
+			},
+		},
+		{
+			name:   "dedicated price",
+			path:   "/api/v1/prices/ec2/eu-west-1/m5.large?tenancy=dedicated",
+			status: http.StatusOK,
+			check: func(price PriceResponse) {
+				assert.Equal(t, 0.106, price.OnDemandPrice)
+				assert.Equal(t, float64(0), price.SpotPrice, "dedicated instances should have no spot prices")
+			},
+		},
+		{
+			name:   "unsupported operating system",
+			path:   "/api/v1/prices/ec2/eu-west-1/m5.large?os=macos",
+			status: http.StatusBadRequest,
+		},
+		{
+			name:   "unsupported tenancy",
+			path:   "/api/v1/prices/ec2/eu-west-1/m5.large?tenancy=reserved",
+			status: http.StatusBadRequest,
+		},
+		{
+			name:   "unknown region",
+			path:   "/api/v1/prices/ec2/eu-west-9/m5.large",
+			status: http.StatusBadRequest,
+		},
+		{
+			name:   "unknown instance type",
+			path:   "/api/v1/prices/ec2/eu-west-1/m5.huge",
+			status: http.StatusNotFound,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, test.path, nil))
+			assert.Equal(t, test.status, w.Code)
+			if test.check != nil {
+				var price PriceResponse
+				assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &price))
+				test.check(price)
+			}
+		})
+	}
+}
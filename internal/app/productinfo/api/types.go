@@ -1,6 +1,9 @@
 package api
 
-import "github.com/banzaicloud/productinfo/pkg/productinfo"
+import (
+	"github.com/banzaicloud/productinfo/pkg/productinfo"
+	"github.com/banzaicloud/productinfo/pkg/productinfo/alert"
+)
 
 // GetProductDetailsParams is a placeholder for the get products route's path parameters
 // swagger:parameters getProductDetails
@@ -9,6 +12,15 @@ type GetProductDetailsParams struct {
 	Provider string `json:"provider"`
 	// in:path
 	Region string `json:"region"`
+	// the operating system of the prices: linux, windows, rhel or suse
+	// in:query
+	Os string `json:"os"`
+	// the tenancy of the prices: shared, dedicated or host
+	// in:query
+	Tenancy string `json:"tenancy"`
+	// the percentage of a month the effective monthly prices are computed for
+	// in:query
+	MonthlyUsage float64 `json:"monthlyUsage"`
 }
 
 // ProductDetailsResponse Api object to be mapped to product info response
@@ -18,6 +30,39 @@ type ProductDetailsResponse struct {
 	Products []productinfo.ProductDetails `json:"products"`
 }
 
+// GetPriceParams is a placeholder for the price route's path and query parameters
+// swagger:parameters getPrice
+type GetPriceParams struct {
+	// in:path
+	Provider string `json:"provider"`
+	// in:path
+	Region string `json:"region"`
+	// in:path
+	Type string `json:"type"`
+	// the comma separated zones the spot price is averaged over, every zone of the region if empty
+	// in:query
+	Zones string `json:"zones"`
+	// the operating system of the prices: linux, windows, rhel or suse
+	// in:query
+	Os string `json:"os"`
+	// the tenancy of the prices: shared, dedicated or host
+	// in:query
+	Tenancy string `json:"tenancy"`
+}
+
+// PriceResponse holds the on demand price and the zone averaged spot price of an instance type
+// swagger:model PriceResponse
+type PriceResponse struct {
+	Type    string   `json:"type"`
+	Os      string   `json:"os"`
+	Tenancy string   `json:"tenancy"`
+	Zones   []string `json:"zones"`
+	// OnDemandPrice the hourly on demand price
+	OnDemandPrice float64 `json:"onDemandPrice"`
+	// SpotPrice the hourly spot price averaged over the zones, the zones without a spot price count as 0
+	SpotPrice float64 `json:"spotPrice"`
+}
+
 // GetRegionsParams is a placeholder for the get regions route's path parameters
 // swagger:parameters getRegions
 type GetRegionsParams struct {
@@ -50,6 +95,8 @@ type GetRegionResp struct {
 	Id    string   `json:"id"`
 	Name  string   `json:"name"`
 	Zones []string `json:"zones"`
+	// ZoneInstanceTypes the instance types available in the zones by zone, empty if the availability is not known
+	ZoneInstanceTypes map[string][]string `json:"zoneInstanceTypes,omitempty"`
 }
 
 // GetAttributeValuesParams is a placeholder for the get attribute values route's path parameters
@@ -73,3 +120,169 @@ type AttributeResponse struct {
 // ProviderResponse is the response used for the supported providers
 // swagger:model ProviderResponse
 type ProviderResponse []string
+
+// GetSpotPriceForecastParams is a placeholder for the spot price forecast route's path and query parameters
+// swagger:parameters getSpotPriceForecast
+type GetSpotPriceForecastParams struct {
+	// in:path
+	Provider string `json:"provider"`
+	// in:path
+	Region string `json:"region"`
+	// in:path
+	Type string `json:"type"`
+	// the forecasted time window in go duration syntax, defaults to 24h
+	// in:query
+	Horizon string `json:"horizon"`
+	// the probability of the actual price falling into the confidence interval, defaults to 0.95
+	// in:query
+	Confidence float64 `json:"confidence"`
+}
+
+// SpotPriceForecastResponse holds the forecasted spot prices of an instance type per zone
+// swagger:model SpotPriceForecastResponse
+type SpotPriceForecastResponse struct {
+	Type       string                     `json:"type"`
+	Confidence float64                    `json:"confidence"`
+	Zones      []productinfo.ZoneForecast `json:"zones"`
+}
+
+// GetCustomMachineTypePriceParams is a placeholder for the custom machine type route's path and query parameters
+// swagger:parameters getCustomMachineTypePrice
+type GetCustomMachineTypePriceParams struct {
+	// in:path
+	Provider string `json:"provider"`
+	// in:path
+	Region string `json:"region"`
+	// the machine series of the custom machine type, defaults to n1
+	// in:query
+	Series string `json:"series"`
+	// the number of vCPUs
+	// in:query
+	Cpus float64 `json:"cpus"`
+	// the memory in GiB, the memory above the per vCPU limit of the series is extended memory
+	// in:query
+	Memory float64 `json:"memory"`
+}
+
+// CustomMachineTypePriceResponse holds the prices of a custom machine type
+// swagger:model CustomMachineTypePriceResponse
+type CustomMachineTypePriceResponse productinfo.CustomMachineTypePrice
+
+// GetAddOnsParams is a placeholder for the add-ons route's path parameters
+// swagger:parameters getAddOns
+type GetAddOnsParams struct {
+	// in:path
+	Provider string `json:"provider"`
+	// in:path
+	Region string `json:"region"`
+}
+
+// AddOnsResponse holds the add-ons of a region
+// swagger:model AddOnsResponse
+type AddOnsResponse []productinfo.AddOn
+
+// GetAddOnPriceParams is a placeholder for the add-on price route's path and query parameters
+// swagger:parameters getAddOnPrice
+type GetAddOnPriceParams struct {
+	// in:path
+	Provider string `json:"provider"`
+	// in:path
+	Region string `json:"region"`
+	// in:path
+	Type string `json:"type"`
+	// the zone the instance is started in
+	// in:query
+	Zone string `json:"zone"`
+	// the model of the attached GPUs
+	// in:query
+	GpuModel string `json:"gpuModel"`
+	// the number of the attached GPUs
+	// in:query
+	Gpus int `json:"gpus"`
+	// the number of the attached local SSDs
+	// in:query
+	LocalSsds int `json:"localSsds"`
+}
+
+// AddOnPriceResponse holds the prices of an instance type with add-ons attached
+// swagger:model AddOnPriceResponse
+type AddOnPriceResponse productinfo.AddOnPrice
+
+// GetChangesParams is a placeholder for the change feed route's query parameters
+// swagger:parameters getChanges
+type GetChangesParams struct {
+	// in:query
+	Provider string `json:"provider"`
+	// in:query
+	Region string `json:"region"`
+	// in:query
+	InstanceType string `json:"instanceType"`
+	// the event types to return, can be repeated
+	// in:query
+	Type []string `json:"type"`
+	// only events with greater ids are returned
+	// in:query
+	SinceId uint64 `json:"sinceId"`
+	// only events after this time (RFC3339) are returned
+	// in:query
+	Since string `json:"since"`
+	// in:query
+	Limit int `json:"limit"`
+}
+
+// ChangesResponse holds the catalog change events
+// swagger:model ChangesResponse
+type ChangesResponse []productinfo.ChangeEvent
+
+// GetRejectedCatalogsParams is a placeholder for the rejected catalogs route's path parameters
+// swagger:parameters getRejectedCatalogs
+type GetRejectedCatalogsParams struct {
+	// in:path
+	Provider string `json:"provider"`
+	// in:path
+	Region string `json:"region"`
+}
+
+// CatalogStatusResponse holds the result of the last sanity check of every catalog
+// swagger:model CatalogStatusResponse
+type CatalogStatusResponse []productinfo.CatalogStatus
+
+// RejectedCatalogsResponse holds the last rejected catalogs of a region
+// swagger:model RejectedCatalogsResponse
+type RejectedCatalogsResponse []productinfo.RejectedCatalog
+
+// GetQualityReportsParams is a placeholder for the data quality route's path and query parameters
+// swagger:parameters getQualityReports
+type GetQualityReportsParams struct {
+	// in:path
+	Provider string `json:"provider"`
+	// only the report of this region is returned, issues without a known region are reported with an empty region
+	// in:query
+	Region string `json:"region"`
+}
+
+// QualityReportsResponse holds the data quality reports of a provider
+// swagger:model QualityReportsResponse
+type QualityReportsResponse []productinfo.QualityReport
+
+// AlertRuleParams is a placeholder for the alert rule routes' path parameters
+// swagger:parameters getAlertRule updateAlertRule deleteAlertRule
+type AlertRuleParams struct {
+	// in:path
+	Id string `json:"id"`
+}
+
+// AlertRuleBody is a placeholder for the alert rule routes' request body
+// swagger:parameters createAlertRule updateAlertRule
+type AlertRuleBody struct {
+	// in:body
+	Rule alert.Rule
+}
+
+// AlertRuleResponse holds a price alert rule, the secret is never returned
+// swagger:model AlertRuleResponse
+type AlertRuleResponse alert.Rule
+
+// AlertRulesResponse holds the price alert rules
+// swagger:model AlertRulesResponse
+type AlertRulesResponse []alert.Rule
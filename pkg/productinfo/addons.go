@@ -0,0 +1,172 @@
+package productinfo
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+)
+
+const (
+	// Gpu the add-on type of the GPUs attached to the instances
+	Gpu = "gpu"
+
+	// LocalSsd the add-on type of the local SSDs attached to the instances
+	LocalSsd = "localSsd"
+
+	// AddOnKeyTemplate format for generating add-on cache keys
+	AddOnKeyTemplate = "/banzaicloud.com/recommender/%s/%s/addons"
+)
+
+// AddOn a device that is attached to the instances instead of being part of the instance type, e.g. a GPU or a local
+// SSD, the prices are the hourly prices of a single device
+type AddOn struct {
+	// Type the type of the add-on: gpu or localSsd
+	Type string `json:"type"`
+	// Model the provider specific name of the device, e.g. nvidia-tesla-t4
+	Model       string `json:"model"`
+	Description string `json:"description,omitempty"`
+	// MaxPerVm the maximum number of devices of the model attached to an instance
+	MaxPerVm int `json:"maxPerVm,omitempty"`
+	// Size the size of a local SSD device in GiB
+	Size float64 `json:"sizeGb,omitempty"`
+	// Zones the zones the add-on is available in, the add-on is available in every zone of the region if it's empty
+	Zones         []string `json:"zones,omitempty"`
+	OnDemandPrice float64  `json:"onDemandPrice"`
+	SpotPrice     float64  `json:"spotPrice,omitempty"`
+	// PreemptiblePrice the price of the device attached to legacy preemptible instances
+	PreemptiblePrice float64 `json:"preemptiblePrice,omitempty"`
+}
+
+// AvailableIn returns whether the add-on can be attached to the instances of a zone
+func (a AddOn) AvailableIn(zone string) bool {
+	return len(a.Zones) == 0 || Contains(a.Zones, zone)
+}
+
+// AddOnInfoer is implemented by the product infoers of providers offering devices attached to the instances
+type AddOnInfoer interface {
+	// GetAddOns returns the GPUs and local SSDs that can be attached to the instances of a region
+	GetAddOns(region string) ([]AddOn, error)
+}
+
+// AddOnRequest an instance type with the devices attached to it
+type AddOnRequest struct {
+	Type string `json:"type"`
+	Zone string `json:"zone"`
+	// GpuModel the model of the attached GPUs
+	GpuModel string `json:"gpuModel,omitempty"`
+	Gpus     int    `json:"gpusPerVm,omitempty"`
+	// LocalSsds the number of the attached local SSDs
+	LocalSsds int `json:"localSsds,omitempty"`
+}
+
+// AddOnPrice the hourly prices of an instance type with the devices attached to it in a zone, the spot (preemptible)
+// price is 0 if the instance type or one of the add-ons has no spot (preemptible) price in the zone
+type AddOnPrice struct {
+	AddOnRequest
+	OnDemandPrice    float64 `json:"onDemandPrice"`
+	SpotPrice        float64 `json:"spotPrice,omitempty"`
+	PreemptiblePrice float64 `json:"preemptiblePrice,omitempty"`
+}
+
+func (cpi *CachingProductInfo) getAddOnKey(provider string, region string) string {
+	return fmt.Sprintf(AddOnKeyTemplate, provider, region)
+}
+
+// renewAddOns retrieves the add-ons of a region from the providers offering them and refreshes the cache with them
+func (cpi *CachingProductInfo) renewAddOns(provider string, region string) error {
+	addOnInfoer, ok := cpi.productInfoers[provider].(AddOnInfoer)
+	if !ok {
+		return nil
+	}
+	addOns, err := addOnInfoer.GetAddOns(region)
+	if err != nil {
+		return err
+	}
+	sort.Slice(addOns, func(i, j int) bool {
+		if addOns[i].Type != addOns[j].Type {
+			return addOns[i].Type < addOns[j].Type
+		}
+		return addOns[i].Model < addOns[j].Model
+	})
+	cpi.vmAttrStore.Set(cpi.getAddOnKey(provider, region), addOns, cpi.renewalInterval)
+	return nil
+}
+
+// GetAddOns returns the cached add-ons of a region
+func (cpi *CachingProductInfo) GetAddOns(provider string, region string) ([]AddOn, error) {
+	if _, ok := cpi.productInfoers[provider].(AddOnInfoer); !ok {
+		return nil, fmt.Errorf("add-ons are not supported by provider: %s", provider)
+	}
+	cachedAddOns, ok := cpi.vmAttrStore.Get(cpi.getAddOnKey(provider, region))
+	if !ok {
+		return nil, fmt.Errorf("add-ons not yet cached for the key: %s", cpi.getAddOnKey(provider, region))
+	}
+	return cachedAddOns.([]AddOn), nil
+}
+
+// GetAddOnPrice returns the prices of an instance type with GPUs and local SSDs attached in a zone, the devices must be
+// available in the zone and their number must not exceed the limit of the add-on
+func (cpi *CachingProductInfo) GetAddOnPrice(provider string, region string, req AddOnRequest) (*AddOnPrice, error) {
+	addOns, err := cpi.GetAddOns(provider, region)
+	if err != nil {
+		return nil, err
+	}
+	zones, err := cpi.GetZones(provider, region)
+	if err != nil {
+		return nil, err
+	}
+	if !Contains(zones, req.Zone) {
+		return nil, fmt.Errorf("zone %s is not in region %s", req.Zone, region)
+	}
+	vmPrice, ok := cpi.getCachedPrice(provider, region, req.Type)
+	if !ok {
+		return nil, fmt.Errorf("price of instance type %s not yet cached", req.Type)
+	}
+	price := AddOnPrice{
+		AddOnRequest:     req,
+		OnDemandPrice:    vmPrice.OnDemandPrice,
+		SpotPrice:        vmPrice.SpotPrice[req.Zone],
+		PreemptiblePrice: vmPrice.PreemptiblePrice[req.Zone],
+	}
+	hasSpotPrice, hasPreemptiblePrice := price.SpotPrice > 0, price.PreemptiblePrice > 0
+
+	attach := func(addOnType string, model string, count int) error {
+		if count == 0 {
+			return nil
+		}
+		for _, addOn := range addOns {
+			if addOn.Type != addOnType || (model != "" && addOn.Model != model) {
+				continue
+			}
+			if !addOn.AvailableIn(req.Zone) {
+				return fmt.Errorf("%s %s is not available in zone %s", addOnType, addOn.Model, req.Zone)
+			}
+			if addOn.MaxPerVm > 0 && count > addOn.MaxPerVm {
+				return fmt.Errorf("at most %d %s %s can be attached to an instance", addOn.MaxPerVm, addOnType, addOn.Model)
+			}
+			price.OnDemandPrice += float64(count) * addOn.OnDemandPrice
+			price.SpotPrice += float64(count) * addOn.SpotPrice
+			hasSpotPrice = hasSpotPrice && addOn.SpotPrice > 0
+			price.PreemptiblePrice += float64(count) * addOn.PreemptiblePrice
+			hasPreemptiblePrice = hasPreemptiblePrice && addOn.PreemptiblePrice > 0
+			return nil
+		}
+		return fmt.Errorf("unknown %s add-on: %s", addOnType, model)
+	}
+	if req.Gpus > 0 && req.GpuModel == "" {
+		return nil, errors.New("the gpu model is required")
+	}
+	if err := attach(Gpu, req.GpuModel, req.Gpus); err != nil {
+		return nil, err
+	}
+	if err := attach(LocalSsd, "", req.LocalSsds); err != nil {
+		return nil, err
+	}
+	if !hasSpotPrice {
+		price.SpotPrice = 0
+	}
+	if !hasPreemptiblePrice {
+		price.PreemptiblePrice = 0
+	}
+	return &price, nil
+}
@@ -0,0 +1,110 @@
+package productinfo
+
+import (
+	"testing"
+	"time"
+
+	"github.com/patrickmn/go-cache"
+	"github.com/stretchr/testify/assert"
+)
+
+// dummyAddOnInfoer a product infoer offering add-ons
+type dummyAddOnInfoer struct {
+	DummyProductInfoer
+	addOns []AddOn
+}
+
+func (dai *dummyAddOnInfoer) GetAddOns(region string) ([]AddOn, error) {
+	return dai.addOns, nil
+}
+
+func TestCachingProductInfo_GetAddOnPrice(t *testing.T) {
+	c := cache.New(5*time.Minute, 10*time.Minute)
+	productInfo, _ := NewCachingProductInfo(10*time.Second, c, map[string]ProductInfoer{
+		"dummy": &dummyAddOnInfoer{addOns: []AddOn{
+			{Type: LocalSsd, Model: "local-ssd", MaxPerVm: 24, OnDemandPrice: 0.041, SpotPrice: 0.033},
+			{Type: Gpu, Model: "nvidia-tesla-t4", MaxPerVm: 4, Zones: []string{"dummyZone1"}, OnDemandPrice: 0.35, SpotPrice: 0.11},
+			{Type: Gpu, Model: "nvidia-tesla-k80", MaxPerVm: 8, OnDemandPrice: 0.45},
+		}},
+		"other": &DummyProductInfoer{},
+	})
+	c.Set(productInfo.getPriceKey("dummy", "dummyRegion", "n1-standard-4"), Price{
+		OnDemandPrice: 0.19,
+		SpotPrice:     SpotPriceInfo{"dummyZone1": 0.04, "dummyZone2": 0.04},
+	}, cache.NoExpiration)
+
+	_, err := productInfo.GetAddOnPrice("dummy", "dummyRegion", AddOnRequest{Type: "n1-standard-4", Zone: "dummyZone1"})
+	assert.EqualError(t, err, "add-ons not yet cached for the key: /banzaicloud.com/recommender/dummy/dummyRegion/addons")
+	assert.Nil(t, productInfo.renewAddOns("dummy", "dummyRegion"))
+	addOns, err := productInfo.GetAddOns("dummy", "dummyRegion")
+	assert.Nil(t, err, "the error should be nil")
+	assert.Equal(t, []string{"nvidia-tesla-k80", "nvidia-tesla-t4", "local-ssd"}, []string{addOns[0].Model, addOns[1].Model, addOns[2].Model})
+
+	tests := []struct {
+		name  string
+		req   AddOnRequest
+		check func(price *AddOnPrice, err error)
+	}{
+		{
+			name: "gpus and local ssds",
+			req:  AddOnRequest{Type: "n1-standard-4", Zone: "dummyZone1", GpuModel: "nvidia-tesla-t4", Gpus: 2, LocalSsds: 1},
+			check: func(price *AddOnPrice, err error) {
+				assert.Nil(t, err, "the error should be nil")
+				assert.InDelta(t, 0.19+2*0.35+0.041, price.OnDemandPrice, 0.000001)
+				assert.InDelta(t, 0.04+2*0.11+0.033, price.SpotPrice, 0.000001)
+			},
+		},
+		{
+			name: "gpu without spot price",
+			req:  AddOnRequest{Type: "n1-standard-4", Zone: "dummyZone2", GpuModel: "nvidia-tesla-k80", Gpus: 1, LocalSsds: 2},
+			check: func(price *AddOnPrice, err error) {
+				assert.Nil(t, err, "the error should be nil")
+				assert.InDelta(t, 0.19+0.45+2*0.041, price.OnDemandPrice, 0.000001)
+				assert.Equal(t, float64(0), price.SpotPrice)
+			},
+		},
+		{
+			name: "gpu not available in the zone",
+			req:  AddOnRequest{Type: "n1-standard-4", Zone: "dummyZone2", GpuModel: "nvidia-tesla-t4", Gpus: 1},
+			check: func(price *AddOnPrice, err error) {
+				assert.EqualError(t, err, "gpu nvidia-tesla-t4 is not available in zone dummyZone2")
+			},
+		},
+		{
+			name: "too many gpus",
+			req:  AddOnRequest{Type: "n1-standard-4", Zone: "dummyZone1", GpuModel: "nvidia-tesla-t4", Gpus: 8},
+			check: func(price *AddOnPrice, err error) {
+				assert.EqualError(t, err, "at most 4 gpu nvidia-tesla-t4 can be attached to an instance")
+			},
+		},
+		{
+			name: "unknown gpu model",
+			req:  AddOnRequest{Type: "n1-standard-4", Zone: "dummyZone1", GpuModel: "nvidia-tesla-v100", Gpus: 1},
+			check: func(price *AddOnPrice, err error) {
+				assert.EqualError(t, err, "unknown gpu add-on: nvidia-tesla-v100")
+			},
+		},
+		{
+			name: "zone outside the region",
+			req:  AddOnRequest{Type: "n1-standard-4", Zone: "otherZone"},
+			check: func(price *AddOnPrice, err error) {
+				assert.EqualError(t, err, "zone otherZone is not in region dummyRegion")
+			},
+		},
+		{
+			name: "price not cached",
+			req:  AddOnRequest{Type: "n1-standard-8", Zone: "dummyZone1"},
+			check: func(price *AddOnPrice, err error) {
+				assert.EqualError(t, err, "price of instance type n1-standard-8 not yet cached")
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			test.check(productInfo.GetAddOnPrice("dummy", "dummyRegion", test.req))
+		})
+	}
+
+	_, err = productInfo.GetAddOns("other", "dummyRegion")
+	assert.EqualError(t, err, "add-ons are not supported by provider: other")
+}
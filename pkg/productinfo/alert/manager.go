@@ -0,0 +1,370 @@
+package alert
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/banzaicloud/productinfo/pkg/productinfo"
+	"github.com/banzaicloud/productinfo/pkg/productinfo/sink"
+	log "github.com/sirupsen/logrus"
+)
+
+// ErrRuleNotFound is returned when there's no rule with the given id
+var ErrRuleNotFound = errors.New("alert rule not found")
+
+// Notifier delivers the notifications of a rule, it must not block
+type Notifier interface {
+	Notify(rule Rule, n Notification)
+}
+
+// WebhookNotifier posts the notifications to the webhook of the rule in the background
+type WebhookNotifier struct {
+	Retries int
+	Backoff time.Duration
+}
+
+// Notify posts the notification in a new goroutine
+func (wn WebhookNotifier) Notify(rule Rule, n Notification) {
+	go func() {
+		if err := sink.NewWebhook(rule.Webhook, rule.Secret, wn.Retries, wn.Backoff).Post(n, 1); err != nil {
+			log.WithError(err).Errorf("couldn't deliver notification of alert rule %s", rule.Id)
+		}
+	}()
+}
+
+// AddOnSource returns the add-ons of the providers attaching the GPUs to the instances
+type AddOnSource interface {
+	GetAddOns(provider string, region string) ([]productinfo.AddOn, error)
+}
+
+// alertKey identifies an alert of a rule, the zone is empty for on demand prices
+type alertKey struct {
+	Rule         string `json:"rule"`
+	Region       string `json:"region"`
+	InstanceType string `json:"instanceType"`
+	Zone         string `json:"zone,omitempty"`
+}
+
+// rulesFile the content of the file the rules are persisted to, the firing alerts are persisted with the rules so they
+// are not notified again after a restart
+type rulesFile struct {
+	Rules  []Rule     `json:"rules"`
+	Firing []alertKey `json:"firing,omitempty"`
+}
+
+// Manager stores the alert rules and evaluates them on every price renewal
+// an alert is only notified when it starts firing and when it's resolved, so repeated renewals with the same prices
+// don't produce duplicate notifications
+type Manager struct {
+	mu       sync.Mutex
+	path     string
+	rules    map[string]Rule
+	firing   map[alertKey]bool
+	products productinfo.ProductDetailSource
+	notifier Notifier
+}
+
+// NewManager creates a new alert manager, rules are persisted to the file at path if it's not empty
+// the products are used to select instance types by their GPU count, the GPU add-ons are retrieved from them if they
+// implement AddOnSource
+func NewManager(path string, products productinfo.ProductDetailSource, notifier Notifier) (*Manager, error) {
+	m := &Manager{
+		path:     path,
+		rules:    make(map[string]Rule),
+		firing:   make(map[alertKey]bool),
+		products: products,
+		notifier: notifier,
+	}
+	if path == "" {
+		return m, nil
+	}
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return m, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var f rulesFile
+	if err := json.Unmarshal(data, &f); err != nil {
+		return nil, err
+	}
+	for _, r := range f.Rules {
+		m.rules[r.Id] = r
+	}
+	for _, k := range f.Firing {
+		if _, ok := m.rules[k.Rule]; ok {
+			m.firing[k] = true
+		}
+	}
+	log.Infof("loaded %d alert rules with %d firing alerts from %s", len(f.Rules), len(m.firing), path)
+	return m, nil
+}
+
+// Rules returns the alert rules ordered by id
+func (m *Manager) Rules() []Rule {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.sortedRules()
+}
+
+// Rule returns the alert rule with the given id
+func (m *Manager) Rule(id string) (Rule, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	r, ok := m.rules[id]
+	if !ok {
+		return r, ErrRuleNotFound
+	}
+	return r, nil
+}
+
+// CreateRule validates and stores a new rule with a generated id
+func (m *Manager) CreateRule(r Rule) (Rule, error) {
+	if err := r.Validate(); err != nil {
+		return r, err
+	}
+	id := make([]byte, 8)
+	if _, err := rand.Read(id); err != nil {
+		return r, err
+	}
+	r.Id = hex.EncodeToString(id)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.rules[r.Id] = r
+	if err := m.save(); err != nil {
+		delete(m.rules, r.Id)
+		return r, err
+	}
+	return r, nil
+}
+
+// UpdateRule validates and replaces the rule with the given id, the alerts of the rule are reset
+// the stored secret is kept if the new rule has no secret
+func (m *Manager) UpdateRule(id string, r Rule) (Rule, error) {
+	if err := r.Validate(); err != nil {
+		return r, err
+	}
+	r.Id = id
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	old, ok := m.rules[id]
+	if !ok {
+		return r, ErrRuleNotFound
+	}
+	if r.Secret == "" {
+		r.Secret = old.Secret
+	}
+	m.rules[id] = r
+	alerts := m.resetAlerts(id)
+	if err := m.save(); err != nil {
+		m.rules[id] = old
+		m.restoreAlerts(alerts)
+		return r, err
+	}
+	return r, nil
+}
+
+// DeleteRule deletes the rule with the given id
+func (m *Manager) DeleteRule(id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	old, ok := m.rules[id]
+	if !ok {
+		return ErrRuleNotFound
+	}
+	delete(m.rules, id)
+	alerts := m.resetAlerts(id)
+	if err := m.save(); err != nil {
+		m.rules[id] = old
+		m.restoreAlerts(alerts)
+		return err
+	}
+	return nil
+}
+
+// PricesRenewed evaluates the rules of the provider on the renewed prices of a region
+func (m *Manager) PricesRenewed(provider string, region string, prices map[string]productinfo.Price) {
+	m.mu.Lock()
+	var rules []Rule
+	needGpus, needAddOns := false, false
+	for _, r := range m.sortedRules() {
+		if r.Provider == provider && (r.Region == "" || r.Region == region) {
+			rules = append(rules, r)
+			needGpus = needGpus || (r.MinGpus > 0 && r.GpuModel == "")
+			needAddOns = needAddOns || r.GpuModel != ""
+		}
+	}
+	m.mu.Unlock()
+	if len(rules) == 0 {
+		return
+	}
+
+	gpus := make(map[string]float64)
+	if needGpus {
+		details, err := m.products.GetProductDetails(provider, region)
+		if err != nil {
+			log.WithError(err).Debugf("couldn't get the GPU counts of the instance types in %s/%s", provider, region)
+		}
+		for _, d := range details {
+			gpus[d.Type] = d.Gpus
+		}
+	}
+	var addOns []productinfo.AddOn
+	if src, ok := m.products.(AddOnSource); ok && needAddOns {
+		var err error
+		if addOns, err = src.GetAddOns(provider, region); err != nil {
+			log.WithError(err).Debugf("couldn't get the GPU add-ons in %s/%s", provider, region)
+		}
+	}
+
+	now := time.Now()
+	var notifications []Notification
+	m.mu.Lock()
+	for _, r := range rules {
+		var addOn *productinfo.AddOn
+		if r.GpuModel != "" {
+			a, ok := r.gpuAddOn(addOns)
+			if !ok {
+				continue
+			}
+			addOn = &a
+		}
+		for instType, p := range prices {
+			if !r.matches(provider, region, instType) || (addOn == nil && r.MinGpus > 0 && gpus[instType] < r.MinGpus) {
+				continue
+			}
+			for zone, value := range ruleValues(r, p, addOn) {
+				key := alertKey{Rule: r.Id, Region: region, InstanceType: instType, Zone: zone}
+				var status Status
+				switch {
+				case !m.firing[key] && r.triggered(value):
+					m.firing[key] = true
+					status = Firing
+				case m.firing[key] && r.cleared(value):
+					delete(m.firing, key)
+					status = Resolved
+				default:
+					continue
+				}
+				n := Notification{RuleId: r.Id, RuleName: r.Name, Status: status, Time: now,
+					Provider: provider, Region: region, InstanceType: instType, Zone: zone, Price: r.Price,
+					Condition: r.Condition, Threshold: r.Threshold, Value: value}
+				if addOn != nil {
+					n.GpuModel, n.Gpus = r.GpuModel, r.attachedGpus()
+				}
+				notifications = append(notifications, n)
+			}
+		}
+	}
+	if len(notifications) > 0 {
+		if err := m.save(); err != nil {
+			log.WithError(err).Error("couldn't persist the firing alerts")
+		}
+	}
+	m.mu.Unlock()
+
+	for _, n := range notifications {
+		log.Infof("alert rule %s is %s for %s/%s/%s %s", n.RuleId, n.Status, provider, region, n.InstanceType, n.Zone)
+		if r, err := m.Rule(n.RuleId); err == nil {
+			m.notifier.Notify(r, n)
+		}
+	}
+}
+
+// ruleValues returns the prices the rule is evaluated on by zone, the zone is empty for the on demand price
+// the prices of the GPU add-ons are added to the prices of the instance type if the add-on is not nil, the zones the
+// add-on is not available or has no spot price in are left out
+func ruleValues(r Rule, p productinfo.Price, addOn *productinfo.AddOn) map[string]float64 {
+	values := make(map[string]float64)
+	if r.Price == OnDemandPrice && p.OnDemandPrice > 0 {
+		values[""] = p.OnDemandPrice
+		if addOn != nil {
+			values[""] += float64(r.attachedGpus()) * addOn.OnDemandPrice
+		}
+	}
+	if r.Price == SpotPrice {
+		for zone, price := range p.SpotPrice {
+			if price <= 0 {
+				continue
+			}
+			if addOn == nil {
+				values[zone] = price
+			} else if addOn.AvailableIn(zone) && addOn.SpotPrice > 0 {
+				values[zone] = price + float64(r.attachedGpus())*addOn.SpotPrice
+			}
+		}
+	}
+	return values
+}
+
+// resetAlerts deletes the firing alerts of a rule and returns them
+func (m *Manager) resetAlerts(id string) []alertKey {
+	var alerts []alertKey
+	for k := range m.firing {
+		if k.Rule == id {
+			alerts = append(alerts, k)
+			delete(m.firing, k)
+		}
+	}
+	return alerts
+}
+
+func (m *Manager) restoreAlerts(alerts []alertKey) {
+	for _, k := range alerts {
+		m.firing[k] = true
+	}
+}
+
+func (m *Manager) sortedRules() []Rule {
+	rules := make([]Rule, 0, len(m.rules))
+	for _, r := range m.rules {
+		rules = append(rules, r)
+	}
+	sort.Slice(rules, func(i, j int) bool {
+		return rules[i].Id < rules[j].Id
+	})
+	return rules
+}
+
+// save writes the rules and the firing alerts to the file atomically, it must be called with the lock held
+func (m *Manager) save() error {
+	if m.path == "" {
+		return nil
+	}
+	f := rulesFile{Rules: m.sortedRules()}
+	for k := range m.firing {
+		f.Firing = append(f.Firing, k)
+	}
+	sort.Slice(f.Firing, func(i, j int) bool {
+		a, b := f.Firing[i], f.Firing[j]
+		if a.Rule != b.Rule {
+			return a.Rule < b.Rule
+		}
+		if a.Region != b.Region {
+			return a.Region < b.Region
+		}
+		if a.InstanceType != b.InstanceType {
+			return a.InstanceType < b.InstanceType
+		}
+		return a.Zone < b.Zone
+	})
+	data, err := json.MarshalIndent(f, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := m.path + ".tmp"
+	if err := ioutil.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, m.path)
+}
@@ -0,0 +1,310 @@
+package alert
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/banzaicloud/productinfo/pkg/productinfo"
+	"github.com/stretchr/testify/assert"
+)
+
+// dummyNotifier records the notifications
+type dummyNotifier struct {
+	notifications []Notification
+}
+
+func (n *dummyNotifier) Notify(rule Rule, notification Notification) {
+	n.notifications = append(n.notifications, notification)
+}
+
+// dummyProductDetailSource returns the configured product details, add-ons or error
+type dummyProductDetailSource struct {
+	details []productinfo.ProductDetails
+	addOns  []productinfo.AddOn
+	err     error
+}
+
+func (s dummyProductDetailSource) GetProductDetails(cloud string, region string) ([]productinfo.ProductDetails, error) {
+	return s.details, s.err
+}
+
+func (s dummyProductDetailSource) GetAddOns(provider string, region string) ([]productinfo.AddOn, error) {
+	return s.addOns, s.err
+}
+
+func spot(price float64) map[string]productinfo.Price {
+	return map[string]productinfo.Price{"c5.4xlarge": {SpotPrice: productinfo.SpotPriceInfo{"us-east-1a": price}}}
+}
+
+func TestRule_Validate(t *testing.T) {
+	valid := Rule{Provider: "ec2", Price: SpotPrice, Condition: Above, Threshold: 0.3, Webhook: "http://example.com/hook"}
+	tests := []struct {
+		name   string
+		modify func(r *Rule)
+		check  func(err error)
+	}{
+		{
+			name:   "valid rule",
+			modify: func(r *Rule) {},
+			check: func(err error) {
+				assert.Nil(t, err, "the error should be nil")
+			},
+		},
+		{
+			name:   "missing provider",
+			modify: func(r *Rule) { r.Provider = "" },
+			check: func(err error) {
+				assert.EqualError(t, err, "provider is required")
+			},
+		},
+		{
+			name:   "invalid condition",
+			modify: func(r *Rule) { r.Condition = "equals" },
+			check: func(err error) {
+				assert.EqualError(t, err, "condition must be above or below")
+			},
+		},
+		{
+			name:   "invalid type pattern",
+			modify: func(r *Rule) { r.Types = []string{"c5.[4"} },
+			check: func(err error) {
+				assert.EqualError(t, err, "invalid type pattern: c5.[4")
+			},
+		},
+		{
+			name:   "gpu selector of a provider with gpu add-ons without a model",
+			modify: func(r *Rule) { r.Provider = "gce"; r.MinGpus = 1 },
+			check: func(err error) {
+				assert.EqualError(t, err, "gpuModel is required with minGpus by provider gce, its GPUs are attached to the instances as add-ons")
+			},
+		},
+		{
+			name:   "gpu model of a provider with gpu add-ons",
+			modify: func(r *Rule) { r.Provider = "gce"; r.MinGpus = 1; r.GpuModel = "nvidia-tesla-v100" },
+			check: func(err error) {
+				assert.Nil(t, err, "the error should be nil")
+			},
+		},
+		{
+			name:   "gpu model of a provider with built in gpus",
+			modify: func(r *Rule) { r.MinGpus = 1; r.GpuModel = "nvidia-tesla-v100" },
+			check: func(err error) {
+				assert.EqualError(t, err, "gpuModel is not supported by provider ec2, its instance types have built in GPUs")
+			},
+		},
+		{
+			name:   "gpu model without a gpu count",
+			modify: func(r *Rule) { r.Provider = "gce"; r.GpuModel = "nvidia-tesla-v100" },
+			check: func(err error) {
+				assert.EqualError(t, err, "minGpus is required with gpuModel")
+			},
+		},
+		{
+			name:   "invalid webhook",
+			modify: func(r *Rule) { r.Webhook = "example.com/hook" },
+			check: func(err error) {
+				assert.EqualError(t, err, "webhook must be an http or https url")
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			r := valid
+			test.modify(&r)
+			test.check(r.Validate())
+		})
+	}
+}
+
+func TestManager_PricesRenewed(t *testing.T) {
+	tests := []struct {
+		name    string
+		rule    Rule
+		details dummyProductDetailSource
+		renew   func(m *Manager)
+		check   func(notifications []Notification)
+	}{
+		{
+			name: "firing once and resolved after the hysteresis",
+			rule: Rule{Provider: "ec2", Region: "us-east-1", Types: []string{"c5.*"}, Price: SpotPrice, Condition: Above, Threshold: 0.3, Hysteresis: 0.02},
+			renew: func(m *Manager) {
+				for _, p := range []float64{0.25, 0.31, 0.35, 0.29, 0.31, 0.27} {
+					m.PricesRenewed("ec2", "us-east-1", spot(p))
+				}
+			},
+			check: func(notifications []Notification) {
+				assert.Equal(t, 2, len(notifications))
+				assert.Equal(t, Firing, notifications[0].Status)
+				assert.Equal(t, 0.31, notifications[0].Value)
+				assert.Equal(t, "us-east-1a", notifications[0].Zone)
+				assert.Equal(t, Resolved, notifications[1].Status)
+				assert.Equal(t, 0.27, notifications[1].Value)
+			},
+		},
+		{
+			name: "not matching selectors are skipped",
+			rule: Rule{Provider: "ec2", Region: "us-east-1", Types: []string{"m5.*"}, Price: SpotPrice, Condition: Above, Threshold: 0.3},
+			renew: func(m *Manager) {
+				m.PricesRenewed("ec2", "us-east-1", spot(0.5))
+				m.PricesRenewed("ec2", "us-west-1", map[string]productinfo.Price{"m5.large": {SpotPrice: productinfo.SpotPriceInfo{"us-west-1a": 0.5}}})
+				m.PricesRenewed("gce", "us-east1", map[string]productinfo.Price{"n1-standard-1": {SpotPrice: productinfo.SpotPriceInfo{"us-east1-b": 0.5}}})
+			},
+			check: func(notifications []Notification) {
+				assert.Equal(t, 0, len(notifications))
+			},
+		},
+		{
+			name: "gpu types below the threshold",
+			rule: Rule{Provider: "ec2", Region: "us-east-1", MinGpus: 1, Price: OnDemandPrice, Condition: Below, Threshold: 1},
+			details: dummyProductDetailSource{details: []productinfo.ProductDetails{
+				{VmInfo: productinfo.VmInfo{Type: "g4dn.xlarge", Gpus: 1}},
+				{VmInfo: productinfo.VmInfo{Type: "m5.xlarge"}},
+			}},
+			renew: func(m *Manager) {
+				m.PricesRenewed("ec2", "us-east-1", map[string]productinfo.Price{
+					"g4dn.xlarge": {OnDemandPrice: 0.526},
+					"m5.xlarge":   {OnDemandPrice: 0.192},
+				})
+			},
+			check: func(notifications []Notification) {
+				assert.Equal(t, 1, len(notifications))
+				assert.Equal(t, "g4dn.xlarge", notifications[0].InstanceType)
+				assert.Equal(t, "", notifications[0].Zone)
+			},
+		},
+		{
+			name: "gpu add-ons are priced with the instance types",
+			rule: Rule{Provider: "gce", Region: "europe-west4", Types: []string{"n1-standard-8"}, MinGpus: 2, GpuModel: "nvidia-tesla-v100",
+				Price: SpotPrice, Condition: Below, Threshold: 2},
+			details: dummyProductDetailSource{addOns: []productinfo.AddOn{
+				{Type: productinfo.Gpu, Model: "nvidia-tesla-t4", MaxPerVm: 4, OnDemandPrice: 0.35, SpotPrice: 0.11},
+				{Type: productinfo.Gpu, Model: "nvidia-tesla-v100", MaxPerVm: 8, Zones: []string{"europe-west4-a"}, OnDemandPrice: 2.55, SpotPrice: 0.74},
+			}},
+			renew: func(m *Manager) {
+				m.PricesRenewed("gce", "europe-west4", map[string]productinfo.Price{
+					"n1-standard-8": {SpotPrice: productinfo.SpotPriceInfo{"europe-west4-a": 0.08, "europe-west4-b": 0.08}},
+				})
+			},
+			check: func(notifications []Notification) {
+				assert.Equal(t, 1, len(notifications), "the zones without the gpu should be left out")
+				assert.Equal(t, "europe-west4-a", notifications[0].Zone)
+				assert.InDelta(t, 0.08+2*0.74, notifications[0].Value, 1e-9)
+				assert.Equal(t, "nvidia-tesla-v100", notifications[0].GpuModel)
+				assert.Equal(t, 2, notifications[0].Gpus)
+			},
+		},
+		{
+			name: "gpu add-on rules don't match more gpus than can be attached",
+			rule: Rule{Provider: "gce", MinGpus: 8, GpuModel: "nvidia-tesla-t4", Price: OnDemandPrice, Condition: Above, Threshold: 1},
+			details: dummyProductDetailSource{addOns: []productinfo.AddOn{
+				{Type: productinfo.Gpu, Model: "nvidia-tesla-t4", MaxPerVm: 4, OnDemandPrice: 0.35},
+			}},
+			renew: func(m *Manager) {
+				m.PricesRenewed("gce", "europe-west4", map[string]productinfo.Price{"n1-standard-8": {OnDemandPrice: 0.38}})
+			},
+			check: func(notifications []Notification) {
+				assert.Equal(t, 0, len(notifications))
+			},
+		},
+		{
+			name:    "gpu rules don't match if the products are not known",
+			rule:    Rule{Provider: "azure", MinGpus: 1, Price: OnDemandPrice, Condition: Below, Threshold: 1},
+			details: dummyProductDetailSource{err: errors.New("vms not yet cached")},
+			renew: func(m *Manager) {
+				m.PricesRenewed("azure", "westeurope", map[string]productinfo.Price{"Standard_NC6": {OnDemandPrice: 0.9}})
+			},
+			check: func(notifications []Notification) {
+				assert.Equal(t, 0, len(notifications))
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			notifier := &dummyNotifier{}
+			m, _ := NewManager("", test.details, notifier)
+			test.rule.Webhook = "http://example.com/hook"
+			_, err := m.CreateRule(test.rule)
+			assert.Nil(t, err, "the error should be nil")
+			test.renew(m)
+			test.check(notifier.notifications)
+		})
+	}
+}
+
+func TestManager_Persistence(t *testing.T) {
+	dir, err := ioutil.TempDir("", "alerts")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "rules.json")
+
+	m, err := NewManager(path, dummyProductDetailSource{}, &dummyNotifier{})
+	assert.Nil(t, err, "the error should be nil")
+	first, err := m.CreateRule(Rule{Provider: "ec2", Price: SpotPrice, Condition: Above, Threshold: 0.3, Webhook: "http://example.com/hook", Secret: "s"})
+	assert.Nil(t, err, "the error should be nil")
+	second, err := m.CreateRule(Rule{Provider: "gce", Price: OnDemandPrice, Condition: Below, Threshold: 1, Webhook: "http://example.com/hook"})
+	assert.Nil(t, err, "the error should be nil")
+	second.Threshold = 2
+	_, err = m.UpdateRule(second.Id, second)
+	assert.Nil(t, err, "the error should be nil")
+	first.Threshold = 0.4
+	first.Secret = ""
+	first, err = m.UpdateRule(first.Id, first)
+	assert.Nil(t, err, "the error should be nil")
+	assert.Equal(t, "s", first.Secret, "the stored secret should be kept")
+	assert.Nil(t, m.DeleteRule(second.Id))
+	assert.Equal(t, ErrRuleNotFound, m.DeleteRule(second.Id))
+
+	reloaded, err := NewManager(path, dummyProductDetailSource{}, &dummyNotifier{})
+	assert.Nil(t, err, "the error should be nil")
+	assert.Equal(t, []Rule{first}, reloaded.Rules())
+}
+
+func TestManager_FiringPersistence(t *testing.T) {
+	dir, err := ioutil.TempDir("", "alerts")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "rules.json")
+
+	notifier := &dummyNotifier{}
+	m, err := NewManager(path, dummyProductDetailSource{}, notifier)
+	assert.Nil(t, err, "the error should be nil")
+	_, err = m.CreateRule(Rule{Provider: "ec2", Price: SpotPrice, Condition: Above, Threshold: 0.3, Webhook: "http://example.com/hook"})
+	assert.Nil(t, err, "the error should be nil")
+	m.PricesRenewed("ec2", "us-east-1", spot(0.35))
+	assert.Equal(t, 1, len(notifier.notifications))
+
+	// the alert keeps firing after a restart, only its resolution is notified
+	notifier = &dummyNotifier{}
+	reloaded, err := NewManager(path, dummyProductDetailSource{}, notifier)
+	assert.Nil(t, err, "the error should be nil")
+	reloaded.PricesRenewed("ec2", "us-east-1", spot(0.35))
+	assert.Equal(t, 0, len(notifier.notifications))
+	reloaded.PricesRenewed("ec2", "us-east-1", spot(0.25))
+	assert.Equal(t, 1, len(notifier.notifications))
+	assert.Equal(t, Resolved, notifier.notifications[0].Status)
+}
+
+func TestManager_SaveFailure(t *testing.T) {
+	dir, err := ioutil.TempDir("", "alerts")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	m, err := NewManager(filepath.Join(dir, "rules.json"), dummyProductDetailSource{}, &dummyNotifier{})
+	assert.Nil(t, err, "the error should be nil")
+	rule, err := m.CreateRule(Rule{Provider: "ec2", Price: SpotPrice, Condition: Above, Threshold: 0.3, Webhook: "http://example.com/hook"})
+	assert.Nil(t, err, "the error should be nil")
+
+	// the rules can't be saved once the directory is gone, the rules in memory must stay unchanged
+	assert.Nil(t, os.RemoveAll(dir))
+	_, err = m.CreateRule(Rule{Provider: "gce", Price: OnDemandPrice, Condition: Below, Threshold: 1, Webhook: "http://example.com/hook"})
+	assert.NotNil(t, err, "the error should not be nil")
+	updated := rule
+	updated.Threshold = 0.5
+	_, err = m.UpdateRule(rule.Id, updated)
+	assert.NotNil(t, err, "the error should not be nil")
+	assert.NotNil(t, m.DeleteRule(rule.Id))
+	assert.Equal(t, []Rule{rule}, m.Rules())
+}
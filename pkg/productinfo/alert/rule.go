@@ -0,0 +1,181 @@
+// Package alert contains the user defined price alert subscriptions evaluated on every price renewal
+package alert
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"net/url"
+	"path"
+	"time"
+
+	"github.com/banzaicloud/productinfo/pkg/productinfo"
+)
+
+// PriceType the price a rule is evaluated on
+type PriceType string
+
+// Condition the comparison of a price to the threshold of a rule
+type Condition string
+
+// Status the state of an alert
+type Status string
+
+const (
+	// SpotPrice rules are evaluated on the spot price of every availability zone
+	SpotPrice PriceType = "spot"
+	// OnDemandPrice rules are evaluated on the on demand price
+	OnDemandPrice PriceType = "ondemand"
+
+	// Above the alert fires when the price goes above the threshold
+	Above Condition = "above"
+	// Below the alert fires when the price goes below the threshold
+	Below Condition = "below"
+
+	// Firing the condition of the rule is met
+	Firing Status = "firing"
+	// Resolved the price got back past the threshold and the hysteresis
+	Resolved Status = "resolved"
+)
+
+// builtInGpuProviders the providers reporting the GPUs of their instance types, the GPUs of the other providers are
+// attached to the instances as add-ons, so their rules are evaluated on the instance types with the GPUs attached
+var builtInGpuProviders = map[string]bool{
+	"ec2":   true,
+	"azure": true,
+}
+
+// Rule a price alert subscription, the selectors that are left empty match everything
+type Rule struct {
+	Id   string `json:"id"`
+	Name string `json:"name,omitempty"`
+	// Provider the provider the rule applies to
+	Provider string `json:"provider"`
+	// Region the region the rule applies to, every region if empty
+	Region string `json:"region,omitempty"`
+	// Types instance type patterns in shell glob syntax, e.g. c5.*
+	Types []string `json:"types,omitempty"`
+	// MinGpus only instance types with at least this many GPUs are selected, the rules of the providers attaching the
+	// GPUs as add-ons are evaluated on the price of the instance types with this many GPUs of the GpuModel attached
+	MinGpus float64 `json:"minGpus,omitempty"`
+	// GpuModel the model of the attached GPU add-ons, e.g. nvidia-tesla-v100, it's required with minGpus by the
+	// providers without GPUs built in the instance types
+	GpuModel string `json:"gpuModel,omitempty"`
+	// Price the price the rule is evaluated on
+	Price     PriceType `json:"price"`
+	Condition Condition `json:"condition"`
+	Threshold float64   `json:"threshold"`
+	// Hysteresis the amount the price has to get back past the threshold to resolve a firing alert
+	Hysteresis float64 `json:"hysteresis,omitempty"`
+	// Webhook the url the notifications are posted to
+	Webhook string `json:"webhook"`
+	// Secret used to sign the notifications, it's never returned by the API
+	Secret string `json:"secret,omitempty"`
+}
+
+// Notification is sent when an alert starts firing or gets resolved
+type Notification struct {
+	RuleId       string    `json:"ruleId"`
+	RuleName     string    `json:"ruleName,omitempty"`
+	Status       Status    `json:"status"`
+	Time         time.Time `json:"time"`
+	Provider     string    `json:"provider"`
+	Region       string    `json:"region"`
+	InstanceType string    `json:"instanceType"`
+	Zone         string    `json:"zone,omitempty"`
+	GpuModel     string    `json:"gpuModel,omitempty"`
+	Gpus         int       `json:"gpus,omitempty"`
+	Price        PriceType `json:"price"`
+	Condition    Condition `json:"condition"`
+	Threshold    float64   `json:"threshold"`
+	Value        float64   `json:"value"`
+}
+
+// Validate checks whether the rule is complete and consistent
+func (r Rule) Validate() error {
+	if r.Provider == "" {
+		return errors.New("provider is required")
+	}
+	if r.Price != SpotPrice && r.Price != OnDemandPrice {
+		return fmt.Errorf("price must be %s or %s", SpotPrice, OnDemandPrice)
+	}
+	if r.Condition != Above && r.Condition != Below {
+		return fmt.Errorf("condition must be %s or %s", Above, Below)
+	}
+	if r.Threshold <= 0 {
+		return errors.New("threshold must be positive")
+	}
+	if r.Hysteresis < 0 {
+		return errors.New("hysteresis must not be negative")
+	}
+	if r.MinGpus < 0 {
+		return errors.New("minGpus must not be negative")
+	}
+	if r.GpuModel != "" && builtInGpuProviders[r.Provider] {
+		return fmt.Errorf("gpuModel is not supported by provider %s, its instance types have built in GPUs", r.Provider)
+	}
+	if r.GpuModel != "" && r.MinGpus == 0 {
+		return errors.New("minGpus is required with gpuModel")
+	}
+	if r.MinGpus > 0 && r.GpuModel == "" && !builtInGpuProviders[r.Provider] {
+		return fmt.Errorf("gpuModel is required with minGpus by provider %s, its GPUs are attached to the instances as add-ons", r.Provider)
+	}
+	for _, t := range r.Types {
+		if _, err := path.Match(t, ""); err != nil {
+			return fmt.Errorf("invalid type pattern: %s", t)
+		}
+	}
+	u, err := url.Parse(r.Webhook)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return errors.New("webhook must be an http or https url")
+	}
+	return nil
+}
+
+// matches reports whether the rule selects the instance type, the gpu selector is checked separately
+func (r Rule) matches(provider string, region string, instanceType string) bool {
+	if r.Provider != provider || (r.Region != "" && r.Region != region) {
+		return false
+	}
+	if len(r.Types) == 0 {
+		return true
+	}
+	for _, t := range r.Types {
+		if ok, _ := path.Match(t, instanceType); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// attachedGpus returns the number of the GPU add-ons attached to the instance types of the rule
+func (r Rule) attachedGpus() int {
+	return int(math.Ceil(r.MinGpus))
+}
+
+// gpuAddOn returns the GPU add-on of the model of the rule, it returns false if the model is not offered in the region
+// or fewer GPUs of the model can be attached to an instance than the rule requires
+func (r Rule) gpuAddOn(addOns []productinfo.AddOn) (productinfo.AddOn, bool) {
+	for _, addOn := range addOns {
+		if addOn.Type == productinfo.Gpu && addOn.Model == r.GpuModel {
+			return addOn, addOn.MaxPerVm == 0 || r.attachedGpus() <= addOn.MaxPerVm
+		}
+	}
+	return productinfo.AddOn{}, false
+}
+
+// triggered reports whether the price meets the condition of the rule
+func (r Rule) triggered(price float64) bool {
+	if r.Condition == Above {
+		return price > r.Threshold
+	}
+	return price < r.Threshold
+}
+
+// cleared reports whether the price got back past the threshold by at least the hysteresis
+func (r Rule) cleared(price float64) bool {
+	if r.Condition == Above {
+		return price <= r.Threshold-r.Hysteresis
+	}
+	return price >= r.Threshold+r.Hysteresis
+}
@@ -0,0 +1,108 @@
+package azure
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"strings"
+
+	"github.com/Azure/go-autorest/autorest"
+	"github.com/Azure/go-autorest/autorest/adal"
+	"github.com/Azure/go-autorest/autorest/azure"
+	"github.com/Azure/go-autorest/autorest/azure/auth"
+)
+
+const (
+	// PayAsYouGoOffer the offer durable id of the pay as you go subscriptions
+	PayAsYouGoOffer = "MS-AZR-0003P"
+
+	defaultCurrency   = "USD"
+	defaultLocale     = "en-US"
+	defaultRegionInfo = "US"
+)
+
+// Config the settings of an Azure infoer, the empty fields are set to the defaults of a pay as you go subscription in
+// the public cloud
+type Config struct {
+	// SubscriptionId the subscription the Azure APIs are accessed with
+	SubscriptionId string
+	// Environment the name of the Azure cloud, e.g. AzurePublicCloud, AzureChinaCloud or AzureUSGovernmentCloud
+	Environment string
+	// AuthLocation the path of the service principal auth file, the file set by AZURE_AUTH_LOCATION is used if empty
+	AuthLocation string
+	// OfferDurableId the offer or agreement the Rate Card prices are queried for, e.g. MS-AZR-0003P
+	OfferDurableId string
+	// Currency the currency of the Rate Card prices
+	Currency string
+	// Locale the locale of the Rate Card prices
+	Locale string
+	// RegionInfo the region the offer was purchased in
+	RegionInfo string
+	// RetailPricesURL the url of the Retail Prices API, the Rate Card API is used if it's empty or the Retail Prices API
+	// doesn't publish the prices of the offer
+	RetailPricesURL string
+	// ReservationSource the source of the reservation prices, reservations are not retrieved if nil
+	ReservationSource ReservationSource
+}
+
+// withDefaults returns the config with the defaults set in the empty fields
+func (c Config) withDefaults() Config {
+	if c.Environment == "" {
+		c.Environment = azure.PublicCloud.Name
+	}
+	if c.OfferDurableId == "" {
+		c.OfferDurableId = PayAsYouGoOffer
+	}
+	if c.Currency == "" {
+		c.Currency = defaultCurrency
+	}
+	if c.Locale == "" {
+		c.Locale = defaultLocale
+	}
+	if c.RegionInfo == "" {
+		c.RegionInfo = defaultRegionInfo
+	}
+	return c
+}
+
+// rateCardFilter returns the Rate Card API filter of the offer
+func (c Config) rateCardFilter() string {
+	return fmt.Sprintf("OfferDurableId eq '%s' and Currency eq '%s' and Locale eq '%s' and RegionInfo eq '%s'",
+		c.OfferDurableId, c.Currency, c.Locale, c.RegionInfo)
+}
+
+// retailPricesAvailable signals if the Retail Prices API publishes the prices of the offer, it only has the pay as you
+// go prices of the public and the US Government clouds in USD
+func (c Config) retailPricesAvailable(env azure.Environment) bool {
+	return (env.Name == azure.PublicCloud.Name || env.Name == azure.USGovernmentCloud.Name) &&
+		strings.EqualFold(c.OfferDurableId, PayAsYouGoOffer) && c.Currency == defaultCurrency
+}
+
+// newAuthorizer creates an authorizer of the service principal in the auth file of the config for the Resource Manager
+// of the environment
+func (c Config) newAuthorizer(env azure.Environment) (autorest.Authorizer, error) {
+	if c.AuthLocation == "" {
+		return auth.NewAuthorizerFromFile(env.ResourceManagerEndpoint)
+	}
+	contents, err := ioutil.ReadFile(c.AuthLocation)
+	if err != nil {
+		return nil, err
+	}
+	var f struct {
+		ClientID     string `json:"clientId"`
+		ClientSecret string `json:"clientSecret"`
+		TenantID     string `json:"tenantId"`
+	}
+	if err := json.Unmarshal(contents, &f); err != nil {
+		return nil, fmt.Errorf("could not parse auth file %s: %s", c.AuthLocation, err.Error())
+	}
+	oauthConfig, err := adal.NewOAuthConfig(env.ActiveDirectoryEndpoint, f.TenantID)
+	if err != nil {
+		return nil, err
+	}
+	token, err := adal.NewServicePrincipalToken(*oauthConfig, f.ClientID, f.ClientSecret, env.ResourceManagerEndpoint)
+	if err != nil {
+		return nil, err
+	}
+	return autorest.NewBearerAuthorizer(token), nil
+}
@@ -0,0 +1,37 @@
+package azure
+
+import (
+	"testing"
+
+	"github.com/Azure/go-autorest/autorest/azure"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConfig_withDefaults(t *testing.T) {
+	cfg := Config{}.withDefaults()
+	assert.Equal(t, azure.PublicCloud.Name, cfg.Environment)
+	assert.Equal(t, "OfferDurableId eq 'MS-AZR-0003P' and Currency eq 'USD' and Locale eq 'en-US' and RegionInfo eq 'US'", cfg.rateCardFilter())
+
+	cfg = Config{Environment: azure.ChinaCloud.Name, OfferDurableId: "MS-MC-AZR-0033P", Currency: "CNY", Locale: "zh-CN", RegionInfo: "CN"}.withDefaults()
+	assert.Equal(t, "OfferDurableId eq 'MS-MC-AZR-0033P' and Currency eq 'CNY' and Locale eq 'zh-CN' and RegionInfo eq 'CN'", cfg.rateCardFilter())
+}
+
+func TestConfig_retailPricesAvailable(t *testing.T) {
+	tests := []struct {
+		name     string
+		cfg      Config
+		env      azure.Environment
+		expected bool
+	}{
+		{name: "pay as you go in the public cloud", cfg: Config{}, env: azure.PublicCloud, expected: true},
+		{name: "pay as you go in the US Government cloud", cfg: Config{}, env: azure.USGovernmentCloud, expected: true},
+		{name: "china cloud", cfg: Config{OfferDurableId: "MS-MC-AZR-0033P", Currency: "CNY"}, env: azure.ChinaCloud, expected: false},
+		{name: "enterprise agreement", cfg: Config{OfferDurableId: "MS-AZR-0017P"}, env: azure.PublicCloud, expected: false},
+		{name: "other currency", cfg: Config{Currency: "EUR"}, env: azure.PublicCloud, expected: false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(t, test.expected, test.cfg.withDefaults().retailPricesAvailable(test.env))
+		})
+	}
+}
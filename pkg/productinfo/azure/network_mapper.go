@@ -2,18 +2,26 @@ package azure
 
 import (
 	"github.com/banzaicloud/productinfo/pkg/productinfo"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	ntwLow      = "Low"
+	ntwModerate = "Moderate"
+	ntwHigh     = "High"
+	ntwVeryHigh = "Very High"
 )
 
 var (
-	// TODO
 	ntwPerfMap = map[string][]string{
-		productinfo.NTW_LOW:    {"Low"},
-		productinfo.NTW_MEDIUM: {"Moderate"},
-		productinfo.NTW_HIGH:   {""},
+		productinfo.NTW_LOW:    {ntwLow},
+		productinfo.NTW_MEDIUM: {ntwModerate},
+		productinfo.NTW_HIGH:   {ntwHigh},
+		productinfo.NTW_EXTRA:  {ntwVeryHigh},
 	}
 )
 
-// AzureNetworkMapper module object for handling Google Cloud specific VM to Networking capabilities mapping
+// AzureNetworkMapper module object for handling Azure specific VM to Networking capabilities mapping
 type AzureNetworkMapper struct {
 }
 
@@ -22,7 +30,14 @@ func newAzureNetworkMapper() *AzureNetworkMapper {
 	return &AzureNetworkMapper{}
 }
 
-// MapNetworkPerf maps the network performance of the gce instance to the category supported by telescopes
+// MapNetworkPerf maps the network performance of the azure vm to the category supported by telescopes
 func (nm *AzureNetworkMapper) MapNetworkPerf(vm productinfo.VmInfo) (string, error) {
-	return ntwPerfMap[productinfo.NTW_MEDIUM][0], nil
+	for perfCat, strVals := range ntwPerfMap {
+		if productinfo.Contains(strVals, vm.NtwPerf) {
+			return perfCat, nil
+		}
+	}
+	// vm sizes not listed in the resource skus have no network performance, these are categorized as moderate
+	log.Warnf("could not determine network performance for: [%s], falling back to %s", vm.NtwPerf, productinfo.NTW_MEDIUM)
+	return productinfo.NTW_MEDIUM, nil
 }
@@ -7,12 +7,13 @@ import (
 	"regexp"
 	"strconv"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/Azure/azure-sdk-for-go/services/compute/mgmt/2018-04-01/compute"
 	"github.com/Azure/azure-sdk-for-go/services/preview/commerce/mgmt/2015-06-01-preview/commerce"
 	"github.com/Azure/azure-sdk-for-go/services/resources/mgmt/2016-06-01/subscriptions"
 	"github.com/Azure/go-autorest/autorest/azure"
-	"github.com/Azure/go-autorest/autorest/azure/auth"
 	"github.com/banzaicloud/productinfo/pkg/productinfo"
 	log "github.com/sirupsen/logrus"
 )
@@ -20,6 +21,10 @@ import (
 const (
 	cpu    = "cpu"
 	memory = "memory"
+
+	// spotPricesMaxAge the age of the spot prices they are queried again after, the spot prices of every region are
+	// queried at once instead of querying every region on every renewal of the short lived prices
+	spotPricesMaxAge = 10 * time.Minute
 )
 
 var (
@@ -28,6 +33,8 @@ var (
 		"au": "australia",
 		"br": "brazil",
 		"ca": "canada",
+		"cn": "china",
+		"de": "germany",
 		"eu": "europe",
 		"fr": "france",
 		"in": "india",
@@ -55,29 +62,74 @@ type AzureInfoer struct {
 	subscriptionsClient subscriptions.Client
 	vmSizesClient       compute.VirtualMachineSizesClient
 	rateCardClient      commerce.RateCardClient
+	rateCardFilter      string
+	resourceSkusClient  compute.ResourceSkusClient
+	retailPricesClient  *RetailPricesClient
+	reservationSource   ReservationSource
+
+	// mu guards the vm sizes, the reservations and the quality issues
+	mu sync.RWMutex
+	// qualityIssues the meters of the last Initialize call that couldn't be mapped to a region or parsed
+	qualityIssues []productinfo.QualityIssue
+	// vms the vm sizes available for the subscription per region, read from the resource skus
+	vms map[string][]productinfo.VmInfo
+	// reservations the reservation prices of the vm sizes per region retrieved by the last Initialize
+	reservations map[string]map[string][]productinfo.CommitmentPrice
+	// spotQualityIssues the issues of the last spot price renewal per region
+	spotQualityIssues map[string][]productinfo.QualityIssue
+	// spotItems the spot meters of the last spot price query per region
+	spotItems   map[string][]retailPrice
+	spotUpdated time.Time
+	spotMu      sync.Mutex
 }
 
 // NewAzureInfoer creates a new instance of the Azure infoer
-func NewAzureInfoer(subscriptionId string) (*AzureInfoer, error) {
-	authorizer, err := auth.NewAuthorizerFromFile(azure.PublicCloud.ResourceManagerEndpoint)
+// prices are retrieved from the Retail Prices API if it publishes the prices of the configured offer, from the
+// deprecated Rate Card API otherwise, reservation prices are added to the prices if the reservation source is set
+func NewAzureInfoer(cfg Config) (*AzureInfoer, error) {
+	cfg = cfg.withDefaults()
+	env, err := azure.EnvironmentFromName(cfg.Environment)
+	if err != nil {
+		return nil, err
+	}
+
+	authorizer, err := cfg.newAuthorizer(env)
 	if err != nil {
 		return nil, err
 	}
 
-	sClient := subscriptions.NewClient()
+	sClient := subscriptions.NewClientWithBaseURI(env.ResourceManagerEndpoint)
 	sClient.Authorizer = authorizer
 
-	vmClient := compute.NewVirtualMachineSizesClient(subscriptionId)
+	vmClient := compute.NewVirtualMachineSizesClientWithBaseURI(env.ResourceManagerEndpoint, cfg.SubscriptionId)
 	vmClient.Authorizer = authorizer
 
-	rcClient := commerce.NewRateCardClient(subscriptionId)
+	rcClient := commerce.NewRateCardClientWithBaseURI(env.ResourceManagerEndpoint, cfg.SubscriptionId)
 	rcClient.Authorizer = authorizer
 
+	skusClient := compute.NewResourceSkusClientWithBaseURI(env.ResourceManagerEndpoint, cfg.SubscriptionId)
+	skusClient.Authorizer = authorizer
+
+	var retailPricesClient *RetailPricesClient
+	if cfg.RetailPricesURL != "" {
+		if cfg.retailPricesAvailable(env) {
+			retailPricesClient = NewRetailPricesClient(cfg.RetailPricesURL)
+		} else {
+			log.Warnf("the Retail Prices API doesn't publish the prices of offer %s in %s of %s, using the Rate Card API",
+				cfg.OfferDurableId, cfg.Currency, env.Name)
+		}
+	}
+
 	return &AzureInfoer{
-		subscriptionId:      subscriptionId,
+		subscriptionId:      cfg.SubscriptionId,
 		subscriptionsClient: sClient,
 		vmSizesClient:       vmClient,
 		rateCardClient:      rcClient,
+		rateCardFilter:      cfg.rateCardFilter(),
+		resourceSkusClient:  skusClient,
+		retailPricesClient:  retailPricesClient,
+		reservationSource:   cfg.ReservationSource,
+		spotQualityIssues:   make(map[string][]productinfo.QualityIssue),
 	}, nil
 }
 
@@ -124,10 +176,10 @@ func (a *AzureInfoer) checkRegionID(regionID string, regions map[string]string)
 	return false
 }
 
-// Initialize downloads and parses the Rate Card API's meter list on Azure
+// Initialize downloads and parses the virtual machine prices of the Retail Prices API, or the Rate Card API's meter list
+// if the retail prices client is not set, the spot prices are spread to the availability zones of the vm sizes
 func (a *AzureInfoer) Initialize() (map[string]map[string]productinfo.Price, error) {
 	log.Debug("initializing Azure price info")
-	allPrices := make(map[string]map[string]productinfo.Price)
 
 	regions, err := a.GetRegions()
 	if err != nil {
@@ -136,70 +188,149 @@ func (a *AzureInfoer) Initialize() (map[string]map[string]productinfo.Price, err
 
 	log.Debugf("queried regions: %v", regions)
 
-	rateCardFilter := "OfferDurableId eq 'MS-AZR-0003p' and Currency eq 'USD' and Locale eq 'en-US' and RegionInfo eq 'US'"
-	result, err := a.rateCardClient.Get(context.TODO(), rateCardFilter)
+	var allPrices map[string]map[string]productinfo.Price
+	var issues []productinfo.QualityIssue
+	if a.retailPricesClient != nil {
+		items, err := a.retailPricesClient.GetRetailPrices(consumptionFilter)
+		if err != nil {
+			return nil, err
+		}
+		allPrices, issues = parseRetailPrices(items, regions)
+	} else {
+		log.Warn("the Rate Card API is deprecated, use the Retail Prices API instead")
+		allPrices, issues, err = a.getRateCardPrices(regions)
+		if err != nil {
+			return nil, err
+		}
+	}
+	a.refreshResourceSkus()
+	a.refreshReservations()
+	for region, prices := range allPrices {
+		spreadSpotPrices(region, prices, a.regionVmSizeZones(region))
+		a.addReservationPrices(region, prices)
+	}
+
+	a.mu.Lock()
+	a.qualityIssues = issues
+	a.mu.Unlock()
+	log.Debug("finished initializing Azure price info")
+	return allPrices, nil
+}
+
+// getRateCardPrices downloads and parses the Rate Card API's meter list, the regions and the vm sizes are guessed from
+// the meter regions and sub categories
+func (a *AzureInfoer) getRateCardPrices(regions map[string]string) (map[string]map[string]productinfo.Price, []productinfo.QualityIssue, error) {
+	allPrices := make(map[string]map[string]productinfo.Price)
+	var issues []productinfo.QualityIssue
+	unmapped := make(map[string]bool)
+
+	result, err := a.rateCardClient.Get(context.TODO(), a.rateCardFilter)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 	for _, v := range *result.Meters {
 		if *v.MeterCategory == "Virtual Machines" && len(*v.MeterTags) == 0 && *v.MeterRegion != "" {
-			if !strings.Contains(*v.MeterSubCategory, "(Windows)") {
-				region, err := a.toRegionID(*v.MeterRegion, regions)
-				if err != nil {
-					log.Debugf(err.Error())
-					continue
-				}
-				var instanceType string
-				category := strings.Split(*v.MeterSubCategory, " ")
-				if len(category) < 2 {
-					log.Debugf("couldn't parse meter sub category: %s, region=%s", *v.MeterSubCategory, *v.MeterRegion)
-					continue
-				}
-				switch category[1] {
-				case "VM":
-					instanceType = category[0]
-				case "VM_Promo":
-					instanceType = category[0] + "_Promo"
-				default:
-					log.Debugf("instance type is empty: %s, region=%s", *v.MeterSubCategory, *v.MeterRegion)
-					continue
+			os := productinfo.Linux
+			if strings.Contains(*v.MeterSubCategory, "(Windows)") {
+				os = productinfo.Windows
+			}
+			region, err := a.toRegionID(*v.MeterRegion, regions)
+			if err != nil {
+				log.Debug(err.Error())
+				if !unmapped[*v.MeterRegion] {
+					unmapped[*v.MeterRegion] = true
+					issues = append(issues, productinfo.QualityIssue{Kind: productinfo.UnmappedRegion, Subject: *v.MeterRegion,
+						Detail: "meter " + *v.MeterSubCategory})
 				}
-				instanceType = a.transformMachineType(instanceType)
+				continue
+			}
+			var instanceType string
+			category := strings.Split(*v.MeterSubCategory, " ")
+			if len(category) < 2 {
+				log.Debugf("couldn't parse meter sub category: %s, region=%s", *v.MeterSubCategory, *v.MeterRegion)
+				issues = append(issues, productinfo.QualityIssue{Kind: productinfo.UnparsableProduct, Region: region,
+					Subject: *v.MeterSubCategory})
+				continue
+			}
+			switch category[1] {
+			case "VM":
+				instanceType = category[0]
+			case "VM_Promo":
+				instanceType = category[0] + "_Promo"
+			default:
+				log.Debugf("instance type is empty: %s, region=%s", *v.MeterSubCategory, *v.MeterRegion)
+				continue
+			}
+			instanceType = a.transformMachineType(instanceType)
 
-				var priceInUsd float64
+			var priceInUsd float64
 
-				if len(v.MeterRates) < 1 {
-					log.Debugf("%s doesn't have rate info in region %s", *v.MeterSubCategory, *v.MeterRegion)
-					continue
-				}
-				for _, rate := range v.MeterRates {
-					priceInUsd += *rate
-				}
-				if allPrices[region] == nil {
-					allPrices[region] = make(map[string]productinfo.Price)
-				}
-				price := allPrices[region][instanceType]
-				if !strings.Contains(*v.MeterSubCategory, "Low Priority") {
-					price.OnDemandPrice = priceInUsd
-				} else {
-					spotPrice := make(productinfo.SpotPriceInfo)
-					spotPrice[region] = priceInUsd
-					price.SpotPrice = spotPrice
-				}
+			if len(v.MeterRates) < 1 {
+				log.Debugf("%s doesn't have rate info in region %s", *v.MeterSubCategory, *v.MeterRegion)
+				continue
+			}
+			for _, rate := range v.MeterRates {
+				priceInUsd += *rate
+			}
+			if allPrices[region] == nil {
+				allPrices[region] = make(map[string]productinfo.Price)
+			}
+			price := allPrices[region][instanceType]
+			if !strings.Contains(*v.MeterSubCategory, "Low Priority") {
+				price.SetOsPrice(os, priceInUsd)
+			} else {
+				spotPrice := make(productinfo.SpotPriceInfo)
+				spotPrice[region] = priceInUsd
+				price.SetOsSpotPrice(os, spotPrice)
+			}
 
-				allPrices[region][instanceType] = price
-				log.Debugf("price info added: [region=%s, machinetype=%s, price=%v]", region, instanceType, price)
-				mts := a.getMachineTypeVariants(instanceType)
-				for _, mt := range mts {
-					allPrices[region][mt] = price
-					log.Debugf("price info added: [region=%s, machinetype=%s, price=%v]", region, mt, price)
-				}
+			allPrices[region][instanceType] = price
+			log.Debugf("price info added: [region=%s, machinetype=%s, price=%v]", region, instanceType, price)
+			mts := a.getMachineTypeVariants(instanceType)
+			for _, mt := range mts {
+				allPrices[region][mt] = price
+				log.Debugf("price info added: [region=%s, machinetype=%s, price=%v]", region, mt, price)
 			}
 		}
 	}
 
-	log.Debug("finished initializing Azure price info")
-	return allPrices, nil
+	return allPrices, issues, nil
+}
+
+// refreshReservations retrieves the reservation prices, the previous prices are kept if the retrieval fails
+func (a *AzureInfoer) refreshReservations() {
+	if a.reservationSource == nil {
+		return
+	}
+	reservations, err := a.reservationSource.GetReservationPrices()
+	if err != nil {
+		log.WithError(err).Warn("could not retrieve the Azure reservation prices")
+		return
+	}
+	a.mu.Lock()
+	a.reservations = reservations
+	a.mu.Unlock()
+}
+
+// addReservationPrices adds the reserved instance prices to the commitment prices of the vm sizes in a region
+func (a *AzureInfoer) addReservationPrices(region string, prices map[string]productinfo.Price) {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	for vmSize, cps := range a.reservations[region] {
+		price, ok := prices[vmSize]
+		if !ok {
+			continue
+		}
+		price.Commitments = cps
+		prices[vmSize] = price
+	}
+}
+
+// QualityIssues returns the meters of the last Initialize call that couldn't be mapped to a region or parsed
+func (a *AzureInfoer) QualityIssues() []productinfo.QualityIssue {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return a.qualityIssues
 }
 
 func (a *AzureInfoer) transformMachineType(mt string) string {
@@ -317,9 +448,16 @@ func (a *AzureInfoer) GetAttributeValues(attribute string) (productinfo.AttrValu
 	return values, nil
 }
 
-// GetProducts retrieves the available virtual machines based on the arguments provided
+// GetProducts retrieves the available virtual machines based on the arguments provided, the vm sizes are read from the
+// resource skus retrieved by the last Initialize, or listed in the region if the resource skus couldn't be retrieved
 func (a *AzureInfoer) GetProducts(regionId string) ([]productinfo.VmInfo, error) {
 	log.Debugf("getting product info [region=%s]", regionId)
+	if skuVms, ok := a.getRegionVms(regionId); ok {
+		vms := make([]productinfo.VmInfo, len(skuVms))
+		copy(vms, skuVms)
+		log.Debugf("found vms: %#v", vms)
+		return vms, nil
+	}
 	var vms []productinfo.VmInfo
 	vmSizes, err := a.vmSizesClient.List(context.TODO(), regionId)
 	if err != nil {
@@ -330,7 +468,6 @@ func (a *AzureInfoer) GetProducts(regionId string) ([]productinfo.VmInfo, error)
 			Type: *v.Name,
 			Cpus: float64(*v.NumberOfCores),
 			Mem:  float64(*v.MemoryInMB) / 1024,
-			// TODO: netw perf
 		})
 	}
 
@@ -338,9 +475,13 @@ func (a *AzureInfoer) GetProducts(regionId string) ([]productinfo.VmInfo, error)
 	return vms, nil
 }
 
-// GetZones returns the availability zones in a region
+// GetZones returns the availability zones in a region, the region itself if it has no availability zones
 func (a *AzureInfoer) GetZones(region string) ([]string, error) {
-	return []string{region}, nil
+	zones := regionZones(a.regionVmSizeZones(region))
+	if len(zones) == 0 {
+		return []string{region}, nil
+	}
+	return zones, nil
 }
 
 // GetRegions returns a map with available regions transforms the api representation into a "plain" map
@@ -356,14 +497,55 @@ func (a *AzureInfoer) GetRegions() (map[string]string, error) {
 	return regions, nil
 }
 
-// HasShortLivedPriceInfo - Azure doesn't have frequently changing prices
+// HasShortLivedPriceInfo - Azure spot prices change frequently, they can only be renewed from the Retail Prices API
 func (a *AzureInfoer) HasShortLivedPriceInfo() bool {
-	return false
+	return a.retailPricesClient != nil
 }
 
-// GetCurrentPrices retrieves all the price info in a region
+// GetCurrentPrices retrieves the spot prices of a region from the Retail Prices API, only the spot meters are queried,
+// the pay as you go and reservation prices are renewed by Initialize
 func (a *AzureInfoer) GetCurrentPrices(region string) (map[string]productinfo.Price, error) {
-	return nil, errors.New("azure prices cannot be queried on the fly")
+	if a.retailPricesClient == nil {
+		return nil, errors.New("azure prices cannot be queried on the fly from the Rate Card API")
+	}
+	items, err := a.getSpotItems()
+	if err != nil {
+		return nil, err
+	}
+	prices, issues := parseRetailSpotPrices(items[region], region)
+	a.mu.Lock()
+	a.spotQualityIssues[region] = issues
+	a.mu.Unlock()
+	spreadSpotPrices(region, prices, a.regionVmSizeZones(region))
+	return prices, nil
+}
+
+// getSpotItems returns the spot meters per region, they're queried for every region at once if the last query is older
+// than spotPricesMaxAge
+func (a *AzureInfoer) getSpotItems() (map[string][]retailPrice, error) {
+	a.spotMu.Lock()
+	defer a.spotMu.Unlock()
+	if a.spotItems != nil && time.Since(a.spotUpdated) < spotPricesMaxAge {
+		return a.spotItems, nil
+	}
+	items, err := a.retailPricesClient.GetRetailPrices(spotFilter)
+	if err != nil {
+		return nil, err
+	}
+	spotItems := make(map[string][]retailPrice)
+	for _, item := range items {
+		spotItems[item.ArmRegionName] = append(spotItems[item.ArmRegionName], item)
+	}
+	a.spotItems, a.spotUpdated = spotItems, time.Now()
+	return spotItems, nil
+}
+
+// CurrentPriceQualityIssues returns the spot meters of the last GetCurrentPrices call of the region that couldn't be
+// parsed
+func (a *AzureInfoer) CurrentPriceQualityIssues(region string) []productinfo.QualityIssue {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return a.spotQualityIssues[region]
 }
 
 // GetMemoryAttrName returns the provider representation of the memory attribute
@@ -42,6 +42,8 @@ func TestAzureInfoer_toRegionID(t *testing.T) {
 		"southeastasia":      "Southeast Asia",
 		"brazilsouth":        "Brazil South",
 		"westcentralus":      "West Central US",
+		"chinaeast":          "China East",
+		"chinanorth2":        "China North 2",
 	}
 
 	tests := []struct {
@@ -161,6 +163,22 @@ func TestAzureInfoer_toRegionID(t *testing.T) {
 				assert.Nil(t, err, "error should be nil")
 			},
 		},
+		{
+			name:         "successful check china",
+			sourceRegion: "CN East",
+			check: func(regionId string, err error) {
+				assert.Equal(t, "chinaeast", regionId, "invalid region ID returned")
+				assert.Nil(t, err, "error should be nil")
+			},
+		},
+		{
+			name:         "successful check china with postfix",
+			sourceRegion: "CN North 2",
+			check: func(regionId string, err error) {
+				assert.Equal(t, "chinanorth2", regionId, "invalid region ID returned")
+				assert.Nil(t, err, "error should be nil")
+			},
+		},
 		{
 			name:         "check not supported region",
 			sourceRegion: "US Gov TX",
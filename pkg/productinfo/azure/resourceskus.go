@@ -0,0 +1,251 @@
+package azure
+
+import (
+	"context"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/Azure/azure-sdk-for-go/services/compute/mgmt/2018-04-01/compute"
+	"github.com/banzaicloud/productinfo/pkg/productinfo"
+	log "github.com/sirupsen/logrus"
+)
+
+// listResourceSkus lists the virtual machine resource SKUs available for the subscription
+func (a *AzureInfoer) listResourceSkus() ([]compute.ResourceSku, error) {
+	var skus []compute.ResourceSku
+	iter, err := a.resourceSkusClient.ListComplete(context.TODO())
+	if err != nil {
+		return nil, err
+	}
+	for iter.NotDone() {
+		sku := iter.Value()
+		if sku.ResourceType != nil && *sku.ResourceType == "virtualMachines" {
+			skus = append(skus, sku)
+		}
+		if err := iter.Next(); err != nil {
+			return nil, err
+		}
+	}
+	return skus, nil
+}
+
+// refreshResourceSkus retrieves the vm sizes available in the regions, the previous vm sizes are kept if the retrieval
+// fails
+func (a *AzureInfoer) refreshResourceSkus() {
+	skus, err := a.listResourceSkus()
+	if err != nil {
+		log.WithError(err).Warn("could not retrieve the Azure resource skus, vm sizes and availability zones are not refreshed")
+		return
+	}
+	vms := regionVms(skus)
+	a.mu.Lock()
+	a.vms = vms
+	a.mu.Unlock()
+}
+
+// getRegionVms returns the vm sizes available in a region, the resource skus are retrieved on first use
+// the second return value is false if the resource skus couldn't be retrieved
+func (a *AzureInfoer) getRegionVms(region string) ([]productinfo.VmInfo, bool) {
+	a.mu.RLock()
+	vms := a.vms
+	a.mu.RUnlock()
+	if vms == nil {
+		a.refreshResourceSkus()
+		a.mu.RLock()
+		vms = a.vms
+		a.mu.RUnlock()
+	}
+	return vms[region], vms != nil
+}
+
+// regionVmSizeZones returns the availability zones of the vm sizes in a region
+func (a *AzureInfoer) regionVmSizeZones(region string) map[string][]string {
+	vms, _ := a.getRegionVms(region)
+	return vmSizeZones(vms)
+}
+
+// regionVms collects the vm sizes available for the subscription per region with their capabilities and availability
+// zones, the locations and the zones the subscription is restricted from are left out
+func regionVms(skus []compute.ResourceSku) map[string][]productinfo.VmInfo {
+	vms := make(map[string][]productinfo.VmInfo)
+	for _, sku := range skus {
+		if sku.Name == nil || sku.LocationInfo == nil {
+			continue
+		}
+		restrictedLocations, restrictedZones := skuRestrictions(sku)
+		caps := newSkuCapabilities(sku)
+		for _, li := range *sku.LocationInfo {
+			if li.Location == nil {
+				continue
+			}
+			region := strings.ToLower(*li.Location)
+			if restrictedLocations[region] {
+				continue
+			}
+			var zones []string
+			if li.Zones != nil && len(*li.Zones) > 0 {
+				for _, z := range *li.Zones {
+					if !restrictedZones[region][z] {
+						zones = append(zones, z)
+					}
+				}
+				if len(zones) == 0 {
+					// restricted in every zone
+					continue
+				}
+				sort.Strings(zones)
+			}
+			vm := caps.vmInfo(*sku.Name)
+			vm.Zones = zones
+			vms[region] = append(vms[region], vm)
+		}
+	}
+	for _, regionVms := range vms {
+		sort.Slice(regionVms, func(i, j int) bool {
+			return regionVms[i].Type < regionVms[j].Type
+		})
+	}
+	return vms
+}
+
+// skuRestrictions collects the locations and the zones per location the subscription can't use the sku in
+func skuRestrictions(sku compute.ResourceSku) (map[string]bool, map[string]map[string]bool) {
+	locations := make(map[string]bool)
+	zones := make(map[string]map[string]bool)
+	if sku.Restrictions == nil {
+		return locations, zones
+	}
+	for _, r := range *sku.Restrictions {
+		if r.RestrictionInfo == nil || r.RestrictionInfo.Locations == nil {
+			continue
+		}
+		for _, l := range *r.RestrictionInfo.Locations {
+			location := strings.ToLower(l)
+			switch r.Type {
+			case compute.Location:
+				locations[location] = true
+			case compute.Zone:
+				if r.RestrictionInfo.Zones == nil {
+					continue
+				}
+				if zones[location] == nil {
+					zones[location] = make(map[string]bool)
+				}
+				for _, z := range *r.RestrictionInfo.Zones {
+					zones[location][z] = true
+				}
+			}
+		}
+	}
+	return locations, zones
+}
+
+// skuCapabilities the capabilities of a resource sku by name, e.g. vCPUs, MemoryGB or GPUs
+type skuCapabilities map[string]string
+
+func newSkuCapabilities(sku compute.ResourceSku) skuCapabilities {
+	caps := make(skuCapabilities)
+	if sku.Capabilities == nil {
+		return caps
+	}
+	for _, c := range *sku.Capabilities {
+		if c.Name != nil && c.Value != nil {
+			caps[*c.Name] = *c.Value
+		}
+	}
+	return caps
+}
+
+func (c skuCapabilities) float(name string) float64 {
+	v, _ := strconv.ParseFloat(c[name], 64)
+	return v
+}
+
+// vmInfo returns the vm size described by the capabilities, the cpus of the constrained vCPU sizes are the available
+// vCPUs
+func (c skuCapabilities) vmInfo(vmSize string) productinfo.VmInfo {
+	cpus := c.float("vCPUsAvailable")
+	if cpus == 0 {
+		cpus = c.float("vCPUs")
+	}
+	return productinfo.VmInfo{
+		Type:    vmSize,
+		Cpus:    cpus,
+		Mem:     c.float("MemoryGB"),
+		Gpus:    c.float("GPUs"),
+		NtwPerf: c.networkPerf(),
+	}
+}
+
+// networkPerf estimates the network performance of a vm size from the accelerated networking support and the number
+// of network interfaces it can have, the bandwidth of the vm sizes grows with both
+func (c skuCapabilities) networkPerf() string {
+	nics := c.float("MaxNetworkInterfaces")
+	switch {
+	case c["AcceleratedNetworkingEnabled"] == "True" && nics >= 8:
+		return ntwVeryHigh
+	case c["AcceleratedNetworkingEnabled"] == "True":
+		return ntwHigh
+	case nics >= 2:
+		return ntwModerate
+	default:
+		return ntwLow
+	}
+}
+
+// vmSizeZones collects the availability zones of the vm sizes, vm sizes without zones are left out
+func vmSizeZones(vms []productinfo.VmInfo) map[string][]string {
+	zones := make(map[string][]string)
+	for _, vm := range vms {
+		if len(vm.Zones) > 0 {
+			zones[vm.Type] = vm.Zones
+		}
+	}
+	return zones
+}
+
+// regionZones returns the availability zones of a region, the union of the zones of its vm sizes
+func regionZones(vmSizes map[string][]string) []string {
+	set := make(map[string]bool)
+	for _, zones := range vmSizes {
+		for _, z := range zones {
+			set[z] = true
+		}
+	}
+	zones := make([]string, 0, len(set))
+	for z := range set {
+		zones = append(zones, z)
+	}
+	sort.Strings(zones)
+	return zones
+}
+
+// spreadSpotPrices replaces the regional spot prices of the vm sizes with the same price in every availability zone
+// the vm size is available in, vm sizes without zones of their own are available in every zone of the region, the
+// prices are kept regional if the region has no zones
+func spreadSpotPrices(region string, prices map[string]productinfo.Price, vmSizes map[string][]string) {
+	allZones := regionZones(vmSizes)
+	spread := func(sp productinfo.SpotPriceInfo, zones []string) productinfo.SpotPriceInfo {
+		price, ok := sp[region]
+		if !ok || len(zones) == 0 {
+			return sp
+		}
+		zoned := make(productinfo.SpotPriceInfo, len(zones))
+		for _, z := range zones {
+			zoned[z] = price
+		}
+		return zoned
+	}
+	for vmSize, price := range prices {
+		zones, ok := vmSizes[vmSize]
+		if !ok {
+			zones = allZones
+		}
+		price.SpotPrice = spread(price.SpotPrice, zones)
+		for os, op := range price.OsPrices {
+			price.SetOsSpotPrice(os, spread(op.SpotPrice, zones))
+		}
+		prices[vmSize] = price
+	}
+}
@@ -0,0 +1,111 @@
+package azure
+
+import (
+	"testing"
+
+	"github.com/Azure/azure-sdk-for-go/services/compute/mgmt/2018-04-01/compute"
+	"github.com/Azure/go-autorest/autorest/to"
+	"github.com/banzaicloud/productinfo/pkg/productinfo"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRegionVms(t *testing.T) {
+	sku := func(name string, location string, zones []string, caps map[string]string, restrictions ...compute.ResourceSkuRestrictions) compute.ResourceSku {
+		var capabilities []compute.ResourceSkuCapabilities
+		for n, v := range caps {
+			capabilities = append(capabilities, compute.ResourceSkuCapabilities{Name: to.StringPtr(n), Value: to.StringPtr(v)})
+		}
+		return compute.ResourceSku{
+			Name:         to.StringPtr(name),
+			ResourceType: to.StringPtr("virtualMachines"),
+			LocationInfo: &[]compute.ResourceSkuLocationInfo{{Location: to.StringPtr(location), Zones: &zones}},
+			Capabilities: &capabilities,
+			Restrictions: &restrictions,
+		}
+	}
+	restriction := func(restrictionType compute.ResourceSkuRestrictionsType, location string, zones ...string) compute.ResourceSkuRestrictions {
+		return compute.ResourceSkuRestrictions{
+			Type:            restrictionType,
+			ReasonCode:      compute.NotAvailableForSubscription,
+			RestrictionInfo: &compute.ResourceSkuRestrictionInfo{Locations: &[]string{location}, Zones: &zones},
+		}
+	}
+	vms := regionVms([]compute.ResourceSku{
+		sku("Standard_D2s_v3", "WestEurope", []string{"3", "1", "2"},
+			map[string]string{"vCPUs": "2", "MemoryGB": "8", "MaxNetworkInterfaces": "2", "AcceleratedNetworkingEnabled": "True"},
+			restriction(compute.Zone, "westeurope", "3")),
+		sku("Standard_M8-2ms", "westeurope", []string{"2"},
+			map[string]string{"vCPUs": "8", "vCPUsAvailable": "2", "MemoryGB": "218.75", "MaxNetworkInterfaces": "4"}),
+		sku("Standard_NC6", "westeurope", nil,
+			map[string]string{"vCPUs": "6", "MemoryGB": "56", "GPUs": "1", "MaxNetworkInterfaces": "1"}),
+		sku("Standard_M8ms", "westeurope", []string{"1"}, map[string]string{"vCPUs": "8"},
+			restriction(compute.Zone, "westeurope", "1")),
+		sku("Standard_D2s_v3", "westcentralus", nil, map[string]string{"vCPUs": "2"},
+			restriction(compute.Location, "WestCentralUS")),
+		sku("Standard_D64s_v3", "westcentralus", nil,
+			map[string]string{"vCPUs": "64", "MaxNetworkInterfaces": "8", "AcceleratedNetworkingEnabled": "True"}),
+		{ResourceType: to.StringPtr("virtualMachines")},
+	})
+	assert.Equal(t, map[string][]productinfo.VmInfo{
+		"westeurope": {
+			{Type: "Standard_D2s_v3", Cpus: 2, Mem: 8, NtwPerf: "High", Zones: []string{"1", "2"}},
+			{Type: "Standard_M8-2ms", Cpus: 2, Mem: 218.75, NtwPerf: "Moderate", Zones: []string{"2"}},
+			{Type: "Standard_NC6", Cpus: 6, Mem: 56, Gpus: 1, NtwPerf: "Low"},
+		},
+		"westcentralus": {
+			{Type: "Standard_D64s_v3", Cpus: 64, NtwPerf: "Very High"},
+		},
+	}, vms)
+
+	zones := vmSizeZones(vms["westeurope"])
+	assert.Equal(t, map[string][]string{"Standard_D2s_v3": {"1", "2"}, "Standard_M8-2ms": {"2"}}, zones)
+	assert.Equal(t, []string{"1", "2"}, regionZones(zones))
+	assert.Equal(t, []string{}, regionZones(vmSizeZones(vms["westcentralus"])))
+}
+
+func TestAzureNetworkMapper_MapNetworkPerf(t *testing.T) {
+	mapper := newAzureNetworkMapper()
+	for ntwPerf, cat := range map[string]string{"Low": productinfo.NTW_LOW, "Moderate": productinfo.NTW_MEDIUM,
+		"High": productinfo.NTW_HIGH, "Very High": productinfo.NTW_EXTRA} {
+		actual, err := mapper.MapNetworkPerf(productinfo.VmInfo{NtwPerf: ntwPerf})
+		assert.Nil(t, err)
+		assert.Equal(t, cat, actual)
+	}
+	for _, ntwPerf := range []string{"", "Unknown"} {
+		actual, err := mapper.MapNetworkPerf(productinfo.VmInfo{NtwPerf: ntwPerf})
+		assert.Nil(t, err)
+		assert.Equal(t, productinfo.NTW_MEDIUM, actual)
+	}
+}
+
+func TestSpreadSpotPrices(t *testing.T) {
+	prices := map[string]productinfo.Price{
+		"Standard_D2s_v3": {
+			OnDemandPrice: 0.11,
+			SpotPrice:     productinfo.SpotPriceInfo{"westeurope": 0.02},
+			OsPrices: map[string]productinfo.OsPrice{productinfo.Windows: {OnDemandPrice: 0.2,
+				SpotPrice: productinfo.SpotPriceInfo{"westeurope": 0.04}}},
+		},
+		"Standard_M8ms": {OnDemandPrice: 2.1, SpotPrice: productinfo.SpotPriceInfo{"westeurope": 0.4}},
+		"Standard_A1":   {OnDemandPrice: 0.06, SpotPrice: productinfo.SpotPriceInfo{"westeurope": 0.01}},
+		"Standard_A2":   {OnDemandPrice: 0.12},
+	}
+	spreadSpotPrices("westeurope", prices, map[string][]string{"Standard_D2s_v3": {"1", "2"}, "Standard_M8ms": {"3"}})
+	assert.Equal(t, map[string]productinfo.Price{
+		"Standard_D2s_v3": {
+			OnDemandPrice: 0.11,
+			SpotPrice:     productinfo.SpotPriceInfo{"1": 0.02, "2": 0.02},
+			OsPrices: map[string]productinfo.OsPrice{productinfo.Windows: {OnDemandPrice: 0.2,
+				SpotPrice: productinfo.SpotPriceInfo{"1": 0.04, "2": 0.04}}},
+		},
+		"Standard_M8ms": {OnDemandPrice: 2.1, SpotPrice: productinfo.SpotPriceInfo{"3": 0.4}},
+		// vm sizes without zones of their own are available in every zone of the region
+		"Standard_A1": {OnDemandPrice: 0.06, SpotPrice: productinfo.SpotPriceInfo{"1": 0.01, "2": 0.01, "3": 0.01}},
+		"Standard_A2": {OnDemandPrice: 0.12},
+	}, prices)
+
+	// the prices are kept regional in regions without zones
+	prices = map[string]productinfo.Price{"Standard_A1": {OnDemandPrice: 0.06, SpotPrice: productinfo.SpotPriceInfo{"westcentralus": 0.01}}}
+	spreadSpotPrices("westcentralus", prices, nil)
+	assert.Equal(t, productinfo.SpotPriceInfo{"westcentralus": 0.01}, prices["Standard_A1"].SpotPrice)
+}
@@ -0,0 +1,276 @@
+package azure
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/banzaicloud/productinfo/pkg/productinfo"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// RetailPricesURL the url of the Azure Retail Prices API
+	RetailPricesURL = "https://prices.azure.com/api/retail/prices"
+
+	// reservationFilter selects the virtual machine reservations from the retail prices
+	reservationFilter = "serviceName eq 'Virtual Machines' and priceType eq 'Reservation' and currencyCode eq 'USD'"
+	// consumptionFilter selects the pay as you go virtual machine prices from the retail prices
+	consumptionFilter = "serviceName eq 'Virtual Machines' and priceType eq 'Consumption' and currencyCode eq 'USD'"
+	// spotFilter selects the spot virtual machine prices from the retail prices
+	spotFilter = consumptionFilter + " and contains(skuName,'Spot')"
+
+	// maxRetailPricePages the maximum number of pages followed, protects against endless paging
+	maxRetailPricePages = 10000
+)
+
+// retailPrice an item of the Azure retail prices data format
+type retailPrice struct {
+	CurrencyCode    string  `json:"currencyCode"`
+	RetailPrice     float64 `json:"retailPrice"`
+	UnitPrice       float64 `json:"unitPrice"`
+	ArmRegionName   string  `json:"armRegionName"`
+	Location        string  `json:"location"`
+	MeterName       string  `json:"meterName"`
+	ProductName     string  `json:"productName"`
+	SkuName         string  `json:"skuName"`
+	ServiceName     string  `json:"serviceName"`
+	UnitOfMeasure   string  `json:"unitOfMeasure"`
+	Type            string  `json:"type"`
+	ArmSkuName      string  `json:"armSkuName"`
+	ReservationTerm string  `json:"reservationTerm"`
+}
+
+// retailPricesPage a page of the Azure retail prices data format
+type retailPricesPage struct {
+	Items        []retailPrice `json:"Items"`
+	NextPageLink string        `json:"NextPageLink"`
+}
+
+// RetailPricesClient queries the Azure Retail Prices API
+type RetailPricesClient struct {
+	baseURL string
+	client  *http.Client
+	// maxPages the maximum number of pages followed
+	maxPages int
+}
+
+// NewRetailPricesClient creates a new client of the Retail Prices API available at the given url, e.g. RetailPricesURL
+func NewRetailPricesClient(baseURL string) *RetailPricesClient {
+	return &RetailPricesClient{
+		baseURL:  baseURL,
+		client:   &http.Client{Timeout: time.Minute},
+		maxPages: maxRetailPricePages,
+	}
+}
+
+// GetRetailPrices follows the pages of the Retail Prices API for the given filter, an error is returned if there are
+// more pages than the maximum
+func (c *RetailPricesClient) GetRetailPrices(filter string) ([]retailPrice, error) {
+	var items []retailPrice
+	next := fmt.Sprintf("%s?$filter=%s", c.baseURL, url.QueryEscape(filter))
+	for i := 0; next != ""; i++ {
+		if i == c.maxPages {
+			return nil, fmt.Errorf("could not get Azure retail prices, there are more than %d pages", c.maxPages)
+		}
+		log.Debugf("getting Azure retail prices page %d", i)
+		resp, err := c.client.Get(next)
+		if err != nil {
+			return nil, err
+		}
+		var page retailPricesPage
+		err = json.NewDecoder(resp.Body).Decode(&page)
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return nil, fmt.Errorf("could not get Azure retail prices, status code: %d", resp.StatusCode)
+		}
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, page.Items...)
+		next = page.NextPageLink
+	}
+	return items, nil
+}
+
+// parseRetailPrices collects the pay as you go and spot prices of the virtual machines per region and vm size, the
+// spot prices are keyed by region, low priority prices are used as spot prices if a vm size has no spot price
+// prices of regions not in the given regions are left out and reported as data quality issues
+func parseRetailPrices(items []retailPrice, regions map[string]string) (map[string]map[string]productinfo.Price, []productinfo.QualityIssue) {
+	allPrices := make(map[string]map[string]productinfo.Price)
+	var issues []productinfo.QualityIssue
+	unmapped := make(map[string]bool)
+	spot := make(map[spotKey]float64)
+	lowPriority := make(map[spotKey]float64)
+	for _, item := range items {
+		if item.Type != "Consumption" || item.ServiceName != "Virtual Machines" || item.CurrencyCode != "USD" {
+			continue
+		}
+		if item.ArmSkuName == "" {
+			issues = append(issues, productinfo.QualityIssue{Kind: productinfo.UnparsableProduct, Region: item.ArmRegionName,
+				Subject: item.SkuName, Detail: "no vm size"})
+			continue
+		}
+		if _, ok := regions[item.ArmRegionName]; !ok {
+			if !unmapped[item.ArmRegionName] {
+				unmapped[item.ArmRegionName] = true
+				issues = append(issues, productinfo.QualityIssue{Kind: productinfo.UnmappedRegion, Subject: item.ArmRegionName,
+					Detail: "location " + item.Location})
+			}
+			continue
+		}
+		opSys := retailPriceOs(item)
+		key := spotKey{region: item.ArmRegionName, vmSize: item.ArmSkuName, os: opSys}
+		switch {
+		case strings.HasSuffix(item.SkuName, " Spot"):
+			spot[key] = item.RetailPrice
+		case strings.HasSuffix(item.SkuName, " Low Priority"):
+			lowPriority[key] = item.RetailPrice
+		default:
+			if allPrices[item.ArmRegionName] == nil {
+				allPrices[item.ArmRegionName] = make(map[string]productinfo.Price)
+			}
+			price := allPrices[item.ArmRegionName][item.ArmSkuName]
+			price.SetOsPrice(opSys, item.RetailPrice)
+			allPrices[item.ArmRegionName][item.ArmSkuName] = price
+		}
+	}
+	for key, p := range lowPriority {
+		if _, ok := spot[key]; !ok {
+			spot[key] = p
+		}
+	}
+	for key, p := range spot {
+		price, ok := allPrices[key.region][key.vmSize]
+		if !ok {
+			// spot only vm sizes can't be recommended without an on demand price
+			continue
+		}
+		price.SetOsSpotPrice(key.os, productinfo.SpotPriceInfo{key.region: p})
+		allPrices[key.region][key.vmSize] = price
+	}
+	return allPrices, issues
+}
+
+// parseRetailSpotPrices collects the spot prices of the virtual machines of a region per vm size, the items that are
+// not spot prices of the region are left out, the prices have no on demand prices
+func parseRetailSpotPrices(items []retailPrice, region string) (map[string]productinfo.Price, []productinfo.QualityIssue) {
+	prices := make(map[string]productinfo.Price)
+	var issues []productinfo.QualityIssue
+	for _, item := range items {
+		if item.Type != "Consumption" || item.ServiceName != "Virtual Machines" || item.CurrencyCode != "USD" ||
+			item.ArmRegionName != region || !strings.HasSuffix(item.SkuName, " Spot") {
+			continue
+		}
+		if item.ArmSkuName == "" {
+			issues = append(issues, productinfo.QualityIssue{Kind: productinfo.UnparsableProduct, Region: region,
+				Subject: item.SkuName, Detail: "no vm size"})
+			continue
+		}
+		price := prices[item.ArmSkuName]
+		price.SetOsSpotPrice(retailPriceOs(item), productinfo.SpotPriceInfo{region: item.RetailPrice})
+		prices[item.ArmSkuName] = price
+	}
+	return prices, issues
+}
+
+// retailPriceOs returns the operating system of a retail price, the Windows products are suffixed with Windows
+func retailPriceOs(item retailPrice) string {
+	if strings.HasSuffix(item.ProductName, " Windows") {
+		return productinfo.Windows
+	}
+	return productinfo.Linux
+}
+
+// spotKey identifies the spot price of a vm size with an operating system in a region
+type spotKey struct {
+	region string
+	vmSize string
+	os     string
+}
+
+// ReservationSource retrieves the reserved virtual machine instance prices
+type ReservationSource interface {
+	// GetReservationPrices returns the reservation prices per region and vm size
+	GetReservationPrices() (map[string]map[string][]productinfo.CommitmentPrice, error)
+}
+
+// RetailPricesReservationSource reads the reservation prices in the Azure retail prices data format from the Retail
+// Prices API or from a local JSON file holding a page of the API
+type RetailPricesReservationSource struct {
+	location string
+}
+
+// NewRetailPricesReservationSource creates a new reservation source, the location is the url of the Retail Prices API
+// or the path of a local JSON file
+func NewRetailPricesReservationSource(location string) *RetailPricesReservationSource {
+	return &RetailPricesReservationSource{
+		location: location,
+	}
+}
+
+// GetReservationPrices reads the retail prices and returns the reservation prices per region and vm size
+func (s *RetailPricesReservationSource) GetReservationPrices() (map[string]map[string][]productinfo.CommitmentPrice, error) {
+	var items []retailPrice
+	var err error
+	if strings.HasPrefix(s.location, "http://") || strings.HasPrefix(s.location, "https://") {
+		items, err = NewRetailPricesClient(s.location).GetRetailPrices(reservationFilter)
+	} else {
+		items, err = readRetailPrices(s.location)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return parseReservations(items), nil
+}
+
+// readRetailPrices reads a page of retail prices from a local file
+func readRetailPrices(path string) ([]retailPrice, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var page retailPricesPage
+	if err := json.NewDecoder(f).Decode(&page); err != nil {
+		return nil, fmt.Errorf("could not parse retail prices file %s: %s", path, err.Error())
+	}
+	return page.Items, nil
+}
+
+// parseReservations collects the virtual machine reservations, the retail price of a reservation is the price of the
+// whole term, it's modeled as an upfront payment
+func parseReservations(items []retailPrice) map[string]map[string][]productinfo.CommitmentPrice {
+	prices := make(map[string]map[string][]productinfo.CommitmentPrice)
+	for _, item := range items {
+		if item.Type != "Reservation" || item.ServiceName != "Virtual Machines" || item.CurrencyCode != "USD" ||
+			item.ArmRegionName == "" || item.ArmSkuName == "" {
+			continue
+		}
+		var term int
+		switch item.ReservationTerm {
+		case "1 Year":
+			term = 1
+		case "3 Years":
+			term = 3
+		default:
+			log.Debugf("unsupported reservation term %s of %s", item.ReservationTerm, item.ArmSkuName)
+			continue
+		}
+		if prices[item.ArmRegionName] == nil {
+			prices[item.ArmRegionName] = make(map[string][]productinfo.CommitmentPrice)
+		}
+		cp := productinfo.NewCommitmentPrice(productinfo.Reserved, term, productinfo.AllUpfront, 0, item.RetailPrice)
+		prices[item.ArmRegionName][item.ArmSkuName] = append(prices[item.ArmRegionName][item.ArmSkuName], cp)
+	}
+	for _, vmSizes := range prices {
+		for _, cps := range vmSizes {
+			productinfo.SortCommitments(cps)
+		}
+	}
+	return prices
+}
@@ -0,0 +1,181 @@
+package azure
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"testing"
+
+	"github.com/banzaicloud/productinfo/pkg/productinfo"
+	"github.com/stretchr/testify/assert"
+)
+
+const reservationsPage = `{
+  "Items": [
+    {"currencyCode": "USD", "retailPrice": 876, "armRegionName": "westeurope", "serviceName": "Virtual Machines",
+     "type": "Reservation", "armSkuName": "Standard_D2s_v3", "reservationTerm": "1 Year"},
+    {"currencyCode": "USD", "retailPrice": 1576.8, "armRegionName": "westeurope", "serviceName": "Virtual Machines",
+     "type": "Reservation", "armSkuName": "Standard_D2s_v3", "reservationTerm": "3 Years"},
+    {"currencyCode": "USD", "retailPrice": 0.11, "armRegionName": "westeurope", "serviceName": "Virtual Machines",
+     "type": "Consumption", "armSkuName": "Standard_D2s_v3"}
+  ],
+  "NextPageLink": %s
+}`
+
+func TestRetailPricesReservationSource_GetReservationPrices(t *testing.T) {
+	expected := map[string]map[string][]productinfo.CommitmentPrice{
+		"westeurope": {
+			"Standard_D2s_v3": {
+				{Type: productinfo.Reserved, Term: 1, PaymentOption: productinfo.AllUpfront, HourlyPrice: 0.1, Upfront: 876},
+				{Type: productinfo.Reserved, Term: 3, PaymentOption: productinfo.AllUpfront, HourlyPrice: 0.06, Upfront: 1576.8},
+			},
+		},
+	}
+
+	t.Run("retail prices api", func(t *testing.T) {
+		var server *httptest.Server
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, reservationFilter, r.URL.Query().Get("$filter"))
+			if r.URL.Query().Get("$skip") == "" {
+				fmt.Fprintf(w, `{"Items": [], "NextPageLink": "%s?$filter=%s&$skip=100"}`, server.URL, url.QueryEscape(reservationFilter))
+				return
+			}
+			fmt.Fprintf(w, reservationsPage, "null")
+		}))
+		defer server.Close()
+
+		prices, err := NewRetailPricesReservationSource(server.URL).GetReservationPrices()
+		assert.Nil(t, err, "the error should be nil")
+		assertCommitments(t, expected, prices)
+	})
+
+	t.Run("local file", func(t *testing.T) {
+		f, err := ioutil.TempFile("", "reservations")
+		assert.Nil(t, err)
+		defer os.Remove(f.Name())
+		fmt.Fprintf(f, reservationsPage, "null")
+		f.Close()
+
+		prices, err := NewRetailPricesReservationSource(f.Name()).GetReservationPrices()
+		assert.Nil(t, err, "the error should be nil")
+		assertCommitments(t, expected, prices)
+	})
+}
+
+func TestRetailPricesClient_GetRetailPrices(t *testing.T) {
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `{"Items": [{"armSkuName": "Standard_D2s_v3"}], "NextPageLink": "%s?$skip=100"}`, server.URL)
+	}))
+	defer server.Close()
+
+	client := NewRetailPricesClient(server.URL)
+	client.maxPages = 3
+	items, err := client.GetRetailPrices(consumptionFilter)
+	assert.Nil(t, items, "the items should be nil")
+	assert.EqualError(t, err, "could not get Azure retail prices, there are more than 3 pages")
+}
+
+func TestAzureInfoer_GetCurrentPrices(t *testing.T) {
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		assert.Equal(t, spotFilter, r.URL.Query().Get("$filter"))
+		fmt.Fprint(w, `{"Items": [
+		  {"currencyCode": "USD", "retailPrice": 0.02, "armRegionName": "westeurope", "serviceName": "Virtual Machines",
+		   "type": "Consumption", "armSkuName": "Standard_D2s_v3", "skuName": "D2s v3 Spot", "productName": "Virtual Machines DSv3 Series"},
+		  {"currencyCode": "USD", "retailPrice": 0.03, "armRegionName": "northeurope", "serviceName": "Virtual Machines",
+		   "type": "Consumption", "armSkuName": "Standard_D2s_v3", "skuName": "D2s v3 Spot", "productName": "Virtual Machines DSv3 Series"}
+		]}`)
+	}))
+	defer server.Close()
+
+	a := &AzureInfoer{retailPricesClient: NewRetailPricesClient(server.URL), spotQualityIssues: make(map[string][]productinfo.QualityIssue)}
+	prices, err := a.GetCurrentPrices("westeurope")
+	assert.Nil(t, err, "the error should be nil")
+	assert.Equal(t, productinfo.SpotPriceInfo{"westeurope": 0.02}, prices["Standard_D2s_v3"].SpotPrice)
+	prices, err = a.GetCurrentPrices("northeurope")
+	assert.Nil(t, err, "the error should be nil")
+	assert.Equal(t, productinfo.SpotPriceInfo{"northeurope": 0.03}, prices["Standard_D2s_v3"].SpotPrice)
+	assert.Equal(t, 1, calls, "the spot prices of every region should be queried at once")
+}
+
+func assertCommitments(t *testing.T, expected map[string]map[string][]productinfo.CommitmentPrice, actual map[string]map[string][]productinfo.CommitmentPrice) {
+	assert.Equal(t, len(expected), len(actual))
+	for region, vmSizes := range expected {
+		for vmSize, cps := range vmSizes {
+			assert.Equal(t, len(cps), len(actual[region][vmSize]))
+			for i, cp := range actual[region][vmSize] {
+				assert.InDelta(t, cps[i].HourlyPrice, cp.HourlyPrice, 1e-9)
+				cp.HourlyPrice = cps[i].HourlyPrice
+				assert.Equal(t, cps[i], cp)
+			}
+		}
+	}
+}
+
+func TestParseRetailPrices(t *testing.T) {
+	item := func(region string, vmSize string, skuName string, productName string, price float64) retailPrice {
+		return retailPrice{CurrencyCode: "USD", RetailPrice: price, ArmRegionName: region, Location: "EU West", SkuName: skuName,
+			ProductName: productName, ServiceName: "Virtual Machines", Type: "Consumption", ArmSkuName: vmSize}
+	}
+	items := []retailPrice{
+		item("westeurope", "Standard_D2s_v3", "D2s v3", "Virtual Machines DSv3 Series", 0.11),
+		item("westeurope", "Standard_D2s_v3", "D2s v3 Low Priority", "Virtual Machines DSv3 Series", 0.022),
+		item("westeurope", "Standard_D2s_v3", "D2s v3", "Virtual Machines DSv3 Series Windows", 0.2),
+		item("westeurope", "Standard_D2s_v3", "D2s v3 Spot", "Virtual Machines DSv3 Series", 0.02),
+		item("westeurope", "Standard_D2s_v3", "D2s v3 Low Priority", "Virtual Machines DSv3 Series Windows", 0.04),
+		item("westeurope", "Standard_D64s_v3", "D64s v3 Spot", "Virtual Machines DSv3 Series", 0.7),
+		item("westeurope", "", "D2s v3", "Virtual Machines DSv3 Series", 0.11),
+		item("usgovvirginia", "Standard_D2s_v3", "D2s v3", "Virtual Machines DSv3 Series", 0.13),
+		item("usgovvirginia", "Standard_D4s_v3", "D4s v3", "Virtual Machines DSv3 Series", 0.26),
+	}
+
+	prices, issues := parseRetailPrices(items, map[string]string{"westeurope": "West Europe"})
+	assert.Equal(t, map[string]map[string]productinfo.Price{
+		"westeurope": {
+			"Standard_D2s_v3": {
+				OnDemandPrice: 0.11,
+				SpotPrice:     productinfo.SpotPriceInfo{"westeurope": 0.02},
+				OsPrices: map[string]productinfo.OsPrice{productinfo.Windows: {OnDemandPrice: 0.2,
+					SpotPrice: productinfo.SpotPriceInfo{"westeurope": 0.04}}},
+			},
+		},
+	}, prices)
+	assert.Equal(t, []productinfo.QualityIssue{
+		{Kind: productinfo.UnparsableProduct, Region: "westeurope", Subject: "D2s v3", Detail: "no vm size"},
+		{Kind: productinfo.UnmappedRegion, Subject: "usgovvirginia", Detail: "location EU West"},
+	}, issues)
+}
+
+func TestParseRetailSpotPrices(t *testing.T) {
+	item := func(region string, vmSize string, skuName string, productName string, price float64) retailPrice {
+		return retailPrice{CurrencyCode: "USD", RetailPrice: price, ArmRegionName: region, Location: "EU West", SkuName: skuName,
+			ProductName: productName, ServiceName: "Virtual Machines", Type: "Consumption", ArmSkuName: vmSize}
+	}
+	items := []retailPrice{
+		item("westeurope", "Standard_D2s_v3", "D2s v3 Spot", "Virtual Machines DSv3 Series", 0.02),
+		item("westeurope", "Standard_D2s_v3", "D2s v3 Spot", "Virtual Machines DSv3 Series Windows", 0.05),
+		item("westeurope", "Standard_D2s_v3", "D2s v3", "Virtual Machines DSv3 Series", 0.11),
+		item("westeurope", "Standard_D2s_v3", "D2s v3 Low Priority", "Virtual Machines DSv3 Series", 0.022),
+		item("westeurope", "Standard_D64s_v3", "D64s v3 Spot", "Virtual Machines DSv3 Series", 0.7),
+		item("westeurope", "", "D4s v3 Spot", "Virtual Machines DSv3 Series", 0.04),
+		item("northeurope", "Standard_D2s_v3", "D2s v3 Spot", "Virtual Machines DSv3 Series", 0.03),
+	}
+
+	prices, issues := parseRetailSpotPrices(items, "westeurope")
+	assert.Equal(t, map[string]productinfo.Price{
+		"Standard_D2s_v3": {
+			SpotPrice: productinfo.SpotPriceInfo{"westeurope": 0.02},
+			OsPrices: map[string]productinfo.OsPrice{productinfo.Windows: {
+				SpotPrice: productinfo.SpotPriceInfo{"westeurope": 0.05}}},
+		},
+		"Standard_D64s_v3": {SpotPrice: productinfo.SpotPriceInfo{"westeurope": 0.7}},
+	}, prices)
+	assert.Equal(t, []productinfo.QualityIssue{
+		{Kind: productinfo.UnparsableProduct, Region: "westeurope", Subject: "D4s v3 Spot", Detail: "no vm size"},
+	}, issues)
+}
@@ -0,0 +1,314 @@
+package productinfo
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// ChangeType the type of a catalog change
+type ChangeType string
+
+const (
+	// RegionAdded a new region appeared at a provider
+	RegionAdded ChangeType = "region_added"
+	// RegionRemoved a region disappeared from a provider
+	RegionRemoved ChangeType = "region_removed"
+	// TypeAdded a new instance type appeared in a region
+	TypeAdded ChangeType = "type_added"
+	// TypeRemoved an instance type disappeared from a region
+	TypeRemoved ChangeType = "type_removed"
+	// OnDemandPriceChanged the on demand price of an instance type changed more than the threshold
+	OnDemandPriceChanged ChangeType = "ondemand_price_changed"
+	// SpotPriceChanged the spot price of an instance type in a zone changed more than the threshold
+	SpotPriceChanged ChangeType = "spot_price_changed"
+)
+
+// ChangeEvent describes a change in the catalog of a provider
+type ChangeEvent struct {
+	Id           uint64     `json:"id"`
+	Time         time.Time  `json:"time"`
+	Type         ChangeType `json:"type"`
+	Provider     string     `json:"provider"`
+	Region       string     `json:"region"`
+	InstanceType string     `json:"instanceType,omitempty"`
+	Zone         string     `json:"zone,omitempty"`
+	OldPrice     float64    `json:"oldPrice,omitempty"`
+	NewPrice     float64    `json:"newPrice,omitempty"`
+	// Key identifies the event across restarts, the ids start again from 1 with the application, the consumers of the
+	// sinks delivering the events at least once drop the redelivered events by it
+	Key string `json:"key"`
+}
+
+// ChangeSink delivers catalog change events to an external system
+type ChangeSink interface {
+	// Send delivers a batch of events, it's called from a dedicated goroutine per sink
+	Send(events []ChangeEvent) error
+}
+
+// ChangeFilter selects events from the change feed, empty fields match everything
+type ChangeFilter struct {
+	Provider     string
+	Region       string
+	InstanceType string
+	Types        []ChangeType
+	// SinceId only events with greater ids are returned
+	SinceId uint64
+	// Since only events after this time are returned
+	Since time.Time
+	// Limit the maximum number of events returned, 0 means no limit
+	Limit int
+}
+
+func (f ChangeFilter) matches(e ChangeEvent) bool {
+	if (f.Provider != "" && f.Provider != e.Provider) || (f.Region != "" && f.Region != e.Region) ||
+		(f.InstanceType != "" && f.InstanceType != e.InstanceType) || e.Id <= f.SinceId || !e.Time.After(f.Since) {
+		return false
+	}
+	if len(f.Types) == 0 {
+		return true
+	}
+	for _, t := range f.Types {
+		if t == e.Type {
+			return true
+		}
+	}
+	return false
+}
+
+// catalogState the last known catalog of a provider
+type catalogState struct {
+	regions map[string]bool
+	// types per region
+	types map[string]map[string]bool
+	// on demand prices per region and instance type
+	onDemand map[string]map[string]float64
+	// spot prices per region and instance type
+	spot map[string]map[string]SpotPriceInfo
+}
+
+func newCatalogState() *catalogState {
+	return &catalogState{
+		types:    make(map[string]map[string]bool),
+		onDemand: make(map[string]map[string]float64),
+		spot:     make(map[string]map[string]SpotPriceInfo),
+	}
+}
+
+// ChangeNotifier diffs every new catalog against the previous one, keeps the resulting events in a bounded feed and
+// delivers them to the registered sinks
+type ChangeNotifier struct {
+	mu        sync.RWMutex
+	threshold float64
+	feedSize  int
+	feed      []ChangeEvent
+	lastId    uint64
+	// epoch makes the event keys unique across restarts
+	epoch    string
+	catalogs map[string]*catalogState
+	sinks    []chan []ChangeEvent
+}
+
+// NewChangeNotifier creates a new change notifier, price changes below the threshold ratio are not reported
+func NewChangeNotifier(threshold float64, feedSize int) *ChangeNotifier {
+	return &ChangeNotifier{
+		threshold: threshold,
+		feedSize:  feedSize,
+		epoch:     strconv.FormatInt(time.Now().UnixNano(), 36),
+		catalogs:  make(map[string]*catalogState),
+	}
+}
+
+// SetThreshold sets the minimum ratio of a price change to be reported
+func (n *ChangeNotifier) SetThreshold(threshold float64) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.threshold = threshold
+}
+
+// AddSink registers a sink, events are delivered to it asynchronously in the order they were emitted
+// batches are dropped if the sink falls too much behind
+func (n *ChangeNotifier) AddSink(sink ChangeSink) {
+	ch := make(chan []ChangeEvent, 100)
+	go func() {
+		for events := range ch {
+			if err := sink.Send(events); err != nil {
+				log.WithError(err).Errorf("couldn't deliver %d change events", len(events))
+			}
+		}
+	}()
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.sinks = append(n.sinks, ch)
+}
+
+// Changes returns the events in the feed matching the filter, in the order they were emitted
+func (n *ChangeNotifier) Changes(filter ChangeFilter) []ChangeEvent {
+	n.mu.RLock()
+	defer n.mu.RUnlock()
+	events := make([]ChangeEvent, 0)
+	for _, e := range n.feed {
+		if filter.matches(e) {
+			events = append(events, e)
+			if filter.Limit > 0 && len(events) == filter.Limit {
+				break
+			}
+		}
+	}
+	return events
+}
+
+func (n *ChangeNotifier) catalog(provider string) *catalogState {
+	c, ok := n.catalogs[provider]
+	if !ok {
+		c = newCatalogState()
+		n.catalogs[provider] = c
+	}
+	return c
+}
+
+// changed reports whether the difference between the prices reaches the threshold
+func (n *ChangeNotifier) changed(old float64, new float64) bool {
+	if old == new {
+		return false
+	}
+	if old == 0 {
+		return true
+	}
+	return math.Abs(new-old)/old >= n.threshold
+}
+
+// UpdateRegions diffs the regions of a provider, the first list of regions is the baseline
+func (n *ChangeNotifier) UpdateRegions(provider string, regions map[string]string) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	c := n.catalog(provider)
+	var events []ChangeEvent
+	if c.regions != nil {
+		for r := range regions {
+			if !c.regions[r] {
+				events = append(events, ChangeEvent{Type: RegionAdded, Provider: provider, Region: r})
+			}
+		}
+		for r := range c.regions {
+			if _, ok := regions[r]; !ok {
+				events = append(events, ChangeEvent{Type: RegionRemoved, Provider: provider, Region: r})
+			}
+		}
+	}
+	c.regions = make(map[string]bool, len(regions))
+	for r := range regions {
+		c.regions[r] = true
+	}
+	n.publish(events)
+}
+
+// UpdateProducts diffs the instance types and on demand prices of a region
+func (n *ChangeNotifier) UpdateProducts(provider string, region string, vms []VmInfo) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	c := n.catalog(provider)
+	var events []ChangeEvent
+	types := make(map[string]bool, len(vms))
+	for _, vm := range vms {
+		types[vm.Type] = true
+	}
+	if old, ok := c.types[region]; ok {
+		for t := range types {
+			if !old[t] {
+				events = append(events, ChangeEvent{Type: TypeAdded, Provider: provider, Region: region, InstanceType: t})
+			}
+		}
+		for t := range old {
+			if !types[t] {
+				events = append(events, ChangeEvent{Type: TypeRemoved, Provider: provider, Region: region, InstanceType: t})
+			}
+		}
+	}
+	c.types[region] = types
+	prices := make(map[string]Price, len(vms))
+	for _, vm := range vms {
+		prices[vm.Type] = Price{OnDemandPrice: vm.OnDemandPrice}
+	}
+	events = append(events, n.diffPrices(c, provider, region, prices)...)
+	n.publish(events)
+}
+
+// UpdatePrices diffs the on demand and spot prices of a region
+func (n *ChangeNotifier) UpdatePrices(provider string, region string, prices map[string]Price) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.publish(n.diffPrices(n.catalog(provider), provider, region, prices))
+}
+
+// diffPrices compares the prices to the last reported ones, so slow drifts are reported once they reach the threshold
+// non positive prices are treated as unknown
+func (n *ChangeNotifier) diffPrices(c *catalogState, provider string, region string, prices map[string]Price) []ChangeEvent {
+	var events []ChangeEvent
+	if c.onDemand[region] == nil {
+		c.onDemand[region] = make(map[string]float64)
+	}
+	if c.spot[region] == nil {
+		c.spot[region] = make(map[string]SpotPriceInfo)
+	}
+	for instType, p := range prices {
+		if p.OnDemandPrice > 0 {
+			old, ok := c.onDemand[region][instType]
+			if ok && n.changed(old, p.OnDemandPrice) {
+				events = append(events, ChangeEvent{Type: OnDemandPriceChanged, Provider: provider, Region: region,
+					InstanceType: instType, OldPrice: old, NewPrice: p.OnDemandPrice})
+			}
+			if !ok || n.changed(old, p.OnDemandPrice) {
+				c.onDemand[region][instType] = p.OnDemandPrice
+			}
+		}
+		for zone, price := range p.SpotPrice {
+			if price <= 0 {
+				continue
+			}
+			if c.spot[region][instType] == nil {
+				c.spot[region][instType] = make(SpotPriceInfo)
+			}
+			old, ok := c.spot[region][instType][zone]
+			if ok && n.changed(old, price) {
+				events = append(events, ChangeEvent{Type: SpotPriceChanged, Provider: provider, Region: region,
+					InstanceType: instType, Zone: zone, OldPrice: old, NewPrice: price})
+			}
+			if !ok || n.changed(old, price) {
+				c.spot[region][instType][zone] = price
+			}
+		}
+	}
+	return events
+}
+
+// publish assigns ids to the events, appends them to the feed and hands them over to the sinks
+// it must be called with the lock held
+func (n *ChangeNotifier) publish(events []ChangeEvent) {
+	if len(events) == 0 {
+		return
+	}
+	now := time.Now()
+	for i := range events {
+		n.lastId++
+		events[i].Id = n.lastId
+		events[i].Key = fmt.Sprintf("%s-%d", n.epoch, n.lastId)
+		events[i].Time = now
+	}
+	n.feed = append(n.feed, events...)
+	if len(n.feed) > n.feedSize {
+		n.feed = append([]ChangeEvent(nil), n.feed[len(n.feed)-n.feedSize:]...)
+	}
+	log.Debugf("%d catalog change events emitted", len(events))
+	for _, ch := range n.sinks {
+		select {
+		case ch <- events:
+		default:
+			log.Warnf("change sink is lagging behind, dropping %d events", len(events))
+		}
+	}
+}
@@ -0,0 +1,128 @@
+package productinfo
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// dummyChangeSink forwards the received events to a channel
+type dummyChangeSink struct {
+	events chan []ChangeEvent
+}
+
+func (s *dummyChangeSink) Send(events []ChangeEvent) error {
+	s.events <- events
+	return nil
+}
+
+func TestChangeNotifier_Updates(t *testing.T) {
+	tests := []struct {
+		name   string
+		update func(n *ChangeNotifier)
+		check  func(events []ChangeEvent)
+	}{
+		{
+			name: "the first catalog is the baseline",
+			update: func(n *ChangeNotifier) {
+				n.UpdateRegions("ec2", map[string]string{"eu-west-1": "EU (Ireland)"})
+				n.UpdateProducts("ec2", "eu-west-1", []VmInfo{{Type: "m5.large", OnDemandPrice: 0.1}})
+				n.UpdatePrices("ec2", "eu-west-1", map[string]Price{"m5.large": {SpotPrice: SpotPriceInfo{"eu-west-1a": 0.03}}})
+			},
+			check: func(events []ChangeEvent) {
+				assert.Equal(t, 0, len(events))
+			},
+		},
+		{
+			name: "regions and types added and removed",
+			update: func(n *ChangeNotifier) {
+				n.UpdateRegions("ec2", map[string]string{"eu-west-1": "EU (Ireland)"})
+				n.UpdateProducts("ec2", "eu-west-1", []VmInfo{{Type: "m5.large"}, {Type: "m4.large"}})
+				n.UpdateRegions("ec2", map[string]string{"eu-west-2": "EU (London)"})
+				n.UpdateProducts("ec2", "eu-west-1", []VmInfo{{Type: "m5.large"}, {Type: "c5.large"}})
+			},
+			check: func(events []ChangeEvent) {
+				assert.Equal(t, 4, len(events))
+				types := make(map[ChangeType]string)
+				for _, e := range events {
+					types[e.Type] = e.Region + "/" + e.InstanceType
+				}
+				assert.Equal(t, map[ChangeType]string{
+					RegionAdded:   "eu-west-2/",
+					RegionRemoved: "eu-west-1/",
+					TypeAdded:     "eu-west-1/c5.large",
+					TypeRemoved:   "eu-west-1/m4.large",
+				}, types)
+			},
+		},
+		{
+			name: "price changes below the threshold are not reported",
+			update: func(n *ChangeNotifier) {
+				n.UpdateProducts("ec2", "eu-west-1", []VmInfo{{Type: "m5.large", OnDemandPrice: 0.1}})
+				n.UpdatePrices("ec2", "eu-west-1", map[string]Price{"m5.large": {SpotPrice: SpotPriceInfo{"eu-west-1a": 0.03}}})
+				n.UpdateProducts("ec2", "eu-west-1", []VmInfo{{Type: "m5.large", OnDemandPrice: 0.102}})
+				n.UpdatePrices("ec2", "eu-west-1", map[string]Price{"m5.large": {SpotPrice: SpotPriceInfo{"eu-west-1a": 0.036}}})
+				n.UpdatePrices("ec2", "eu-west-1", map[string]Price{"m5.large": {OnDemandPrice: 0.12}})
+			},
+			check: func(events []ChangeEvent) {
+				assert.Equal(t, []ChangeEvent{
+					{Id: 1, Type: SpotPriceChanged, Provider: "ec2", Region: "eu-west-1", InstanceType: "m5.large", Zone: "eu-west-1a", OldPrice: 0.03, NewPrice: 0.036},
+					{Id: 2, Type: OnDemandPriceChanged, Provider: "ec2", Region: "eu-west-1", InstanceType: "m5.large", OldPrice: 0.1, NewPrice: 0.12},
+				}, events)
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			n := NewChangeNotifier(0.05, 100)
+			test.update(n)
+			events := n.Changes(ChangeFilter{})
+			for i := range events {
+				assert.Equal(t, fmt.Sprintf("%s-%d", n.epoch, events[i].Id), events[i].Key)
+				events[i].Time, events[i].Key = time.Time{}, ""
+			}
+			test.check(events)
+		})
+	}
+}
+
+func TestChangeNotifier_Changes(t *testing.T) {
+	n := NewChangeNotifier(0, 3)
+	n.UpdatePrices("ec2", "eu-west-1", map[string]Price{"m5.large": {OnDemandPrice: 0.1}, "c5.large": {OnDemandPrice: 0.1}})
+	n.UpdatePrices("gce", "us-east1", map[string]Price{"n1-standard-1": {OnDemandPrice: 0.1}})
+	for i := 1; i <= 2; i++ {
+		n.UpdatePrices("ec2", "eu-west-1", map[string]Price{"m5.large": {OnDemandPrice: 0.1 + float64(i)/10}})
+		n.UpdatePrices("gce", "us-east1", map[string]Price{"n1-standard-1": {OnDemandPrice: 0.1 + float64(i)/10}})
+	}
+
+	all := n.Changes(ChangeFilter{})
+	assert.Equal(t, 3, len(all), "the feed should be bounded")
+	assert.Equal(t, uint64(2), all[0].Id, "the oldest events should be dropped")
+
+	ec2 := n.Changes(ChangeFilter{Provider: "ec2"})
+	assert.Equal(t, 1, len(ec2))
+	assert.InDelta(t, 0.3, ec2[0].NewPrice, 1e-9)
+
+	assert.Equal(t, 1, len(n.Changes(ChangeFilter{SinceId: 3})))
+	assert.Equal(t, 2, len(n.Changes(ChangeFilter{Limit: 2})))
+	assert.Equal(t, 0, len(n.Changes(ChangeFilter{Types: []ChangeType{TypeAdded}})))
+	assert.Equal(t, 0, len(n.Changes(ChangeFilter{Since: time.Now().Add(time.Hour)})))
+}
+
+func TestChangeNotifier_AddSink(t *testing.T) {
+	n := NewChangeNotifier(0.05, 100)
+	s := &dummyChangeSink{events: make(chan []ChangeEvent, 1)}
+	n.AddSink(s)
+	n.UpdateRegions("ec2", map[string]string{})
+	n.UpdateRegions("ec2", map[string]string{"eu-west-1": "EU (Ireland)"})
+
+	select {
+	case events := <-s.events:
+		assert.Equal(t, 1, len(events))
+		assert.Equal(t, RegionAdded, events[0].Type)
+	case <-time.After(time.Second):
+		t.Fatal("the sink didn't receive the events")
+	}
+}
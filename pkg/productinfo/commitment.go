@@ -0,0 +1,103 @@
+package productinfo
+
+import (
+	"math"
+	"sort"
+)
+
+const (
+	// Reserved reserved instance pricing
+	Reserved = "reserved"
+	// ComputeSavingsPlan savings plan pricing applying to every instance family of a provider
+	ComputeSavingsPlan = "compute_savings_plan"
+	// InstanceSavingsPlan savings plan pricing applying to an instance family in a region
+	InstanceSavingsPlan = "instance_savings_plan"
+	// CommittedUse committed use pricing of the resources of a region
+	CommittedUse = "committed_use"
+
+	// NoUpfront the whole commitment is paid hourly
+	NoUpfront = "no_upfront"
+	// PartialUpfront a part of the commitment is paid in advance, the rest hourly
+	PartialUpfront = "partial_upfront"
+	// AllUpfront the whole commitment is paid in advance
+	AllUpfront = "all_upfront"
+
+	// hoursPerYear the number of hours an upfront payment is amortized over in a year of commitment
+	hoursPerYear = 365 * 24
+
+	// HoursPerMonth the number of hours in an average month used to compute monthly prices
+	HoursPerMonth = 730
+)
+
+// CommitmentPrice the price of an instance type in exchange for a usage commitment over a term
+type CommitmentPrice struct {
+	// Type the kind of the commitment: reserved, compute_savings_plan, instance_savings_plan or committed_use
+	Type string `json:"type"`
+	// Term the length of the commitment in years
+	Term int `json:"term"`
+	// PaymentOption no_upfront, partial_upfront or all_upfront
+	PaymentOption string `json:"paymentOption,omitempty"`
+	// OfferingClass the provider specific class of the offering, e.g. standard or convertible reserved instances
+	OfferingClass string `json:"offeringClass,omitempty"`
+	// HourlyPrice the effective hourly price, the upfront payment amortized over the term included
+	HourlyPrice float64 `json:"hourlyPrice"`
+	// Upfront the amount paid in advance
+	Upfront float64 `json:"upfront,omitempty"`
+}
+
+// NewCommitmentPrice creates a commitment price, the effective hourly price is computed from the hourly and the
+// upfront prices
+func NewCommitmentPrice(commitmentType string, term int, paymentOption string, hourly float64, upfront float64) CommitmentPrice {
+	return CommitmentPrice{
+		Type:          commitmentType,
+		Term:          term,
+		PaymentOption: paymentOption,
+		HourlyPrice:   hourly + upfront/float64(term*hoursPerYear),
+		Upfront:       upfront,
+	}
+}
+
+// SortCommitments orders the commitment prices by type, term, offering class and payment option
+func SortCommitments(prices []CommitmentPrice) {
+	sort.Slice(prices, func(i, j int) bool {
+		a, b := prices[i], prices[j]
+		if a.Type != b.Type {
+			return a.Type < b.Type
+		}
+		if a.Term != b.Term {
+			return a.Term < b.Term
+		}
+		if a.OfferingClass != b.OfferingClass {
+			return a.OfferingClass < b.OfferingClass
+		}
+		return a.PaymentOption < b.PaymentOption
+	})
+}
+
+// SustainedUseDiscounter is implemented by the product infoers of providers discounting the on demand prices
+// automatically based on the usage in a month
+type SustainedUseDiscounter interface {
+	// SustainedUseRates returns the rates of the on demand price charged in the consecutive equal usage tiers of a
+	// month, e.g. [1, 0.8, 0.6, 0.4], or nil if the instance type is not discounted
+	SustainedUseRates(instanceType string) []float64
+}
+
+// EffectiveMonthlyPrice computes the price of running an instance for the given ratio of a month (0-1), the usage
+// tiers are charged at the given rates of the hourly price, the full hourly price is charged if there are no rates
+func EffectiveMonthlyPrice(hourly float64, usage float64, rates []float64) float64 {
+	hours := usage * HoursPerMonth
+	if len(rates) == 0 {
+		return hourly * hours
+	}
+	tierHours := float64(HoursPerMonth) / float64(len(rates))
+	var price float64
+	for _, rate := range rates {
+		if hours <= 0 {
+			break
+		}
+		h := math.Min(hours, tierHours)
+		price += h * hourly * rate
+		hours -= h
+	}
+	return price
+}
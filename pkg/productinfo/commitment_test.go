@@ -0,0 +1,69 @@
+package productinfo
+
+import (
+	"testing"
+	"time"
+
+	"github.com/patrickmn/go-cache"
+	"github.com/stretchr/testify/assert"
+)
+
+// discountingInfoer applies sustained use discounts to every instance type
+type discountingInfoer struct {
+	DummyProductInfoer
+}
+
+func (d *discountingInfoer) SustainedUseRates(instanceType string) []float64 {
+	return []float64{1, 0.8, 0.6, 0.4}
+}
+
+func TestEffectiveMonthlyPrice(t *testing.T) {
+	tests := []struct {
+		name  string
+		usage float64
+		rates []float64
+		price float64
+	}{
+		{name: "no discounts", usage: 0.5, price: 365},
+		{name: "first tier only", usage: 0.25, rates: []float64{1, 0.8, 0.6, 0.4}, price: 182.5},
+		{name: "partial tier", usage: 0.375, rates: []float64{1, 0.8, 0.6, 0.4}, price: 255.5},
+		{name: "full month", usage: 1, rates: []float64{1, 0.8, 0.6, 0.4}, price: 511},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert.InDelta(t, test.price, EffectiveMonthlyPrice(1, test.usage, test.rates), 1e-9)
+		})
+	}
+}
+
+func TestNewCommitmentPrice(t *testing.T) {
+	cp := NewCommitmentPrice(Reserved, 3, PartialUpfront, 0.02, 525.6)
+	assert.InDelta(t, 0.04, cp.HourlyPrice, 1e-9)
+	assert.Equal(t, 525.6, cp.Upfront)
+}
+
+func TestCachingProductInfo_Commitments(t *testing.T) {
+	c := cache.New(5*time.Minute, 10*time.Minute)
+	productInfo, _ := NewCachingProductInfo(10*time.Second, c, map[string]ProductInfoer{
+		"dummy":       &DummyProductInfoer{},
+		"discounting": &discountingInfoer{},
+	})
+	committed := []CommitmentPrice{NewCommitmentPrice(CommittedUse, 1, NoUpfront, 0.03, 0)}
+	c.Set(productInfo.getVmKey("dummy", "dummyRegion"), []VmInfo{{Type: "n1-standard-1", OnDemandPrice: 0.05}}, cache.NoExpiration)
+	c.Set(productInfo.getPriceKey("dummy", "dummyRegion", "n1-standard-1"), Price{OnDemandPrice: 0.05, Commitments: committed}, cache.NoExpiration)
+
+	details, err := productInfo.GetProductDetails("dummy", "dummyRegion")
+	assert.Nil(t, err, "the error should be nil")
+	assert.Equal(t, committed, details[0].Commitments, "the commitments of the prices should be filled")
+
+	assert.InDelta(t, 36.5, productInfo.EffectiveMonthlyPrice("dummy", "dummyRegion", "n1-standard-1", Linux, 0.1, 0.5), 1e-9)
+	assert.InDelta(t, 32.85, productInfo.EffectiveMonthlyPrice("discounting", "dummyRegion", "n1-standard-1", Linux, 0.1, 0.5), 1e-9)
+
+	// the licensing fee of 0.04 is not discounted, only the Linux price of 0.05
+	c.Set(productInfo.getPriceKey("discounting", "dummyRegion", "n1-standard-1"), Price{OnDemandPrice: 0.05,
+		OsPrices: map[string]OsPrice{Windows: {OnDemandPrice: 0.09}}}, cache.NoExpiration)
+	assert.InDelta(t, 0.05/0.1*32.85+0.04*0.5*HoursPerMonth,
+		productInfo.EffectiveMonthlyPrice("discounting", "dummyRegion", "n1-standard-1", Windows, 0.09, 0.5), 1e-9)
+	assert.InDelta(t, 36.5, productInfo.EffectiveMonthlyPrice("dummy", "dummyRegion", "n1-standard-1", Windows, 0.1, 0.5), 1e-9,
+		"the price should not be changed without discounts")
+}
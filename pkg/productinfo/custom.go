@@ -0,0 +1,40 @@
+package productinfo
+
+// CustomMachineType the shape of a machine type with an arbitrary number of vCPUs and memory
+type CustomMachineType struct {
+	// Series the machine series the custom machine type is created in, e.g. n1
+	Series string  `json:"series"`
+	Cpus   float64 `json:"cpusPerVm"`
+	// Mem the memory of the machine type in GiB
+	Mem float64 `json:"memPerVm"`
+}
+
+// CustomMachineTypePrice the prices of a custom machine type in a region
+type CustomMachineTypePrice struct {
+	CustomMachineType
+	// Type the provider specific name of the custom machine type
+	Type string `json:"type"`
+	// ExtendedMem the memory above the per vCPU limit of the series, charged at the extended memory rate
+	ExtendedMem      float64     `json:"extendedMemPerVm,omitempty"`
+	OnDemandPrice    float64     `json:"onDemandPrice"`
+	SpotPrice        []ZonePrice `json:"spotPrice,omitempty"`
+	PreemptiblePrice []ZonePrice `json:"preemptiblePrice,omitempty"`
+}
+
+// CustomMachineTypePricer is implemented by the product infoers of providers offering custom machine types
+type CustomMachineTypePricer interface {
+	// ValidateCustomMachineType checks whether a custom machine type can be created according to the rules of the
+	// provider
+	ValidateCustomMachineType(mt CustomMachineType) error
+
+	// PriceCustomMachineType returns the on demand, spot and preemptible prices of a valid custom machine type in a
+	// region
+	PriceCustomMachineType(region string, mt CustomMachineType) (*CustomMachineTypePrice, error)
+}
+
+// GetCustomMachineTypePricer returns the custom machine type pricer of a provider, false is returned if the provider
+// doesn't offer custom machine types
+func (cpi *CachingProductInfo) GetCustomMachineTypePricer(provider string) (CustomMachineTypePricer, bool) {
+	pricer, ok := cpi.productInfoers[provider].(CustomMachineTypePricer)
+	return pricer, ok
+}
@@ -0,0 +1,86 @@
+package ec2
+
+import (
+	"fmt"
+
+	"github.com/aws/aws-sdk-go/aws/endpoints"
+	"github.com/aws/aws-sdk-go/aws/session"
+)
+
+// partitionPricing the Price List API endpoint region and the currency of the prices of a partition
+type partitionPricing struct {
+	region   string
+	currency string
+}
+
+// partitionDefaults the pricing defaults of the partitions, GovCloud has no Price List API of its own, its prices are
+// published by the API of the commercial partition
+var partitionDefaults = map[string]partitionPricing{
+	endpoints.AwsPartitionID:      {region: "us-east-1", currency: "USD"},
+	endpoints.AwsCnPartitionID:    {region: "cn-northwest-1", currency: "CNY"},
+	endpoints.AwsUsGovPartitionID: {region: "us-east-1", currency: "USD"},
+}
+
+// pricingLocations the locations of the Price List API that differ from the region descriptions of the SDK
+var pricingLocations = map[string]string{
+	"us-gov-west-1": "AWS GovCloud (US-West)",
+	"us-gov-east-1": "AWS GovCloud (US-East)",
+}
+
+// Config the settings of an EC2 infoer, the empty fields are set to the defaults of the partition
+type Config struct {
+	// Partition the id of the AWS partition: aws, aws-cn or aws-us-gov, defaults to aws
+	Partition string
+	// Profile the shared credentials profile of the partition, the default credential chain is used if empty
+	Profile string
+	// PricingRegion the region of the Price List API endpoint
+	PricingRegion string
+	// PricingProfile the shared credentials profile of the Price List API, defaults to Profile
+	PricingProfile string
+	// Currency the currency of the prices
+	Currency string
+	// SavingsPlanSource the source of the savings plan prices, savings plans are not retrieved if nil
+	SavingsPlanSource SavingsPlanSource
+	// ProductSource the source of the products, the Price List API is queried if nil or the source fails
+	ProductSource ProductSource
+}
+
+// withDefaults returns the config with the defaults of the partition set in the empty fields, and the partition
+func (c Config) withDefaults() (Config, endpoints.Partition, error) {
+	if c.Partition == "" {
+		c.Partition = endpoints.AwsPartitionID
+	}
+	var partition endpoints.Partition
+	var found bool
+	for _, p := range endpoints.DefaultPartitions() {
+		if p.ID() == c.Partition {
+			partition, found = p, true
+		}
+	}
+	defaults, ok := partitionDefaults[c.Partition]
+	if !found || !ok {
+		return c, partition, fmt.Errorf("unsupported partition: %s", c.Partition)
+	}
+	if c.PricingRegion == "" {
+		c.PricingRegion = defaults.region
+	}
+	if c.Currency == "" {
+		c.Currency = defaults.currency
+	}
+	if c.PricingProfile == "" {
+		c.PricingProfile = c.Profile
+	}
+	return c, partition, nil
+}
+
+// newSession creates a session with the credentials of the shared credentials profile, or of the default credential
+// chain if the profile is empty
+func newSession(profile string) (*session.Session, error) {
+	if profile == "" {
+		return session.NewSession()
+	}
+	return session.NewSessionWithOptions(session.Options{
+		Profile:           profile,
+		SharedConfigState: session.SharedConfigEnable,
+	})
+}
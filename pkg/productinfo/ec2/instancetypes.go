@@ -0,0 +1,317 @@
+package ec2
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/banzaicloud/productinfo/pkg/productinfo"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// opDescribeInstanceTypes the name of the DescribeInstanceTypes operation of the EC2 API, the vendored SDK predates
+	// the operation so its shapes are declared here
+	opDescribeInstanceTypes = "DescribeInstanceTypes"
+
+	// opDescribeInstanceTypeOfferings the name of the DescribeInstanceTypeOfferings operation of the EC2 API
+	opDescribeInstanceTypeOfferings = "DescribeInstanceTypeOfferings"
+
+	// locationTypeAvailabilityZone the location type of the instance type offerings per availability zone
+	locationTypeAvailabilityZone = "availability-zone"
+)
+
+// DescribeInstanceTypesInput the input of the DescribeInstanceTypes operation
+type DescribeInstanceTypesInput struct {
+	_ struct{} `type:"structure"`
+
+	MaxResults *int64  `type:"integer"`
+	NextToken  *string `type:"string"`
+}
+
+// DescribeInstanceTypesOutput a page of the DescribeInstanceTypes operation
+type DescribeInstanceTypesOutput struct {
+	_ struct{} `type:"structure"`
+
+	InstanceTypes []*InstanceTypeInfo `locationName:"instanceTypeSet" locationNameList:"item" type:"list"`
+	NextToken     *string             `locationName:"nextToken" type:"string"`
+}
+
+// InstanceTypeInfo the description of an instance type, only the attributes used by the infoer are declared
+type InstanceTypeInfo struct {
+	_ struct{} `type:"structure"`
+
+	EbsInfo             *EbsInfo             `locationName:"ebsInfo" type:"structure"`
+	GpuInfo             *GpuInfo             `locationName:"gpuInfo" type:"structure"`
+	Hypervisor          *string              `locationName:"hypervisor" type:"string"`
+	InstanceStorageInfo *InstanceStorageInfo `locationName:"instanceStorageInfo" type:"structure"`
+	InstanceType        *string              `locationName:"instanceType" type:"string"`
+	NetworkInfo         *NetworkInfo         `locationName:"networkInfo" type:"structure"`
+	ProcessorInfo       *ProcessorInfo       `locationName:"processorInfo" type:"structure"`
+}
+
+// EbsInfo the EBS features of an instance type
+type EbsInfo struct {
+	_ struct{} `type:"structure"`
+
+	EbsOptimizedInfo *EbsOptimizedInfo `locationName:"ebsOptimizedInfo" type:"structure"`
+}
+
+// EbsOptimizedInfo the EBS performance of an EBS optimized instance type
+type EbsOptimizedInfo struct {
+	_ struct{} `type:"structure"`
+
+	BaselineBandwidthInMbps *int64 `locationName:"baselineBandwidthInMbps" type:"integer"`
+}
+
+// GpuInfo the GPUs of an instance type
+type GpuInfo struct {
+	_ struct{} `type:"structure"`
+
+	Gpus                []*GpuDeviceInfo `locationName:"gpus" locationNameList:"item" type:"list"`
+	TotalGpuMemoryInMiB *int64           `locationName:"totalGpuMemoryInMiB" type:"integer"`
+}
+
+// GpuDeviceInfo a GPU model of an instance type
+type GpuDeviceInfo struct {
+	_ struct{} `type:"structure"`
+
+	Count        *int64  `locationName:"count" type:"integer"`
+	Manufacturer *string `locationName:"manufacturer" type:"string"`
+	Name         *string `locationName:"name" type:"string"`
+}
+
+// InstanceStorageInfo the instance store volumes of an instance type
+type InstanceStorageInfo struct {
+	_ struct{} `type:"structure"`
+
+	Disks         []*DiskInfo `locationName:"disks" locationNameList:"item" type:"list"`
+	TotalSizeInGB *int64      `locationName:"totalSizeInGB" type:"long"`
+}
+
+// DiskInfo an instance store volume type of an instance type
+type DiskInfo struct {
+	_ struct{} `type:"structure"`
+
+	Count    *int64  `locationName:"count" type:"integer"`
+	SizeInGB *int64  `locationName:"sizeInGB" type:"long"`
+	Type     *string `locationName:"type" type:"string"`
+}
+
+// NetworkInfo the networking features of an instance type
+type NetworkInfo struct {
+	_ struct{} `type:"structure"`
+
+	EnaSupport                *string `locationName:"enaSupport" type:"string"`
+	Ipv4AddressesPerInterface *int64  `locationName:"ipv4AddressesPerInterface" type:"integer"`
+	MaximumNetworkInterfaces  *int64  `locationName:"maximumNetworkInterfaces" type:"integer"`
+	NetworkPerformance        *string `locationName:"networkPerformance" type:"string"`
+}
+
+// ProcessorInfo the processor of an instance type
+type ProcessorInfo struct {
+	_ struct{} `type:"structure"`
+
+	SupportedArchitectures   []*string `locationName:"supportedArchitectures" locationNameList:"item" type:"list"`
+	SustainedClockSpeedInGhz *float64  `locationName:"sustainedClockSpeedInGhz" type:"double"`
+}
+
+// DescribeInstanceTypeOfferingsInput the input of the DescribeInstanceTypeOfferings operation
+type DescribeInstanceTypeOfferingsInput struct {
+	_ struct{} `type:"structure"`
+
+	LocationType *string `type:"string"`
+	MaxResults   *int64  `type:"integer"`
+	NextToken    *string `type:"string"`
+}
+
+// DescribeInstanceTypeOfferingsOutput a page of the DescribeInstanceTypeOfferings operation
+type DescribeInstanceTypeOfferingsOutput struct {
+	_ struct{} `type:"structure"`
+
+	InstanceTypeOfferings []*InstanceTypeOffering `locationName:"instanceTypeOfferingSet" locationNameList:"item" type:"list"`
+	NextToken             *string                 `locationName:"nextToken" type:"string"`
+}
+
+// InstanceTypeOffering an instance type offered in a location, e.g. in an availability zone
+type InstanceTypeOffering struct {
+	_ struct{} `type:"structure"`
+
+	InstanceType *string `locationName:"instanceType" type:"string"`
+	Location     *string `locationName:"location" type:"string"`
+	LocationType *string `locationName:"locationType" type:"string"`
+}
+
+// ec2Client extends the EC2 API client of the SDK with the operations it doesn't have yet
+type ec2Client struct {
+	*ec2.EC2
+}
+
+// DescribeInstanceTypesPages iterates over the pages of the DescribeInstanceTypes operation, iteration stops if fn
+// returns false
+func (c *ec2Client) DescribeInstanceTypesPages(input *DescribeInstanceTypesInput, fn func(*DescribeInstanceTypesOutput, bool) bool) error {
+	return c.eachPage(opDescribeInstanceTypes, func() (interface{}, interface{}) {
+		inCpy := &DescribeInstanceTypesInput{}
+		if input != nil {
+			tmp := *input
+			inCpy = &tmp
+		}
+		return inCpy, &DescribeInstanceTypesOutput{}
+	}, func(page interface{}, lastPage bool) bool {
+		return fn(page.(*DescribeInstanceTypesOutput), lastPage)
+	})
+}
+
+// DescribeInstanceTypeOfferingsPages iterates over the pages of the DescribeInstanceTypeOfferings operation, iteration
+// stops if fn returns false
+func (c *ec2Client) DescribeInstanceTypeOfferingsPages(input *DescribeInstanceTypeOfferingsInput, fn func(*DescribeInstanceTypeOfferingsOutput, bool) bool) error {
+	return c.eachPage(opDescribeInstanceTypeOfferings, func() (interface{}, interface{}) {
+		inCpy := &DescribeInstanceTypeOfferingsInput{}
+		if input != nil {
+			tmp := *input
+			inCpy = &tmp
+		}
+		return inCpy, &DescribeInstanceTypeOfferingsOutput{}
+	}, func(page interface{}, lastPage bool) bool {
+		return fn(page.(*DescribeInstanceTypeOfferingsOutput), lastPage)
+	})
+}
+
+// eachPage iterates over the pages of a paginated operation, newParams returns a copy of the input and an empty output
+// for every page
+func (c *ec2Client) eachPage(operation string, newParams func() (interface{}, interface{}), fn func(interface{}, bool) bool) error {
+	p := request.Pagination{
+		NewRequest: func() (*request.Request, error) {
+			input, output := newParams()
+			return c.NewRequest(&request.Operation{
+				Name:       operation,
+				HTTPMethod: "POST",
+				HTTPPath:   "/",
+				Paginator: &request.Paginator{
+					InputTokens:  []string{"NextToken"},
+					OutputTokens: []string{"NextToken"},
+					LimitToken:   "MaxResults",
+				},
+			}, input, output), nil
+		},
+	}
+
+	cont := true
+	for p.Next() && cont {
+		cont = fn(p.Page(), !p.HasNextPage())
+	}
+	return p.Err()
+}
+
+// describeInstanceTypes returns the descriptions of the instance types available in the region by instance type
+func describeInstanceTypes(describer Ec2Describer) (map[string]*InstanceTypeInfo, error) {
+	instanceTypes := make(map[string]*InstanceTypeInfo)
+	err := describer.DescribeInstanceTypesPages(&DescribeInstanceTypesInput{
+		MaxResults: aws.Int64(100),
+	}, func(output *DescribeInstanceTypesOutput, lastPage bool) bool {
+		for _, it := range output.InstanceTypes {
+			if it != nil && it.InstanceType != nil {
+				instanceTypes[*it.InstanceType] = it
+			}
+		}
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	return instanceTypes, nil
+}
+
+// describeInstanceTypeZones returns the sorted availability zones of the region the instance types are offered in by
+// instance type
+func describeInstanceTypeZones(describer Ec2Describer) (map[string][]string, error) {
+	zones := make(map[string][]string)
+	err := describer.DescribeInstanceTypeOfferingsPages(&DescribeInstanceTypeOfferingsInput{
+		LocationType: aws.String(locationTypeAvailabilityZone),
+		MaxResults:   aws.Int64(1000),
+	}, func(output *DescribeInstanceTypeOfferingsOutput, lastPage bool) bool {
+		for _, o := range output.InstanceTypeOfferings {
+			if o != nil && o.InstanceType != nil && o.Location != nil {
+				zones[*o.InstanceType] = append(zones[*o.InstanceType], *o.Location)
+			}
+		}
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	for _, z := range zones {
+		sort.Strings(z)
+	}
+	return zones, nil
+}
+
+// addZones sets the availability zones the vms are offered in, the zones are left empty if the offerings can't be
+// described
+func (e *Ec2Infoer) addZones(regionId string, vms []productinfo.VmInfo) {
+	zones, err := describeInstanceTypeZones(e.ec2Describer(regionId))
+	if err != nil {
+		log.WithError(err).Warnf("could not describe the instance type offerings in region %s", regionId)
+		return
+	}
+	for i := range vms {
+		vms[i].Zones = zones[vms[i].Type]
+	}
+}
+
+// addInstanceTypeInfo enriches the vms with the descriptions of their instance types, the vms are left unchanged if
+// the instance types can't be described
+func (e *Ec2Infoer) addInstanceTypeInfo(regionId string, vms []productinfo.VmInfo) {
+	instanceTypes, err := describeInstanceTypes(e.ec2Describer(regionId))
+	if err != nil {
+		log.WithError(err).Warnf("could not describe the instance types in region %s", regionId)
+		return
+	}
+	for i := range vms {
+		if it, ok := instanceTypes[vms[i].Type]; ok {
+			setInstanceTypeInfo(&vms[i], it)
+		}
+	}
+}
+
+// setInstanceTypeInfo sets the attributes of the instance type description on the vm, the gpus and the network
+// performance of the price list are only replaced if they are missing
+func setInstanceTypeInfo(vm *productinfo.VmInfo, it *InstanceTypeInfo) {
+	vm.Hypervisor = aws.StringValue(it.Hypervisor)
+	if pi := it.ProcessorInfo; pi != nil {
+		vm.CpuArchitectures = aws.StringValueSlice(pi.SupportedArchitectures)
+		vm.ClockSpeed = aws.Float64Value(pi.SustainedClockSpeedInGhz)
+	}
+	if ni := it.NetworkInfo; ni != nil {
+		vm.EnaSupport = aws.StringValue(ni.EnaSupport)
+		vm.MaxNetworkInterfaces = int(aws.Int64Value(ni.MaximumNetworkInterfaces))
+		vm.IpsPerNetworkInterface = int(aws.Int64Value(ni.Ipv4AddressesPerInterface))
+		if vm.NtwPerf == "" {
+			vm.NtwPerf = aws.StringValue(ni.NetworkPerformance)
+		}
+	}
+	if it.EbsInfo != nil && it.EbsInfo.EbsOptimizedInfo != nil {
+		vm.EbsBandwidth = float64(aws.Int64Value(it.EbsInfo.EbsOptimizedInfo.BaselineBandwidthInMbps))
+	}
+	if si := it.InstanceStorageInfo; si != nil {
+		vm.InstanceStorage = float64(aws.Int64Value(si.TotalSizeInGB))
+		if len(si.Disks) > 0 {
+			vm.InstanceStorageType = aws.StringValue(si.Disks[0].Type)
+		}
+	}
+	if gi := it.GpuInfo; gi != nil {
+		var gpus int64
+		var models []string
+		for _, g := range gi.Gpus {
+			gpus += aws.Int64Value(g.Count)
+			models = append(models, strings.TrimSpace(aws.StringValue(g.Manufacturer)+" "+aws.StringValue(g.Name)))
+		}
+		if vm.Gpus == 0 {
+			vm.Gpus = float64(gpus)
+		}
+		vm.GpuModel = strings.Join(models, ", ")
+		vm.GpuMem = float64(aws.Int64Value(gi.TotalGpuMemoryInMiB)) / 1024
+	}
+}
@@ -0,0 +1,115 @@
+package ec2
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/banzaicloud/productinfo/pkg/productinfo"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSetInstanceTypeInfo(t *testing.T) {
+	tests := []struct {
+		name         string
+		vm           productinfo.VmInfo
+		instanceType *InstanceTypeInfo
+		check        func(vm productinfo.VmInfo)
+	}{
+		{
+			name: "gpus and instance store",
+			vm:   productinfo.VmInfo{Type: "p3dn.24xlarge", NtwPerf: "100 Gigabit"},
+			instanceType: &InstanceTypeInfo{
+				InstanceType: aws.String("p3dn.24xlarge"),
+				GpuInfo: &GpuInfo{
+					Gpus: []*GpuDeviceInfo{
+						{Count: aws.Int64(8), Manufacturer: aws.String("NVIDIA"), Name: aws.String("V100")},
+					},
+					TotalGpuMemoryInMiB: aws.Int64(262144),
+				},
+				InstanceStorageInfo: &InstanceStorageInfo{
+					Disks:         []*DiskInfo{{Count: aws.Int64(2), SizeInGB: aws.Int64(900), Type: aws.String("ssd")}},
+					TotalSizeInGB: aws.Int64(1800),
+				},
+				NetworkInfo: &NetworkInfo{NetworkPerformance: aws.String("100 Gigabit")},
+			},
+			check: func(vm productinfo.VmInfo) {
+				assert.Equal(t, 8.0, vm.Gpus, "the missing gpu count should be set")
+				assert.Equal(t, "NVIDIA V100", vm.GpuModel)
+				assert.Equal(t, 256.0, vm.GpuMem)
+				assert.Equal(t, 1800.0, vm.InstanceStorage)
+				assert.Equal(t, "ssd", vm.InstanceStorageType)
+				assert.Equal(t, "100 Gigabit", vm.NtwPerf)
+			},
+		},
+		{
+			name: "price list attributes are kept",
+			vm:   productinfo.VmInfo{Type: "g4dn.xlarge", Gpus: 1, NtwPerf: "Up to 25 Gigabit"},
+			instanceType: &InstanceTypeInfo{
+				InstanceType: aws.String("g4dn.xlarge"),
+				Hypervisor:   aws.String("nitro"),
+				GpuInfo:      &GpuInfo{Gpus: []*GpuDeviceInfo{{Count: aws.Int64(4), Name: aws.String("T4")}}},
+				NetworkInfo:  &NetworkInfo{NetworkPerformance: aws.String("25 Gigabit")},
+				ProcessorInfo: &ProcessorInfo{
+					SupportedArchitectures:   aws.StringSlice([]string{"x86_64"}),
+					SustainedClockSpeedInGhz: aws.Float64(2.5),
+				},
+			},
+			check: func(vm productinfo.VmInfo) {
+				assert.Equal(t, 1.0, vm.Gpus)
+				assert.Equal(t, "T4", vm.GpuModel)
+				assert.Equal(t, "Up to 25 Gigabit", vm.NtwPerf)
+				assert.Equal(t, "nitro", vm.Hypervisor)
+				assert.Equal(t, []string{"x86_64"}, vm.CpuArchitectures)
+				assert.Equal(t, 2.5, vm.ClockSpeed)
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			setInstanceTypeInfo(&test.vm, test.instanceType)
+			test.check(test.vm)
+		})
+	}
+}
+
+func TestEc2Infoer_addInstanceTypeInfo(t *testing.T) {
+	productInfoer, err := NewEc2Infoer(Config{})
+	assert.Nil(t, err, "the error should be nil")
+
+	vms := []productinfo.VmInfo{{Type: "m5.large"}, {Type: "t2.small"}}
+	productInfoer.ec2Describer = func(region string) Ec2Describer {
+		return &testStruct{}
+	}
+	productInfoer.addInstanceTypeInfo("eu-west-1", vms)
+	assert.Equal(t, "required", vms[0].EnaSupport)
+	assert.Equal(t, 10, vms[0].IpsPerNetworkInterface)
+	assert.Equal(t, productinfo.VmInfo{Type: "t2.small"}, vms[1], "undescribed instance types should be unchanged")
+
+	// the vms are unchanged if the instance types can't be described
+	vms = []productinfo.VmInfo{{Type: "m5.large"}}
+	productInfoer.ec2Describer = func(region string) Ec2Describer {
+		return &testStruct{TcId: 11}
+	}
+	productInfoer.addInstanceTypeInfo("eu-west-1", vms)
+	assert.Equal(t, []productinfo.VmInfo{{Type: "m5.large"}}, vms)
+}
+
+func TestEc2Infoer_addZones(t *testing.T) {
+	productInfoer, err := NewEc2Infoer(Config{})
+	assert.Nil(t, err, "the error should be nil")
+
+	vms := []productinfo.VmInfo{{Type: "m5.large"}, {Type: "t2.small"}}
+	productInfoer.ec2Describer = func(region string) Ec2Describer {
+		return &testStruct{}
+	}
+	productInfoer.addZones("eu-central-1", vms)
+	assert.Equal(t, []string{"eu-central-1a", "eu-central-1b"}, vms[0].Zones, "the offerings of every page should be collected")
+	assert.Nil(t, vms[1].Zones, "instance types without offerings should have no zones")
+
+	vms = []productinfo.VmInfo{{Type: "m5.large"}}
+	productInfoer.ec2Describer = func(region string) Ec2Describer {
+		return &testStruct{TcId: 11}
+	}
+	productInfoer.addZones("eu-central-1", vms)
+	assert.Nil(t, vms[0].Zones, "the zones should be unknown if the offerings can't be described")
+}
@@ -0,0 +1,244 @@
+package ec2
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/banzaicloud/productinfo/pkg/productinfo"
+	log "github.com/sirupsen/logrus"
+)
+
+// offerRegionIndexPath the path of the region index of the EC2 offer files
+const offerRegionIndexPath = "/offers/v1.0/aws/AmazonEC2/current/region_index.json"
+
+// ProductSource retrieves the price list products of the instance types and the dedicated hosts in a region
+type ProductSource interface {
+	// GetProducts returns the products of the instance types without pre installed software per tenancy and operating
+	// system in the format of the Price List API, the dedicated hosts are not bound to an operating system, they are
+	// stored with an empty one
+	GetProducts(region string) (map[string]map[string][]aws.JSONValue, error)
+}
+
+// OfferFileProductSource streams the products from the EC2 offer files of the AWS Price List, the offer files are
+// read from a base url, e.g. https://pricing.us-east-1.amazonaws.com, or from a local directory holding the offer
+// files of the regions as <region>.json
+type OfferFileProductSource struct {
+	location string
+	client   *http.Client
+}
+
+// NewOfferFileProductSource creates a new product source reading the offer files from the given base url or directory
+func NewOfferFileProductSource(location string) *OfferFileProductSource {
+	return &OfferFileProductSource{
+		location: strings.TrimSuffix(location, "/"),
+		client:   &http.Client{Timeout: 30 * time.Minute},
+	}
+}
+
+type offerRegionIndex struct {
+	Regions map[string]struct {
+		CurrentVersionURL string `json:"currentVersionUrl"`
+	} `json:"regions"`
+}
+
+// offerProduct a product of an offer file
+type offerProduct struct {
+	Sku           string                 `json:"sku"`
+	ProductFamily string                 `json:"productFamily"`
+	Attributes    map[string]interface{} `json:"attributes"`
+}
+
+// GetProducts reads the offer file of the region and returns the instance type and the dedicated host products per
+// tenancy and operating system
+func (s *OfferFileProductSource) GetProducts(region string) (map[string]map[string][]aws.JSONValue, error) {
+	if !strings.HasPrefix(s.location, "http://") && !strings.HasPrefix(s.location, "https://") {
+		f, err := os.Open(filepath.Join(s.location, region+".json"))
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		return parseOfferFile(f)
+	}
+
+	var index offerRegionIndex
+	body, err := s.get(offerRegionIndexPath)
+	if err != nil {
+		return nil, err
+	}
+	err = json.NewDecoder(body).Decode(&index)
+	body.Close()
+	if err != nil {
+		return nil, err
+	}
+	r, ok := index.Regions[region]
+	if !ok {
+		return nil, fmt.Errorf("there's no offer file for region %s", region)
+	}
+	body, err = s.get(r.CurrentVersionURL)
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+	return parseOfferFile(body)
+}
+
+func (s *OfferFileProductSource) get(path string) (io.ReadCloser, error) {
+	log.Debugf("getting offer file %s", path)
+	resp, err := s.client.Get(s.location + path)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("could not get offer file %s, status code: %d", path, resp.StatusCode)
+	}
+	return resp.Body, nil
+}
+
+// parseOfferFile streams an offer file and collects the instance type products without pre installed software and the
+// dedicated host products per tenancy and operating system, only the products and the terms kept are held in memory
+// the products precede the terms in the offer files, the terms of the products not read yet are skipped
+func parseOfferFile(r io.Reader) (map[string]map[string][]aws.JSONValue, error) {
+	oses := make(map[string]string, len(operatingSystems))
+	for opSys, value := range operatingSystems {
+		oses[value] = opSys
+	}
+
+	products := make(map[string]offerProduct)
+	terms := make(map[string]map[string]interface{})
+	dec := json.NewDecoder(r)
+	if err := expectDelim(dec, '{'); err != nil {
+		return nil, err
+	}
+	for dec.More() {
+		key, err := dec.Token()
+		if err != nil {
+			return nil, err
+		}
+		switch key {
+		case "products":
+			err = decodeObject(dec, func(sku string) error {
+				var p offerProduct
+				if err := dec.Decode(&p); err != nil {
+					return err
+				}
+				if _, ok := productTenancy(p, oses); ok {
+					products[sku] = p
+				}
+				return nil
+			})
+		case "terms":
+			err = decodeObject(dec, func(termType string) error {
+				return decodeObject(dec, func(sku string) error {
+					if _, ok := products[sku]; !ok || (termType != "OnDemand" && termType != "Reserved") {
+						var skip json.RawMessage
+						return dec.Decode(&skip)
+					}
+					var term map[string]interface{}
+					if err := dec.Decode(&term); err != nil {
+						return err
+					}
+					if terms[sku] == nil {
+						terms[sku] = make(map[string]interface{})
+					}
+					terms[sku][termType] = term
+					return nil
+				})
+			})
+		default:
+			var skip json.RawMessage
+			err = dec.Decode(&skip)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("could not parse offer file: %s", err.Error())
+		}
+	}
+
+	skus := make([]string, 0, len(products))
+	for sku := range products {
+		skus = append(skus, sku)
+	}
+	sort.Strings(skus)
+	tenancyProducts := make(map[string]map[string][]aws.JSONValue)
+	for _, sku := range skus {
+		p := products[sku]
+		tenancy, _ := productTenancy(p, oses)
+		opSys := oses[fmt.Sprint(p.Attributes["operatingSystem"])]
+		if tenancyProducts[tenancy] == nil {
+			tenancyProducts[tenancy] = make(map[string][]aws.JSONValue)
+		}
+		tenancyProducts[tenancy][opSys] = append(tenancyProducts[tenancy][opSys], aws.JSONValue{
+			"product": map[string]interface{}{
+				"sku":           p.Sku,
+				"productFamily": p.ProductFamily,
+				"attributes":    p.Attributes,
+			},
+			"terms": terms[sku],
+		})
+	}
+	if len(tenancyProducts[productinfo.Shared][productinfo.Linux]) == 0 {
+		return nil, fmt.Errorf("the offer file has no %s products", productinfo.Linux)
+	}
+	return tenancyProducts, nil
+}
+
+// productTenancy returns the tenancy of the product if it's an instance of a known operating system without pre
+// installed software or a dedicated host that's charged when it's used, the capacity reservations are left out
+func productTenancy(p offerProduct, oses map[string]string) (string, bool) {
+	attr := func(name string) string {
+		return fmt.Sprint(p.Attributes[name])
+	}
+	if capacityStatus, ok := p.Attributes["capacitystatus"]; ok && capacityStatus != "Used" {
+		return "", false
+	}
+	tenancy, ok := priceListTenancies[strings.ToLower(attr("tenancy"))]
+	if !ok {
+		return "", false
+	}
+	if tenancy == productinfo.Host {
+		return tenancy, p.ProductFamily == dedicatedHostFamily
+	}
+	_, knownOs := oses[attr("operatingSystem")]
+	return tenancy, knownOs && p.ProductFamily == "Compute Instance" && attr("preInstalledSw") == "NA"
+}
+
+// decodeObject reads the members of the next JSON object, decodeMember is called with the key of every member and it
+// must consume the value of the member
+func decodeObject(dec *json.Decoder, decodeMember func(key string) error) error {
+	if err := expectDelim(dec, '{'); err != nil {
+		return err
+	}
+	for dec.More() {
+		t, err := dec.Token()
+		if err != nil {
+			return err
+		}
+		key, ok := t.(string)
+		if !ok {
+			return fmt.Errorf("unexpected token %v, expected an object key", t)
+		}
+		if err := decodeMember(key); err != nil {
+			return err
+		}
+	}
+	return expectDelim(dec, '}')
+}
+
+func expectDelim(dec *json.Decoder, delim json.Delim) error {
+	t, err := dec.Token()
+	if err != nil {
+		return err
+	}
+	if d, ok := t.(json.Delim); !ok || d != delim {
+		return fmt.Errorf("unexpected token %v, expected %v", t, delim)
+	}
+	return nil
+}
@@ -0,0 +1,169 @@
+package ec2
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/pricing"
+	"github.com/banzaicloud/productinfo/pkg/productinfo"
+	"github.com/stretchr/testify/assert"
+)
+
+const ec2OfferFile = `{
+  "formatVersion": "v1.0",
+  "offerCode": "AmazonEC2",
+  "products": {
+    "L1": {"sku": "L1", "productFamily": "Compute Instance", "attributes": {"instanceType": "m5.large", "vcpu": "2", "memory": "8 GiB",
+      "networkPerformance": "Up to 10 Gigabit", "operatingSystem": "Linux", "tenancy": "Shared", "preInstalledSw": "NA", "capacitystatus": "Used"}},
+    "L2": {"sku": "L2", "productFamily": "Compute Instance", "attributes": {"instanceType": "m5.large", "vcpu": "2", "memory": "8 GiB",
+      "networkPerformance": "Up to 10 Gigabit", "operatingSystem": "Linux", "tenancy": "Shared", "preInstalledSw": "NA", "capacitystatus": "UnusedCapacityReservation"}},
+    "L3": {"sku": "L3", "productFamily": "Compute Instance", "attributes": {"instanceType": "m5.large", "vcpu": "2", "memory": "8 GiB",
+      "networkPerformance": "Up to 10 Gigabit", "operatingSystem": "Linux", "tenancy": "Dedicated", "preInstalledSw": "NA", "capacitystatus": "Used"}},
+    "W1": {"sku": "W1", "productFamily": "Compute Instance", "attributes": {"instanceType": "m5.large", "vcpu": "2", "memory": "8 GiB",
+      "networkPerformance": "Up to 10 Gigabit", "operatingSystem": "Windows", "tenancy": "Shared", "preInstalledSw": "NA", "capacitystatus": "Used",
+      "licenseModel": "License Included"}},
+    "W2": {"sku": "W2", "productFamily": "Compute Instance", "attributes": {"instanceType": "m5.large", "vcpu": "2", "memory": "8 GiB",
+      "networkPerformance": "Up to 10 Gigabit", "operatingSystem": "Windows", "tenancy": "Shared", "preInstalledSw": "SQL Std", "capacitystatus": "Used"}},
+    "H1": {"sku": "H1", "productFamily": "Dedicated Host", "attributes": {"instanceType": "m5", "vcpu": "96", "physicalCores": "48",
+      "sockets": "2", "operatingSystem": "NA", "tenancy": "Host", "preInstalledSw": "NA", "capacitystatus": "Used"}},
+    "S1": {"sku": "S1", "productFamily": "Storage", "attributes": {"volumeType": "General Purpose"}}
+  },
+  "terms": {
+    "OnDemand": {
+      "L1": {"L1.JRTCKXETXF": {"priceDimensions": {"L1.JRTCKXETXF.6YS6EN2CT7": {"unit": "Hrs", "pricePerUnit": {"USD": "0.096"}}}}},
+      "L2": {"L2.JRTCKXETXF": {"priceDimensions": {"L2.JRTCKXETXF.6YS6EN2CT7": {"unit": "Hrs", "pricePerUnit": {"USD": "0"}}}}},
+      "L3": {"L3.JRTCKXETXF": {"priceDimensions": {"L3.JRTCKXETXF.6YS6EN2CT7": {"unit": "Hrs", "pricePerUnit": {"USD": "0.106"}}}}},
+      "H1": {"H1.JRTCKXETXF": {"priceDimensions": {"H1.JRTCKXETXF.6YS6EN2CT7": {"unit": "Hrs", "pricePerUnit": {"USD": "5.069"}}}}},
+      "W1": {"W1.JRTCKXETXF": {"priceDimensions": {"W1.JRTCKXETXF.6YS6EN2CT7": {"unit": "Hrs", "pricePerUnit": {"USD": "0.188"}}}}},
+      "S1": {"S1.JRTCKXETXF": {"priceDimensions": {"S1.JRTCKXETXF.6YS6EN2CT7": {"unit": "GB-Mo", "pricePerUnit": {"USD": "0.1"}}}}}
+    },
+    "Reserved": {
+      "L1": {"L1.4NA7Y494T4": {"termAttributes": {"LeaseContractLength": "1yr", "OfferingClass": "standard", "PurchaseOption": "No Upfront"},
+        "priceDimensions": {"L1.4NA7Y494T4.6YS6EN2CT7": {"unit": "Hrs", "pricePerUnit": {"USD": "0.06"}}}}}
+    }
+  }
+}`
+
+func TestParseOfferFile(t *testing.T) {
+	products, err := parseOfferFile(strings.NewReader(ec2OfferFile))
+	assert.Nil(t, err, "the error should be nil")
+	assert.Equal(t, 1, len(products[productinfo.Shared][productinfo.Linux]))
+	assert.Equal(t, 1, len(products[productinfo.Shared][productinfo.Windows]))
+	assert.Equal(t, 1, len(products[productinfo.Dedicated][productinfo.Linux]))
+	assert.Equal(t, 1, len(products[productinfo.Host][""]))
+
+	vm, err := newVmInfo(products[productinfo.Shared][productinfo.Linux][0], "USD")
+	assert.Nil(t, err, "the error should be nil")
+	assert.Equal(t, "m5.large", vm.Type)
+	assert.Equal(t, 0.096, vm.OnDemandPrice)
+	assert.Equal(t, []productinfo.CommitmentPrice{{Type: productinfo.Reserved, Term: 1, PaymentOption: productinfo.NoUpfront,
+		OfferingClass: "standard", HourlyPrice: 0.06}}, vm.Commitments)
+
+	host, err := newHostInfo(products[productinfo.Host][""][0], "USD")
+	assert.Nil(t, err, "the error should be nil")
+	assert.Equal(t, productinfo.VmInfo{Type: "m5", OnDemandPrice: 5.069, Cpus: 96, Sockets: 2, Cores: 48, CurrentGen: true}, *host)
+
+	_, err = parseOfferFile(strings.NewReader(`{"products": {}, "terms": {}}`))
+	assert.EqualError(t, err, "the offer file has no linux products")
+	_, err = parseOfferFile(strings.NewReader(`{"products": [`))
+	assert.NotNil(t, err)
+}
+
+func TestOfferFileProductSource_GetProducts(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case offerRegionIndexPath:
+			w.Write([]byte(`{"regions": {"eu-west-1": {"regionCode": "eu-west-1", "currentVersionUrl": "/offers/v1.0/aws/AmazonEC2/20200101/eu-west-1/index.json"}}}`))
+		case "/offers/v1.0/aws/AmazonEC2/20200101/eu-west-1/index.json":
+			w.Write([]byte(ec2OfferFile))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	products, err := NewOfferFileProductSource(server.URL + "/").GetProducts("eu-west-1")
+	assert.Nil(t, err, "the error should be nil")
+	assert.Equal(t, 1, len(products[productinfo.Shared][productinfo.Linux]))
+	_, err = NewOfferFileProductSource(server.URL).GetProducts("us-east-1")
+	assert.EqualError(t, err, "there's no offer file for region us-east-1")
+
+	dir, err := ioutil.TempDir("", "offers")
+	assert.Nil(t, err, "the error should be nil")
+	defer os.RemoveAll(dir)
+	assert.Nil(t, ioutil.WriteFile(filepath.Join(dir, "eu-west-1.json"), []byte(ec2OfferFile), 0644))
+	products, err = NewOfferFileProductSource(dir).GetProducts("eu-west-1")
+	assert.Nil(t, err, "the error should be nil")
+	assert.Equal(t, 1, len(products[productinfo.Shared][productinfo.Windows]))
+}
+
+// pagingPricingSource returns the products one per page
+type pagingPricingSource struct {
+	testStruct
+	calls int
+}
+
+func (ps *pagingPricingSource) GetProducts(input *pricing.GetProductsInput) (*pricing.GetProductsOutput, error) {
+	ps.calls++
+	if *input.Filters[0].Value != "Linux" || *input.Filters[2].Value != "Shared" || filterValue(input, "capacitystatus") != "Used" {
+		return &pricing.GetProductsOutput{}, nil
+	}
+	product := func(instanceType string) aws.JSONValue {
+		return aws.JSONValue{
+			"product": map[string]interface{}{
+				"attributes": map[string]interface{}{
+					"instanceType":       instanceType,
+					Cpu:                  "2",
+					Memory:               "8 GiB",
+					"networkPerformance": "Up to 10 Gigabit",
+				}},
+			"terms": map[string]interface{}{
+				"OnDemand": map[string]interface{}{
+					"term": map[string]interface{}{
+						"priceDimensions": map[string]interface{}{
+							"dimension": map[string]interface{}{
+								"pricePerUnit": map[string]interface{}{"USD": "0.1"},
+							}}}}},
+		}
+	}
+	if input.NextToken == nil {
+		return &pricing.GetProductsOutput{PriceList: []aws.JSONValue{product("m5.large")}, NextToken: aws.String("2")}, nil
+	}
+	return &pricing.GetProductsOutput{PriceList: []aws.JSONValue{product("m5.xlarge")}}, nil
+}
+
+func TestEc2Infoer_GetProducts_pagination(t *testing.T) {
+	productInfoer, err := NewEc2Infoer(Config{})
+	assert.Nil(t, err, "the error should be nil")
+	ps := &pagingPricingSource{}
+	productInfoer.pricingSvc = ps
+	productInfoer.ec2Describer = func(region string) Ec2Describer {
+		return ps
+	}
+
+	vms, err := productInfoer.GetProducts("eu-west-1")
+	assert.Nil(t, err, "the error should be nil")
+	assert.Equal(t, 2, len(vms))
+	assert.Equal(t, "m5.xlarge", vms[1].Type)
+	// two pages of shared Linux products, a page of every other shared and dedicated operating system and the hosts
+	assert.Equal(t, 2+len(productinfo.OperatingSystems)-1+len(productinfo.OperatingSystems)+1, ps.calls)
+
+	// the dedicated instance and host products are not queried again until they're older than the max age
+	ps.calls = 0
+	vms, err = productInfoer.GetProducts("eu-west-1")
+	assert.Nil(t, err, "the error should be nil")
+	assert.Equal(t, 2, len(vms))
+	assert.Equal(t, 2+len(productinfo.OperatingSystems)-1, ps.calls)
+
+	// the Price List API is the fallback of the failing product source
+	productInfoer.productSource = NewOfferFileProductSource("/nonexistent")
+	vms, err = productInfoer.GetProducts("eu-west-1")
+	assert.Nil(t, err, "the error should be nil")
+	assert.Equal(t, 2, len(vms))
+}
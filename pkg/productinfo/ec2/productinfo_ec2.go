@@ -1,11 +1,11 @@
 package ec2
 
 import (
-	"context"
 	"errors"
 	"fmt"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
@@ -14,9 +14,6 @@ import (
 	"github.com/aws/aws-sdk-go/service/ec2"
 	"github.com/aws/aws-sdk-go/service/pricing"
 	"github.com/banzaicloud/productinfo/pkg/productinfo"
-	"github.com/prometheus/client_golang/api"
-	"github.com/prometheus/client_golang/api/prometheus/v1"
-	"github.com/prometheus/common/model"
 	log "github.com/sirupsen/logrus"
 )
 
@@ -26,8 +23,58 @@ const (
 
 	// Cpu represents the cpu attribute for the recommender
 	Cpu = "vcpu"
+
+	// tenancyProductsMaxAge the age of the dedicated instance and host products they are queried again after from
+	// the Price List API, these prices rarely change and they take a paginated query per operating system
+	tenancyProductsMaxAge = 7 * 24 * time.Hour
 )
 
+// operatingSystems maps the operating systems to the values of the operatingSystem attribute of the pricing API
+var operatingSystems = map[string]string{
+	productinfo.Linux:   "Linux",
+	productinfo.Windows: "Windows",
+	productinfo.Rhel:    "RHEL",
+	productinfo.Suse:    "SUSE",
+}
+
+// tenancies maps the tenancies to the values of the tenancy attribute of the pricing API
+var tenancies = map[string]string{
+	productinfo.Shared:    "Shared",
+	productinfo.Dedicated: "Dedicated",
+	productinfo.Host:      "Host",
+}
+
+// priceListTenancies maps the lower case values of the tenancy attribute of the pricing API to the tenancies
+var priceListTenancies = map[string]string{
+	"shared":    productinfo.Shared,
+	"dedicated": productinfo.Dedicated,
+	"host":      productinfo.Host,
+}
+
+// dedicatedHostFamily the product family of the dedicated hosts in the pricing API
+const dedicatedHostFamily = "Dedicated Host"
+
+// leaseContractLengths maps the lease contract lengths of the reserved terms to years
+var leaseContractLengths = map[string]int{
+	"1yr": 1,
+	"3yr": 3,
+}
+
+// purchaseOptions maps the purchase options of the reserved terms and savings plans to payment options
+var purchaseOptions = map[string]string{
+	"No Upfront":      productinfo.NoUpfront,
+	"Partial Upfront": productinfo.PartialUpfront,
+	"All Upfront":     productinfo.AllUpfront,
+}
+
+// spotProductDescriptions maps the operating systems to the product descriptions of the spot price history
+var spotProductDescriptions = map[string]string{
+	productinfo.Linux:   "Linux/UNIX",
+	productinfo.Windows: "Windows",
+	productinfo.Rhel:    "Red Hat Enterprise Linux",
+	productinfo.Suse:    "SUSE Linux",
+}
+
 // PricingSource list of operations for retrieving pricing information
 // Decouples the pricing logic from the aws api
 type PricingSource interface {
@@ -39,51 +86,76 @@ type PricingSource interface {
 type Ec2Infoer struct {
 	pricingSvc   PricingSource
 	session      *session.Session
-	prometheus   v1.API
-	promQuery    string
+	partition    endpoints.Partition
+	currency     string
 	ec2Describer func(region string) Ec2Describer
+	// savingsPlanSource the source of the savings plan prices, savings plans are not retrieved if it's nil
+	savingsPlanSource SavingsPlanSource
+	// productSource the source of the products, the Price List API is queried if it's nil or fails
+	productSource ProductSource
+	// hosts the dedicated host families per region read with the products
+	hosts   map[string][]productinfo.VmInfo
+	hostsMu sync.RWMutex
+	// qualityIssues the products per region of the last GetProducts call that couldn't be parsed
+	qualityIssues   map[string][]productinfo.QualityIssue
+	qualityIssuesMu sync.RWMutex
+	// tenancyProducts the dedicated instance and host products per region last queried from the Price List API
+	tenancyProducts   map[string]tenancyProducts
+	tenancyProductsMu sync.Mutex
+}
+
+// tenancyProducts the products of the non-shared tenancies per tenancy and operating system, and the time they were
+// queried at
+type tenancyProducts struct {
+	products map[string]map[string][]aws.JSONValue
+	updated  time.Time
 }
 
 // Ec2Describer interface for operations describing EC2 artifacts. (a subset of the Ec2 cli operations iused by this app)
 type Ec2Describer interface {
 	DescribeAvailabilityZones(input *ec2.DescribeAvailabilityZonesInput) (*ec2.DescribeAvailabilityZonesOutput, error)
 	DescribeSpotPriceHistoryPages(input *ec2.DescribeSpotPriceHistoryInput, fn func(*ec2.DescribeSpotPriceHistoryOutput, bool) bool) error
+	DescribeInstanceTypesPages(input *DescribeInstanceTypesInput, fn func(*DescribeInstanceTypesOutput, bool) bool) error
+	DescribeInstanceTypeOfferingsPages(input *DescribeInstanceTypeOfferingsInput, fn func(*DescribeInstanceTypeOfferingsOutput, bool) bool) error
 }
 
-// NewEc2Infoer creates a new instance of the infoer
-func NewEc2Infoer(promAddr string, pq string) (*Ec2Infoer, error) {
-	s, err := session.NewSession()
+// NewEc2Infoer creates a new instance of the infoer in the partition of the config, spot prices are queried directly
+// from the AWS API
+// savings plan prices are added to the products if the savings plan source is not nil
+func NewEc2Infoer(cfg Config) (*Ec2Infoer, error) {
+	cfg, partition, err := cfg.withDefaults()
+	if err != nil {
+		return nil, err
+	}
 
+	s, err := newSession(cfg.Profile)
 	if err != nil {
 		log.WithError(err).Error("Error creating AWS session")
 		return nil, err
 	}
-	var promApi v1.API
-	if promAddr == "" {
-		log.Warn("Prometheus API address is not set, fallback to direct API access.")
-		promApi = nil
-	} else {
-		promClient, err := api.NewClient(api.Config{
-			Address: promAddr,
-		})
-		if err != nil {
-			log.WithError(err).Warn("Error creating Prometheus client, fallback to direct API access.")
-			promApi = nil
-		} else {
-			promApi = v1.NewAPI(promClient)
+	pricingSession := s
+	if cfg.PricingProfile != cfg.Profile {
+		if pricingSession, err = newSession(cfg.PricingProfile); err != nil {
+			log.WithError(err).Error("Error creating AWS pricing session")
+			return nil, err
 		}
 	}
 
-	const defaultPricingRegion = "us-east-1"
-	return &Ec2Infoer{
-		pricingSvc: pricing.New(s, aws.NewConfig().WithRegion(defaultPricingRegion)),
+	infoer := &Ec2Infoer{
+		pricingSvc: pricing.New(pricingSession, aws.NewConfig().WithRegion(cfg.PricingRegion)),
 		session:    s,
-		prometheus: promApi,
-		promQuery:  pq,
+		partition:  partition,
+		currency:   cfg.Currency,
 		ec2Describer: func(region string) Ec2Describer {
-			return ec2.New(s, aws.NewConfig().WithRegion(region))
+			return &ec2Client{ec2.New(s, aws.NewConfig().WithRegion(region))}
 		},
-	}, nil
+		savingsPlanSource: cfg.SavingsPlanSource,
+		productSource:     cfg.ProductSource,
+		hosts:             make(map[string][]productinfo.VmInfo),
+		qualityIssues:     make(map[string][]productinfo.QualityIssue),
+		tenancyProducts:   make(map[string]tenancyProducts),
+	}
+	return infoer, nil
 }
 
 // NewConfig creates a new  Config instance and returns a pointer to it
@@ -120,87 +192,410 @@ func (e *Ec2Infoer) GetAttributeValues(attribute string) (productinfo.AttrValues
 }
 
 // GetProducts retrieves the available virtual machines based on the arguments provided
-// Delegates to the underlying PricingSource instance and performs transformations
+// The products are read from the product source, or from the Price List API if the source is not set or fails
+// The products are queried for every operating system, the prices of the operating systems other than Linux are
+// added to the OsPrices of the Linux instance types. The attributes of the price list are completed with the
+// descriptions of the instance types and the availability zones they are offered in
+// The dedicated instance prices are added to the TenancyPrices of the instance types, the dedicated hosts read with
+// the products are kept for GetDedicatedHosts
 func (e *Ec2Infoer) GetProducts(regionId string) ([]productinfo.VmInfo, error) {
 
 	var vms []productinfo.VmInfo
 	log.Debugf("Getting available instance types from AWS API. [region=%s]", regionId)
 
-	products, err := e.pricingSvc.GetProducts(e.newGetProductsInput(regionId))
-
+	tenancyProducts, err := e.getProducts(regionId)
 	if err != nil {
 		return nil, err
 	}
-	for i, price := range products.PriceList {
-		pd, err := newPriceData(price)
+	e.setHosts(regionId, e.parseHosts(tenancyProducts[productinfo.Host][""]))
+	products := tenancyProducts[productinfo.Shared]
+	var issues []productinfo.QualityIssue
+	for i, price := range products[productinfo.Linux] {
+		vm, err := newVmInfo(price, e.currency)
 		if err != nil {
-			log.Warn("could not extract pricing info for the item with index: [ %d ]", i)
+			log.Warnf("could not extract pricing info for the item with index: [ %d ], %s", i, err.Error())
+			issues = append(issues, unparsableProduct(regionId, price, err))
 			continue
 		}
+		vms = append(vms, *vm)
+	}
+	if vms == nil {
+		e.setQualityIssues(regionId, issues)
+		log.Debugf("couldn't find any virtual machines to recommend")
+		return nil, nil
+	}
 
-		instanceType, err := pd.GetDataForKey("instanceType")
-		if err != nil {
-			log.Warn("could not retrieve instance type")
+	for _, os := range productinfo.OperatingSystems {
+		if os == productinfo.Linux {
 			continue
 		}
-		cpusStr, err := pd.GetDataForKey(Cpu)
+		osPrices, osIssues := e.getOsOnDemandPrices(regionId, os, products[os])
+		issues = append(issues, osIssues...)
+		for i := range vms {
+			if odPrice, ok := osPrices[vms[i].Type]; ok {
+				if vms[i].OsPrices == nil {
+					vms[i].OsPrices = make(map[string]productinfo.OsPrice)
+				}
+				vms[i].OsPrices[os] = productinfo.OsPrice{OnDemandPrice: odPrice}
+			}
+		}
+	}
+
+	issues = append(issues, e.addDedicatedPrices(regionId, vms, tenancyProducts[productinfo.Dedicated])...)
+	e.setQualityIssues(regionId, issues)
+	e.addInstanceTypeInfo(regionId, vms)
+	e.addZones(regionId, vms)
+	e.addSavingsPlanPrices(regionId, vms)
+
+	log.Debugf("found vms: %#v", vms)
+	return vms, nil
+}
+
+// getProducts returns the products of the region per tenancy and operating system from the product source, the Price
+// List API is queried if the source is not set or fails
+func (e *Ec2Infoer) getProducts(regionId string) (map[string]map[string][]aws.JSONValue, error) {
+	if e.productSource != nil {
+		products, err := e.productSource.GetProducts(regionId)
+		if err == nil {
+			return products, nil
+		}
+		log.WithError(err).Warnf("could not read the products in region %s, falling back to the Price List API", regionId)
+	}
+	products := make(map[string]map[string][]aws.JSONValue, len(productinfo.Tenancies))
+	products[productinfo.Shared] = make(map[string][]aws.JSONValue, len(productinfo.OperatingSystems))
+	for _, os := range productinfo.OperatingSystems {
+		osProducts, err := e.getApiProducts(e.newGetProductsInput(regionId, os, productinfo.Shared))
 		if err != nil {
-			log.Warn("could not retrieve vcpu")
+			if os == productinfo.Linux {
+				return nil, err
+			}
+			log.WithError(err).Warnf("could not retrieve the %s %s prices in region %s", productinfo.Shared, os, regionId)
 			continue
 		}
-		memStr, err := pd.GetDataForKey(Memory)
+		products[productinfo.Shared][os] = osProducts
+	}
+	for tenancy, tp := range e.getTenancyProducts(regionId) {
+		products[tenancy] = tp
+	}
+	return products, nil
+}
+
+// getTenancyProducts returns the dedicated instance and host products of the region, they're queried from the Price
+// List API only if the last query is older than tenancyProductsMaxAge, the last products are kept if a query fails
+func (e *Ec2Infoer) getTenancyProducts(regionId string) map[string]map[string][]aws.JSONValue {
+	e.tenancyProductsMu.Lock()
+	cached, ok := e.tenancyProducts[regionId]
+	e.tenancyProductsMu.Unlock()
+	if ok && time.Since(cached.updated) < tenancyProductsMaxAge {
+		return cached.products
+	}
+
+	products := map[string]map[string][]aws.JSONValue{
+		productinfo.Dedicated: make(map[string][]aws.JSONValue, len(productinfo.OperatingSystems)),
+	}
+	var failed bool
+	for _, os := range productinfo.OperatingSystems {
+		osProducts, err := e.getApiProducts(e.newGetProductsInput(regionId, os, productinfo.Dedicated))
 		if err != nil {
-			log.Warn("could not retrieve memory")
+			log.WithError(err).Warnf("could not retrieve the %s %s prices in region %s", productinfo.Dedicated, os, regionId)
+			failed = true
 			continue
 		}
-		gpu, err := pd.GetDataForKey("gpu")
+		products[productinfo.Dedicated][os] = osProducts
+	}
+	hostProducts, err := e.getApiProducts(e.newGetHostProductsInput(regionId))
+	if err != nil {
+		log.WithError(err).Warnf("could not retrieve the dedicated host prices in region %s", regionId)
+		failed = true
+	} else {
+		products[productinfo.Host] = map[string][]aws.JSONValue{"": hostProducts}
+	}
+	if failed {
+		if ok {
+			return cached.products
+		}
+		return products
+	}
+
+	e.tenancyProductsMu.Lock()
+	e.tenancyProducts[regionId] = tenancyProducts{products: products, updated: time.Now()}
+	e.tenancyProductsMu.Unlock()
+	return products
+}
+
+// getApiProducts queries the products from the Price List API following every page of the results
+func (e *Ec2Infoer) getApiProducts(input *pricing.GetProductsInput) ([]aws.JSONValue, error) {
+	var products []aws.JSONValue
+	for {
+		output, err := e.pricingSvc.GetProducts(input)
+		if err != nil {
+			return nil, err
+		}
+		products = append(products, output.PriceList...)
+		if output.NextToken == nil || *output.NextToken == "" {
+			return products, nil
+		}
+		next := *input
+		next.NextToken = output.NextToken
+		input = &next
+	}
+}
+
+// addDedicatedPrices adds the on demand prices of the dedicated instances of every operating system to the tenancy
+// prices of the vms, it returns the products that couldn't be parsed
+func (e *Ec2Infoer) addDedicatedPrices(regionId string, vms []productinfo.VmInfo, products map[string][]aws.JSONValue) []productinfo.QualityIssue {
+	var issues []productinfo.QualityIssue
+	for _, os := range productinfo.OperatingSystems {
+		osPrices, osIssues := e.getOsOnDemandPrices(regionId, os, products[os])
+		issues = append(issues, osIssues...)
+		for i := range vms {
+			odPrice, ok := osPrices[vms[i].Type]
+			if !ok {
+				continue
+			}
+			if vms[i].TenancyPrices == nil {
+				vms[i].TenancyPrices = make(map[string]productinfo.TenancyPrice)
+			}
+			tp := vms[i].TenancyPrices[productinfo.Dedicated]
+			if os == productinfo.Linux {
+				tp.OnDemandPrice = odPrice
+			} else {
+				if tp.OsPrices == nil {
+					tp.OsPrices = make(map[string]float64)
+				}
+				tp.OsPrices[os] = odPrice
+			}
+			vms[i].TenancyPrices[productinfo.Dedicated] = tp
+		}
+	}
+	return issues
+}
+
+// parseHosts extracts the dedicated host families from the host products, the products without the attributes of a
+// host are skipped
+func (e *Ec2Infoer) parseHosts(products []aws.JSONValue) []productinfo.VmInfo {
+	var hosts []productinfo.VmInfo
+	for i, price := range products {
+		host, err := newHostInfo(price, e.currency)
 		if err != nil {
-			log.Debugf("could not retrieve gpu")
+			log.Debugf("could not extract the dedicated host info of the item with index: [ %d ], %s", i, err.Error())
+			continue
 		}
-		odPriceStr, err := pd.GetOnDemandPrice()
+		hosts = append(hosts, *host)
+	}
+	return hosts
+}
+
+func (e *Ec2Infoer) setQualityIssues(regionId string, issues []productinfo.QualityIssue) {
+	e.qualityIssuesMu.Lock()
+	defer e.qualityIssuesMu.Unlock()
+	e.qualityIssues[regionId] = issues
+}
+
+// ProductQualityIssues returns the products of the price list that couldn't be parsed in the last GetProducts call of
+// the region
+func (e *Ec2Infoer) ProductQualityIssues(regionId string) []productinfo.QualityIssue {
+	e.qualityIssuesMu.RLock()
+	defer e.qualityIssuesMu.RUnlock()
+	return e.qualityIssues[regionId]
+}
+
+// unparsableProduct returns the quality issue of a product that couldn't be parsed, the subject is the instance type of
+// the product or its sku if the instance type is missing
+func unparsableProduct(regionId string, price aws.JSONValue, err error) productinfo.QualityIssue {
+	subject := "unknown product"
+	if product, ok := price["product"].(map[string]interface{}); ok {
+		if sku, ok := product["sku"].(string); ok {
+			subject = sku
+		}
+		if attrs, ok := product["attributes"].(map[string]interface{}); ok {
+			if instanceType, ok := attrs["instanceType"].(string); ok {
+				subject = instanceType
+			}
+		}
+	}
+	return productinfo.QualityIssue{Kind: productinfo.UnparsableProduct, Region: regionId, Subject: subject, Detail: err.Error()}
+}
+
+func (e *Ec2Infoer) setHosts(regionId string, hosts []productinfo.VmInfo) {
+	e.hostsMu.Lock()
+	defer e.hostsMu.Unlock()
+	e.hosts[regionId] = hosts
+}
+
+// GetDedicatedHosts returns the dedicated host families of a region with the number of sockets, physical cores and
+// vCPUs per host, the hosts read with the last products of the region are returned if there are any
+func (e *Ec2Infoer) GetDedicatedHosts(regionId string) ([]productinfo.VmInfo, error) {
+	e.hostsMu.RLock()
+	hosts, ok := e.hosts[regionId]
+	e.hostsMu.RUnlock()
+	if ok {
+		return hosts, nil
+	}
+	products, err := e.getProducts(regionId)
+	if err != nil {
+		return nil, err
+	}
+	hosts = e.parseHosts(products[productinfo.Host][""])
+	e.setHosts(regionId, hosts)
+	return hosts, nil
+}
+
+// addSavingsPlanPrices adds the savings plan prices to the commitment prices of the vms
+func (e *Ec2Infoer) addSavingsPlanPrices(regionId string, vms []productinfo.VmInfo) {
+	if e.savingsPlanSource == nil {
+		return
+	}
+	savingsPlans, err := e.savingsPlanSource.GetSavingsPlanPrices(regionId, e.currency)
+	if err != nil {
+		log.WithError(err).Warnf("could not retrieve the savings plan prices in region %s", regionId)
+		return
+	}
+	for i := range vms {
+		if plans, ok := savingsPlans[vms[i].Type]; ok {
+			vms[i].Commitments = append(vms[i].Commitments, plans...)
+			productinfo.SortCommitments(vms[i].Commitments)
+		}
+	}
+}
+
+// getOsOnDemandPrices collects the on demand prices of the instance types from the products of the given operating
+// system, products with the bring your own license model are skipped, the products that couldn't be parsed are returned
+// as quality issues
+func (e *Ec2Infoer) getOsOnDemandPrices(regionId string, os string, products []aws.JSONValue) (map[string]float64, []productinfo.QualityIssue) {
+	prices := make(map[string]float64)
+	var issues []productinfo.QualityIssue
+	for _, price := range products {
+		pd, err := newPriceData(price)
 		if err != nil {
-			log.Warn("could not retrieve on demand price")
+			issues = append(issues, unparsableProduct(regionId, price, fmt.Errorf("%s: %s", os, err)))
 			continue
 		}
-		ntwPerf, err := pd.GetDataForKey("networkPerformance")
+		if licenseModel, err := pd.GetDataForKey("licenseModel"); err == nil && licenseModel == "Bring your own license" {
+			continue
+		}
+		vm, err := newVmInfo(price, e.currency)
 		if err != nil {
-			log.Warn("could not parse network performance")
+			log.Debugf("could not extract %s pricing info: %s", os, err.Error())
+			issues = append(issues, unparsableProduct(regionId, price, fmt.Errorf("%s: %s", os, err)))
 			continue
 		}
+		prices[vm.Type] = vm.OnDemandPrice
+	}
+	return prices, issues
+}
 
-		var currGen bool = true
-		if currentGenStr, err := pd.GetDataForKey("currentGeneration"); err == nil {
-			if strings.ToLower(currentGenStr) == "no" {
-				currGen = false
-			}
+// newVmInfo extracts the instance type attributes and the on demand price in the given currency of a product
+func newVmInfo(price aws.JSONValue, currency string) (*productinfo.VmInfo, error) {
+	pd, err := newPriceData(price)
+	if err != nil {
+		return nil, err
+	}
+	pd.currency = currency
+
+	instanceType, err := pd.GetDataForKey("instanceType")
+	if err != nil {
+		return nil, errors.New("could not retrieve instance type")
+	}
+	cpusStr, err := pd.GetDataForKey(Cpu)
+	if err != nil {
+		return nil, errors.New("could not retrieve vcpu")
+	}
+	memStr, err := pd.GetDataForKey(Memory)
+	if err != nil {
+		return nil, errors.New("could not retrieve memory")
+	}
+	gpu, err := pd.GetDataForKey("gpu")
+	if err != nil {
+		log.Debugf("could not retrieve gpu")
+	}
+	odPriceStr, err := pd.GetOnDemandPrice()
+	if err != nil {
+		return nil, errors.New("could not retrieve on demand price")
+	}
+	ntwPerf, err := pd.GetDataForKey("networkPerformance")
+	if err != nil {
+		return nil, errors.New("could not parse network performance")
+	}
+
+	var currGen bool = true
+	if currentGenStr, err := pd.GetDataForKey("currentGeneration"); err == nil {
+		if strings.ToLower(currentGenStr) == "no" {
+			currGen = false
 		}
+	}
+
+	reserved, err := pd.GetReservedPrices()
+	if err != nil {
+		log.Debugf("could not parse the reserved prices of %s: %s", instanceType, err.Error())
+	}
 
-		onDemandPrice, _ := strconv.ParseFloat(odPriceStr, 64)
-		cpus, _ := strconv.ParseFloat(cpusStr, 64)
-		mem, _ := strconv.ParseFloat(strings.Split(memStr, " ")[0], 64)
-		gpus, _ := strconv.ParseFloat(gpu, 64)
-		vm := productinfo.VmInfo{
-			Type:          instanceType,
-			OnDemandPrice: onDemandPrice,
-			Cpus:          cpus,
-			Mem:           mem,
-			Gpus:          gpus,
-			NtwPerf:       ntwPerf,
-			CurrentGen:    currGen,
+	var onDemandPrice float64
+	if odPriceStr != "" {
+		if onDemandPrice, err = strconv.ParseFloat(odPriceStr, 64); err != nil {
+			return nil, fmt.Errorf("could not parse on demand price: %s", odPriceStr)
 		}
-		vms = append(vms, vm)
 	}
-	if vms == nil {
-		log.Debugf("couldn't find any virtual machines to recommend")
+	cpus, _ := strconv.ParseFloat(cpusStr, 64)
+	mem, _ := strconv.ParseFloat(strings.Split(memStr, " ")[0], 64)
+	gpus, _ := strconv.ParseFloat(gpu, 64)
+	return &productinfo.VmInfo{
+		Type:          instanceType,
+		OnDemandPrice: onDemandPrice,
+		Cpus:          cpus,
+		Mem:           mem,
+		Gpus:          gpus,
+		NtwPerf:       ntwPerf,
+		CurrentGen:    currGen,
+		Commitments:   reserved,
+	}, nil
+}
+
+// newHostInfo extracts the dedicated host family, its sockets, physical cores and vCPUs and the on demand price in the
+// given currency of a host product
+func newHostInfo(price aws.JSONValue, currency string) (*productinfo.VmInfo, error) {
+	pd, err := newPriceData(price)
+	if err != nil {
+		return nil, err
 	}
+	pd.currency = currency
 
-	log.Debugf("found vms: %#v", vms)
-	return vms, nil
+	family, err := pd.GetDataForKey("instanceType")
+	if err != nil {
+		return nil, errors.New("could not retrieve host family")
+	}
+	odPriceStr, err := pd.GetOnDemandPrice()
+	if err != nil {
+		return nil, errors.New("could not retrieve on demand price")
+	}
+	onDemandPrice, err := strconv.ParseFloat(odPriceStr, 64)
+	if err != nil || onDemandPrice <= 0 {
+		return nil, fmt.Errorf("invalid on demand price: %s", odPriceStr)
+	}
+	number := func(attr string) float64 {
+		value, _ := pd.GetDataForKey(attr)
+		n, _ := strconv.ParseFloat(strings.Split(value, " ")[0], 64)
+		return n
+	}
+	var currGen = true
+	if currentGenStr, err := pd.GetDataForKey("currentGeneration"); err == nil && strings.ToLower(currentGenStr) == "no" {
+		currGen = false
+	}
+	return &productinfo.VmInfo{
+		Type:          family,
+		OnDemandPrice: onDemandPrice,
+		Cpus:          number(Cpu),
+		Sockets:       int(number("sockets")),
+		Cores:         int(number("physicalCores")),
+		CurrentGen:    currGen,
+	}, nil
 }
 
 type priceData struct {
 	awsData aws.JSONValue
 	attrMap map[string]interface{}
+	// currency the currency the prices are read in, USD if empty
+	currency string
 }
 
 func newPriceData(prData aws.JSONValue) (*priceData, error) {
@@ -221,6 +616,15 @@ func newPriceData(prData aws.JSONValue) (*priceData, error) {
 
 	return &pd, nil
 }
+
+// priceCurrency returns the currency the prices are read in
+func (pd *priceData) priceCurrency() string {
+	if pd.currency == "" {
+		return "USD"
+	}
+	return pd.currency
+}
+
 func (pd *priceData) GetDataForKey(attr string) (string, error) {
 	if value, ok := pd.attrMap[attr].(string); ok {
 		return value, nil
@@ -248,7 +652,7 @@ func (pd *priceData) GetOnDemandPrice() (string, error) {
 			if err != nil {
 				return "", err
 			}
-			odPrice, ok := pricePerUnitMap["USD"].(string)
+			odPrice, ok := pricePerUnitMap[pd.priceCurrency()].(string)
 			if !ok {
 				return "", errors.New("could not get on demand price or could not cast on demand price to string")
 			}
@@ -258,6 +662,70 @@ func (pd *priceData) GetOnDemandPrice() (string, error) {
 	return "", nil
 }
 
+// GetReservedPrices parses the reserved terms of the product into commitment prices ordered by term, offering class
+// and payment option
+func (pd *priceData) GetReservedPrices() ([]productinfo.CommitmentPrice, error) {
+	termsMap, err := getMapForKey("terms", pd.awsData)
+	if err != nil {
+		return nil, err
+	}
+	reservedMap, err := getMapForKey("Reserved", termsMap)
+	if err != nil {
+		// products without reserved offerings
+		return nil, nil
+	}
+	var prices []productinfo.CommitmentPrice
+	for _, term := range reservedMap {
+		termMap, ok := term.(map[string]interface{})
+		if !ok {
+			return nil, errors.New("the reserved term could not be cast to map[string]interface{}")
+		}
+		attrMap, err := getMapForKey("termAttributes", termMap)
+		if err != nil {
+			return nil, err
+		}
+		years, ok := leaseContractLengths[fmt.Sprint(attrMap["LeaseContractLength"])]
+		if !ok {
+			return nil, fmt.Errorf("unsupported lease contract length: %v", attrMap["LeaseContractLength"])
+		}
+		paymentOption, ok := purchaseOptions[fmt.Sprint(attrMap["PurchaseOption"])]
+		if !ok {
+			return nil, fmt.Errorf("unsupported purchase option: %v", attrMap["PurchaseOption"])
+		}
+		priceDimensionsMap, err := getMapForKey("priceDimensions", termMap)
+		if err != nil {
+			return nil, err
+		}
+		var hourly, upfront float64
+		for _, dimension := range priceDimensionsMap {
+			dimensionMap, ok := dimension.(map[string]interface{})
+			if !ok {
+				return nil, errors.New("the price dimension could not be cast to map[string]interface{}")
+			}
+			pricePerUnitMap, err := getMapForKey("pricePerUnit", dimensionMap)
+			if err != nil {
+				return nil, err
+			}
+			price, err := strconv.ParseFloat(fmt.Sprint(pricePerUnitMap[pd.priceCurrency()]), 64)
+			if err != nil {
+				return nil, fmt.Errorf("could not parse reserved price: %v", pricePerUnitMap[pd.priceCurrency()])
+			}
+			if dimensionMap["unit"] == "Quantity" {
+				upfront += price
+			} else {
+				hourly += price
+			}
+		}
+		cp := productinfo.NewCommitmentPrice(productinfo.Reserved, years, paymentOption, hourly, upfront)
+		if class, ok := attrMap["OfferingClass"].(string); ok {
+			cp.OfferingClass = class
+		}
+		prices = append(prices, cp)
+	}
+	productinfo.SortCommitments(prices)
+	return prices, nil
+}
+
 func getMapForKey(key string, srcMap map[string]interface{}) (map[string]interface{}, error) {
 	rawMap, ok := srcMap[key]
 	if !ok {
@@ -271,10 +739,9 @@ func getMapForKey(key string, srcMap map[string]interface{}) (map[string]interfa
 	return remap, nil
 }
 
-// GetRegion gets the api specific region representation based on the provided id
+// GetRegion gets the api specific region representation of the partition based on the provided id
 func (e *Ec2Infoer) GetRegion(id string) *endpoints.Region {
-	awsp := endpoints.AwsPartition()
-	for _, r := range awsp.Regions() {
+	for _, r := range e.partition.Regions() {
 		if r.ID() == id {
 			return &r
 		}
@@ -290,8 +757,9 @@ func (e *Ec2Infoer) newAttributeValuesInput(attr string) *pricing.GetAttributeVa
 	}
 }
 
-// newAttributeValuesInput assembles a GetAttributeValuesInput instance for querying the provider
-func (e *Ec2Infoer) newGetProductsInput(regionId string) *pricing.GetProductsInput {
+// newGetProductsInput assembles a GetProductsInput instance for querying the products of an operating system and a
+// tenancy
+func (e *Ec2Infoer) newGetProductsInput(regionId string, os string, tenancy string) *pricing.GetProductsInput {
 	return &pricing.GetProductsInput{
 
 		ServiceCode: aws.String("AmazonEC2"),
@@ -299,32 +767,70 @@ func (e *Ec2Infoer) newGetProductsInput(regionId string) *pricing.GetProductsInp
 			{
 				Type:  aws.String("TERM_MATCH"),
 				Field: aws.String("operatingSystem"),
-				Value: aws.String("Linux"),
+				Value: aws.String(operatingSystems[os]),
 			},
 			{
 				Type:  aws.String("TERM_MATCH"),
 				Field: aws.String("location"),
-				Value: aws.String(e.GetRegion(regionId).Description()),
+				Value: aws.String(e.pricingLocation(regionId)),
 			},
 			{
 				Type:  aws.String("TERM_MATCH"),
 				Field: aws.String("tenancy"),
-				Value: aws.String("shared"),
+				Value: aws.String(tenancies[tenancy]),
 			},
 			{
 				Type:  aws.String("TERM_MATCH"),
 				Field: aws.String("preInstalledSw"),
 				Value: aws.String("NA"),
 			},
+			{
+				// the capacity reservation products have the same instance types with different prices
+				Type:  aws.String("TERM_MATCH"),
+				Field: aws.String("capacitystatus"),
+				Value: aws.String("Used"),
+			},
+		},
+	}
+}
+
+// newGetHostProductsInput assembles a GetProductsInput instance for querying the dedicated host products
+func (e *Ec2Infoer) newGetHostProductsInput(regionId string) *pricing.GetProductsInput {
+	return &pricing.GetProductsInput{
+		ServiceCode: aws.String("AmazonEC2"),
+		Filters: []*pricing.Filter{
+			{
+				Type:  aws.String("TERM_MATCH"),
+				Field: aws.String("tenancy"),
+				Value: aws.String(tenancies[productinfo.Host]),
+			},
+			{
+				Type:  aws.String("TERM_MATCH"),
+				Field: aws.String("location"),
+				Value: aws.String(e.pricingLocation(regionId)),
+			},
+			{
+				Type:  aws.String("TERM_MATCH"),
+				Field: aws.String("productFamily"),
+				Value: aws.String(dedicatedHostFamily),
+			},
 		},
 	}
 }
 
-// GetRegions returns a map with available regions
+// pricingLocation returns the location of a region in the Price List API
+func (e *Ec2Infoer) pricingLocation(regionId string) string {
+	if location, ok := pricingLocations[regionId]; ok {
+		return location
+	}
+	return e.GetRegion(regionId).Description()
+}
+
+// GetRegions returns a map with available regions of the partition
 // transforms the api representation into a "plain" map
 func (e *Ec2Infoer) GetRegions() (map[string]string, error) {
 	regionIdMap := make(map[string]string)
-	for key, region := range endpoints.AwsPartition().Regions() {
+	for key, region := range e.partition.Regions() {
 		regionIdMap[key] = region.Description()
 	}
 	return regionIdMap, nil
@@ -351,42 +857,44 @@ func (e *Ec2Infoer) HasShortLivedPriceInfo() bool {
 	return true
 }
 
-func (e *Ec2Infoer) getSpotPricesFromPrometheus(region string) (map[string]productinfo.SpotPriceInfo, error) {
-	log.Debug("getting spot price averages from Prometheus API")
-	priceInfo := make(map[string]productinfo.SpotPriceInfo)
-	query := fmt.Sprintf(e.promQuery, region)
-	log.Debugf("sending prometheus query: %s", query)
-	result, err := e.prometheus.Query(context.Background(), query, time.Now())
+func (e *Ec2Infoer) getCurrentSpotPrices(region string) (map[string]productinfo.SpotPriceInfo, error) {
+	return getCurrentSpotPrices(e.ec2Describer(region))
+}
+
+func getCurrentSpotPrices(describer Ec2Describer) (map[string]productinfo.SpotPriceInfo, error) {
+	osPrices, err := getCurrentOsSpotPrices(describer, productinfo.Linux)
 	if err != nil {
 		return nil, err
 	}
-	if result.String() == "" {
-		log.Warnf("Prometheus metric is empty")
-	} else {
-		r := result.(model.Vector)
-		for _, value := range r {
-			instanceType := string(value.Metric["instance_type"])
-			az := string(value.Metric["availability_zone"])
-			price, err := strconv.ParseFloat(value.Value.String(), 64)
-			if err != nil {
-				return nil, err
-			}
-			if priceInfo[instanceType] == nil {
-				priceInfo[instanceType] = make(productinfo.SpotPriceInfo)
-			}
-			priceInfo[instanceType][az] = price
-		}
-	}
-	return priceInfo, nil
+	return osPrices[productinfo.Linux], nil
 }
 
-func (e *Ec2Infoer) getCurrentSpotPrices(region string) (map[string]productinfo.SpotPriceInfo, error) {
-	priceInfo := make(map[string]productinfo.SpotPriceInfo)
-	err := e.ec2Describer(region).DescribeSpotPriceHistoryPages(&ec2.DescribeSpotPriceHistoryInput{
+// getCurrentOsSpotPrices retrieves the current spot prices of the given operating systems, keyed by operating system
+func getCurrentOsSpotPrices(describer Ec2Describer, oses ...string) (map[string]map[string]productinfo.SpotPriceInfo, error) {
+	osPrices := make(map[string]map[string]productinfo.SpotPriceInfo, len(oses))
+	descriptions := make([]*string, 0, len(oses))
+	descriptionOs := make(map[string]string, len(oses))
+	for _, os := range oses {
+		osPrices[os] = make(map[string]productinfo.SpotPriceInfo)
+		descriptions = append(descriptions, aws.String(spotProductDescriptions[os]))
+		descriptionOs[spotProductDescriptions[os]] = os
+	}
+	err := describer.DescribeSpotPriceHistoryPages(&ec2.DescribeSpotPriceHistoryInput{
 		StartTime:           aws.Time(time.Now()),
-		ProductDescriptions: []*string{aws.String("Linux/UNIX")},
+		ProductDescriptions: descriptions,
 	}, func(history *ec2.DescribeSpotPriceHistoryOutput, lastPage bool) bool {
 		for _, pe := range history.SpotPriceHistory {
+			os := productinfo.Linux
+			if pe.ProductDescription != nil {
+				var ok bool
+				if os, ok = descriptionOs[*pe.ProductDescription]; !ok {
+					continue
+				}
+			}
+			priceInfo := osPrices[os]
+			if priceInfo == nil {
+				continue
+			}
 			price, err := strconv.ParseFloat(*pe.SpotPrice, 64)
 			if err != nil {
 				log.WithError(err).Errorf("couldn't parse spot price from history")
@@ -402,34 +910,27 @@ func (e *Ec2Infoer) getCurrentSpotPrices(region string) (map[string]productinfo.
 	if err != nil {
 		return nil, err
 	}
-	return priceInfo, nil
+	return osPrices, nil
 }
 
 // GetCurrentPrices returns the current spot prices of every instance type in every availability zone in a given region
+// the spot prices of every operating system are queried from the AWS API at once
 func (e *Ec2Infoer) GetCurrentPrices(region string) (map[string]productinfo.Price, error) {
-	var spotPrices map[string]productinfo.SpotPriceInfo
-	var err error
-	if e.prometheus != nil {
-		spotPrices, err = e.getSpotPricesFromPrometheus(region)
-		if err != nil {
-			log.WithError(err).Warn("Couldn't get spot price info from Prometheus API, fallback to direct AWS API access.")
-		}
-	}
-
-	if len(spotPrices) == 0 {
-		log.Debug("getting current spot prices directly from the AWS API")
-		spotPrices, err = e.getCurrentSpotPrices(region)
-		if err != nil {
-			log.Errorf("could notr retrieve current prices. region %s, error: %s", region, err.Error())
-			return nil, err
-		}
+	osSpotPrices, err := getCurrentOsSpotPrices(e.ec2Describer(region), productinfo.Linux, productinfo.Windows, productinfo.Rhel, productinfo.Suse)
+	if err != nil {
+		log.Errorf("could not retrieve current prices. region %s, error: %s", region, err.Error())
+		return nil, err
 	}
 
 	prices := make(map[string]productinfo.Price)
-	for region, sp := range spotPrices {
-		prices[region] = productinfo.Price{
-			SpotPrice:     sp,
-			OnDemandPrice: -1,
+	for os, osPrices := range osSpotPrices {
+		for instType, sp := range osPrices {
+			p, ok := prices[instType]
+			if !ok {
+				p = productinfo.Price{OnDemandPrice: -1}
+			}
+			p.SetOsSpotPrice(os, sp)
+			prices[instType] = p
 		}
 	}
 	return prices, nil
@@ -12,7 +12,7 @@ import (
 	"github.com/stretchr/testify/assert"
 )
 
-//testStruct helps to mock external calls
+// testStruct helps to mock external calls
 type testStruct struct {
 	TcId int
 }
@@ -66,6 +66,32 @@ func (dps *testStruct) GetAttributeValues(input *pricing.GetAttributeValuesInput
 	return nil, nil
 }
 func (dps *testStruct) GetProducts(input *pricing.GetProductsInput) (*pricing.GetProductsOutput, error) {
+	os, tenancy := filterValue(input, "operatingSystem"), filterValue(input, "tenancy")
+	if dps.TcId == 12 && tenancy == "Host" {
+		return &pricing.GetProductsOutput{
+			PriceList: []aws.JSONValue{
+				{
+					"product": map[string]interface{}{
+						"attributes": map[string]interface{}{
+							"instanceType":  "m5",
+							Cpu:             "96",
+							"physicalCores": "48",
+							"sockets":       "2",
+						}},
+					"terms": map[string]interface{}{
+						"OnDemand": map[string]interface{}{
+							"randomNumber": map[string]interface{}{
+								"priceDimensions": map[string]interface{}{
+									"randomNumber": map[string]interface{}{
+										"pricePerUnit": map[string]interface{}{
+											"USD": "5.069",
+										}}}}}}},
+			},
+		}, nil
+	}
+	if (os != "Linux" || tenancy != "Shared") && dps.TcId != 12 {
+		return &pricing.GetProductsOutput{}, nil
+	}
 	switch dps.TcId {
 	case 4:
 		return &pricing.GetProductsOutput{
@@ -130,6 +156,36 @@ func (dps *testStruct) GetProducts(input *pricing.GetProductsInput) (*pricing.Ge
 						}}},
 			},
 		}, nil
+	case 12:
+		prices := map[string]string{"Linux": "0.1", "Windows": "0.2", "RHEL": "0.16", "SUSE": "0.2"}
+		if tenancy == "Dedicated" {
+			prices = map[string]string{"Linux": "0.11", "Windows": "0.22", "RHEL": "0.17", "SUSE": "0.21"}
+		}
+		licenseModel := "License Included"
+		if os == "SUSE" {
+			licenseModel = "Bring your own license"
+		}
+		return &pricing.GetProductsOutput{
+			PriceList: []aws.JSONValue{
+				{
+					"product": map[string]interface{}{
+						"attributes": map[string]interface{}{
+							"instanceType":       ec2.InstanceTypeM5Large,
+							Cpu:                  "2",
+							Memory:               "8 GiB",
+							"networkPerformance": "Up to 10 Gigabit",
+							"licenseModel":       licenseModel,
+						}},
+					"terms": map[string]interface{}{
+						"OnDemand": map[string]interface{}{
+							"randomNumber": map[string]interface{}{
+								"priceDimensions": map[string]interface{}{
+									"randomNumber": map[string]interface{}{
+										"pricePerUnit": map[string]interface{}{
+											"USD": prices[os],
+										}}}}}}},
+			},
+		}, nil
 	case 9:
 		return &pricing.GetProductsOutput{
 			PriceList: []aws.JSONValue{
@@ -144,6 +200,16 @@ func (dps *testStruct) GetProducts(input *pricing.GetProductsInput) (*pricing.Ge
 	return nil, nil
 }
 
+// filterValue returns the value of the filter of the field, or an empty string if the input has no such filter
+func filterValue(input *pricing.GetProductsInput, field string) string {
+	for _, f := range input.Filters {
+		if *f.Field == field {
+			return *f.Value
+		}
+	}
+	return ""
+}
+
 // strPointer gets the pointer to the passed string
 func (dps *testStruct) strPointer(str string) *string {
 	return &str
@@ -173,37 +239,69 @@ func (dps *testStruct) DescribeSpotPriceHistoryPages(input *ec2.DescribeSpotPric
 	if dps.TcId == 11 {
 		return errors.New("invalid")
 	}
+	if dps.TcId == 13 {
+		history := &ec2.DescribeSpotPriceHistoryOutput{}
+		for _, desc := range input.ProductDescriptions {
+			history.SpotPriceHistory = append(history.SpotPriceHistory, &ec2.SpotPrice{
+				AvailabilityZone:   aws.String("eu-central-1a"),
+				InstanceType:       aws.String(ec2.InstanceTypeM5Large),
+				ProductDescription: desc,
+				SpotPrice:          aws.String(map[string]string{"Linux/UNIX": "0.04", "Windows": "0.12"}[*desc]),
+			})
+		}
+		fn(history, true)
+	}
 	return nil
 }
 
-func TestNewEc2Infoer(t *testing.T) {
-	tests := []struct {
-		name  string
-		prom  string
-		check func(info *Ec2Infoer, err error)
-	}{
-		{
-			name: "create Ec2Infoer - Prometheus API address is not set",
-			prom: "",
-			check: func(info *Ec2Infoer, err error) {
-				assert.Nil(t, err, "the error should be nil")
-				assert.NotNil(t, info, "the Ec2Infoer should not be nil")
-			},
-		},
-		{
-			name: "create Ec2Infoer - Prometheus API address is set",
-			prom: "PromAPIAddress",
-			check: func(info *Ec2Infoer, err error) {
-				assert.Nil(t, err, "the error should be nil")
-				assert.NotNil(t, info, "the Ec2Infoer should not be nil")
+func (dps *testStruct) DescribeInstanceTypesPages(input *DescribeInstanceTypesInput, fn func(*DescribeInstanceTypesOutput, bool) bool) error {
+	if dps.TcId == 11 {
+		return errors.New("invalid")
+	}
+	fn(&DescribeInstanceTypesOutput{
+		InstanceTypes: []*InstanceTypeInfo{
+			{
+				InstanceType: aws.String(ec2.InstanceTypeM5Large),
+				Hypervisor:   aws.String("nitro"),
+				ProcessorInfo: &ProcessorInfo{
+					SupportedArchitectures:   aws.StringSlice([]string{"x86_64"}),
+					SustainedClockSpeedInGhz: aws.Float64(3.1),
+				},
+				NetworkInfo: &NetworkInfo{
+					EnaSupport:                aws.String("required"),
+					MaximumNetworkInterfaces:  aws.Int64(3),
+					Ipv4AddressesPerInterface: aws.Int64(10),
+					NetworkPerformance:        aws.String("Up to 10 Gigabit"),
+				},
+				EbsInfo: &EbsInfo{EbsOptimizedInfo: &EbsOptimizedInfo{BaselineBandwidthInMbps: aws.Int64(650)}},
 			},
 		},
+	}, true)
+	return nil
+}
+
+func (dps *testStruct) DescribeInstanceTypeOfferingsPages(input *DescribeInstanceTypeOfferingsInput, fn func(*DescribeInstanceTypeOfferingsOutput, bool) bool) error {
+	if dps.TcId == 11 {
+		return errors.New("invalid")
 	}
-	for _, test := range tests {
-		t.Run(test.name, func(t *testing.T) {
-			test.check(NewEc2Infoer(test.prom, ""))
-		})
+	offering := func(zone string) *InstanceTypeOffering {
+		return &InstanceTypeOffering{
+			InstanceType: aws.String(ec2.InstanceTypeM5Large),
+			Location:     aws.String(zone),
+			LocationType: input.LocationType,
+		}
+	}
+	if !fn(&DescribeInstanceTypeOfferingsOutput{InstanceTypeOfferings: []*InstanceTypeOffering{offering("eu-central-1b")}}, false) {
+		return nil
 	}
+	fn(&DescribeInstanceTypeOfferingsOutput{InstanceTypeOfferings: []*InstanceTypeOffering{offering("eu-central-1a")}}, true)
+	return nil
+}
+
+func TestNewEc2Infoer(t *testing.T) {
+	info, err := NewEc2Infoer(Config{})
+	assert.Nil(t, err, "the error should be nil")
+	assert.NotNil(t, info, "the Ec2Infoer should not be nil")
 }
 
 func TestEc2Infoer_GetAttributeValues(t *testing.T) {
@@ -240,7 +338,7 @@ func TestEc2Infoer_GetAttributeValues(t *testing.T) {
 	}
 	for _, test := range tests {
 		t.Run(test.name, func(t *testing.T) {
-			productInfoer, err := NewEc2Infoer("", "")
+			productInfoer, err := NewEc2Infoer(Config{})
 			// override pricingSvc
 			productInfoer.pricingSvc = test.pricingService
 			if err != nil {
@@ -268,7 +366,7 @@ func TestEc2Infoer_GetRegions(t *testing.T) {
 	}
 	for _, test := range tests {
 		t.Run(test.name, func(t *testing.T) {
-			productInfoer, err := NewEc2Infoer("", "")
+			productInfoer, err := NewEc2Infoer(Config{})
 			if err != nil {
 				t.Fatalf("failed to create productinfoer; [%s]", err.Error())
 			}
@@ -283,7 +381,9 @@ func TestEc2Infoer_GetProducts(t *testing.T) {
 		name           string
 		regionId       string
 		pricingService PricingSource
-		check          func(vm []productinfo.VmInfo, err error)
+		// issues the subjects and details of the unparsable products
+		issues []string
+		check  func(vm []productinfo.VmInfo, err error)
 	}{
 		{
 			name:           "successful - retrieves the available virtual machines",
@@ -294,6 +394,28 @@ func TestEc2Infoer_GetProducts(t *testing.T) {
 				assert.Equal(t, []productinfo.VmInfo{{Type: "t2.small", OnDemandPrice: 5, SpotPrice: productinfo.SpotPriceInfo(nil), Cpus: 1, Mem: 2, Gpus: 0, NtwPerf: "Low to Moderate", NtwPerfCat: "", CurrentGen: true}}, vm)
 			},
 		},
+		{
+			name:           "successful - prices of other operating systems",
+			regionId:       "eu-central-1",
+			pricingService: &testStruct{TcId: 12},
+			check: func(vm []productinfo.VmInfo, err error) {
+				assert.Nil(t, err, "the error should be nil")
+				assert.Equal(t, 1, len(vm))
+				assert.Equal(t, 0.1, vm[0].OnDemandPrice)
+				assert.Equal(t, map[string]productinfo.OsPrice{
+					productinfo.Windows: {OnDemandPrice: 0.2},
+					productinfo.Rhel:    {OnDemandPrice: 0.16},
+				}, vm[0].OsPrices, "bring your own license products should be skipped")
+				assert.Equal(t, []string{"x86_64"}, vm[0].CpuArchitectures)
+				assert.Equal(t, "nitro", vm[0].Hypervisor)
+				assert.Equal(t, 650.0, vm[0].EbsBandwidth)
+				assert.Equal(t, 3, vm[0].MaxNetworkInterfaces)
+				assert.Equal(t, []string{"eu-central-1a", "eu-central-1b"}, vm[0].Zones)
+				assert.Equal(t, map[string]productinfo.TenancyPrice{
+					productinfo.Dedicated: {OnDemandPrice: 0.11, OsPrices: map[string]float64{productinfo.Windows: 0.22, productinfo.Rhel: 0.17}},
+				}, vm[0].TenancyPrices)
+			},
+		},
 		{
 			name:           "error - GetProducts",
 			regionId:       "eu-central-1",
@@ -307,6 +429,7 @@ func TestEc2Infoer_GetProducts(t *testing.T) {
 			name:           "error - on demand price",
 			regionId:       "eu-central-1",
 			pricingService: &testStruct{TcId: 6},
+			issues:         []string{"t2.small: could not retrieve on demand price"},
 			check: func(vm []productinfo.VmInfo, err error) {
 				assert.Nil(t, err, "the error should be nil")
 				assert.Nil(t, vm, "the vm should be nil")
@@ -316,6 +439,7 @@ func TestEc2Infoer_GetProducts(t *testing.T) {
 			name:           "error - memory",
 			regionId:       "eu-central-1",
 			pricingService: &testStruct{TcId: 7},
+			issues:         []string{"t2.small: could not retrieve memory"},
 			check: func(vm []productinfo.VmInfo, err error) {
 				assert.Nil(t, err, "the error should be nil")
 				assert.Nil(t, vm, "the vm should be nil")
@@ -325,6 +449,7 @@ func TestEc2Infoer_GetProducts(t *testing.T) {
 			name:           "error - cpu",
 			regionId:       "eu-central-1",
 			pricingService: &testStruct{TcId: 8},
+			issues:         []string{"t2.small: could not retrieve vcpu"},
 			check: func(vm []productinfo.VmInfo, err error) {
 				assert.Nil(t, err, "the error should be nil")
 				assert.Nil(t, vm, "the vm should be nil")
@@ -334,6 +459,7 @@ func TestEc2Infoer_GetProducts(t *testing.T) {
 			name:           "error - instance type",
 			regionId:       "eu-central-1",
 			pricingService: &testStruct{TcId: 9},
+			issues:         []string{"unknown product: could not retrieve instance type"},
 			check: func(vm []productinfo.VmInfo, err error) {
 				assert.Nil(t, err, "the error should be nil")
 				assert.Nil(t, vm, "the vm should be nil")
@@ -342,18 +468,50 @@ func TestEc2Infoer_GetProducts(t *testing.T) {
 	}
 	for _, test := range tests {
 		t.Run(test.name, func(t *testing.T) {
-			productInfoer, err := NewEc2Infoer("", "")
+			productInfoer, err := NewEc2Infoer(Config{})
 			// override pricingSvc
 			productInfoer.pricingSvc = test.pricingService
 			if err != nil {
 				t.Fatalf("failed to create productinfoer; [%s]", err.Error())
 			}
+			productInfoer.ec2Describer = func(region string) Ec2Describer {
+				return test.pricingService.(Ec2Describer)
+			}
 
 			test.check(productInfoer.GetProducts(test.regionId))
+			var issues []string
+			for _, issue := range productInfoer.ProductQualityIssues(test.regionId) {
+				assert.Equal(t, productinfo.UnparsableProduct, issue.Kind)
+				issues = append(issues, issue.Subject+": "+issue.Detail)
+			}
+			assert.Equal(t, test.issues, issues)
 		})
 	}
 }
 
+func TestEc2Infoer_GetDedicatedHosts(t *testing.T) {
+	productInfoer, err := NewEc2Infoer(Config{})
+	assert.Nil(t, err, "the error should be nil")
+	ts := &testStruct{TcId: 12}
+	productInfoer.pricingSvc = ts
+	productInfoer.ec2Describer = func(region string) Ec2Describer {
+		return ts
+	}
+
+	hosts, err := productInfoer.GetDedicatedHosts("eu-central-1")
+	assert.Nil(t, err, "the error should be nil")
+	assert.Equal(t, []productinfo.VmInfo{{Type: "m5", OnDemandPrice: 5.069, Cpus: 96, Sockets: 2, Cores: 48, CurrentGen: true}}, hosts)
+
+	// the hosts read with the products are returned without querying the products again
+	productInfoer.setHosts("eu-central-1", nil)
+	_, err = productInfoer.GetProducts("eu-central-1")
+	assert.Nil(t, err, "the error should be nil")
+	productInfoer.pricingSvc = &testStruct{TcId: 5}
+	hosts, err = productInfoer.GetDedicatedHosts("eu-central-1")
+	assert.Nil(t, err, "the error should be nil")
+	assert.Equal(t, 1, len(hosts))
+}
+
 func TestEc2Infoer_GetRegion(t *testing.T) {
 	tests := []struct {
 		name           string
@@ -381,7 +539,7 @@ func TestEc2Infoer_GetRegion(t *testing.T) {
 	}
 	for _, test := range tests {
 		t.Run(test.name, func(t *testing.T) {
-			productInfoer, err := NewEc2Infoer("", "")
+			productInfoer, err := NewEc2Infoer(Config{})
 			if err != nil {
 				t.Fatalf("failed to create productinfoer; [%s]", err.Error())
 			}
@@ -391,6 +549,50 @@ func TestEc2Infoer_GetRegion(t *testing.T) {
 	}
 }
 
+func TestNewEc2Infoer_partitions(t *testing.T) {
+	cn, err := NewEc2Infoer(Config{Partition: endpoints.AwsCnPartitionID})
+	assert.Nil(t, err)
+	assert.Equal(t, "CNY", cn.currency)
+	regions, _ := cn.GetRegions()
+	assert.Equal(t, "China (Beijing)", regions["cn-north-1"])
+	assert.NotContains(t, regions, "eu-west-1")
+	assert.Nil(t, cn.GetRegion("eu-west-1"))
+
+	gov, err := NewEc2Infoer(Config{Partition: endpoints.AwsUsGovPartitionID})
+	assert.Nil(t, err)
+	assert.Equal(t, "USD", gov.currency)
+	assert.Equal(t, "AWS GovCloud (US-West)", gov.pricingLocation("us-gov-west-1"))
+
+	_, err = NewEc2Infoer(Config{Partition: "aws-moon"})
+	assert.EqualError(t, err, "unsupported partition: aws-moon")
+}
+
+func TestNewVmInfo_currency(t *testing.T) {
+	price := aws.JSONValue{
+		"product": map[string]interface{}{
+			"attributes": map[string]interface{}{
+				"instanceType":       "m5.large",
+				"vcpu":               "2",
+				"memory":             "8 GiB",
+				"networkPerformance": "Up to 10 Gigabit",
+			}},
+		"terms": map[string]interface{}{
+			"OnDemand": map[string]interface{}{
+				"term": map[string]interface{}{
+					"priceDimensions": map[string]interface{}{
+						"dimension": map[string]interface{}{
+							"pricePerUnit": map[string]interface{}{"CNY": "0.65", "EUR": "n/a"},
+						}}}}},
+	}
+	vm, err := newVmInfo(price, "CNY")
+	assert.Nil(t, err)
+	assert.Equal(t, 0.65, vm.OnDemandPrice)
+	_, err = newVmInfo(price, "USD")
+	assert.NotNil(t, err)
+	_, err = newVmInfo(price, "EUR")
+	assert.EqualError(t, err, "could not parse on demand price: n/a")
+}
+
 func TestEc2Infoer_getCurrentSpotPrices(t *testing.T) {
 	tests := []struct {
 		name       string
@@ -423,7 +625,7 @@ func TestEc2Infoer_getCurrentSpotPrices(t *testing.T) {
 	}
 	for _, test := range tests {
 		t.Run(test.name, func(t *testing.T) {
-			productInfoer, err := NewEc2Infoer("", "")
+			productInfoer, err := NewEc2Infoer(Config{})
 			// override ec2cli
 			productInfoer.ec2Describer = test.ec2CliMock
 			if err != nil {
@@ -453,6 +655,19 @@ func TestEc2Infoer_GetCurrentPrices(t *testing.T) {
 				assert.Equal(t, 0, len(price))
 			},
 		},
+		{
+			name:   "success - spot prices of other operating systems",
+			region: "eu-central-1",
+			ec2CliMock: func(region string) Ec2Describer {
+				return &testStruct{13}
+			},
+			check: func(price map[string]productinfo.Price, err error) {
+				assert.Nil(t, err, "the error should be nil")
+				assert.Equal(t, productinfo.SpotPriceInfo{"eu-central-1a": 0.04}, price[ec2.InstanceTypeM5Large].SpotPrice)
+				assert.Equal(t, productinfo.SpotPriceInfo{"eu-central-1a": 0.12},
+					price[ec2.InstanceTypeM5Large].ForOs(productinfo.Windows).SpotPrice)
+			},
+		},
 		{
 			name:   "error - unknown region",
 			region: "dummyRegion",
@@ -467,7 +682,7 @@ func TestEc2Infoer_GetCurrentPrices(t *testing.T) {
 	}
 	for _, test := range tests {
 		t.Run(test.name, func(t *testing.T) {
-			productInfoer, err := NewEc2Infoer("PromAPIAddress", "")
+			productInfoer, err := NewEc2Infoer(Config{})
 			// override ec2cli
 			productInfoer.ec2Describer = test.ec2CliMock
 			if err != nil {
@@ -512,7 +727,7 @@ func TestEc2Infoer_GetZones(t *testing.T) {
 	}
 	for _, test := range tests {
 		t.Run(test.name, func(t *testing.T) {
-			productInfoer, err := NewEc2Infoer("PromAPIAddress", "")
+			productInfoer, err := NewEc2Infoer(Config{})
 			// override ec2cli
 			productInfoer.ec2Describer = test.ec2CliMock
 			if err != nil {
@@ -836,3 +1051,73 @@ func TestPriceData_newPriceData(t *testing.T) {
 		})
 	}
 }
+
+func TestPriceData_GetReservedPrices(t *testing.T) {
+	reservedTerm := func(length string, option string, hourly string, upfront string) map[string]interface{} {
+		return map[string]interface{}{
+			"termAttributes": map[string]interface{}{
+				"LeaseContractLength": length,
+				"OfferingClass":       "standard",
+				"PurchaseOption":      option,
+			},
+			"priceDimensions": map[string]interface{}{
+				"hrs": map[string]interface{}{
+					"unit":         "Hrs",
+					"pricePerUnit": map[string]interface{}{"USD": hourly},
+				},
+				"upfront": map[string]interface{}{
+					"unit":         "Quantity",
+					"pricePerUnit": map[string]interface{}{"USD": upfront},
+				},
+			},
+		}
+	}
+	tests := []struct {
+		name  string
+		terms map[string]interface{}
+		check func(prices []productinfo.CommitmentPrice, err error)
+	}{
+		{
+			name: "successful - effective hourly prices",
+			terms: map[string]interface{}{
+				"Reserved": map[string]interface{}{
+					"a": reservedTerm("3yr", "All Upfront", "0", "2628"),
+					"b": reservedTerm("1yr", "Partial Upfront", "0.05", "438"),
+				},
+			},
+			check: func(prices []productinfo.CommitmentPrice, err error) {
+				assert.Nil(t, err, "the error should be nil")
+				assert.Equal(t, 2, len(prices))
+				assert.Equal(t, productinfo.CommitmentPrice{Type: productinfo.Reserved, Term: 1, PaymentOption: productinfo.PartialUpfront,
+					OfferingClass: "standard", HourlyPrice: 0.1, Upfront: 438}, prices[0])
+				assert.Equal(t, 3, prices[1].Term)
+				assert.Equal(t, productinfo.AllUpfront, prices[1].PaymentOption)
+				assert.InDelta(t, 0.1, prices[1].HourlyPrice, 1e-9)
+			},
+		},
+		{
+			name:  "no reserved terms",
+			terms: map[string]interface{}{"OnDemand": map[string]interface{}{}},
+			check: func(prices []productinfo.CommitmentPrice, err error) {
+				assert.Nil(t, err, "the error should be nil")
+				assert.Nil(t, prices)
+			},
+		},
+		{
+			name: "error - unsupported purchase option",
+			terms: map[string]interface{}{
+				"Reserved": map[string]interface{}{"a": reservedTerm("1yr", "Heavy Utilization", "0.05", "0")},
+			},
+			check: func(prices []productinfo.CommitmentPrice, err error) {
+				assert.Nil(t, prices)
+				assert.EqualError(t, err, "unsupported purchase option: Heavy Utilization")
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			pd := priceData{awsData: aws.JSONValue{"terms": test.terms}}
+			test.check(pd.GetReservedPrices())
+		})
+	}
+}
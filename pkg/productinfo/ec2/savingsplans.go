@@ -0,0 +1,178 @@
+package ec2
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/banzaicloud/productinfo/pkg/productinfo"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// savingsPlanRegionIndexPath the path of the region index of the savings plan offer files
+	savingsPlanRegionIndexPath = "/savingsPlan/v1.0/aws/AWSComputeSavingsPlan/current/region_index.json"
+
+	computeSavingsPlans  = "ComputeSavingsPlans"
+	instanceSavingsPlans = "EC2InstanceSavingsPlans"
+)
+
+// SavingsPlanSource retrieves the savings plan prices of the instance types in a region
+type SavingsPlanSource interface {
+	// GetSavingsPlanPrices returns the savings plan prices in the given currency of the Linux instances with shared
+	// tenancy per instance type
+	GetSavingsPlanPrices(region string, currency string) (map[string][]productinfo.CommitmentPrice, error)
+}
+
+// OfferFileSavingsPlanSource retrieves the savings plan rates from the public offer files of the AWS Price List
+type OfferFileSavingsPlanSource struct {
+	baseURL string
+	client  *http.Client
+}
+
+// NewOfferFileSavingsPlanSource creates a new savings plan source reading the offer files from the given base url,
+// e.g. https://pricing.us-east-1.amazonaws.com
+func NewOfferFileSavingsPlanSource(baseURL string) *OfferFileSavingsPlanSource {
+	return &OfferFileSavingsPlanSource{
+		baseURL: strings.TrimSuffix(baseURL, "/"),
+		client:  &http.Client{Timeout: 5 * time.Minute},
+	}
+}
+
+type savingsPlanRegionIndex struct {
+	Regions []struct {
+		RegionCode string `json:"regionCode"`
+		VersionURL string `json:"versionUrl"`
+	} `json:"regions"`
+}
+
+type savingsPlanOffer struct {
+	Products []struct {
+		Sku           string `json:"sku"`
+		ProductFamily string `json:"productFamily"`
+		Attributes    struct {
+			PurchaseOption string `json:"purchaseOption"`
+			PurchaseTerm   string `json:"purchaseTerm"`
+		} `json:"attributes"`
+	} `json:"products"`
+	Terms struct {
+		SavingsPlan []struct {
+			Sku   string `json:"sku"`
+			Rates []struct {
+				DiscountedUsageType   string `json:"discountedUsageType"`
+				DiscountedOperation   string `json:"discountedOperation"`
+				DiscountedServiceCode string `json:"discountedServiceCode"`
+				DiscountedRate        struct {
+					Price    string `json:"price"`
+					Currency string `json:"currency"`
+				} `json:"discountedRate"`
+			} `json:"rates"`
+		} `json:"savingsPlan"`
+	} `json:"terms"`
+}
+
+// GetSavingsPlanPrices retrieves the offer file of the region and returns the savings plan rates of the Linux instances
+func (s *OfferFileSavingsPlanSource) GetSavingsPlanPrices(region string, currency string) (map[string][]productinfo.CommitmentPrice, error) {
+	var index savingsPlanRegionIndex
+	if err := s.get(savingsPlanRegionIndexPath, &index); err != nil {
+		return nil, err
+	}
+	var versionURL string
+	for _, r := range index.Regions {
+		if r.RegionCode == region {
+			versionURL = r.VersionURL
+		}
+	}
+	if versionURL == "" {
+		return nil, fmt.Errorf("there's no savings plan offer file for region %s", region)
+	}
+	var offer savingsPlanOffer
+	if err := s.get(versionURL, &offer); err != nil {
+		return nil, err
+	}
+	return parseSavingsPlanOffer(offer, currency), nil
+}
+
+func (s *OfferFileSavingsPlanSource) get(path string, v interface{}) error {
+	log.Debugf("getting savings plan offer file %s", path)
+	resp, err := s.client.Get(s.baseURL + path)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("could not get savings plan offer file %s, status code: %d", path, resp.StatusCode)
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+// parseSavingsPlanOffer collects the rates in the given currency of the Linux instances with shared tenancy per
+// instance type
+func parseSavingsPlanOffer(offer savingsPlanOffer, currency string) map[string][]productinfo.CommitmentPrice {
+	plans := make(map[string]productinfo.CommitmentPrice)
+	for _, p := range offer.Products {
+		var planType string
+		switch p.ProductFamily {
+		case computeSavingsPlans:
+			planType = productinfo.ComputeSavingsPlan
+		case instanceSavingsPlans:
+			planType = productinfo.InstanceSavingsPlan
+		default:
+			continue
+		}
+		years, ok := leaseContractLengths[p.Attributes.PurchaseTerm]
+		if !ok {
+			log.Debugf("unsupported savings plan term: %s", p.Attributes.PurchaseTerm)
+			continue
+		}
+		paymentOption, ok := purchaseOptions[p.Attributes.PurchaseOption]
+		if !ok {
+			log.Debugf("unsupported savings plan purchase option: %s", p.Attributes.PurchaseOption)
+			continue
+		}
+		plans[p.Sku] = productinfo.CommitmentPrice{Type: planType, Term: years, PaymentOption: paymentOption}
+	}
+
+	prices := make(map[string][]productinfo.CommitmentPrice)
+	for _, term := range offer.Terms.SavingsPlan {
+		plan, ok := plans[term.Sku]
+		if !ok {
+			continue
+		}
+		for _, rate := range term.Rates {
+			// RunInstances without an operation code is Linux
+			if rate.DiscountedServiceCode != "AmazonEC2" || rate.DiscountedOperation != "RunInstances" ||
+				rate.DiscountedRate.Currency != currency {
+				continue
+			}
+			instanceType := boxUsageType(rate.DiscountedUsageType)
+			if instanceType == "" {
+				continue
+			}
+			price, err := strconv.ParseFloat(rate.DiscountedRate.Price, 64)
+			if err != nil {
+				log.Debugf("could not parse savings plan rate: %s", rate.DiscountedRate.Price)
+				continue
+			}
+			cp := plan
+			cp.HourlyPrice = price
+			prices[instanceType] = append(prices[instanceType], cp)
+		}
+	}
+	for _, cps := range prices {
+		productinfo.SortCommitments(cps)
+	}
+	return prices
+}
+
+// boxUsageType returns the instance type of a shared tenancy usage type, e.g. EUW1-BoxUsage:m5.large
+func boxUsageType(usageType string) string {
+	i := strings.Index(usageType, "BoxUsage:")
+	if i < 0 || (i > 0 && usageType[i-1] != '-') {
+		return ""
+	}
+	return usageType[i+len("BoxUsage:"):]
+}
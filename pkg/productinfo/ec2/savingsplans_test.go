@@ -0,0 +1,64 @@
+package ec2
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/banzaicloud/productinfo/pkg/productinfo"
+	"github.com/stretchr/testify/assert"
+)
+
+const savingsPlanOfferFile = `{
+  "products": [
+    {"sku": "C1", "productFamily": "ComputeSavingsPlans", "attributes": {"purchaseOption": "No Upfront", "purchaseTerm": "1yr"}},
+    {"sku": "I3", "productFamily": "EC2InstanceSavingsPlans", "attributes": {"purchaseOption": "All Upfront", "purchaseTerm": "3yr"}}
+  ],
+  "terms": {
+    "savingsPlan": [
+      {"sku": "C1", "rates": [
+        {"discountedUsageType": "EUW1-BoxUsage:m5.large", "discountedOperation": "RunInstances", "discountedServiceCode": "AmazonEC2", "discountedRate": {"price": "0.081", "currency": "USD"}},
+        {"discountedUsageType": "EUW1-BoxUsage:m5.large", "discountedOperation": "RunInstances:0002", "discountedServiceCode": "AmazonEC2", "discountedRate": {"price": "0.163", "currency": "USD"}},
+        {"discountedUsageType": "EUW1-DedicatedUsage:m5.large", "discountedOperation": "RunInstances", "discountedServiceCode": "AmazonEC2", "discountedRate": {"price": "0.089", "currency": "USD"}},
+        {"discountedUsageType": "EUW1-Fargate-vCPU-Hours:perCPU", "discountedOperation": "", "discountedServiceCode": "AmazonECS", "discountedRate": {"price": "0.03", "currency": "USD"}}
+      ]},
+      {"sku": "I3", "rates": [
+        {"discountedUsageType": "EUW1-BoxUsage:m5.large", "discountedOperation": "RunInstances", "discountedServiceCode": "AmazonEC2", "discountedRate": {"price": "0.044", "currency": "USD"}}
+      ]}
+    ]
+  }
+}`
+
+func TestOfferFileSavingsPlanSource_GetSavingsPlanPrices(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case savingsPlanRegionIndexPath:
+			w.Write([]byte(`{"regions": [{"regionCode": "eu-west-1", "versionUrl": "/savingsPlan/v1.0/aws/AWSComputeSavingsPlan/20200101/eu-west-1/index.json"}]}`))
+		case "/savingsPlan/v1.0/aws/AWSComputeSavingsPlan/20200101/eu-west-1/index.json":
+			w.Write([]byte(savingsPlanOfferFile))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+	source := NewOfferFileSavingsPlanSource(server.URL + "/")
+
+	prices, err := source.GetSavingsPlanPrices("eu-west-1", "USD")
+	assert.Nil(t, err, "the error should be nil")
+	assert.Equal(t, map[string][]productinfo.CommitmentPrice{
+		"m5.large": {
+			{Type: productinfo.ComputeSavingsPlan, Term: 1, PaymentOption: productinfo.NoUpfront, HourlyPrice: 0.081},
+			{Type: productinfo.InstanceSavingsPlan, Term: 3, PaymentOption: productinfo.AllUpfront, HourlyPrice: 0.044},
+		},
+	}, prices)
+
+	_, err = source.GetSavingsPlanPrices("us-east-1", "USD")
+	assert.EqualError(t, err, "there's no savings plan offer file for region us-east-1")
+}
+
+func TestBoxUsageType(t *testing.T) {
+	assert.Equal(t, "m5.large", boxUsageType("BoxUsage:m5.large"))
+	assert.Equal(t, "m5.large", boxUsageType("EUW1-BoxUsage:m5.large"))
+	assert.Equal(t, "", boxUsageType("EUW1-DedicatedUsage:m5.large"))
+	assert.Equal(t, "", boxUsageType("EUW1-UnusedBox:m5.large"))
+}
@@ -0,0 +1,63 @@
+package forecast
+
+import (
+	"errors"
+	"math"
+)
+
+// BacktestResult error statistics of a model's forecasts compared to the actual values
+type BacktestResult struct {
+	// Forecasts the number of forecasts evaluated
+	Forecasts int `json:"forecasts"`
+	// MAE mean absolute error
+	MAE float64 `json:"mae"`
+	// RMSE root mean squared error
+	RMSE float64 `json:"rmse"`
+	// MAPE mean absolute percentage error, actual values of 0 are skipped
+	MAPE float64 `json:"mape"`
+	// Coverage the ratio of actual values falling into the confidence interval
+	Coverage float64 `json:"coverage"`
+}
+
+// Backtest evaluates the model selected by Fit with a rolling origin: the model is fitted on the values before the origin
+// and its forecast for the next horizon values is compared to the actual ones, then the origin is moved by horizon steps
+func Backtest(series []float64, seasonLength int, minTrain int, horizon int, confidence float64) (BacktestResult, error) {
+	var res BacktestResult
+	if minTrain < 1 || horizon < 1 {
+		return res, errors.New("the training size and the horizon must be positive")
+	}
+	if len(series) < minTrain+horizon {
+		return res, errors.New("the series is too short for the given training size and horizon")
+	}
+
+	var absSum, sqSum, pctSum float64
+	var pctCount, covered int
+	for origin := minTrain; origin+horizon <= len(series); origin += horizon {
+		train := series[:origin]
+		points, err := Fit(train, seasonLength).Forecast(train, horizon, confidence)
+		if err != nil {
+			return res, err
+		}
+		for h, p := range points {
+			actual := series[origin+h]
+			diff := p.Value - actual
+			absSum += math.Abs(diff)
+			sqSum += diff * diff
+			if actual != 0 {
+				pctSum += math.Abs(diff / actual)
+				pctCount++
+			}
+			if actual >= p.Lower && actual <= p.Upper {
+				covered++
+			}
+			res.Forecasts++
+		}
+	}
+	res.MAE = absSum / float64(res.Forecasts)
+	res.RMSE = math.Sqrt(sqSum / float64(res.Forecasts))
+	if pctCount > 0 {
+		res.MAPE = pctSum / float64(pctCount)
+	}
+	res.Coverage = float64(covered) / float64(res.Forecasts)
+	return res, nil
+}
@@ -0,0 +1,144 @@
+// Package forecast contains time series forecasting models used for predicting spot prices
+package forecast
+
+import (
+	"errors"
+	"math"
+)
+
+// Point a single forecasted value with its confidence interval
+type Point struct {
+	Value float64 `json:"value"`
+	Lower float64 `json:"lower"`
+	Upper float64 `json:"upper"`
+}
+
+// HoltWinters additive triple exponential smoothing model
+// a model with a SeasonLength below 2 doesn't have a seasonal component (Holt's linear trend method)
+type HoltWinters struct {
+	Alpha        float64
+	Beta         float64
+	Gamma        float64
+	SeasonLength int
+}
+
+// fitted holds the state of a model after smoothing a series
+type fitted struct {
+	level     float64
+	trend     float64
+	seasonals []float64
+	// offset of the first forecasted step in the seasonal cycle
+	offset int
+	// sse sum of squared one-step-ahead errors, n the number of errors
+	sse float64
+	n   int
+}
+
+// ErrNoData is returned when there are no values to forecast from
+var ErrNoData = errors.New("no data to forecast from")
+
+// Forecast predicts the next horizon values of the series, the confidence interval is computed from the
+// one-step-ahead errors of the model on the series and widens with the square root of the steps
+func (hw HoltWinters) Forecast(series []float64, horizon int, confidence float64) ([]Point, error) {
+	if len(series) == 0 {
+		return nil, ErrNoData
+	}
+	if confidence <= 0 || confidence >= 1 {
+		return nil, errors.New("confidence must be between 0 and 1")
+	}
+	f := hw.fit(series)
+	var sigma float64
+	if f.n > 0 {
+		sigma = math.Sqrt(f.sse / float64(f.n))
+	}
+	z := math.Sqrt2 * math.Erfinv(confidence)
+
+	points := make([]Point, horizon)
+	for h := 1; h <= horizon; h++ {
+		value := f.level + float64(h)*f.trend
+		if len(f.seasonals) > 0 {
+			value += f.seasonals[(f.offset+h-1)%len(f.seasonals)]
+		}
+		width := z * sigma * math.Sqrt(float64(h))
+		points[h-1] = Point{
+			Value: math.Max(value, 0),
+			Lower: math.Max(value-width, 0),
+			Upper: math.Max(value+width, 0),
+		}
+	}
+	return points, nil
+}
+
+// seasonal reports whether the series is long enough to be smoothed with a seasonal component
+func (hw HoltWinters) seasonal(series []float64) bool {
+	return hw.SeasonLength > 1 && len(series) >= 2*hw.SeasonLength
+}
+
+func (hw HoltWinters) fit(series []float64) fitted {
+	if len(series) == 1 {
+		return fitted{level: series[0]}
+	}
+	if !hw.seasonal(series) {
+		f := fitted{level: series[0], trend: series[1] - series[0]}
+		for _, v := range series[1:] {
+			forecast := f.level + f.trend
+			f.sse += (v - forecast) * (v - forecast)
+			f.n++
+			level := hw.Alpha*v + (1-hw.Alpha)*(f.level+f.trend)
+			f.trend = hw.Beta*(level-f.level) + (1-hw.Beta)*f.trend
+			f.level = level
+		}
+		return f
+	}
+
+	l := hw.SeasonLength
+	// initial level and trend from the first two seasons, initial seasonals from the first season
+	var firstSum, secondSum float64
+	for i := 0; i < l; i++ {
+		firstSum += series[i]
+		secondSum += series[l+i]
+	}
+	f := fitted{
+		level:     firstSum / float64(l),
+		trend:     (secondSum - firstSum) / float64(l*l),
+		seasonals: make([]float64, l),
+	}
+	for i := 0; i < l; i++ {
+		f.seasonals[i] = series[i] - f.level
+	}
+	for i := l; i < len(series); i++ {
+		v := series[i]
+		s := f.seasonals[i%l]
+		forecast := f.level + f.trend + s
+		f.sse += (v - forecast) * (v - forecast)
+		f.n++
+		level := hw.Alpha*(v-s) + (1-hw.Alpha)*(f.level+f.trend)
+		f.trend = hw.Beta*(level-f.level) + (1-hw.Beta)*f.trend
+		f.seasonals[i%l] = hw.Gamma*(v-level) + (1-hw.Gamma)*s
+		f.level = level
+	}
+	f.offset = len(series) % l
+	return f
+}
+
+// Fit searches a grid of smoothing parameters for the model with the lowest one-step-ahead error on the series
+func Fit(series []float64, seasonLength int) HoltWinters {
+	grid := []float64{0.05, 0.2, 0.4, 0.6, 0.8, 0.95}
+	best := HoltWinters{Alpha: 0.5, Beta: 0.1, Gamma: 0.1, SeasonLength: seasonLength}
+	bestSse := math.Inf(1)
+	gammas := grid
+	if !best.seasonal(series) {
+		gammas = []float64{0}
+	}
+	for _, alpha := range grid {
+		for _, beta := range grid {
+			for _, gamma := range gammas {
+				hw := HoltWinters{Alpha: alpha, Beta: beta, Gamma: gamma, SeasonLength: seasonLength}
+				if f := hw.fit(series); f.n > 0 && f.sse < bestSse {
+					best, bestSse = hw, f.sse
+				}
+			}
+		}
+	}
+	return best
+}
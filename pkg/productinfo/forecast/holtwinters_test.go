@@ -0,0 +1,97 @@
+package forecast
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// seasonalSeries generates a daily seasonal series with a slight upward trend
+func seasonalSeries(days int) []float64 {
+	series := make([]float64, days*24)
+	for i := range series {
+		series[i] = 0.1 + 0.0001*float64(i) + 0.02*math.Sin(2*math.Pi*float64(i)/24)
+	}
+	return series
+}
+
+func TestHoltWinters_Forecast(t *testing.T) {
+	tests := []struct {
+		name   string
+		series []float64
+		model  HoltWinters
+		check  func(points []Point, err error)
+	}{
+		{
+			name:   "seasonal series is followed",
+			series: seasonalSeries(7),
+			model:  HoltWinters{Alpha: 0.3, Beta: 0.05, Gamma: 0.3, SeasonLength: 24},
+			check: func(points []Point, err error) {
+				assert.Nil(t, err, "the error should be nil")
+				assert.Equal(t, 24, len(points))
+				expected := seasonalSeries(8)[7*24:]
+				for i, p := range points {
+					assert.InDelta(t, expected[i], p.Value, 0.005)
+					assert.True(t, p.Lower <= p.Value && p.Value <= p.Upper, "the value should be in the confidence interval")
+				}
+			},
+		},
+		{
+			name:   "short series is smoothed without seasonality",
+			series: []float64{0.1, 0.1, 0.1},
+			model:  HoltWinters{Alpha: 0.5, Beta: 0.1, Gamma: 0.1, SeasonLength: 24},
+			check: func(points []Point, err error) {
+				assert.Nil(t, err, "the error should be nil")
+				assert.Equal(t, []Point{{0.1, 0.1, 0.1}, {0.1, 0.1, 0.1}}, points)
+			},
+		},
+		{
+			name:   "single value is repeated",
+			series: []float64{0.2},
+			model:  HoltWinters{Alpha: 0.5, Beta: 0.1, Gamma: 0.1, SeasonLength: 24},
+			check: func(points []Point, err error) {
+				assert.Nil(t, err, "the error should be nil")
+				assert.Equal(t, []Point{{0.2, 0.2, 0.2}, {0.2, 0.2, 0.2}}, points)
+			},
+		},
+		{
+			name:   "confidence interval widens with the horizon",
+			series: []float64{0.1, 0.3, 0.1, 0.3, 0.1, 0.3},
+			model:  HoltWinters{Alpha: 0.5, Beta: 0.1, SeasonLength: 0},
+			check: func(points []Point, err error) {
+				assert.Nil(t, err, "the error should be nil")
+				assert.True(t, points[1].Upper-points[1].Lower > points[0].Upper-points[0].Lower)
+			},
+		},
+		{
+			name:   "empty series",
+			series: []float64{},
+			model:  HoltWinters{Alpha: 0.5, Beta: 0.1, Gamma: 0.1, SeasonLength: 24},
+			check: func(points []Point, err error) {
+				assert.Nil(t, points, "the points should be nil")
+				assert.Equal(t, ErrNoData, err)
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			horizon := 2
+			if test.model.SeasonLength > 0 && len(test.series) > test.model.SeasonLength {
+				horizon = test.model.SeasonLength
+			}
+			test.check(test.model.Forecast(test.series, horizon, 0.95))
+		})
+	}
+}
+
+func TestBacktest(t *testing.T) {
+	res, err := Backtest(seasonalSeries(10), 24, 7*24, 24, 0.95)
+	assert.Nil(t, err, "the error should be nil")
+	assert.Equal(t, 72, res.Forecasts)
+	assert.True(t, res.MAPE < 0.05, "the seasonal series should be forecasted within 5%")
+	assert.True(t, res.RMSE >= res.MAE)
+
+	_, err = Backtest(seasonalSeries(1), 24, 7*24, 24, 0.95)
+	assert.EqualError(t, err, "the series is too short for the given training size and horizon")
+}
@@ -0,0 +1,180 @@
+package gce
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/banzaicloud/productinfo/pkg/productinfo"
+	billing "google.golang.org/api/cloudbilling/v1"
+	"google.golang.org/api/compute/v1"
+)
+
+const (
+	// localSsdModel the model of the local SSD add-on
+	localSsdModel = "local-ssd"
+	// localSsdSize the size of a local SSD device in GiB
+	localSsdSize = 375
+	// maxLocalSsds the maximum number of local SSD devices attached to an instance
+	maxLocalSsds = 24
+)
+
+// gpuRegex matches the GPU model in the GPU SKU descriptions, e.g. "Nvidia Tesla T4 GPU attached to Preemptible VMs
+// running in Americas" or "Spot Preemptible Nvidia Tesla T4 GPU running in Americas"
+var gpuRegex = regexp.MustCompile(`^(?:` + spotPrefix + `)?(Nvidia [\w ]+?) GPU`)
+
+// deviceRates the hourly prices of a single device by region, model and usage type (OnDemand, Preemptible, Spot)
+type deviceRates map[string]map[string]map[string]float64
+
+// add parses a GPU or local SSD SKU and records its hourly rate, other SKUs are skipped
+func (dr deviceRates) add(sku *billing.Sku) {
+	model, ok := parseDeviceModel(sku)
+	if !ok || len(sku.PricingInfo) != 1 {
+		return
+	}
+	usageType := skuUsageType(sku)
+	if usageType != onDemand && usageType != preemptible && usageType != spot {
+		return
+	}
+	rate := unitPrice(sku.PricingInfo[0])
+	if model == localSsdModel {
+		// local SSDs are charged per GiB-month
+		rate = rate * localSsdSize / productinfo.HoursPerMonth
+	}
+	for _, region := range sku.ServiceRegions {
+		dr.set(region, model, usageType, rate)
+	}
+}
+
+// set records the hourly rate of a device model with a usage type in a region
+func (dr deviceRates) set(region string, model string, usageType string, rate float64) {
+	if dr[region] == nil {
+		dr[region] = make(map[string]map[string]float64)
+	}
+	if dr[region][model] == nil {
+		dr[region][model] = make(map[string]float64)
+	}
+	dr[region][model][usageType] = rate
+}
+
+// parseDeviceModel returns the accelerator type name of a GPU SKU (e.g. nvidia-tesla-t4) or local-ssd for the local SSD
+// SKUs, the virtual workstation GPUs are skipped
+func parseDeviceModel(sku *billing.Sku) (string, bool) {
+	switch {
+	case sku.Category.ResourceGroup == "GPU":
+		match := gpuRegex.FindStringSubmatch(sku.Description)
+		if match == nil || strings.Contains(sku.Description, "Virtual Workstation") {
+			return "", false
+		}
+		return strings.Replace(strings.ToLower(match[1]), " ", "-", -1), true
+	case sku.Category.ResourceGroup == "LocalSSD" || strings.HasPrefix(sku.Description, "SSD backed Local Storage"):
+		return localSsdModel, true
+	}
+	return "", false
+}
+
+// acceleratorType the GPU model attached to the instances of some zones of a region
+type acceleratorType struct {
+	description string
+	maxPerVm    int
+	zones       []string
+}
+
+// listAcceleratorTypes returns the accelerator types available in the given zones by name
+func (g *GceInfoer) listAcceleratorTypes(zones []string) (map[string]acceleratorType, error) {
+	accelerators := make(map[string]acceleratorType)
+	err := g.computeSvc.AcceleratorTypes.AggregatedList(g.projectId).Pages(context.TODO(), func(allAts *compute.AcceleratorTypeAggregatedList) error {
+		for _, scope := range allAts.Items {
+			for _, at := range scope.AcceleratorTypes {
+				s := strings.Split(at.Zone, "/")
+				zone := s[len(s)-1]
+				if !productinfo.Contains(zones, zone) {
+					continue
+				}
+				accelerator := accelerators[at.Name]
+				accelerator.description = at.Description
+				accelerator.maxPerVm = int(at.MaximumCardsPerInstance)
+				accelerator.zones = append(accelerator.zones, zone)
+				accelerators[at.Name] = accelerator
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("unable to list accelerator types: %v", err.Error())
+	}
+	return accelerators, nil
+}
+
+// GetAddOns returns the GPUs available in the zones of a region and the local SSDs with their hourly prices per device
+// the zones and the limits of the GPUs are not known without the Compute Engine API, every priced GPU is returned then
+func (g *GceInfoer) GetAddOns(region string) ([]productinfo.AddOn, error) {
+	g.ratesMu.RLock()
+	devices := g.devices
+	g.ratesMu.RUnlock()
+	if devices == nil {
+		return nil, fmt.Errorf("device rates not yet cached")
+	}
+	if g.computeSvc == nil {
+		accelerators := make(map[string]acceleratorType)
+		for model := range devices[region] {
+			if model != localSsdModel {
+				accelerators[model] = acceleratorType{}
+			}
+		}
+		return devices.addOns(region, accelerators), nil
+	}
+	zones, err := g.GetZones(region)
+	if err != nil {
+		return nil, err
+	}
+	accelerators, err := g.listAcceleratorTypes(zones)
+	if err != nil {
+		return nil, err
+	}
+	return devices.addOns(region, accelerators), nil
+}
+
+// addOns returns the priced add-ons of a region, the accelerator types without an on demand rate are skipped
+func (dr deviceRates) addOns(region string, accelerators map[string]acceleratorType) []productinfo.AddOn {
+	var addOns []productinfo.AddOn
+	for model, accelerator := range accelerators {
+		rates, ok := dr[region][model]
+		if !ok || rates[onDemand] <= 0 {
+			continue
+		}
+		var zones []string
+		if len(accelerator.zones) > 0 {
+			zones = append(zones, accelerator.zones...)
+			sort.Strings(zones)
+		}
+		addOns = append(addOns, productinfo.AddOn{
+			Type:             productinfo.Gpu,
+			Model:            model,
+			Description:      accelerator.description,
+			MaxPerVm:         accelerator.maxPerVm,
+			Zones:            zones,
+			OnDemandPrice:    rates[onDemand],
+			SpotPrice:        rates[spot],
+			PreemptiblePrice: rates[preemptible],
+		})
+	}
+	if rates, ok := dr[region][localSsdModel]; ok && rates[onDemand] > 0 {
+		addOns = append(addOns, productinfo.AddOn{
+			Type:             productinfo.LocalSsd,
+			Model:            localSsdModel,
+			Description:      "Local SSD",
+			MaxPerVm:         maxLocalSsds,
+			Size:             localSsdSize,
+			OnDemandPrice:    rates[onDemand],
+			SpotPrice:        rates[spot],
+			PreemptiblePrice: rates[preemptible],
+		})
+	}
+	sort.Slice(addOns, func(i, j int) bool {
+		return addOns[i].Model < addOns[j].Model
+	})
+	return addOns
+}
@@ -0,0 +1,81 @@
+package gce
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/banzaicloud/productinfo/pkg/productinfo"
+	"github.com/stretchr/testify/assert"
+	billing "google.golang.org/api/cloudbilling/v1"
+)
+
+// deviceSkus hand written GPU and local SSD SKUs in the format of the Cloud Billing Catalog API, trimmed to the fields
+// used, these are not recorded responses: the SKU ids are made up and the rates follow the published us-central1 list prices
+const deviceSkus = `{
+  "skus": [
+    {"skuId": "F6BF-6E8E-5FA5", "description": "Nvidia Tesla T4 GPU running in Americas",
+      "category": {"resourceFamily": "Compute", "resourceGroup": "GPU", "usageType": "OnDemand"}, "serviceRegions": ["us-central1"],
+      "pricingInfo": [{"pricingExpression": {"usageUnit": "h", "tieredRates": [{"unitPrice": {"currencyCode": "USD", "nanos": 350000000}}]}}]},
+    {"skuId": "9A2F-8C7B-2E5B", "description": "Nvidia Tesla T4 GPU attached to Preemptible VMs running in Americas",
+      "category": {"resourceFamily": "Compute", "resourceGroup": "GPU", "usageType": "Preemptible"}, "serviceRegions": ["us-central1"],
+      "pricingInfo": [{"pricingExpression": {"usageUnit": "h", "tieredRates": [{"unitPrice": {"currencyCode": "USD", "nanos": 110000000}}]}}]},
+    {"skuId": "4C5E-21AF-93D0", "description": "Spot Preemptible Nvidia Tesla T4 GPU running in Americas",
+      "category": {"resourceFamily": "Compute", "resourceGroup": "GPU", "usageType": "Preemptible"}, "serviceRegions": ["us-central1"],
+      "pricingInfo": [{"pricingExpression": {"usageUnit": "h", "tieredRates": [{"unitPrice": {"currencyCode": "USD", "nanos": 105000000}}]}}]},
+    {"skuId": "0BB6-3C35-1D5A", "description": "Commitment v1: Nvidia Tesla T4 GPU in Americas for 1 Year",
+      "category": {"resourceFamily": "Compute", "resourceGroup": "GPU", "usageType": "Commit1Yr"}, "serviceRegions": ["us-central1"],
+      "pricingInfo": [{"pricingExpression": {"usageUnit": "h", "tieredRates": [{"unitPrice": {"currencyCode": "USD", "nanos": 220000000}}]}}]},
+    {"skuId": "2D1F-6C59-8F1C", "description": "Nvidia Tesla T4 Virtual Workstation GPU running in Americas",
+      "category": {"resourceFamily": "Compute", "resourceGroup": "GPU", "usageType": "OnDemand"}, "serviceRegions": ["us-central1"],
+      "pricingInfo": [{"pricingExpression": {"usageUnit": "h", "tieredRates": [{"unitPrice": {"currencyCode": "USD", "nanos": 550000000}}]}}]},
+    {"skuId": "039F-D0DA-4055", "description": "Nvidia Tesla V100 GPU running in Americas",
+      "category": {"resourceFamily": "Compute", "resourceGroup": "GPU", "usageType": "OnDemand"}, "serviceRegions": ["us-central1"],
+      "pricingInfo": [{"pricingExpression": {"usageUnit": "h", "tieredRates": [{"unitPrice": {"currencyCode": "USD", "units": "2", "nanos": 480000000}}]}}]},
+    {"skuId": "B188-61DD-52E4", "description": "SSD backed Local Storage",
+      "category": {"resourceFamily": "Storage", "resourceGroup": "LocalSSD", "usageType": "OnDemand"}, "serviceRegions": ["us-central1"],
+      "pricingInfo": [{"pricingExpression": {"usageUnit": "GiBy.mo", "tieredRates": [{"unitPrice": {"currencyCode": "USD", "nanos": 80000000}}]}}]},
+    {"skuId": "1D83-D6F4-5D36", "description": "SSD backed Local Storage attached to Preemptible VMs",
+      "category": {"resourceFamily": "Storage", "resourceGroup": "LocalSSD", "usageType": "Preemptible"}, "serviceRegions": ["us-central1"],
+      "pricingInfo": [{"pricingExpression": {"usageUnit": "GiBy.mo", "tieredRates": [{"unitPrice": {"currencyCode": "USD", "nanos": 48000000}}]}}]},
+    {"skuId": "D973-5D65-BAB2", "description": "Storage PD Capacity",
+      "category": {"resourceFamily": "Storage", "resourceGroup": "PDStandard", "usageType": "OnDemand"}, "serviceRegions": ["us-central1"],
+      "pricingInfo": [{"pricingExpression": {"usageUnit": "GiBy.mo", "tieredRates": [{"unitPrice": {"currencyCode": "USD", "nanos": 40000000}}]}}]}
+  ]
+}`
+
+func TestDeviceRates_AddOns(t *testing.T) {
+	var response billing.ListSkusResponse
+	if err := json.Unmarshal([]byte(deviceSkus), &response); err != nil {
+		t.Fatalf("failed to unmarshal the SKUs: %v", err)
+	}
+	devices := make(deviceRates)
+	for _, sku := range response.Skus {
+		devices.add(sku)
+	}
+	assert.InDelta(t, 0.35, devices["us-central1"]["nvidia-tesla-t4"][onDemand], 0.000001)
+	assert.InDelta(t, 0.11, devices["us-central1"]["nvidia-tesla-t4"][preemptible], 0.000001)
+	assert.InDelta(t, 0.105, devices["us-central1"]["nvidia-tesla-t4"][spot], 0.000001)
+	assert.Equal(t, 3, len(devices["us-central1"]), "the virtual workstation and persistent disk SKUs should be skipped")
+
+	addOns := devices.addOns("us-central1", map[string]acceleratorType{
+		"nvidia-tesla-t4":   {description: "NVIDIA Tesla T4", maxPerVm: 4, zones: []string{"us-central1-f", "us-central1-a"}},
+		"nvidia-tesla-p100": {description: "NVIDIA Tesla P100", maxPerVm: 4, zones: []string{"us-central1-c"}},
+	})
+	assert.Equal(t, 2, len(addOns), "accelerators without prices and prices without accelerators should be left out")
+	assert.Equal(t, productinfo.LocalSsd, addOns[0].Type)
+	assert.Equal(t, maxLocalSsds, addOns[0].MaxPerVm)
+	assert.Equal(t, float64(localSsdSize), addOns[0].Size)
+	assert.InDelta(t, 0.08*localSsdSize/productinfo.HoursPerMonth, addOns[0].OnDemandPrice, 0.000001)
+	assert.InDelta(t, 0.048*localSsdSize/productinfo.HoursPerMonth, addOns[0].PreemptiblePrice, 0.000001)
+	assert.Zero(t, addOns[0].SpotPrice, "the preemptible price should not be reported as Spot VM price")
+
+	assert.Equal(t, productinfo.Gpu, addOns[1].Type)
+	assert.Equal(t, "nvidia-tesla-t4", addOns[1].Model)
+	assert.Equal(t, "NVIDIA Tesla T4", addOns[1].Description)
+	assert.Equal(t, 4, addOns[1].MaxPerVm)
+	assert.Equal(t, []string{"us-central1-a", "us-central1-f"}, addOns[1].Zones)
+	assert.InDelta(t, 0.35, addOns[1].OnDemandPrice, 0.000001)
+	assert.InDelta(t, 0.105, addOns[1].SpotPrice, 0.000001)
+	assert.InDelta(t, 0.11, addOns[1].PreemptiblePrice, 0.000001)
+	assert.Empty(t, devices.addOns("europe-west1", nil))
+}
@@ -0,0 +1,31 @@
+package gce
+
+// Config the settings of a GCE infoer
+type Config struct {
+	// ApiKey the Cloud Billing API key the SKUs are listed with, it's not needed if the prices are read from a price
+	// list
+	ApiKey string
+	// PriceListSource the source of the prices in the format of the Google Cloud pricing calculator, the Cloud Billing
+	// API is used if nil, the Compute Engine API credentials are optional with a price list
+	PriceListSource PriceListSource
+}
+
+// regionZones the zones of the regions, used instead of the Compute Engine API if its credentials are not available
+var regionZones = map[string][]string{
+	"asia-east1":              {"asia-east1-a", "asia-east1-b", "asia-east1-c"},
+	"asia-northeast1":         {"asia-northeast1-a", "asia-northeast1-b", "asia-northeast1-c"},
+	"asia-south1":             {"asia-south1-a", "asia-south1-b", "asia-south1-c"},
+	"asia-southeast1":         {"asia-southeast1-a", "asia-southeast1-b", "asia-southeast1-c"},
+	"australia-southeast1":    {"australia-southeast1-a", "australia-southeast1-b", "australia-southeast1-c"},
+	"europe-north1":           {"europe-north1-a", "europe-north1-b", "europe-north1-c"},
+	"europe-west1":            {"europe-west1-b", "europe-west1-c", "europe-west1-d"},
+	"europe-west2":            {"europe-west2-a", "europe-west2-b", "europe-west2-c"},
+	"europe-west3":            {"europe-west3-a", "europe-west3-b", "europe-west3-c"},
+	"europe-west4":            {"europe-west4-a", "europe-west4-b", "europe-west4-c"},
+	"northamerica-northeast1": {"northamerica-northeast1-a", "northamerica-northeast1-b", "northamerica-northeast1-c"},
+	"southamerica-east1":      {"southamerica-east1-a", "southamerica-east1-b", "southamerica-east1-c"},
+	"us-central1":             {"us-central1-a", "us-central1-b", "us-central1-c", "us-central1-f"},
+	"us-east1":                {"us-east1-b", "us-east1-c", "us-east1-d"},
+	"us-east4":                {"us-east4-a", "us-east4-b", "us-east4-c"},
+	"us-west1":                {"us-west1-a", "us-west1-b", "us-west1-c"},
+}
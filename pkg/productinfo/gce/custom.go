@@ -0,0 +1,137 @@
+package gce
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/banzaicloud/productinfo/pkg/productinfo"
+)
+
+// customRules the shapes the custom machine types of a series can be created with
+type customRules struct {
+	// prefix the prefix of the custom machine type names
+	prefix  string
+	minCpus float64
+	maxCpus float64
+	// cpuStep the vCPUs must be a multiple of the step, a single vCPU is allowed if the minimum is 1
+	cpuStep float64
+	// largeCpus the number of vCPUs above which the vCPUs must be a multiple of the large step
+	largeCpus    float64
+	largeCpuStep float64
+	// minMemPerCpu and maxMemPerCpu the range of memory per vCPU in GiB, the memory above the maximum is extended memory
+	minMemPerCpu float64
+	maxMemPerCpu float64
+	// maxExtendedMem the maximum memory of a machine type with extended memory in GiB, 0 if extended memory is not
+	// supported
+	maxExtendedMem float64
+	// predefinedRates the custom machine types are charged at the rates of the predefined machine types
+	predefinedRates bool
+}
+
+// customSeries the machine series custom machine types can be created in
+var customSeries = map[string]customRules{
+	"n1": {prefix: "custom", minCpus: 1, maxCpus: 96, cpuStep: 2, minMemPerCpu: 0.9, maxMemPerCpu: 6.5, maxExtendedMem: 624},
+	"n2": {prefix: "n2-custom", minCpus: 2, maxCpus: 80, cpuStep: 2, largeCpus: 32, largeCpuStep: 4, minMemPerCpu: 0.5,
+		maxMemPerCpu: 8, maxExtendedMem: 864},
+	"e2": {prefix: "e2-custom", minCpus: 2, maxCpus: 32, cpuStep: 2, minMemPerCpu: 0.5, maxMemPerCpu: 8,
+		predefinedRates: true},
+}
+
+// ValidateCustomMachineType checks the vCPUs and the memory of a custom machine type against the rules of its series,
+// the memory must be a multiple of 256 MiB
+func (g *GceInfoer) ValidateCustomMachineType(mt productinfo.CustomMachineType) error {
+	rules, ok := customSeries[mt.Series]
+	if !ok {
+		return fmt.Errorf("custom machine types are not supported in the series: %s", mt.Series)
+	}
+	if mt.Cpus < rules.minCpus || mt.Cpus > rules.maxCpus {
+		return fmt.Errorf("the vCPUs of %s custom machine types must be between %v and %v", mt.Series, rules.minCpus, rules.maxCpus)
+	}
+	step := rules.cpuStep
+	if rules.largeCpus > 0 && mt.Cpus > rules.largeCpus {
+		step = rules.largeCpuStep
+	}
+	if !(mt.Cpus == 1 && rules.minCpus == 1) && math.Mod(mt.Cpus, step) != 0 {
+		return fmt.Errorf("the vCPUs of %s custom machine types must be a multiple of %v", mt.Series, step)
+	}
+	if math.Mod(mt.Mem*1024, 256) != 0 {
+		return errors.New("the memory of custom machine types must be a multiple of 256 MiB")
+	}
+	if mt.Mem < rules.minMemPerCpu*mt.Cpus {
+		return fmt.Errorf("%s custom machine types must have at least %v GiB memory per vCPU", mt.Series, rules.minMemPerCpu)
+	}
+	if mt.Mem > rules.maxMemPerCpu*mt.Cpus {
+		if rules.maxExtendedMem == 0 {
+			return fmt.Errorf("%s custom machine types can have at most %v GiB memory per vCPU", mt.Series, rules.maxMemPerCpu)
+		}
+		if mt.Mem > rules.maxExtendedMem {
+			return fmt.Errorf("%s custom machine types can have at most %v GiB extended memory", mt.Series, rules.maxExtendedMem)
+		}
+	}
+	return nil
+}
+
+// PriceCustomMachineType returns the on demand, Spot VM and legacy preemptible prices of a custom machine type composed
+// of the custom vCPU, memory and extended memory rates of its series
+func (g *GceInfoer) PriceCustomMachineType(region string, mt productinfo.CustomMachineType) (*productinfo.CustomMachineTypePrice, error) {
+	if err := g.ValidateCustomMachineType(mt); err != nil {
+		return nil, err
+	}
+	g.ratesMu.RLock()
+	rates, zones := g.rates, g.zonesInRegions[region]
+	g.ratesMu.RUnlock()
+	if rates == nil {
+		return nil, errors.New("custom machine type rates not yet cached")
+	}
+	rates = rates.withSpotRates(region, g.lastSpotRates())
+
+	rules := customSeries[mt.Series]
+	price := productinfo.CustomMachineTypePrice{
+		CustomMachineType: mt,
+		Type:              fmt.Sprintf("%s-%d-%d", rules.prefix, int(mt.Cpus), int(mt.Mem*1024)),
+		ExtendedMem:       math.Max(0, mt.Mem-rules.maxMemPerCpu*mt.Cpus),
+	}
+	if price.ExtendedMem > 0 {
+		price.Type += "-ext"
+	}
+
+	onDemandPrice, ok := rates.customRate(region, price, onDemand)
+	if !ok {
+		return nil, fmt.Errorf("no %s custom machine type rates found in region %s", mt.Series, region)
+	}
+	price.OnDemandPrice = onDemandPrice
+	sortedZones := append([]string{}, zones...)
+	sort.Strings(sortedZones)
+	if spotPrice, ok := rates.customRate(region, price, spot); ok {
+		price.SpotPrice = sortedZonePrices(sortedZones, spotPrice)
+	}
+	if preemptiblePrice, ok := rates.customRate(region, price, preemptible); ok {
+		price.PreemptiblePrice = sortedZonePrices(sortedZones, preemptiblePrice)
+	}
+	return &price, nil
+}
+
+// sortedZonePrices returns the same price for each of the sorted zones
+func sortedZonePrices(sortedZones []string, price float64) []productinfo.ZonePrice {
+	var prices []productinfo.ZonePrice
+	for _, zone := range sortedZones {
+		prices = append(prices, productinfo.ZonePrice{Zone: zone, Price: price})
+	}
+	return prices
+}
+
+// customRate returns the hourly price of a custom machine type with a usage type in a region, false is returned if a
+// rate needed for the price is not known
+func (rt rateTable) customRate(region string, mt productinfo.CustomMachineTypePrice, usageType string) (float64, bool) {
+	series := mt.Series + customSuffix
+	if customSeries[mt.Series].predefinedRates {
+		series = mt.Series
+	}
+	rates := rt[region][series][usageType]
+	if rates.cpu <= 0 || rates.ram <= 0 || (mt.ExtendedMem > 0 && rates.extRam <= 0) {
+		return 0, false
+	}
+	return mt.Cpus*rates.cpu + (mt.Mem-mt.ExtendedMem)*rates.ram + mt.ExtendedMem*rates.extRam, true
+}
@@ -0,0 +1,108 @@
+package gce
+
+import (
+	"testing"
+
+	"github.com/banzaicloud/productinfo/pkg/productinfo"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGceInfoer_ValidateCustomMachineType(t *testing.T) {
+	tests := []struct {
+		name  string
+		mt    productinfo.CustomMachineType
+		valid bool
+	}{
+		{name: "n1 single vCPU", mt: productinfo.CustomMachineType{Series: "n1", Cpus: 1, Mem: 1}, valid: true},
+		{name: "n1 extended memory", mt: productinfo.CustomMachineType{Series: "n1", Cpus: 2, Mem: 20}, valid: true},
+		{name: "n1 odd vCPUs", mt: productinfo.CustomMachineType{Series: "n1", Cpus: 3, Mem: 6}, valid: false},
+		{name: "n1 too little memory", mt: productinfo.CustomMachineType{Series: "n1", Cpus: 4, Mem: 3}, valid: false},
+		{name: "n1 memory not a multiple of 256 MiB", mt: productinfo.CustomMachineType{Series: "n1", Cpus: 2, Mem: 4.1}, valid: false},
+		{name: "n1 too much extended memory", mt: productinfo.CustomMachineType{Series: "n1", Cpus: 96, Mem: 640}, valid: false},
+		{name: "n2 large vCPUs", mt: productinfo.CustomMachineType{Series: "n2", Cpus: 36, Mem: 72}, valid: true},
+		{name: "n2 large vCPUs not a multiple of 4", mt: productinfo.CustomMachineType{Series: "n2", Cpus: 34, Mem: 68}, valid: false},
+		{name: "e2 without extended memory", mt: productinfo.CustomMachineType{Series: "e2", Cpus: 2, Mem: 20}, valid: false},
+		{name: "unsupported series", mt: productinfo.CustomMachineType{Series: "c2", Cpus: 4, Mem: 16}, valid: false},
+	}
+	g := &GceInfoer{}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := g.ValidateCustomMachineType(test.mt)
+			assert.Equal(t, test.valid, err == nil, "unexpected validation result: %v", err)
+		})
+	}
+}
+
+func TestGceInfoer_PriceCustomMachineType(t *testing.T) {
+	g := &GceInfoer{
+		rates:          newRateTable(t),
+		zonesInRegions: map[string][]string{"us-central1": {"us-central1-b", "us-central1-a"}},
+	}
+	tests := []struct {
+		name     string
+		mt       productinfo.CustomMachineType
+		check    func(price *productinfo.CustomMachineTypePrice, err error)
+		noRegion bool
+	}{
+		{
+			name: "n1 with preemptible prices",
+			mt:   productinfo.CustomMachineType{Series: "n1", Cpus: 2, Mem: 4},
+			check: func(price *productinfo.CustomMachineTypePrice, err error) {
+				assert.Nil(t, err, "the error should be nil")
+				assert.Equal(t, "custom-2-4096", price.Type)
+				assert.InDelta(t, 2*0.033174+4*0.004446, price.OnDemandPrice, 0.000001)
+				assert.Equal(t, []productinfo.ZonePrice{{Zone: "us-central1-a", Price: 2*0.00698 + 4*0.00094}, {Zone: "us-central1-b", Price: 2*0.00698 + 4*0.00094}}, price.PreemptiblePrice)
+				assert.Empty(t, price.SpotPrice, "the preemptible prices should not be reported as Spot VM prices")
+			},
+		},
+		{
+			name: "n2 with extended memory",
+			mt:   productinfo.CustomMachineType{Series: "n2", Cpus: 2, Mem: 20},
+			check: func(price *productinfo.CustomMachineTypePrice, err error) {
+				assert.Nil(t, err, "the error should be nil")
+				assert.Equal(t, "n2-custom-2-20480-ext", price.Type)
+				assert.Equal(t, float64(4), price.ExtendedMem)
+				assert.InDelta(t, 2*0.033174+16*0.004446+4*0.00955, price.OnDemandPrice, 0.000001)
+				assert.Empty(t, price.SpotPrice)
+			},
+		},
+		{
+			name: "e2 priced at the predefined rates",
+			mt:   productinfo.CustomMachineType{Series: "e2", Cpus: 4, Mem: 8},
+			check: func(price *productinfo.CustomMachineTypePrice, err error) {
+				assert.Nil(t, err, "the error should be nil")
+				assert.Equal(t, "e2-custom-4-8192", price.Type)
+				assert.InDelta(t, 4*0.02181159+8*0.00292353, price.OnDemandPrice, 0.000001)
+			},
+		},
+		{
+			name: "invalid shape",
+			mt:   productinfo.CustomMachineType{Series: "n1", Cpus: 3, Mem: 6},
+			check: func(price *productinfo.CustomMachineTypePrice, err error) {
+				assert.NotNil(t, err, "the error should not be nil")
+				assert.Nil(t, price)
+			},
+		},
+		{
+			name:     "region without rates",
+			mt:       productinfo.CustomMachineType{Series: "n1", Cpus: 2, Mem: 4},
+			noRegion: true,
+			check: func(price *productinfo.CustomMachineTypePrice, err error) {
+				assert.EqualError(t, err, "no n1 custom machine type rates found in region europe-west1")
+				assert.Nil(t, price)
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			region := "us-central1"
+			if test.noRegion {
+				region = "europe-west1"
+			}
+			test.check(g.PriceCustomMachineType(region, test.mt))
+		})
+	}
+
+	_, err := (&GceInfoer{}).PriceCustomMachineType("us-central1", productinfo.CustomMachineType{Series: "n1", Cpus: 2, Mem: 4})
+	assert.EqualError(t, err, "custom machine type rates not yet cached")
+}
@@ -0,0 +1,274 @@
+package gce
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/banzaicloud/productinfo/pkg/productinfo"
+	"google.golang.org/api/compute/v1"
+)
+
+const (
+	// CalculatorPriceListURL the url of the price list of the Google Cloud pricing calculator
+	CalculatorPriceListURL = "https://cloudpricingcalculator.appspot.com/static/data/pricelist.json"
+
+	vmImagePrefix     = "CP-COMPUTEENGINE-VMIMAGE-"
+	gpuPrefix         = "GPU_"
+	localSsdKey       = "CP-COMPUTEENGINE-LOCAL-SSD"
+	osKey             = "CP-COMPUTEENGINE-OS"
+	preemptibleSuffix = "-PREEMPTIBLE"
+)
+
+var (
+	// customKeyRegex matches the keys of the custom machine type rates, e.g. CP-COMPUTEENGINE-N2-CUSTOM-VM-EXTENDED-RAM
+	customKeyRegex = regexp.MustCompile(`^CP-COMPUTEENGINE-(?:(\w+)-)?CUSTOM-VM-(CORE|RAM|EXTENDED-RAM)$`)
+	// commitmentKeyRegex matches the keys of the committed use rates, e.g. CP-CUD-1-YEAR-CPU or CP-CUD-3-YEAR-E2-RAM
+	commitmentKeyRegex = regexp.MustCompile(`^CP-CUD-(1|3)-YEAR-(?:(\w+)-)?(CPU|RAM)$`)
+
+	// priceListOses the operating systems of the licensing fees of the price list
+	priceListOses = map[string]string{
+		"win":  productinfo.Windows,
+		"rhel": productinfo.Rhel,
+		"suse": productinfo.Suse,
+	}
+	// sharedCoreGuestCpus the number of vCPUs of the shared core machine types reported by the Compute Engine API, the
+	// other shared core machine types have 1 vCPU
+	sharedCoreGuestCpus = map[string]int64{
+		"e2-micro":  2,
+		"e2-small":  2,
+		"e2-medium": 2,
+	}
+)
+
+// PriceList the price list of the Google Cloud pricing calculator, the products are keyed by their calculator ids and
+// hold their hourly prices per region (CP-COMPUTEENGINE-LOCAL-SSD is priced per GiB-month)
+type PriceList struct {
+	Version string                     `json:"version"`
+	Updated string                     `json:"updated"`
+	Prices  map[string]json.RawMessage `json:"gcp_price_list"`
+}
+
+// PriceListSource retrieves the price list the Google Cloud prices are read from instead of the Cloud Billing API
+type PriceListSource interface {
+	// GetPriceList returns the price list in the format of the Google Cloud pricing calculator
+	GetPriceList() (*PriceList, error)
+}
+
+// CalculatorPriceListSource reads the price list of the Google Cloud pricing calculator from a url or a local JSON file
+type CalculatorPriceListSource struct {
+	location string
+	client   *http.Client
+}
+
+// NewCalculatorPriceListSource creates a new price list source, the location is the url or the path of the price list
+func NewCalculatorPriceListSource(location string) *CalculatorPriceListSource {
+	return &CalculatorPriceListSource{
+		location: location,
+		client:   http.DefaultClient,
+	}
+}
+
+// GetPriceList downloads or reads the price list
+func (s *CalculatorPriceListSource) GetPriceList() (*PriceList, error) {
+	var r io.ReadCloser
+	if strings.HasPrefix(s.location, "http://") || strings.HasPrefix(s.location, "https://") {
+		resp, err := s.client.Get(s.location)
+		if err != nil {
+			return nil, err
+		}
+		if resp.StatusCode != http.StatusOK {
+			resp.Body.Close()
+			return nil, fmt.Errorf("could not download price list %s, status: %s", s.location, resp.Status)
+		}
+		r = resp.Body
+	} else {
+		f, err := os.Open(s.location)
+		if err != nil {
+			return nil, err
+		}
+		r = f
+	}
+	defer r.Close()
+	var priceList PriceList
+	if err := json.NewDecoder(r).Decode(&priceList); err != nil {
+		return nil, fmt.Errorf("could not parse price list %s: %s", s.location, err.Error())
+	}
+	return &priceList, nil
+}
+
+// priceListEntry a product of the price list
+type priceListEntry struct {
+	// prices the hourly prices of the product in the known regions
+	prices map[string]float64
+	cores  string
+	memory string
+}
+
+// entry parses a product of the price list, only the prices of the given regions are kept, false is returned if the
+// key is not a product
+func (pl *PriceList) entry(key string, regions map[string][]string) (priceListEntry, bool) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(pl.Prices[key], &fields); err != nil {
+		return priceListEntry{}, false
+	}
+	entry := priceListEntry{prices: make(map[string]float64)}
+	for name, value := range fields {
+		switch name {
+		case "cores":
+			entry.cores = jsonString(value)
+		case "memory":
+			entry.memory = jsonString(value)
+		default:
+			if _, ok := regions[name]; !ok {
+				continue
+			}
+			var price float64
+			if err := json.Unmarshal(value, &price); err == nil {
+				entry.prices[name] = price
+			}
+		}
+	}
+	return entry, true
+}
+
+// jsonString returns a JSON string or number as a string
+func jsonString(value json.RawMessage) string {
+	var s string
+	if err := json.Unmarshal(value, &s); err == nil {
+		return s
+	}
+	return string(value)
+}
+
+// parse collects the prices of the predefined machine types, the custom and committed use rates, the add-on rates and
+// the licensing fees of the given regions, and the specifications of the predefined machine types
+// the predefined machine types are priced per instance, the committed use prices are composed of their vCPU and memory
+func (pl *PriceList) parse(regions map[string][]string) (rateTable, deviceRates, map[string][]licenseFee, []*compute.MachineType) {
+	rates := make(rateTable)
+	devices := make(deviceRates)
+	licenseFees := make(map[string][]licenseFee)
+	var machineTypes []*compute.MachineType
+
+	for key := range pl.Prices {
+		if key == osKey {
+			licenseFees = pl.licenseFees()
+			continue
+		}
+		entry, ok := pl.entry(key, regions)
+		if !ok {
+			continue
+		}
+		usageType, base := onDemand, key
+		if strings.HasSuffix(key, preemptibleSuffix) {
+			usageType, base = preemptible, strings.TrimSuffix(key, preemptibleSuffix)
+		}
+
+		switch {
+		case strings.HasPrefix(base, vmImagePrefix):
+			name := strings.ToLower(strings.TrimPrefix(base, vmImagePrefix))
+			for region, price := range entry.prices {
+				rates.set(region, name, usageType, cpu, price)
+			}
+			if usageType == onDemand {
+				if mt, ok := newPriceListMachineType(name, entry); ok {
+					machineTypes = append(machineTypes, mt)
+				}
+			}
+		case customKeyRegex.MatchString(base):
+			match := customKeyRegex.FindStringSubmatch(base)
+			series := "n1"
+			if match[1] != "" {
+				series = strings.ToLower(match[1])
+			}
+			resource := map[string]string{"CORE": cpu, "RAM": memory, "EXTENDED-RAM": extendedMemory}[match[2]]
+			for region, price := range entry.prices {
+				rates.set(region, series+customSuffix, usageType, resource, price)
+			}
+		case commitmentKeyRegex.MatchString(base):
+			match := commitmentKeyRegex.FindStringSubmatch(base)
+			usageType = map[string]string{"1": commit1Yr, "3": commit3Yr}[match[1]]
+			series := "n1"
+			if match[2] != "" {
+				series = strings.ToLower(match[2])
+			}
+			resource := map[string]string{"CPU": cpu, "RAM": memory}[match[3]]
+			for region, price := range entry.prices {
+				rates.set(region, series, usageType, resource, price)
+			}
+		case strings.HasPrefix(base, gpuPrefix):
+			model := strings.ToLower(strings.Replace(strings.TrimPrefix(base, gpuPrefix), "_", "-", -1))
+			for region, price := range entry.prices {
+				devices.set(region, model, usageType, price)
+			}
+		case base == localSsdKey:
+			for region, price := range entry.prices {
+				devices.set(region, localSsdModel, usageType, price*localSsdSize/productinfo.HoursPerMonth)
+			}
+		}
+	}
+	return rates, devices, licenseFees, machineTypes
+}
+
+// newPriceListMachineType creates the specification of a predefined machine type from its price list entry
+func newPriceListMachineType(name string, entry priceListEntry) (*compute.MachineType, bool) {
+	memGiB, err := strconv.ParseFloat(entry.memory, 64)
+	if err != nil {
+		return nil, false
+	}
+	mt := &compute.MachineType{
+		Name:     name,
+		MemoryMb: int64(memGiB * 1024),
+	}
+	if entry.cores == "shared" {
+		mt.IsSharedCpu = true
+		mt.GuestCpus = 1
+		if cpus, ok := sharedCoreGuestCpus[name]; ok {
+			mt.GuestCpus = cpus
+		}
+		return mt, true
+	}
+	if mt.GuestCpus, err = strconv.ParseInt(entry.cores, 10, 64); err != nil {
+		return nil, false
+	}
+	return mt, true
+}
+
+// priceListLicense the licensing fee of an operating system in the price list, the low fee applies to the shared core
+// machine types and to the machine types with at most the given cores, the high fee to the others
+type priceListLicense struct {
+	Low     float64     `json:"low"`
+	High    float64     `json:"high"`
+	Cores   interface{} `json:"cores"`
+	PerCore bool        `json:"percore"`
+}
+
+// licenseFees returns the licensing fees of the Windows Server, RHEL and SLES images
+func (pl *PriceList) licenseFees() map[string][]licenseFee {
+	licenseFees := make(map[string][]licenseFee)
+	var licenses map[string]json.RawMessage
+	if err := json.Unmarshal(pl.Prices[osKey], &licenses); err != nil {
+		return licenseFees
+	}
+	for name, raw := range licenses {
+		os, ok := priceListOses[name]
+		if !ok {
+			continue
+		}
+		var license priceListLicense
+		if err := json.Unmarshal(raw, &license); err != nil {
+			continue
+		}
+		fees := []licenseFee{{sharedCore: true, price: license.Low}}
+		if cores, err := strconv.ParseFloat(fmt.Sprint(license.Cores), 64); err == nil {
+			fees = append(fees, licenseFee{maxCpus: cores, perCpu: license.PerCore, price: license.Low})
+		}
+		licenseFees[os] = append(fees, licenseFee{perCpu: license.PerCore, price: license.High})
+	}
+	return licenseFees
+}
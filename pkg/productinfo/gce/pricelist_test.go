@@ -0,0 +1,152 @@
+package gce
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/banzaicloud/productinfo/pkg/productinfo"
+	"github.com/stretchr/testify/assert"
+)
+
+// calculatorPriceList a price list of the Google Cloud pricing calculator, trimmed to a few products
+const calculatorPriceList = `{
+  "comment": "Google Cloud Platform pricing",
+  "version": "v1.62",
+  "updated": "05-March-2020",
+  "gcp_price_list": {
+    "sustained_use_base": 0.25,
+    "CP-COMPUTEENGINE-VMIMAGE-N1-STANDARD-1": {"us": 0.0475, "us-central1": 0.0475, "europe-west1": 0.0523, "cores": "1", "memory": "3.75", "gceu": 2.75, "maxNumberOfPd": 128, "ssd": [0, 1, 2, 3, 4, 5, 6, 7, 8]},
+    "CP-COMPUTEENGINE-VMIMAGE-N1-STANDARD-1-PREEMPTIBLE": {"us": 0.01, "us-central1": 0.01, "europe-west1": 0.011, "cores": "1", "memory": "3.75"},
+    "CP-COMPUTEENGINE-VMIMAGE-F1-MICRO": {"us": 0.0076, "us-central1": 0.0076, "europe-west1": 0.0086, "cores": "shared", "memory": "0.6"},
+    "CP-COMPUTEENGINE-VMIMAGE-E2-MICRO": {"us-central1": 0.008376, "cores": "shared", "memory": "1"},
+    "CP-COMPUTEENGINE-CUSTOM-VM-CORE": {"us": 0.033174, "us-central1": 0.033174, "europe-west1": 0.036489},
+    "CP-COMPUTEENGINE-CUSTOM-VM-RAM": {"us": 0.004446, "us-central1": 0.004446, "europe-west1": 0.004892},
+    "CP-COMPUTEENGINE-CUSTOM-VM-EXTENDED-RAM": {"us-central1": 0.009550},
+    "CP-COMPUTEENGINE-N2-CUSTOM-VM-CORE-PREEMPTIBLE": {"us-central1": 0.00802},
+    "CP-CUD-1-YEAR-CPU": {"us-central1": 0.019915, "europe-west1": 0.021907},
+    "CP-CUD-1-YEAR-RAM": {"us-central1": 0.002669, "europe-west1": 0.002936},
+    "GPU_NVIDIA_TESLA_T4": {"us-central1": 0.35, "europe-west1": 0.35},
+    "GPU_NVIDIA_TESLA_T4-PREEMPTIBLE": {"us-central1": 0.11},
+    "CP-COMPUTEENGINE-LOCAL-SSD": {"us-central1": 0.08, "europe-west1": 0.088},
+    "CP-COMPUTEENGINE-OS": {
+      "win": {"low": 0.02, "high": 0.04, "cores": "shared", "percore": true},
+      "rhel": {"low": 0.06, "high": 0.13, "cores": "4", "percore": false},
+      "suse": {"low": 0.02, "high": 0.11, "cores": "shared", "percore": false},
+      "sles-sap-12": {"low": 0.17, "high": 0.41, "cores": "4", "percore": false}
+    }
+  }
+}`
+
+func TestPriceList_parse(t *testing.T) {
+	var priceList PriceList
+	if err := json.Unmarshal([]byte(calculatorPriceList), &priceList); err != nil {
+		t.Fatalf("failed to unmarshal the price list: %v", err)
+	}
+	rates, devices, licenseFees, machineTypes := priceList.parse(map[string][]string{
+		"us-central1":  {"us-central1-a", "us-central1-b"},
+		"europe-west1": {"europe-west1-b"},
+	})
+
+	assert.Nil(t, rates["us"], "the multi-regional prices should be skipped")
+	assert.Equal(t, resourceRates{cpu: 0.0475}, rates["us-central1"]["n1-standard-1"][onDemand])
+	assert.Equal(t, resourceRates{cpu: 0.01}, rates["us-central1"]["n1-standard-1"][preemptible])
+	assert.Equal(t, resourceRates{cpu: 0.033174, ram: 0.004446, extRam: 0.00955}, rates["us-central1"]["n1-custom"][onDemand])
+	assert.Equal(t, resourceRates{cpu: 0.00802}, rates["us-central1"]["n2-custom"][preemptible])
+	assert.Equal(t, resourceRates{cpu: 0.021907, ram: 0.002936}, rates["europe-west1"]["n1"][commit1Yr])
+
+	assert.Equal(t, 0.35, devices["us-central1"]["nvidia-tesla-t4"][onDemand])
+	assert.Equal(t, 0.11, devices["us-central1"]["nvidia-tesla-t4"][preemptible])
+	assert.InDelta(t, 0.088*localSsdSize/productinfo.HoursPerMonth, devices["europe-west1"][localSsdModel][onDemand], 0.000001)
+
+	assert.Equal(t, 3, len(licenseFees), "only the Windows, RHEL and SLES fees should be kept")
+	assert.Equal(t, []licenseFee{
+		{sharedCore: true, price: 0.06},
+		{maxCpus: 4, price: 0.06},
+		{price: 0.13},
+	}, licenseFees[productinfo.Rhel])
+
+	specs := make(map[string][]int64)
+	for _, mt := range machineTypes {
+		specs[mt.Name] = []int64{mt.GuestCpus, mt.MemoryMb}
+	}
+	assert.Equal(t, map[string][]int64{
+		"n1-standard-1": {1, 3840},
+		"f1-micro":      {1, 614},
+		"e2-micro":      {2, 1024},
+	}, specs)
+}
+
+func TestCalculatorPriceListSource_GetPriceList(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/pricelist.json" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Write([]byte(calculatorPriceList))
+	}))
+	defer server.Close()
+
+	priceList, err := NewCalculatorPriceListSource(server.URL + "/pricelist.json").GetPriceList()
+	assert.Nil(t, err, "the error should be nil")
+	assert.Equal(t, "v1.62", priceList.Version)
+	_, err = NewCalculatorPriceListSource(server.URL + "/missing.json").GetPriceList()
+	assert.EqualError(t, err, "could not download price list "+server.URL+"/missing.json, status: 404 Not Found")
+
+	dir, err := ioutil.TempDir("", "pricelist")
+	assert.Nil(t, err, "the error should be nil")
+	defer os.RemoveAll(dir)
+	location := filepath.Join(dir, "pricelist.json")
+	assert.Nil(t, ioutil.WriteFile(location, []byte(calculatorPriceList), 0644))
+	priceList, err = NewCalculatorPriceListSource(location).GetPriceList()
+	assert.Nil(t, err, "the error should be nil")
+	assert.Equal(t, "05-March-2020", priceList.Updated)
+}
+
+func TestGceInfoer_Initialize_priceList(t *testing.T) {
+	dir, err := ioutil.TempDir("", "pricelist")
+	assert.Nil(t, err, "the error should be nil")
+	defer os.RemoveAll(dir)
+	location := filepath.Join(dir, "pricelist.json")
+	assert.Nil(t, ioutil.WriteFile(location, []byte(calculatorPriceList), 0644))
+
+	infoer, err := NewGceInfoer(Config{PriceListSource: NewCalculatorPriceListSource(location)})
+	assert.Nil(t, err, "the error should be nil")
+	// the machine types are taken from the price list even if the Compute Engine API credentials are available
+	infoer.computeSvc = nil
+
+	_, err = infoer.GetProducts("us-central1")
+	assert.EqualError(t, err, "gce machine types not yet cached")
+
+	allPrices, err := infoer.Initialize()
+	assert.Nil(t, err, "the error should be nil")
+	price := allPrices["us-central1"]["n1-standard-1"]
+	assert.Equal(t, 0.0475, price.OnDemandPrice)
+	assert.Equal(t, productinfo.SpotPriceInfo{"us-central1-a": 0.01, "us-central1-b": 0.01, "us-central1-c": 0.01, "us-central1-f": 0.01}, price.PreemptiblePrice)
+	assert.Empty(t, price.SpotPrice, "the price list has no Spot VM prices")
+	assert.Equal(t, 1, len(price.Commitments))
+	assert.InDelta(t, 0.019915+3.75*0.002669, price.Commitments[0].HourlyPrice, 0.000001)
+	assert.InDelta(t, 0.0475+0.04, price.ForOs(productinfo.Windows).OnDemandPrice, 0.000001)
+	assert.Equal(t, 0.0086, allPrices["europe-west1"]["f1-micro"].OnDemandPrice)
+
+	regions, err := infoer.GetRegions()
+	assert.Nil(t, err, "the error should be nil")
+	assert.Equal(t, "EU (Belgium)", regions["europe-west1"])
+	vms, err := infoer.GetProducts("europe-west1")
+	assert.Nil(t, err, "the error should be nil")
+	assert.Equal(t, 3, len(vms))
+	assert.Equal(t, productinfo.VmInfo{Type: "e2-micro", Cpus: 2, Mem: 1, NtwPerf: "4", Zones: []string{"europe-west1-b", "europe-west1-c", "europe-west1-d"}}, vms[0])
+	cpus, err := infoer.GetAttributeValues(cpu)
+	assert.Nil(t, err, "the error should be nil")
+	assert.Equal(t, 2, len(cpus))
+
+	addOns, err := infoer.GetAddOns("us-central1")
+	assert.Nil(t, err, "the error should be nil")
+	assert.Equal(t, 2, len(addOns))
+	assert.Equal(t, "nvidia-tesla-t4", addOns[1].Model)
+	assert.Empty(t, addOns[1].Zones)
+}
@@ -0,0 +1,296 @@
+package gce
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/banzaicloud/productinfo/pkg/productinfo"
+	billing "google.golang.org/api/cloudbilling/v1"
+	"google.golang.org/api/compute/v1"
+)
+
+const (
+	// extendedMemory the memory of the custom machine types above the per vCPU limit of their series
+	extendedMemory = "extendedMemory"
+	// customSuffix the suffix of the series keys of the custom machine type rates
+	customSuffix = "-custom"
+
+	onDemand    = "OnDemand"
+	preemptible = "Preemptible"
+	commit1Yr   = "Commit1Yr"
+	commit3Yr   = "Commit3Yr"
+	// spot the usage type the Spot VM rates are recorded with, the Spot VM SKUs are published with the Preemptible
+	// usage type, their descriptions start with spotPrefix
+	spot       = "Spot"
+	spotPrefix = "Spot Preemptible "
+)
+
+var (
+	// seriesRegex matches the machine series named in the SKU descriptions
+	seriesRegex = regexp.MustCompile(`\b(N1|N2D|N2|E2|C2|M1|M2)\b`)
+	// skippedSkus the compute SKUs that are not priced per vCPU or GiB of predefined or custom machine types
+	skippedSkus = []string{"Sole Tenancy", "Premium", "Upgrade", "Reserved", "GPU", "Local SSD"}
+	// sharedCoreCpus the fraction of a vCPU the shared core E2 machine types are charged for
+	sharedCoreCpus = map[string]float64{
+		"e2-micro":  0.25,
+		"e2-small":  0.5,
+		"e2-medium": 1,
+	}
+)
+
+// machineType the specification of a machine type the price is composed of
+type machineType struct {
+	name   string
+	cpus   float64
+	memGiB float64
+	// zones the zones the machine type is available in, the availability is not known if it's empty
+	zones []string
+}
+
+// newMachineType creates a machine type specification from its Compute Engine API representation
+func newMachineType(mt *compute.MachineType) machineType {
+	return machineType{
+		name:   mt.Name,
+		cpus:   float64(mt.GuestCpus),
+		memGiB: float64(mt.MemoryMb) / 1024,
+	}
+}
+
+// availableZones returns the zones of a region the machine type is available in, every zone of the region is returned
+// if the availability of the machine type is not known
+func (mt machineType) availableZones(regionZones []string) []string {
+	if len(mt.zones) == 0 {
+		return regionZones
+	}
+	var zones []string
+	for _, zone := range regionZones {
+		if productinfo.Contains(mt.zones, zone) {
+			zones = append(zones, zone)
+		}
+	}
+	return zones
+}
+
+// series returns the machine series of a machine type, e.g. n1 for n1-highmem-2
+func (mt machineType) series() string {
+	return strings.Split(mt.name, "-")[0]
+}
+
+// resourceRates the hourly price of a vCPU and of a GiB of memory, the price of a whole instance is stored as the vCPU
+// rate for the machine types priced per instance
+type resourceRates struct {
+	cpu float64
+	ram float64
+	// extRam the hourly price of a GiB of extended memory of the custom machine types
+	extRam float64
+}
+
+// rateTable the resource rates of the machine series by region, series and usage type (OnDemand, Preemptible,
+// Commit1Yr, Commit3Yr), the machine types priced per instance are stored with their name instead of the series
+type rateTable map[string]map[string]map[string]resourceRates
+
+// add parses a compute SKU and records its rate, the SKUs that are not vCPU, memory or shared core instance SKUs are
+// skipped
+func (rt rateTable) add(sku *billing.Sku) error {
+	series, resource, ok := parseSkuResource(sku.Description)
+	if !ok {
+		return nil
+	}
+	if len(sku.PricingInfo) != 1 {
+		return fmt.Errorf("pricing info not parsable, %d pricing info entries are returned", len(sku.PricingInfo))
+	}
+	rate := unitPrice(sku.PricingInfo[0])
+	usageType := skuUsageType(sku)
+	for _, region := range sku.ServiceRegions {
+		rt.set(region, series, usageType, resource, rate)
+	}
+	return nil
+}
+
+// skuUsageType returns the usage type of a SKU, the Spot VM SKUs are told apart from the legacy preemptible ones
+func skuUsageType(sku *billing.Sku) string {
+	if sku.Category.UsageType == preemptible && strings.HasPrefix(sku.Description, spotPrefix) {
+		return spot
+	}
+	return sku.Category.UsageType
+}
+
+// set records the rate of a resource of a machine series with a usage type in a region
+func (rt rateTable) set(region string, series string, usageType string, resource string, rate float64) {
+	if rt[region] == nil {
+		rt[region] = make(map[string]map[string]resourceRates)
+	}
+	if rt[region][series] == nil {
+		rt[region][series] = make(map[string]resourceRates)
+	}
+	rates := rt[region][series][usageType]
+	switch resource {
+	case memory:
+		rates.ram = rate
+	case extendedMemory:
+		rates.extRam = rate
+	default:
+		rates.cpu = rate
+	}
+	rt[region][series][usageType] = rates
+}
+
+// rate returns the hourly price of a machine type with a usage type in a region, false is returned if a rate needed
+// for the price is not known
+func (rt rateTable) rate(region string, mt machineType, usageType string) (float64, bool) {
+	if rates, ok := rt[region][mt.name][usageType]; ok {
+		// the machine type is priced per instance
+		return rates.cpu, rates.cpu > 0
+	}
+	rates, ok := rt[region][mt.series()][usageType]
+	if !ok || rates.cpu <= 0 || rates.ram <= 0 {
+		return 0, false
+	}
+	cpus := mt.cpus
+	if shared, ok := sharedCoreCpus[mt.name]; ok {
+		cpus = shared
+	}
+	return cpus*rates.cpu + mt.memGiB*rates.ram, true
+}
+
+// withSpotRates returns the rates of a region with the Spot VM rates taken from the given rate table, the rate tables
+// are not modified
+func (rt rateTable) withSpotRates(region string, spotRates rateTable) rateTable {
+	regionRates := make(map[string]map[string]resourceRates, len(rt[region]))
+	for series, usageRates := range rt[region] {
+		regionRates[series] = make(map[string]resourceRates, len(usageRates))
+		for usageType, rates := range usageRates {
+			if usageType != spot {
+				regionRates[series][usageType] = rates
+			}
+		}
+	}
+	for series, usageRates := range spotRates[region] {
+		rates, ok := usageRates[spot]
+		if !ok {
+			continue
+		}
+		if regionRates[series] == nil {
+			regionRates[series] = make(map[string]resourceRates)
+		}
+		regionRates[series][spot] = rates
+	}
+	return rateTable{region: regionRates}
+}
+
+// prices composes the prices of the machine types in every region from the resource rates, the Spot VM and the legacy
+// preemptible prices are set for each zone of the region the machine type is available in
+func (rt rateTable) prices(machineTypes []machineType, zonesInRegions map[string][]string) map[string]map[string]productinfo.Price {
+	allPrices := make(map[string]map[string]productinfo.Price)
+	for region := range rt {
+		prices := make(map[string]productinfo.Price)
+		for _, mt := range machineTypes {
+			var price productinfo.Price
+			if p, ok := rt.rate(region, mt, onDemand); ok {
+				price.OnDemandPrice = p
+			}
+			zones := mt.availableZones(zonesInRegions[region])
+			if p, ok := rt.rate(region, mt, spot); ok && len(zones) > 0 {
+				price.SpotPrice = zonePrices(zones, p)
+			}
+			if p, ok := rt.rate(region, mt, preemptible); ok && len(zones) > 0 {
+				price.PreemptiblePrice = zonePrices(zones, p)
+			}
+			for usageType, term := range map[string]int{commit1Yr: 1, commit3Yr: 3} {
+				if p, ok := rt.rate(region, mt, usageType); ok {
+					price.Commitments = append(price.Commitments, productinfo.NewCommitmentPrice(productinfo.CommittedUse, term, productinfo.NoUpfront, p, 0))
+				}
+			}
+			if price.OnDemandPrice <= 0 && len(price.SpotPrice) == 0 && len(price.PreemptiblePrice) == 0 &&
+				len(price.Commitments) == 0 {
+				continue
+			}
+			productinfo.SortCommitments(price.Commitments)
+			prices[mt.name] = price
+		}
+		if len(prices) > 0 {
+			allPrices[region] = prices
+		}
+	}
+	return allPrices
+}
+
+// zonePrices returns the same price for each zone
+func zonePrices(zones []string, price float64) productinfo.SpotPriceInfo {
+	prices := make(productinfo.SpotPriceInfo, len(zones))
+	for _, z := range zones {
+		prices[z] = price
+	}
+	return prices
+}
+
+// parseSkuResource returns the machine series and the resource (cpu, memory or extended memory) a compute SKU is
+// charged for based on its description, e.g. "N1 Predefined Instance Ram running in Americas", "Preemptible E2 Instance
+// Core running in EMEA" or "Commitment v1: Cpu in Americas for 1 Year"
+// the shared core f1-micro and g1-small SKUs are charged per instance and returned with the machine type name as cpu,
+// the series of the custom machine type SKUs are suffixed with -custom, e.g. "Custom Extended Instance Ram running in
+// Americas" is n1-custom
+func parseSkuResource(desc string) (string, string, bool) {
+	for _, skipped := range skippedSkus {
+		if strings.Contains(desc, skipped) {
+			return "", "", false
+		}
+	}
+	switch {
+	case strings.Contains(desc, "Micro Instance with burstable CPU"):
+		return "f1-micro", cpu, true
+	case strings.Contains(desc, "Small Instance with 1 VCPU"):
+		return "g1-small", cpu, true
+	}
+
+	custom := strings.Contains(desc, "Custom")
+	var resource string
+	switch {
+	case strings.Contains(desc, "Extended"):
+		if !custom {
+			return "", "", false
+		}
+		resource = extendedMemory
+	case strings.Contains(desc, "Core") || strings.Contains(desc, "Cpu"):
+		resource = cpu
+	case strings.Contains(desc, "Ram"):
+		resource = memory
+	default:
+		return "", "", false
+	}
+
+	var series string
+	switch {
+	case seriesRegex.MatchString(desc):
+		series = strings.ToLower(seriesRegex.FindString(desc))
+	case strings.Contains(desc, "Compute optimized"):
+		series = "c2"
+	case strings.Contains(desc, "Memory-optimized") || strings.Contains(desc, "Memory Optimized"):
+		series = "m1"
+	case strings.HasPrefix(desc, "Commitment") || custom:
+		// the first generation commitments and custom machine types are of the N1 series
+		series = "n1"
+	default:
+		return "", "", false
+	}
+	if custom {
+		series += customSuffix
+	}
+	return series, resource, true
+}
+
+// unitPrice returns the price of a usage unit, the rate of the tier with the highest start usage amount is used if the
+// pricing expression is tiered
+func unitPrice(pricingInfo *billing.PricingInfo) float64 {
+	var rate *billing.TierRate
+	for _, tr := range pricingInfo.PricingExpression.TieredRates {
+		if rate == nil || tr.StartUsageAmount > rate.StartUsageAmount {
+			rate = tr
+		}
+	}
+	if rate == nil || rate.UnitPrice == nil {
+		return 0
+	}
+	return float64(rate.UnitPrice.Units) + float64(rate.UnitPrice.Nanos)*1e-9
+}
@@ -0,0 +1,256 @@
+package gce
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/banzaicloud/productinfo/pkg/productinfo"
+	"github.com/stretchr/testify/assert"
+	billing "google.golang.org/api/cloudbilling/v1"
+)
+
+// computeSkus hand written SKUs in the format of the Cloud Billing Catalog API, trimmed to the fields used, these are
+// not recorded responses: the SKU ids are made up and the rates follow the published us-central1 list prices, the F1Micro
+// SKU has a free usage tier like the real one
+const computeSkus = `{
+  "skus": [
+    {"skuId": "2E27-4F75-95CD", "description": "N1 Predefined Instance Core running in Americas",
+      "category": {"resourceFamily": "Compute", "resourceGroup": "N1Standard", "usageType": "OnDemand"}, "serviceRegions": ["us-central1"],
+      "pricingInfo": [{"pricingExpression": {"usageUnit": "h", "tieredRates": [{"unitPrice": {"currencyCode": "USD", "nanos": 31611000}}]}}]},
+    {"skuId": "6B8F-E63D-832B", "description": "N1 Predefined Instance Ram running in Americas",
+      "category": {"resourceFamily": "Compute", "resourceGroup": "RAM", "usageType": "OnDemand"}, "serviceRegions": ["us-central1"],
+      "pricingInfo": [{"pricingExpression": {"usageUnit": "GiBy.h", "tieredRates": [{"unitPrice": {"currencyCode": "USD", "nanos": 4237000}}]}}]},
+    {"skuId": "7E72-A4B5-0D0C", "description": "Preemptible N1 Predefined Instance Core running in Americas",
+      "category": {"resourceFamily": "Compute", "resourceGroup": "N1Standard", "usageType": "Preemptible"}, "serviceRegions": ["us-central1"],
+      "pricingInfo": [{"pricingExpression": {"usageUnit": "h", "tieredRates": [{"unitPrice": {"currencyCode": "USD", "nanos": 6655000}}]}}]},
+    {"skuId": "5BB2-2E95-0E29", "description": "Preemptible N1 Predefined Instance Ram running in Americas",
+      "category": {"resourceFamily": "Compute", "resourceGroup": "RAM", "usageType": "Preemptible"}, "serviceRegions": ["us-central1"],
+      "pricingInfo": [{"pricingExpression": {"usageUnit": "GiBy.h", "tieredRates": [{"unitPrice": {"currencyCode": "USD", "nanos": 892000}}]}}]},
+    {"skuId": "4A00-1544-8BEB", "description": "Commitment v1: Cpu in Americas for 1 Year",
+      "category": {"resourceFamily": "Compute", "resourceGroup": "CPU", "usageType": "Commit1Yr"}, "serviceRegions": ["us-central1"],
+      "pricingInfo": [{"pricingExpression": {"usageUnit": "h", "tieredRates": [{"unitPrice": {"currencyCode": "USD", "nanos": 19915000}}]}}]},
+    {"skuId": "53C2-BE39-1BB3", "description": "Commitment v1: Ram in Americas for 1 Year",
+      "category": {"resourceFamily": "Compute", "resourceGroup": "RAM", "usageType": "Commit1Yr"}, "serviceRegions": ["us-central1"],
+      "pricingInfo": [{"pricingExpression": {"usageUnit": "GiBy.h", "tieredRates": [{"unitPrice": {"currencyCode": "USD", "nanos": 2669000}}]}}]},
+    {"skuId": "CF4E-A0C7-E3BF", "description": "E2 Instance Core running in Americas",
+      "category": {"resourceFamily": "Compute", "resourceGroup": "CPU", "usageType": "OnDemand"}, "serviceRegions": ["us-central1"],
+      "pricingInfo": [{"pricingExpression": {"usageUnit": "h", "tieredRates": [{"unitPrice": {"currencyCode": "USD", "nanos": 21811590}}]}}]},
+    {"skuId": "F449-33EC-A5EF", "description": "E2 Instance Ram running in Americas",
+      "category": {"resourceFamily": "Compute", "resourceGroup": "RAM", "usageType": "OnDemand"}, "serviceRegions": ["us-central1"],
+      "pricingInfo": [{"pricingExpression": {"usageUnit": "GiBy.h", "tieredRates": [{"unitPrice": {"currencyCode": "USD", "nanos": 2923530}}]}}]},
+    {"skuId": "2F8A-1B4D-6C3E", "description": "Spot Preemptible E2 Instance Core running in Americas",
+      "category": {"resourceFamily": "Compute", "resourceGroup": "CPU", "usageType": "Preemptible"}, "serviceRegions": ["us-central1"],
+      "pricingInfo": [{"pricingExpression": {"usageUnit": "h", "tieredRates": [{"unitPrice": {"currencyCode": "USD", "nanos": 6543000}}]}}]},
+    {"skuId": "8D1C-3E7F-2A9B", "description": "Spot Preemptible E2 Instance Ram running in Americas",
+      "category": {"resourceFamily": "Compute", "resourceGroup": "RAM", "usageType": "Preemptible"}, "serviceRegions": ["us-central1"],
+      "pricingInfo": [{"pricingExpression": {"usageUnit": "GiBy.h", "tieredRates": [{"unitPrice": {"currencyCode": "USD", "nanos": 877000}}]}}]},
+    {"skuId": "BB77-5FDA-4ED8", "description": "N2 Instance Core running in Americas",
+      "category": {"resourceFamily": "Compute", "resourceGroup": "CPU", "usageType": "OnDemand"}, "serviceRegions": ["us-central1"],
+      "pricingInfo": [{"pricingExpression": {"usageUnit": "h", "tieredRates": [{"unitPrice": {"currencyCode": "USD", "nanos": 31611000}}]}}]},
+    {"skuId": "5C6E-C1D4-A8E7", "description": "N2 Instance Ram running in Americas",
+      "category": {"resourceFamily": "Compute", "resourceGroup": "RAM", "usageType": "OnDemand"}, "serviceRegions": ["us-central1"],
+      "pricingInfo": [{"pricingExpression": {"usageUnit": "GiBy.h", "tieredRates": [{"unitPrice": {"currencyCode": "USD", "nanos": 4237000}}]}}]},
+    {"skuId": "848B-0575-88EB", "description": "N2 Custom Instance Core running in Americas",
+      "category": {"resourceFamily": "Compute", "resourceGroup": "CPU", "usageType": "OnDemand"}, "serviceRegions": ["us-central1"],
+      "pricingInfo": [{"pricingExpression": {"usageUnit": "h", "tieredRates": [{"unitPrice": {"currencyCode": "USD", "nanos": 33174000}}]}}]},
+    {"skuId": "D7E2-4F51-3B1B", "description": "N2 Custom Instance Ram running in Americas",
+      "category": {"resourceFamily": "Compute", "resourceGroup": "RAM", "usageType": "OnDemand"}, "serviceRegions": ["us-central1"],
+      "pricingInfo": [{"pricingExpression": {"usageUnit": "GiBy.h", "tieredRates": [{"unitPrice": {"currencyCode": "USD", "nanos": 4446000}}]}}]},
+    {"skuId": "2A8C-9B6F-07B2", "description": "N2 Custom Extended Instance Ram running in Americas",
+      "category": {"resourceFamily": "Compute", "resourceGroup": "RAM", "usageType": "OnDemand"}, "serviceRegions": ["us-central1"],
+      "pricingInfo": [{"pricingExpression": {"usageUnit": "GiBy.h", "tieredRates": [{"unitPrice": {"currencyCode": "USD", "nanos": 9550000}}]}}]},
+    {"skuId": "0A4B-4C1E-8B48", "description": "Custom Instance Core running in Americas",
+      "category": {"resourceFamily": "Compute", "resourceGroup": "CPU", "usageType": "OnDemand"}, "serviceRegions": ["us-central1"],
+      "pricingInfo": [{"pricingExpression": {"usageUnit": "h", "tieredRates": [{"unitPrice": {"currencyCode": "USD", "nanos": 33174000}}]}}]},
+    {"skuId": "9F94-4CE8-4E5B", "description": "Custom Instance Ram running in Americas",
+      "category": {"resourceFamily": "Compute", "resourceGroup": "RAM", "usageType": "OnDemand"}, "serviceRegions": ["us-central1"],
+      "pricingInfo": [{"pricingExpression": {"usageUnit": "GiBy.h", "tieredRates": [{"unitPrice": {"currencyCode": "USD", "nanos": 4446000}}]}}]},
+    {"skuId": "4B4B-7A22-5F3B", "description": "Custom Extended Instance Ram running in Americas",
+      "category": {"resourceFamily": "Compute", "resourceGroup": "RAM", "usageType": "OnDemand"}, "serviceRegions": ["us-central1"],
+      "pricingInfo": [{"pricingExpression": {"usageUnit": "GiBy.h", "tieredRates": [{"unitPrice": {"currencyCode": "USD", "nanos": 9550000}}]}}]},
+    {"skuId": "1F0C-6C21-0A2F", "description": "Preemptible Custom Instance Core running in Americas",
+      "category": {"resourceFamily": "Compute", "resourceGroup": "CPU", "usageType": "Preemptible"}, "serviceRegions": ["us-central1"],
+      "pricingInfo": [{"pricingExpression": {"usageUnit": "h", "tieredRates": [{"unitPrice": {"currencyCode": "USD", "nanos": 6980000}}]}}]},
+    {"skuId": "8C24-16E4-3DB5", "description": "Preemptible Custom Instance Ram running in Americas",
+      "category": {"resourceFamily": "Compute", "resourceGroup": "RAM", "usageType": "Preemptible"}, "serviceRegions": ["us-central1"],
+      "pricingInfo": [{"pricingExpression": {"usageUnit": "GiBy.h", "tieredRates": [{"unitPrice": {"currencyCode": "USD", "nanos": 940000}}]}}]},
+    {"skuId": "9D55-E3F5-8E2B", "description": "Micro Instance with burstable CPU running in Americas",
+      "category": {"resourceFamily": "Compute", "resourceGroup": "F1Micro", "usageType": "OnDemand"}, "serviceRegions": ["us-central1"],
+      "pricingInfo": [{"pricingExpression": {"usageUnit": "h", "tieredRates": [{"unitPrice": {"currencyCode": "USD", "units": "0"}},
+        {"startUsageAmount": 730, "unitPrice": {"currencyCode": "USD", "nanos": 7600000}}]}}]},
+    {"skuId": "7E5B-1A3C-9F3E", "description": "Small Instance with 1 VCPU running in Americas",
+      "category": {"resourceFamily": "Compute", "resourceGroup": "G1Small", "usageType": "OnDemand"}, "serviceRegions": ["us-central1"],
+      "pricingInfo": [{"pricingExpression": {"usageUnit": "h", "tieredRates": [{"unitPrice": {"currencyCode": "USD", "nanos": 25700000}}]}}]},
+    {"skuId": "C54B-CB63-1F5C", "description": "Network Inter Zone Egress",
+      "category": {"resourceFamily": "Network", "resourceGroup": "InterzoneEgress", "usageType": "OnDemand"}, "serviceRegions": ["us-central1"],
+      "pricingInfo": [{"pricingExpression": {"usageUnit": "GiBy", "tieredRates": [{"unitPrice": {"currencyCode": "USD", "nanos": 10000000}}]}}]}
+  ]
+}`
+
+func newRateTable(t *testing.T) rateTable {
+	var response billing.ListSkusResponse
+	if err := json.Unmarshal([]byte(computeSkus), &response); err != nil {
+		t.Fatalf("failed to unmarshal the SKUs: %v", err)
+	}
+	rates := make(rateTable)
+	for _, sku := range response.Skus {
+		if sku.Category.ResourceFamily != "Compute" {
+			continue
+		}
+		if err := rates.add(sku); err != nil {
+			t.Fatalf("failed to add the SKU %s: %v", sku.Description, err)
+		}
+	}
+	return rates
+}
+
+func TestGceInfoer_addSkus(t *testing.T) {
+	var response billing.ListSkusResponse
+	if err := json.Unmarshal([]byte(computeSkus), &response); err != nil {
+		t.Fatalf("failed to unmarshal the SKUs: %v", err)
+	}
+	// a SKU with two pricing info entries can't be parsed
+	unparsable := *response.Skus[0]
+	unparsable.ServiceRegions = []string{"us-central1", "us-east1"}
+	unparsable.PricingInfo = append(unparsable.PricingInfo, unparsable.PricingInfo[0])
+	skus := append([]*billing.Sku{&unparsable}, response.Skus[1:]...)
+
+	rates := make(rateTable)
+	issues := (&GceInfoer{}).addSkus(skus, rates, make(deviceRates), make(map[string][]licenseFee))
+	assert.Equal(t, []productinfo.QualityIssue{
+		{Kind: productinfo.UnparsableProduct, Region: "us-central1", Subject: "N1 Predefined Instance Core running in Americas",
+			Detail: "SKU 2E27-4F75-95CD: pricing info not parsable, 2 pricing info entries are returned"},
+		{Kind: productinfo.UnparsableProduct, Region: "us-east1", Subject: "N1 Predefined Instance Core running in Americas",
+			Detail: "SKU 2E27-4F75-95CD: pricing info not parsable, 2 pricing info entries are returned"},
+	}, issues)
+	assert.Equal(t, resourceRates{ram: 0.004237}, rates["us-central1"]["n1"][onDemand], "the other SKUs should be added")
+}
+
+func TestParseSkuResource(t *testing.T) {
+	tests := []struct {
+		desc     string
+		series   string
+		resource string
+		ok       bool
+	}{
+		{desc: "N1 Predefined Instance Core running in Americas", series: "n1", resource: cpu, ok: true},
+		{desc: "Preemptible N1 Predefined Instance Ram running in EMEA", series: "n1", resource: memory, ok: true},
+		{desc: "Spot Preemptible E2 Instance Core running in Americas", series: "e2", resource: cpu, ok: true},
+		{desc: "N2D AMD Instance Core running in Americas", series: "n2d", resource: cpu, ok: true},
+		{desc: "Compute optimized Ram running in Americas", series: "c2", resource: memory, ok: true},
+		{desc: "Memory-optimized Instance Core running in Americas", series: "m1", resource: cpu, ok: true},
+		{desc: "Commitment v1: Cpu in Americas for 3 Year", series: "n1", resource: cpu, ok: true},
+		{desc: "Commitment v1: E2 Ram in Americas for 1 Year", series: "e2", resource: memory, ok: true},
+		{desc: "Preemptible Small Instance with 1 VCPU running in Americas", series: "g1-small", resource: cpu, ok: true},
+		{desc: "Custom Instance Core running in Americas", series: "n1-custom", resource: cpu, ok: true},
+		{desc: "Preemptible N2 Custom Extended Instance Ram running in Americas", series: "n2-custom", resource: extendedMemory, ok: true},
+		{desc: "N1 Extended Memory running in Americas", ok: false},
+		{desc: "N1 Sole Tenancy Instance Ram running in Americas", ok: false},
+		{desc: "Nvidia Tesla T4 GPU running in Americas", ok: false},
+	}
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			series, resource, ok := parseSkuResource(test.desc)
+			assert.Equal(t, test.ok, ok)
+			assert.Equal(t, test.series, series)
+			assert.Equal(t, test.resource, resource)
+		})
+	}
+}
+
+func TestRateTable_Prices(t *testing.T) {
+	machineTypes := []machineType{
+		{name: "n1-standard-1", cpus: 1, memGiB: 3.75},
+		{name: "n1-highmem-2", cpus: 2, memGiB: 13},
+		{name: "n1-highcpu-4", cpus: 4, memGiB: 3.6},
+		{name: "n2-standard-2", cpus: 2, memGiB: 8},
+		{name: "e2-standard-2", cpus: 2, memGiB: 8},
+		{name: "e2-micro", cpus: 2, memGiB: 1},
+		{name: "f1-micro", cpus: 1, memGiB: 0.6},
+		{name: "g1-small", cpus: 1, memGiB: 1.7},
+		{name: "c2-standard-4", cpus: 4, memGiB: 16},
+		{name: "n1-standard-2", cpus: 2, memGiB: 7.5, zones: []string{"us-central1-b", "europe-west1-b"}},
+	}
+	prices := newRateTable(t).prices(machineTypes, map[string][]string{"us-central1": {"us-central1-a", "us-central1-b"}})
+	regionPrices := prices["us-central1"]
+
+	tests := []struct {
+		instanceType string
+		onDemand     float64
+		preemptible  float64
+		spot         float64
+		commit1Yr    float64
+	}{
+		{instanceType: "n1-standard-1", onDemand: 0.04750, preemptible: 0.01000, commit1Yr: 0.02992},
+		{instanceType: "n1-highmem-2", onDemand: 0.11830, preemptible: 0.02491, commit1Yr: 0.07453},
+		{instanceType: "n1-highcpu-4", onDemand: 0.14170, preemptible: 0.02983, commit1Yr: 0.08927},
+		{instanceType: "n2-standard-2", onDemand: 0.09712},
+		{instanceType: "e2-standard-2", onDemand: 0.06701, spot: 0.02010},
+		{instanceType: "e2-micro", onDemand: 0.00838, spot: 0.00251},
+		{instanceType: "f1-micro", onDemand: 0.0076}, // the rate after the free tier, not the sum of the tiers
+		{instanceType: "g1-small", onDemand: 0.0257},
+	}
+	for _, test := range tests {
+		t.Run(test.instanceType, func(t *testing.T) {
+			price, ok := regionPrices[test.instanceType]
+			assert.True(t, ok, "the machine type should be priced")
+			assert.InDelta(t, test.onDemand, price.OnDemandPrice, 0.00001)
+			if test.preemptible > 0 {
+				assert.Equal(t, 2, len(price.PreemptiblePrice))
+				assert.InDelta(t, test.preemptible, price.PreemptiblePrice["us-central1-b"], 0.00001)
+			} else {
+				assert.Empty(t, price.PreemptiblePrice)
+			}
+			if test.spot > 0 {
+				assert.Equal(t, 2, len(price.SpotPrice))
+				assert.InDelta(t, test.spot, price.SpotPrice["us-central1-b"], 0.00001)
+			} else {
+				assert.Empty(t, price.SpotPrice, "the preemptible prices should not be reported as Spot VM prices")
+			}
+			if test.commit1Yr > 0 {
+				assert.Equal(t, 1, len(price.Commitments))
+				assert.Equal(t, productinfo.CommittedUse, price.Commitments[0].Type)
+				assert.InDelta(t, test.commit1Yr, price.Commitments[0].HourlyPrice, 0.00001)
+			} else {
+				assert.Empty(t, price.Commitments)
+			}
+		})
+	}
+
+	preemptiblePrice := regionPrices["n1-standard-2"].PreemptiblePrice
+	assert.Equal(t, 1, len(preemptiblePrice), "the preemptible prices should be set only in the zones the machine type is available in")
+	assert.InDelta(t, 2*0.006655+7.5*0.000892, preemptiblePrice["us-central1-b"], 0.000001)
+
+	_, ok := regionPrices["c2-standard-4"]
+	assert.False(t, ok, "the machine types of a series without rates should not be priced")
+}
+
+func TestRateTable_WithSpotRates(t *testing.T) {
+	rates := newRateTable(t)
+	assert.InDelta(t, 0.006543, rates["us-central1"]["e2"][spot].cpu, 0.000001)
+	assert.InDelta(t, 0.000877, rates["us-central1"]["e2"][spot].ram, 0.000001)
+	_, ok := rates["us-central1"]["e2"][preemptible]
+	assert.False(t, ok, "the Spot VM rates should be recorded separately from the preemptible ones")
+
+	spotRates := make(rateTable)
+	spotRates.set("us-central1", "n1", spot, cpu, 0.005)
+	spotRates.set("us-central1", "n1", spot, memory, 0.0007)
+	spotRates.set("europe-west1", "n1", spot, cpu, 0.006)
+	regionRates := rates.withSpotRates("us-central1", spotRates)
+
+	assert.Equal(t, 1, len(regionRates), "only the rates of the region should be kept")
+	assert.Equal(t, resourceRates{cpu: 0.005, ram: 0.0007}, regionRates["us-central1"]["n1"][spot])
+	assert.Equal(t, rates["us-central1"]["n1"][onDemand], regionRates["us-central1"]["n1"][onDemand])
+	_, ok = regionRates["us-central1"]["e2"][spot]
+	assert.False(t, ok, "the previous Spot VM rates should be replaced")
+	_, ok = rates["us-central1"]["n1"][spot]
+	assert.False(t, ok, "the rate table should not be modified")
+
+	n1 := machineType{name: "n1-standard-1", cpus: 1, memGiB: 3.75}
+	p, ok := regionRates.rate("us-central1", n1, spot)
+	assert.True(t, ok)
+	assert.InDelta(t, 0.005+3.75*0.0007, p, 0.000001)
+	p, ok = regionRates.rate("us-central1", n1, preemptible)
+	assert.True(t, ok, "the preemptible rates should be kept apart from the Spot VM rates")
+	assert.InDelta(t, 0.01, p, 0.00001)
+}
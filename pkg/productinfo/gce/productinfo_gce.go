@@ -2,11 +2,15 @@ package gce
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net/http"
 	"regexp"
+	"sort"
 	"strconv"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/banzaicloud/productinfo/pkg/productinfo"
 	log "github.com/sirupsen/logrus"
@@ -19,6 +23,10 @@ import (
 const (
 	cpu    = "cpu"
 	memory = "memory"
+
+	// spotRatesMaxAge the age of the Spot VM rates they are listed again after, the SKU list of the Compute Engine
+	// service is too large to be listed on every renewal of the short lived prices
+	spotRatesMaxAge = 10 * time.Minute
 )
 
 var regionNames = map[string]string{
@@ -42,56 +50,142 @@ var regionNames = map[string]string{
 
 // GceInfoer encapsulates the data and operations needed to access external resources
 type GceInfoer struct {
-	cbSvc              *billing.APIService
-	computeSvc         *compute.Service
-	projectId          string
-	cpuRegex           *regexp.Regexp
-	resourceGroupRegex *regexp.Regexp
+	cbSvc *billing.APIService
+	// computeSvc is nil if the Compute Engine API credentials are not available, the regions, zones and machine types
+	// are taken from the price list then
+	computeSvc       *compute.Service
+	projectId        string
+	licenseTierRegex *regexp.Regexp
+	priceListSource  PriceListSource
+	// rates, devices and zonesInRegions are kept from the last initialization to price custom machine types and
+	// add-ons, and with the machine types and licensing fees to compose the current prices, priceListTypes are the
+	// machine types of the last price list
+	rates          rateTable
+	devices        deviceRates
+	zonesInRegions map[string][]string
+	machineTypes   []machineType
+	licenseFees    map[string][]licenseFee
+	priceListTypes []*compute.MachineType
+	ratesMu        sync.RWMutex
+	// spotRates the last listed Spot VM rates, renewed independently of the other rates
+	spotRates   rateTable
+	spotUpdated time.Time
+	spotMu      sync.Mutex
+	// qualityIssues the SKUs of the last initialization that couldn't be parsed
+	qualityIssues []productinfo.QualityIssue
+}
+
+// licenseFee the hourly licensing fee of a premium operating system image for a range of vCPUs
+type licenseFee struct {
+	// sharedCore the fee applies to the shared core machine types (f1-micro, g1-small)
+	sharedCore bool
+	minCpus    float64
+	// maxCpus the upper limit of the vCPU range, 0 if there's no limit
+	maxCpus float64
+	// perCpu the fee is charged for every vCPU
+	perCpu bool
+	price  float64
 }
 
-// NewGceInfoer creates a new instance of the infoer
-func NewGceInfoer(apiKey string) (*GceInfoer, error) {
+// NewGceInfoer creates a new instance of the infoer, the Compute Engine API credentials are only required if the prices
+// are not read from a price list
+func NewGceInfoer(cfg Config) (*GceInfoer, error) {
+	ltReg, _ := regexp.Compile("(\\d+) (?:to (\\d+)|or more) VCPU")
+	infoer := &GceInfoer{
+		licenseTierRegex: ltReg,
+		priceListSource:  cfg.PriceListSource,
+	}
+
 	defaultCredential, err := google.FindDefaultCredentials(context.Background(), compute.ComputeScope, billing.CloudPlatformScope)
-	if err != nil {
-		return nil, err
+	if err == nil {
+		var client *http.Client
+		if client, err = google.DefaultClient(context.Background(), compute.ComputeScope, billing.CloudPlatformScope); err == nil {
+			infoer.computeSvc, err = compute.New(client)
+			infoer.projectId = defaultCredential.ProjectID
+		}
 	}
-	client, err := google.DefaultClient(context.Background(), compute.ComputeScope, billing.CloudPlatformScope)
 	if err != nil {
-		return nil, err
+		if cfg.PriceListSource == nil {
+			return nil, err
+		}
+		log.WithError(err).Warn("Compute Engine API is not available, using the machine types of the GCE price list")
 	}
-	computeSvc, err := compute.New(client)
-	if err != nil {
-		return nil, err
+
+	if cfg.PriceListSource == nil {
+		if infoer.cbSvc, err = billing.New(&http.Client{Transport: &transport.APIKey{Key: cfg.ApiKey}}); err != nil {
+			return nil, err
+		}
 	}
+	return infoer, nil
+}
 
-	billingSvc, err := billing.New(&http.Client{
-		Transport: &transport.APIKey{Key: apiKey},
-	})
+// Initialize downloads and parses the SKU list of the Compute Engine service or the price list
+// the prices of the machine types are composed of the vCPU and memory rates of their series
+func (g *GceInfoer) Initialize() (map[string]map[string]productinfo.Price, error) {
+
+	log.Debug("initializing GCE price info")
+
+	zonesInRegions := make(map[string][]string)
+	regions, err := g.GetRegions()
 	if err != nil {
 		return nil, err
 	}
+	for r := range regions {
+		zones, err := g.GetZones(r)
+		if err != nil {
+			return nil, err
+		}
+		zonesInRegions[r] = zones
+	}
 
-	cpuReg, _ := regexp.Compile("\\d+ VCPU")
-	rgReg, _ := regexp.Compile("^[a-z]+\\d+")
+	log.Debugf("queried zones and regions: %v", zonesInRegions)
 
-	return &GceInfoer{
-		cbSvc:              billingSvc,
-		computeSvc:         computeSvc,
-		projectId:          defaultCredential.ProjectID,
-		cpuRegex:           cpuReg,
-		resourceGroupRegex: rgReg,
-	}, nil
-}
+	var rates rateTable
+	var devices deviceRates
+	var licenseFees map[string][]licenseFee
+	var priceListTypes []*compute.MachineType
+	var issues []productinfo.QualityIssue
+	if g.priceListSource != nil {
+		priceList, err := g.priceListSource.GetPriceList()
+		if err != nil {
+			return nil, err
+		}
+		log.Debugf("gce price list version: %s, updated: %s", priceList.Version, priceList.Updated)
+		rates, devices, licenseFees, priceListTypes = priceList.parse(zonesInRegions)
+	} else if rates, devices, licenseFees, issues, err = g.listSkus(); err != nil {
+		return nil, err
+	}
 
-// Initialize downloads and parses the SKU list of the Compute Engine service
-func (g *GceInfoer) Initialize() (map[string]map[string]productinfo.Price, error) {
+	var machineTypes []machineType
+	if g.computeSvc != nil {
+		if machineTypes, err = g.listMachineTypes(); err != nil {
+			return nil, err
+		}
+	} else {
+		for _, mt := range priceListTypes {
+			machineTypes = append(machineTypes, newMachineType(mt))
+		}
+	}
 
-	log.Debug("initializing GCE price info")
-	allPrices := make(map[string]map[string]productinfo.Price)
+	allPrices := rates.prices(machineTypes, zonesInRegions)
+	g.ratesMu.Lock()
+	g.rates, g.devices, g.zonesInRegions, g.priceListTypes = rates, devices, zonesInRegions, priceListTypes
+	g.machineTypes, g.licenseFees, g.qualityIssues = machineTypes, licenseFees, issues
+	g.ratesMu.Unlock()
+	g.spotMu.Lock()
+	g.spotRates, g.spotUpdated = rates, time.Now()
+	g.spotMu.Unlock()
+	addLicenseFees(allPrices, licenseFees)
+
+	log.Debug("finished initializing GCE price info")
+	return allPrices, nil
+}
 
+// computeEngineServiceId returns the id of the Compute Engine service in the Cloud Billing Catalog
+func (g *GceInfoer) computeEngineServiceId() (string, error) {
 	svcList, err := g.cbSvc.Services.List().Do()
 	if err != nil {
-		return nil, err
+		return "", err
 	}
 
 	var compEngId string
@@ -102,67 +196,91 @@ func (g *GceInfoer) Initialize() (map[string]map[string]productinfo.Price, error
 	}
 
 	log.Debugf("gce compute engine service id: %s", compEngId)
+	return compEngId, nil
+}
 
-	zonesInRegions := make(map[string][]string)
-	regions, err := g.GetRegions()
+// listSkus lists the SKUs of the Compute Engine service and returns the machine series and device rates, the
+// licensing fees and the SKUs that couldn't be parsed
+func (g *GceInfoer) listSkus() (rateTable, deviceRates, map[string][]licenseFee, []productinfo.QualityIssue, error) {
+	compEngId, err := g.computeEngineServiceId()
 	if err != nil {
-		return nil, err
+		return nil, nil, nil, nil, err
 	}
-	for r := range regions {
-		zones, err := g.GetZones(r)
-		if err != nil {
-			return nil, err
+
+	rates := make(rateTable)
+	devices := make(deviceRates)
+	licenseFees := make(map[string][]licenseFee)
+	var issues []productinfo.QualityIssue
+	err = g.cbSvc.Services.Skus.List(compEngId).Pages(context.Background(), func(response *billing.ListSkusResponse) error {
+		issues = append(issues, g.addSkus(response.Skus, rates, devices, licenseFees)...)
+		return nil
+	})
+	if err != nil {
+		return nil, nil, nil, nil, err
+	}
+	return rates, devices, licenseFees, issues, nil
+}
+
+// addSkus records the rates and the licensing fees of the SKUs, the SKUs that couldn't be parsed are skipped and
+// returned as quality issues
+func (g *GceInfoer) addSkus(skus []*billing.Sku, rates rateTable, devices deviceRates, licenseFees map[string][]licenseFee) []productinfo.QualityIssue {
+	var issues []productinfo.QualityIssue
+	for _, sku := range skus {
+		switch sku.Category.ResourceFamily {
+		case "License":
+			if os, fee, ok := g.parseLicenseFee(sku); ok {
+				licenseFees[os] = append(licenseFees[os], fee)
+			}
+		case "Compute":
+			if err := rates.add(sku); err != nil {
+				log.WithError(err).Warnf("could not parse the SKU %s", sku.Description)
+				issues = append(issues, unparsableSku(sku, err)...)
+				continue
+			}
+			devices.add(sku)
+		case "Storage":
+			devices.add(sku)
 		}
-		zonesInRegions[r] = zones
 	}
+	return issues
+}
 
-	log.Debugf("queried zones and regions: %v", zonesInRegions)
+// unparsableSku returns the quality issues of a SKU that couldn't be parsed, one for each region of the SKU
+func unparsableSku(sku *billing.Sku, err error) []productinfo.QualityIssue {
+	regions := sku.ServiceRegions
+	if len(regions) == 0 {
+		regions = []string{""}
+	}
+	issues := make([]productinfo.QualityIssue, 0, len(regions))
+	for _, region := range regions {
+		issues = append(issues, productinfo.QualityIssue{Kind: productinfo.UnparsableProduct, Region: region,
+			Subject: sku.Description, Detail: fmt.Sprintf("SKU %s: %s", sku.SkuId, err)})
+	}
+	return issues
+}
 
+// QualityIssues returns the SKUs of the last Initialize call that couldn't be parsed
+func (g *GceInfoer) QualityIssues() []productinfo.QualityIssue {
+	g.ratesMu.RLock()
+	defer g.ratesMu.RUnlock()
+	return g.qualityIssues
+}
+
+// listSpotRates lists the SKUs of the Compute Engine service and returns the Spot VM rates of the machine series
+func (g *GceInfoer) listSpotRates() (rateTable, error) {
+	compEngId, err := g.computeEngineServiceId()
+	if err != nil {
+		return nil, err
+	}
+
+	spotRates := make(rateTable)
 	err = g.cbSvc.Services.Skus.List(compEngId).Pages(context.Background(), func(response *billing.ListSkusResponse) error {
 		for _, sku := range response.Skus {
-			if sku.Category.ResourceFamily != "Compute" {
+			if sku.Category.ResourceFamily != "Compute" || skuUsageType(sku) != spot {
 				continue
-			} else {
-				if strings.Contains(sku.Description, "CPU") {
-
-					vcpus := strings.Split(g.cpuRegex.FindString(sku.Description), " ")[0]
-					resourceGroup := strings.ToLower(sku.Category.ResourceGroup)
-					prefix := g.resourceGroupRegex.FindString(resourceGroup)
-					instanceType := fmt.Sprintf("%s-%s", strings.Join(strings.SplitAfter(resourceGroup, prefix), "-"), vcpus)
-
-					switch instanceType {
-					case "f1-micro-":
-						instanceType = "f1-micro"
-					case "g1-small-1":
-						instanceType = "g1-small"
-					}
-					if len(sku.PricingInfo) != 1 {
-						return fmt.Errorf("pricing info not parsable, %d pricing info entries are returned", len(sku.PricingInfo))
-					}
-					pricingInfo := sku.PricingInfo[0]
-					var priceInUsd float64
-					for _, tr := range pricingInfo.PricingExpression.TieredRates {
-						priceInUsd += float64(tr.UnitPrice.Units) + float64(tr.UnitPrice.Nanos)*1e-9
-					}
-
-					for _, region := range sku.ServiceRegions {
-						if allPrices[region] == nil {
-							allPrices[region] = make(map[string]productinfo.Price)
-						}
-						price := allPrices[region][instanceType]
-						if sku.Category.UsageType == "OnDemand" {
-							price.OnDemandPrice = priceInUsd
-						} else {
-							spotPrice := make(productinfo.SpotPriceInfo)
-							for _, z := range zonesInRegions[region] {
-								spotPrice[z] = priceInUsd
-							}
-							price.SpotPrice = spotPrice
-						}
-						allPrices[region][instanceType] = price
-						log.Debugf("price info added: [region=%s, machinetype=%s, price=%v]", region, instanceType, price)
-					}
-				}
+			}
+			if err := spotRates.add(sku); err != nil {
+				log.WithError(err).Warnf("could not parse the SKU %s", sku.Description)
 			}
 		}
 		return nil
@@ -170,35 +288,133 @@ func (g *GceInfoer) Initialize() (map[string]map[string]productinfo.Price, error
 	if err != nil {
 		return nil, err
 	}
+	return spotRates, nil
+}
 
-	log.Debug("finished initializing GCE price info")
-	return allPrices, nil
+// getSpotRates returns the Spot VM rates, they are listed again if they are older than spotRatesMaxAge
+func (g *GceInfoer) getSpotRates() (rateTable, error) {
+	g.spotMu.Lock()
+	defer g.spotMu.Unlock()
+	if g.spotRates != nil && time.Since(g.spotUpdated) < spotRatesMaxAge {
+		return g.spotRates, nil
+	}
+	spotRates, err := g.listSpotRates()
+	if err != nil {
+		return nil, err
+	}
+	g.spotRates, g.spotUpdated = spotRates, time.Now()
+	return spotRates, nil
 }
 
-// GetAttributeValues gets the AttributeValues for the given attribute name
-// Queries the Google Cloud Compute API's machine type list endpoint
-func (g *GceInfoer) GetAttributeValues(attribute string) (productinfo.AttrValues, error) {
+// lastSpotRates returns the last listed Spot VM rates without renewing them
+func (g *GceInfoer) lastSpotRates() rateTable {
+	g.spotMu.Lock()
+	defer g.spotMu.Unlock()
+	return g.spotRates
+}
 
-	log.Debugf("getting %s values", attribute)
+// parseLicenseFee parses the licensing fee SKUs of the Windows Server, RHEL and SLES images
+// the fees of the special editions (SQL Server, SAP) and the memory based fees are skipped
+func (g *GceInfoer) parseLicenseFee(sku *billing.Sku) (string, licenseFee, bool) {
+	var fee licenseFee
+	desc := sku.Description
+	if !strings.HasPrefix(desc, "Licensing Fee for") || strings.Contains(desc, "SQL") || strings.Contains(desc, "SAP") ||
+		strings.Contains(desc, "RAM cost") || len(sku.PricingInfo) != 1 {
+		return "", fee, false
+	}
+	var os string
+	switch {
+	case strings.Contains(desc, "Windows Server"):
+		os = productinfo.Windows
+	case strings.Contains(desc, "RHEL") || strings.Contains(desc, "Red Hat"):
+		os = productinfo.Rhel
+	case strings.Contains(desc, "SLES") || strings.Contains(desc, "SUSE"):
+		os = productinfo.Suse
+	default:
+		return "", fee, false
+	}
 
-	values := make(productinfo.AttrValues, 0)
-	valueSet := make(map[productinfo.AttrValue]interface{})
+	fee.sharedCore = strings.Contains(desc, "f1-micro") || strings.Contains(desc, "g1-small")
+	fee.perCpu = strings.Contains(desc, "CPU cost") && !fee.sharedCore
+	if tier := g.licenseTierRegex.FindStringSubmatch(desc); tier != nil {
+		fee.minCpus, _ = strconv.ParseFloat(tier[1], 64)
+		fee.maxCpus, _ = strconv.ParseFloat(tier[2], 64)
+	}
+	fee.price = unitPrice(sku.PricingInfo[0])
+	return os, fee, true
+}
+
+// addLicenseFees adds the prices of the premium operating systems, the licensing fees are added to the Linux prices
+// both for on demand and preemptible instances
+func addLicenseFees(allPrices map[string]map[string]productinfo.Price, licenseFees map[string][]licenseFee) {
+	for _, prices := range allPrices {
+		for instanceType, price := range prices {
+			sharedCore := instanceType == "f1-micro" || instanceType == "g1-small"
+			parts := strings.Split(instanceType, "-")
+			cpus, err := strconv.ParseFloat(parts[len(parts)-1], 64)
+			if err != nil && !sharedCore {
+				continue
+			}
+			for os, fees := range licenseFees {
+				fee, ok := licenseFeeFor(fees, sharedCore, cpus)
+				if !ok {
+					continue
+				}
+				if price.OnDemandPrice > 0 {
+					price.SetOsPrice(os, price.OnDemandPrice+fee)
+				}
+				if len(price.SpotPrice) > 0 {
+					price.SetOsSpotPrice(os, withFee(price.SpotPrice, fee))
+				}
+				if len(price.PreemptiblePrice) > 0 {
+					price.SetOsPreemptiblePrice(os, withFee(price.PreemptiblePrice, fee))
+				}
+			}
+			prices[instanceType] = price
+		}
+	}
+}
 
+// withFee returns the zone prices with the licensing fee added
+func withFee(prices productinfo.SpotPriceInfo, fee float64) productinfo.SpotPriceInfo {
+	feePrices := make(productinfo.SpotPriceInfo, len(prices))
+	for zone, p := range prices {
+		feePrices[zone] = p + fee
+	}
+	return feePrices
+}
+
+// licenseFeeFor returns the hourly licensing fee of a machine type with the given number of vCPUs
+func licenseFeeFor(fees []licenseFee, sharedCore bool, cpus float64) (float64, bool) {
+	for _, fee := range fees {
+		if fee.sharedCore != sharedCore {
+			continue
+		}
+		if !sharedCore && (cpus < fee.minCpus || (fee.maxCpus > 0 && cpus > fee.maxCpus)) {
+			continue
+		}
+		if fee.perCpu {
+			return fee.price * cpus, true
+		}
+		return fee.price, true
+	}
+	return 0, false
+}
+
+// listMachineTypes returns the specifications of the machine types available in any of the zones with the zones they
+// are available in
+func (g *GceInfoer) listMachineTypes() ([]machineType, error) {
+	machineTypes := make(map[string]machineType)
 	err := g.computeSvc.MachineTypes.AggregatedList(g.projectId).Pages(context.TODO(), func(allMts *compute.MachineTypeAggregatedList) error {
-		for _, scope := range allMts.Items {
+		for scopeName, scope := range allMts.Items {
+			zone := strings.TrimPrefix(scopeName, "zones/")
 			for _, mt := range scope.MachineTypes {
-				switch attribute {
-				case cpu:
-					valueSet[productinfo.AttrValue{
-						Value:    float64(mt.GuestCpus),
-						StrValue: fmt.Sprintf("%v", mt.GuestCpus),
-					}] = ""
-				case memory:
-					valueSet[productinfo.AttrValue{
-						Value:    float64(mt.MemoryMb) / 1024,
-						StrValue: fmt.Sprintf("%v", mt.MemoryMb),
-					}] = ""
+				spec, ok := machineTypes[mt.Name]
+				if !ok {
+					spec = newMachineType(mt)
 				}
+				spec.zones = append(spec.zones, zone)
+				machineTypes[mt.Name] = spec
 			}
 		}
 		return nil
@@ -206,6 +422,69 @@ func (g *GceInfoer) GetAttributeValues(attribute string) (productinfo.AttrValues
 	if err != nil {
 		return nil, fmt.Errorf("unable to list machine types: %v", err.Error())
 	}
+	list := make([]machineType, 0, len(machineTypes))
+	for _, mt := range machineTypes {
+		list = append(list, mt)
+	}
+	return list, nil
+}
+
+// SustainedUseRates returns the rates of the on demand price charged in the quarters of a month
+// the N1 family (including the shared core and memory optimized types) is discounted up to 30%, the N2, N2D, C2 and M2
+// families up to 20%, other families are not discounted
+func (g *GceInfoer) SustainedUseRates(instanceType string) []float64 {
+	switch strings.Split(instanceType, "-")[0] {
+	case "n1", "f1", "g1", "m1":
+		return []float64{1, 0.8, 0.6, 0.4}
+	case "n2", "n2d", "c2", "m2":
+		return []float64{1, 0.8678, 0.733, 0.6}
+	}
+	return nil
+}
+
+// GetAttributeValues gets the AttributeValues for the given attribute name
+// Queries the Google Cloud Compute API's machine type list endpoint, or uses the machine types of the price list if
+// the API is not available
+func (g *GceInfoer) GetAttributeValues(attribute string) (productinfo.AttrValues, error) {
+
+	log.Debugf("getting %s values", attribute)
+
+	values := make(productinfo.AttrValues, 0)
+	valueSet := make(map[productinfo.AttrValue]interface{})
+	addValues := func(machineTypes []*compute.MachineType) {
+		for _, mt := range machineTypes {
+			switch attribute {
+			case cpu:
+				valueSet[productinfo.AttrValue{
+					Value:    float64(mt.GuestCpus),
+					StrValue: fmt.Sprintf("%v", mt.GuestCpus),
+				}] = ""
+			case memory:
+				valueSet[productinfo.AttrValue{
+					Value:    float64(mt.MemoryMb) / 1024,
+					StrValue: fmt.Sprintf("%v", mt.MemoryMb),
+				}] = ""
+			}
+		}
+	}
+
+	if g.computeSvc == nil {
+		machineTypes, err := g.getPriceListTypes()
+		if err != nil {
+			return nil, err
+		}
+		addValues(machineTypes)
+	} else {
+		err := g.computeSvc.MachineTypes.AggregatedList(g.projectId).Pages(context.TODO(), func(allMts *compute.MachineTypeAggregatedList) error {
+			for _, scope := range allMts.Items {
+				addValues(scope.MachineTypes)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("unable to list machine types: %v", err.Error())
+		}
+	}
 
 	for attr := range valueSet {
 		values = append(values, attr)
@@ -216,48 +495,86 @@ func (g *GceInfoer) GetAttributeValues(attribute string) (productinfo.AttrValues
 }
 
 // GetProducts retrieves the available virtual machines based on the arguments provided
-// Queries the Google Cloud Compute API's machine type list endpoint and CloudBilling's sku list endpoint
+// The machine types listed by the last Initialize call are used, the zones of the virtual machines are the zones of the
+// region their machine type is available in
 func (g *GceInfoer) GetProducts(regionId string) ([]productinfo.VmInfo, error) {
 	log.Debugf("getting product info [region=%s]", regionId)
-	var vms []productinfo.VmInfo
-	var ntwPerf string
 	zones, err := g.GetZones(regionId)
 	if err != nil {
 		return nil, err
 	}
-	// TODO: check if all machine types are available in every regions??
-	err = g.computeSvc.MachineTypes.List(g.projectId, zones[0]).Pages(context.TODO(), func(allMts *compute.MachineTypeList) error {
-		for _, mt := range allMts.Items {
-			if mt.GuestCpus < 1 {
-				// minimum 1 Gbps network performance for each virtual machine
-				ntwPerf = strconv.Itoa(1)
-			} else if mt.GuestCpus > 8 {
-				// theoretical maximum of 16 Gbps for each virtual machine
-				ntwPerf = strconv.Itoa(16)
-			} else {
-				// each vCPU has a 2 Gbps egress cap for peak performance
-				ntwPerf = strconv.Itoa(int(mt.GuestCpus * 2))
-			}
-			vms = append(vms, productinfo.VmInfo{
-				Type:    mt.Name,
-				Cpus:    float64(mt.GuestCpus),
-				Mem:     float64(mt.MemoryMb) / 1024,
-				NtwPerf: ntwPerf,
-			})
-		}
-		return nil
-	})
-	if err != nil {
-		return nil, err
+	if len(zones) == 0 {
+		return nil, fmt.Errorf("no zones found in region %s", regionId)
+	}
+	g.ratesMu.RLock()
+	machineTypes := g.machineTypes
+	g.ratesMu.RUnlock()
+	if machineTypes == nil {
+		return nil, errors.New("gce machine types not yet cached")
 	}
+	vms := newVmInfos(machineTypes, zones)
 	log.Debugf("found vms: %#v", vms)
 	return vms, nil
 }
 
+// newVmInfos creates the virtual machines of the machine types available in the zones of a region sorted by type, the
+// zones of a virtual machine are sorted as well
+func newVmInfos(machineTypes []machineType, regionZones []string) []productinfo.VmInfo {
+	sortedZones := append([]string{}, regionZones...)
+	sort.Strings(sortedZones)
+
+	vms := make([]productinfo.VmInfo, 0, len(machineTypes))
+	for _, mt := range machineTypes {
+		zones := mt.availableZones(sortedZones)
+		if len(zones) == 0 {
+			continue
+		}
+		var ntwPerf string
+		if mt.cpus < 1 {
+			// minimum 1 Gbps network performance for each virtual machine
+			ntwPerf = strconv.Itoa(1)
+		} else if mt.cpus > 8 {
+			// theoretical maximum of 16 Gbps for each virtual machine
+			ntwPerf = strconv.Itoa(16)
+		} else {
+			// each vCPU has a 2 Gbps egress cap for peak performance
+			ntwPerf = strconv.Itoa(int(mt.cpus * 2))
+		}
+		vms = append(vms, productinfo.VmInfo{
+			Type:    mt.name,
+			Cpus:    mt.cpus,
+			Mem:     mt.memGiB,
+			NtwPerf: ntwPerf,
+			Zones:   zones,
+		})
+	}
+	sort.Slice(vms, func(i, j int) bool {
+		return vms[i].Type < vms[j].Type
+	})
+	return vms
+}
+
+// getPriceListTypes returns the machine types of the last price list
+func (g *GceInfoer) getPriceListTypes() ([]*compute.MachineType, error) {
+	g.ratesMu.RLock()
+	defer g.ratesMu.RUnlock()
+	if g.priceListTypes == nil {
+		return nil, fmt.Errorf("price list machine types not yet cached")
+	}
+	return g.priceListTypes, nil
+}
+
 // GetRegions returns a map with available regions transforms the api representation into a "plain" map
+// the known regions are returned if the Compute Engine API is not available
 func (g *GceInfoer) GetRegions() (map[string]string, error) {
 	log.Debugf("getting regions")
 	regionIdMap := make(map[string]string)
+	if g.computeSvc == nil {
+		for region, displayName := range regionNames {
+			regionIdMap[region] = displayName
+		}
+		return regionIdMap, nil
+	}
 	regionList, err := g.computeSvc.Regions.List(g.projectId).Do()
 	if err != nil {
 		return nil, err
@@ -278,6 +595,9 @@ func (g *GceInfoer) GetRegions() (map[string]string, error) {
 func (g *GceInfoer) GetZones(region string) ([]string, error) {
 	log.Debugf("getting zones in region %s", region)
 	zones := make([]string, 0)
+	if g.computeSvc == nil {
+		return append(zones, regionZones[region]...), nil
+	}
 	err := g.computeSvc.Zones.List(g.projectId).Pages(context.TODO(), func(zoneList *compute.ZoneList) error {
 		for _, z := range zoneList.Items {
 			s := strings.Split(z.Region, "/")
@@ -294,20 +614,38 @@ func (g *GceInfoer) GetZones(region string) ([]string, error) {
 	return zones, nil
 }
 
-// HasShortLivedPriceInfo - Google Cloud has static prices for preemptible instances as well
+// HasShortLivedPriceInfo - Google Cloud Spot VM prices change over time, they are renewed from the Cloud Billing API,
+// the prices of a price list are static
 func (g *GceInfoer) HasShortLivedPriceInfo() bool {
-	return false
+	return g.cbSvc != nil
 }
 
-// GetCurrentPrices retrieves all the spot prices in a region
+// GetCurrentPrices retrieves the current Spot VM prices of a region in each zone, the other prices are renewed by
+// Initialize
 func (g *GceInfoer) GetCurrentPrices(region string) (map[string]productinfo.Price, error) {
 	log.Debugf("getting current prices in region %s", region)
-	allPrices, err := g.Initialize()
-	if err != nil {
-		return nil, err
+	g.ratesMu.RLock()
+	rates, machineTypes, licenseFees, zones := g.rates, g.machineTypes, g.licenseFees, g.zonesInRegions[region]
+	g.ratesMu.RUnlock()
+	if rates == nil {
+		return nil, errors.New("gce rates not yet cached")
+	}
+
+	spotRates := rates
+	if g.cbSvc != nil {
+		var err error
+		if spotRates, err = g.getSpotRates(); err != nil {
+			return nil, err
+		}
+	}
+	allPrices := rates.withSpotRates(region, spotRates).prices(machineTypes, map[string][]string{region: zones})
+	addLicenseFees(allPrices, licenseFees)
+	prices := make(map[string]productinfo.Price, len(allPrices[region]))
+	for machineType, price := range allPrices[region] {
+		prices[machineType] = price.SpotPrices()
 	}
 	log.Debugf("found prices in region %s", region)
-	return allPrices[region], nil
+	return prices, nil
 }
 
 // GetMemoryAttrName returns the provider representation of the memory attribute
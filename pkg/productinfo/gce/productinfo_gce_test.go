@@ -0,0 +1,109 @@
+package gce
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/banzaicloud/productinfo/pkg/productinfo"
+	"github.com/stretchr/testify/assert"
+	billing "google.golang.org/api/cloudbilling/v1"
+)
+
+func TestNewVmInfos(t *testing.T) {
+	tests := []struct {
+		name         string
+		machineTypes []machineType
+		zones        []string
+		check        func(vms []productinfo.VmInfo)
+	}{
+		{
+			name: "machine types available in some of the zones",
+			machineTypes: []machineType{
+				{name: "n1-standard-1", cpus: 1, memGiB: 3.75, zones: []string{"us-central1-c", "us-central1-a", "europe-west1-b"}},
+				{name: "m1-ultramem-40", cpus: 40, memGiB: 961, zones: []string{"us-central1-c"}},
+				{name: "e2-micro", cpus: 2, memGiB: 1, zones: []string{"us-central1-a"}},
+				{name: "c2-standard-4", cpus: 4, memGiB: 16, zones: []string{"europe-west1-b"}},
+			},
+			zones: []string{"us-central1-c", "us-central1-a", "us-central1-b"},
+			check: func(vms []productinfo.VmInfo) {
+				assert.Equal(t, []productinfo.VmInfo{
+					{Type: "e2-micro", Cpus: 2, Mem: 1, NtwPerf: "4", Zones: []string{"us-central1-a"}},
+					{Type: "m1-ultramem-40", Cpus: 40, Mem: 961, NtwPerf: "16", Zones: []string{"us-central1-c"}},
+					{Type: "n1-standard-1", Cpus: 1, Mem: 3.75, NtwPerf: "2", Zones: []string{"us-central1-a", "us-central1-c"}},
+				}, vms)
+			},
+		},
+		{
+			name:         "machine types without known availability",
+			machineTypes: []machineType{{name: "f1-micro", cpus: 1, memGiB: 0.6}},
+			zones:        []string{"us-central1-b", "us-central1-a"},
+			check: func(vms []productinfo.VmInfo) {
+				assert.Equal(t, []productinfo.VmInfo{
+					{Type: "f1-micro", Cpus: 1, Mem: 0.6, NtwPerf: "2", Zones: []string{"us-central1-a", "us-central1-b"}},
+				}, vms, "the machine types should be listed in every zone of the region")
+			},
+		},
+		{
+			name:         "no zones",
+			machineTypes: []machineType{{name: "n1-standard-1", cpus: 1, memGiB: 3.75, zones: []string{"us-central1-a"}}},
+			check: func(vms []productinfo.VmInfo) {
+				assert.Empty(t, vms)
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			test.check(newVmInfos(test.machineTypes, test.zones))
+		})
+	}
+}
+
+func TestGceInfoer_GetCurrentPrices(t *testing.T) {
+	cbSvc, err := billing.New(http.DefaultClient)
+	assert.Nil(t, err, "the error should be nil")
+	spotRates := make(rateTable)
+	spotRates.set("us-central1", "n1", spot, cpu, 0.005)
+	spotRates.set("us-central1", "n1", spot, memory, 0.0007)
+	g := &GceInfoer{
+		cbSvc: cbSvc,
+		rates: newRateTable(t),
+		machineTypes: []machineType{
+			{name: "n1-standard-1", cpus: 1, memGiB: 3.75},
+			{name: "n1-standard-2", cpus: 2, memGiB: 7.5, zones: []string{"us-central1-b"}},
+			{name: "e2-standard-2", cpus: 2, memGiB: 8},
+		},
+		licenseFees:    map[string][]licenseFee{productinfo.Rhel: {{price: 0.06}}},
+		zonesInRegions: map[string][]string{"us-central1": {"us-central1-a", "us-central1-b"}},
+		// the Spot VM rates are fresh, they're not listed again
+		spotRates:   spotRates,
+		spotUpdated: time.Now(),
+	}
+	assert.True(t, g.HasShortLivedPriceInfo())
+
+	prices, err := g.GetCurrentPrices("us-central1")
+	assert.Nil(t, err, "the error should be nil")
+	assert.Equal(t, float64(0), prices["n1-standard-1"].OnDemandPrice, "only the spot prices should be renewed")
+	assert.Equal(t, 2, len(prices["n1-standard-1"].SpotPrice))
+	assert.InDelta(t, 0.005+3.75*0.0007, prices["n1-standard-1"].SpotPrice["us-central1-a"], 0.000001)
+	assert.InDelta(t, 0.005+3.75*0.0007+0.06, prices["n1-standard-1"].ForOs(productinfo.Rhel).SpotPrice["us-central1-a"], 0.000001)
+	assert.Equal(t, []string{"us-central1-b"}, keys(prices["n1-standard-2"].SpotPrice), "the Spot VM prices should be set only in the available zones")
+	assert.Empty(t, prices["e2-standard-2"].SpotPrice, "the Spot VM rates of the last Initialize should be replaced")
+	assert.Empty(t, prices["n1-standard-1"].Commitments, "only the spot prices should be renewed")
+
+	prices, err = g.GetCurrentPrices("europe-west1")
+	assert.Nil(t, err, "the error should be nil")
+	assert.Empty(t, prices)
+
+	_, err = (&GceInfoer{}).GetCurrentPrices("us-central1")
+	assert.EqualError(t, err, "gce rates not yet cached")
+}
+
+// keys returns the zones of the spot prices
+func keys(spotPrice productinfo.SpotPriceInfo) []string {
+	var zones []string
+	for zone := range spotPrice {
+		zones = append(zones, zone)
+	}
+	return zones
+}
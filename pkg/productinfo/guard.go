@@ -0,0 +1,335 @@
+package productinfo
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// ProductsCatalog the catalog of the instance types of a region, renewed by renewVms
+	ProductsCatalog = "products"
+	// PricesCatalog the catalog of the prices of a region, renewed by Initialize
+	PricesCatalog = "prices"
+
+	// maxReportedViolations the maximum number of violations kept for a rejected catalog
+	maxReportedViolations = 50
+)
+
+var (
+	// CatalogRejectionsTotalCounter collects metrics for the prometheus
+	CatalogRejectionsTotalCounter = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "productinfo",
+		Name:      "catalog_rejections_total",
+		Help:      "Total number of catalogs rejected by the sanity guard, partitioned by provider, region and catalog",
+	},
+		[]string{"provider", "region", "catalog"},
+	)
+	// CatalogRejectedGauge collects metrics for the prometheus
+	CatalogRejectedGauge = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "productinfo",
+		Name:      "catalog_rejected",
+		Help:      "1 if the latest catalog was rejected by the sanity guard, partitioned by provider, region and catalog",
+	},
+		[]string{"provider", "region", "catalog"},
+	)
+)
+
+// GuardRules the validation rules a new catalog must satisfy before it's published, zero values disable the rules
+type GuardRules struct {
+	// MaxShrink the maximum ratio of the instance types (or prices) of a region that may disappear in a renewal
+	MaxShrink float64
+	// MaxPriceDelta the maximum relative change of an on demand price in a renewal
+	MaxPriceDelta float64
+	// RejectZeroOnDemand rejects product catalogs with instance types without an on demand price
+	RejectZeroOnDemand bool
+	// RequiredAttributes the attributes every instance type must have: cpu, memory, gpu or ntwPerf
+	RequiredAttributes []string
+	// RebaselineAfter the number of consecutive rejections with the same violations a catalog is accepted after as the
+	// new baseline, e.g. when the provider retired instance types for good
+	RebaselineAfter int
+}
+
+// GuardAttributes the attributes that can be required by the guard rules
+var GuardAttributes = []string{Cpu, Memory, "gpu", "ntwPerf"}
+
+// Validate checks whether the rules are consistent
+func (r GuardRules) Validate() error {
+	if r.MaxShrink < 0 || r.MaxShrink >= 1 {
+		return fmt.Errorf("the maximum shrink must be between 0 and 1")
+	}
+	if r.MaxPriceDelta < 0 {
+		return fmt.Errorf("the maximum price delta must not be negative")
+	}
+	if r.RebaselineAfter < 0 {
+		return fmt.Errorf("the number of rejections before rebaselining must not be negative")
+	}
+	for _, attr := range r.RequiredAttributes {
+		valid := false
+		for _, a := range GuardAttributes {
+			valid = valid || a == attr
+		}
+		if !valid {
+			return fmt.Errorf("unsupported required attribute: %s", attr)
+		}
+	}
+	return nil
+}
+
+// RejectedCatalog a catalog that violated the guard rules, kept aside for inspection
+type RejectedCatalog struct {
+	Provider   string           `json:"provider"`
+	Region     string           `json:"region"`
+	Catalog    string           `json:"catalog"`
+	Time       time.Time        `json:"time"`
+	Violations []string         `json:"violations"`
+	Vms        []VmInfo         `json:"vms,omitempty"`
+	Prices     map[string]Price `json:"prices,omitempty"`
+}
+
+// CatalogStatus the result of the last validation of a catalog
+type CatalogStatus struct {
+	Provider string `json:"provider"`
+	Region   string `json:"region"`
+	Catalog  string `json:"catalog"`
+	// Accepted the time the last valid catalog was published
+	Accepted time.Time `json:"accepted,omitempty"`
+	// Rejected whether the latest catalog was rejected
+	Rejected   bool      `json:"rejected"`
+	RejectedAt time.Time `json:"rejectedAt,omitempty"`
+	Violations []string  `json:"violations,omitempty"`
+	// Rejections the number of consecutive rejections with the same violations
+	Rejections int `json:"rejections,omitempty"`
+}
+
+type catalogKey struct {
+	provider string
+	region   string
+	catalog  string
+}
+
+// CatalogGuard validates the renewed catalogs against the last accepted ones
+type CatalogGuard struct {
+	mu       sync.RWMutex
+	rules    GuardRules
+	vms      map[catalogKey][]VmInfo
+	prices   map[catalogKey]map[string]Price
+	status   map[catalogKey]*CatalogStatus
+	rejected map[catalogKey]RejectedCatalog
+}
+
+// NewCatalogGuard creates a new catalog guard with the given rules
+func NewCatalogGuard(rules GuardRules) *CatalogGuard {
+	return &CatalogGuard{
+		rules:    rules,
+		vms:      make(map[catalogKey][]VmInfo),
+		prices:   make(map[catalogKey]map[string]Price),
+		status:   make(map[catalogKey]*CatalogStatus),
+		rejected: make(map[catalogKey]RejectedCatalog),
+	}
+}
+
+// SetRules replaces the validation rules
+func (g *CatalogGuard) SetRules(rules GuardRules) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.rules = rules
+}
+
+// CheckProducts validates the instance types of a region, onDemand returns the separately stored on demand price of a type
+// it returns the last accepted catalog and false if the new one is rejected
+func (g *CatalogGuard) CheckProducts(provider string, region string, vms []VmInfo, onDemand func(instanceType string) float64) ([]VmInfo, bool) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	key := catalogKey{provider, region, ProductsCatalog}
+	previous, hasPrevious := g.vms[key]
+
+	var violations []string
+	if hasPrevious {
+		violations = append(violations, g.checkShrink(len(previous), len(vms))...)
+	}
+	prevPrices := make(map[string]float64, len(previous))
+	for _, vm := range previous {
+		prevPrices[vm.Type] = vm.OnDemandPrice
+	}
+	for _, vm := range vms {
+		price := vm.OnDemandPrice
+		if price <= 0 {
+			price = onDemand(vm.Type)
+		}
+		if g.rules.RejectZeroOnDemand && price <= 0 {
+			violations = append(violations, fmt.Sprintf("%s has no on demand price", vm.Type))
+		}
+		if vm.OnDemandPrice > 0 {
+			violations = append(violations, g.checkPriceDelta(vm.Type, prevPrices[vm.Type], vm.OnDemandPrice)...)
+		}
+		for _, attr := range g.rules.RequiredAttributes {
+			if !hasAttribute(vm, attr) {
+				violations = append(violations, fmt.Sprintf("%s has no %s attribute", vm.Type, attr))
+			}
+		}
+	}
+
+	if g.reject(key, violations, RejectedCatalog{Vms: vms}) {
+		return previous, false
+	}
+	g.vms[key] = vms
+	return vms, true
+}
+
+// CheckPrices validates the prices of a region
+// it returns the last accepted prices and false if the new ones are rejected
+func (g *CatalogGuard) CheckPrices(provider string, region string, prices map[string]Price) (map[string]Price, bool) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	key := catalogKey{provider, region, PricesCatalog}
+	previous, hasPrevious := g.prices[key]
+
+	var violations []string
+	if hasPrevious {
+		violations = append(violations, g.checkShrink(len(previous), len(prices))...)
+	}
+	types := make([]string, 0, len(prices))
+	for t := range prices {
+		types = append(types, t)
+	}
+	sort.Strings(types)
+	for _, t := range types {
+		violations = append(violations, g.checkPriceDelta(t, previous[t].OnDemandPrice, prices[t].OnDemandPrice)...)
+	}
+
+	if g.reject(key, violations, RejectedCatalog{Prices: prices}) {
+		return previous, false
+	}
+	g.prices[key] = prices
+	return prices, true
+}
+
+// Status returns the validation status of every catalog ordered by provider, region and catalog
+func (g *CatalogGuard) Status() []CatalogStatus {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	status := make([]CatalogStatus, 0, len(g.status))
+	for _, s := range g.status {
+		status = append(status, *s)
+	}
+	sort.Slice(status, func(i, j int) bool {
+		a, b := status[i], status[j]
+		if a.Provider != b.Provider {
+			return a.Provider < b.Provider
+		}
+		if a.Region != b.Region {
+			return a.Region < b.Region
+		}
+		return a.Catalog < b.Catalog
+	})
+	return status
+}
+
+// Rejected returns the last rejected catalogs of a region
+func (g *CatalogGuard) Rejected(provider string, region string) []RejectedCatalog {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	rejected := make([]RejectedCatalog, 0)
+	for _, catalog := range []string{PricesCatalog, ProductsCatalog} {
+		if rc, ok := g.rejected[catalogKey{provider, region, catalog}]; ok {
+			rejected = append(rejected, rc)
+		}
+	}
+	return rejected
+}
+
+func (g *CatalogGuard) checkShrink(previous int, current int) []string {
+	if g.rules.MaxShrink <= 0 || previous == 0 || current >= previous {
+		return nil
+	}
+	if shrink := float64(previous-current) / float64(previous); shrink > g.rules.MaxShrink {
+		return []string{fmt.Sprintf("catalog shrank by %.1f%% from %d to %d entries", shrink*100, previous, current)}
+	}
+	return nil
+}
+
+func (g *CatalogGuard) checkPriceDelta(instanceType string, previous float64, current float64) []string {
+	if g.rules.MaxPriceDelta <= 0 || previous <= 0 || current <= 0 {
+		return nil
+	}
+	if delta := math.Abs(current-previous) / previous; delta > g.rules.MaxPriceDelta {
+		return []string{fmt.Sprintf("on demand price of %s changed by %.1f%% from %v to %v", instanceType, delta*100, previous, current)}
+	}
+	return nil
+}
+
+// reject records the result of a validation, it returns true if the catalog is rejected
+func (g *CatalogGuard) reject(key catalogKey, violations []string, catalog RejectedCatalog) bool {
+	status, ok := g.status[key]
+	if !ok {
+		status = &CatalogStatus{Provider: key.provider, Region: key.region, Catalog: key.catalog}
+		g.status[key] = status
+	}
+	now := time.Now()
+	count := len(violations)
+	if len(violations) > maxReportedViolations {
+		violations = append(violations[:maxReportedViolations], fmt.Sprintf("%d more violations", len(violations)-maxReportedViolations))
+	}
+	rejections := 1
+	if status.Rejected && equalViolations(status.Violations, violations) {
+		rejections = status.Rejections + 1
+	}
+	if len(violations) > 0 && g.rules.RebaselineAfter > 0 && rejections > g.rules.RebaselineAfter {
+		log.Warnf("%s catalog of %s/%s accepted as the new baseline after %d rejections, first violation: %s",
+			key.catalog, key.provider, key.region, g.rules.RebaselineAfter, violations[0])
+		violations = nil
+	}
+	if len(violations) == 0 {
+		status.Accepted = now
+		status.Rejected = false
+		status.Violations = nil
+		status.Rejections = 0
+		CatalogRejectedGauge.WithLabelValues(key.provider, key.region, key.catalog).Set(0)
+		return false
+	}
+
+	log.Warnf("%s catalog of %s/%s rejected, %d rule violations, first: %s", key.catalog, key.provider, key.region,
+		count, violations[0])
+	catalog.Provider, catalog.Region, catalog.Catalog, catalog.Time, catalog.Violations = key.provider, key.region, key.catalog, now, violations
+	g.rejected[key] = catalog
+	status.Rejected = true
+	status.RejectedAt = now
+	status.Violations = violations
+	status.Rejections = rejections
+	CatalogRejectionsTotalCounter.WithLabelValues(key.provider, key.region, key.catalog).Inc()
+	CatalogRejectedGauge.WithLabelValues(key.provider, key.region, key.catalog).Set(1)
+	return true
+}
+
+// equalViolations returns whether two validations found the same violations
+func equalViolations(a []string, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func hasAttribute(vm VmInfo, attr string) bool {
+	switch attr {
+	case Cpu:
+		return vm.Cpus > 0
+	case Memory:
+		return vm.Mem > 0
+	case "gpu":
+		return vm.Gpus > 0
+	case "ntwPerf":
+		return vm.NtwPerf != ""
+	}
+	return true
+}
@@ -0,0 +1,170 @@
+package productinfo
+
+import (
+	"testing"
+	"time"
+
+	"github.com/patrickmn/go-cache"
+	"github.com/stretchr/testify/assert"
+)
+
+func noOnDemandPrice(instanceType string) float64 {
+	return 0
+}
+
+func TestCatalogGuard_CheckProducts(t *testing.T) {
+	baseline := []VmInfo{
+		{Type: "m5.large", Cpus: 2, Mem: 8, OnDemandPrice: 0.1},
+		{Type: "m5.xlarge", Cpus: 4, Mem: 16, OnDemandPrice: 0.2},
+		{Type: "c5.large", Cpus: 2, Mem: 4, OnDemandPrice: 0.09},
+		{Type: "c5.xlarge", Cpus: 4, Mem: 8, OnDemandPrice: 0.18},
+	}
+	tests := []struct {
+		name     string
+		rules    GuardRules
+		vms      []VmInfo
+		onDemand func(instanceType string) float64
+		check    func(vms []VmInfo, accepted bool, status []CatalogStatus)
+	}{
+		{
+			name:     "catalog within the limits accepted",
+			rules:    GuardRules{MaxShrink: 0.5, MaxPriceDelta: 0.5, RejectZeroOnDemand: true, RequiredAttributes: []string{Cpu, Memory}},
+			vms:      baseline[1:],
+			onDemand: noOnDemandPrice,
+			check: func(vms []VmInfo, accepted bool, status []CatalogStatus) {
+				assert.True(t, accepted)
+				assert.Equal(t, 3, len(vms))
+				assert.False(t, status[0].Rejected)
+			},
+		},
+		{
+			name:     "shrunk catalog rejected",
+			rules:    GuardRules{MaxShrink: 0.5},
+			vms:      baseline[:1],
+			onDemand: noOnDemandPrice,
+			check: func(vms []VmInfo, accepted bool, status []CatalogStatus) {
+				assert.False(t, accepted)
+				assert.Equal(t, baseline, vms, "the last accepted catalog should be returned")
+				assert.True(t, status[0].Rejected)
+				assert.Equal(t, []string{"catalog shrank by 75.0% from 4 to 1 entries"}, status[0].Violations)
+			},
+		},
+		{
+			name:     "price jump rejected",
+			rules:    GuardRules{MaxPriceDelta: 0.5},
+			vms:      []VmInfo{{Type: "m5.large", Cpus: 2, Mem: 8, OnDemandPrice: 1}},
+			onDemand: noOnDemandPrice,
+			check: func(vms []VmInfo, accepted bool, status []CatalogStatus) {
+				assert.False(t, accepted)
+				assert.Equal(t, []string{"on demand price of m5.large changed by 900.0% from 0.1 to 1"}, status[0].Violations)
+			},
+		},
+		{
+			name:  "zero prices are looked up before rejected",
+			rules: GuardRules{RejectZeroOnDemand: true},
+			vms:   []VmInfo{{Type: "m5.large", Cpus: 2, Mem: 8}, {Type: "m5.xlarge", Cpus: 4, Mem: 16}},
+			onDemand: func(instanceType string) float64 {
+				if instanceType == "m5.large" {
+					return 0.1
+				}
+				return 0
+			},
+			check: func(vms []VmInfo, accepted bool, status []CatalogStatus) {
+				assert.False(t, accepted)
+				assert.Equal(t, []string{"m5.xlarge has no on demand price"}, status[0].Violations)
+			},
+		},
+		{
+			name:     "missing attributes rejected",
+			rules:    GuardRules{RequiredAttributes: []string{Memory}},
+			vms:      []VmInfo{{Type: "m5.large", Cpus: 2, OnDemandPrice: 0.1}},
+			onDemand: noOnDemandPrice,
+			check: func(vms []VmInfo, accepted bool, status []CatalogStatus) {
+				assert.False(t, accepted)
+				assert.Equal(t, []string{"m5.large has no memory attribute"}, status[0].Violations)
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			g := NewCatalogGuard(GuardRules{})
+			_, accepted := g.CheckProducts("ec2", "eu-west-1", baseline, noOnDemandPrice)
+			assert.True(t, accepted, "the baseline should be accepted")
+			g.SetRules(test.rules)
+			vms, accepted := g.CheckProducts("ec2", "eu-west-1", test.vms, test.onDemand)
+			test.check(vms, accepted, g.Status())
+		})
+	}
+}
+
+func TestCatalogGuard_CheckPrices(t *testing.T) {
+	g := NewCatalogGuard(GuardRules{MaxShrink: 0.3, MaxPriceDelta: 0.5})
+	baseline := map[string]Price{"n1-standard-1": {OnDemandPrice: 0.05}, "n1-standard-2": {OnDemandPrice: 0.1}}
+	_, accepted := g.CheckPrices("gce", "us-east1", baseline)
+	assert.True(t, accepted, "the baseline should be accepted")
+
+	prices, accepted := g.CheckPrices("gce", "us-east1", map[string]Price{"n1-standard-1": {OnDemandPrice: 0.05}})
+	assert.False(t, accepted)
+	assert.Equal(t, baseline, prices)
+
+	rejected := g.Rejected("gce", "us-east1")
+	assert.Equal(t, 1, len(rejected))
+	assert.Equal(t, PricesCatalog, rejected[0].Catalog)
+	assert.Equal(t, map[string]Price{"n1-standard-1": {OnDemandPrice: 0.05}}, rejected[0].Prices)
+
+	_, accepted = g.CheckPrices("gce", "us-east1", map[string]Price{"n1-standard-1": {OnDemandPrice: 0.06}, "n1-standard-2": {OnDemandPrice: 0.1}})
+	assert.True(t, accepted)
+	assert.False(t, g.Status()[0].Rejected)
+	assert.Equal(t, 1, len(g.Rejected("gce", "us-east1")), "the rejected catalog should be kept for inspection")
+}
+
+func TestCatalogGuard_Rebaseline(t *testing.T) {
+	g := NewCatalogGuard(GuardRules{MaxShrink: 0.3, RebaselineAfter: 2})
+	baseline := map[string]Price{"n1-standard-1": {OnDemandPrice: 0.05}, "n1-standard-2": {OnDemandPrice: 0.1}}
+	_, accepted := g.CheckPrices("gce", "us-east1", baseline)
+	assert.True(t, accepted, "the baseline should be accepted")
+
+	shrunk := map[string]Price{"n1-standard-1": {OnDemandPrice: 0.05}}
+	for i := 1; i <= 2; i++ {
+		prices, accepted := g.CheckPrices("gce", "us-east1", shrunk)
+		assert.False(t, accepted)
+		assert.Equal(t, baseline, prices)
+		assert.Equal(t, i, g.Status()[0].Rejections)
+	}
+	prices, accepted := g.CheckPrices("gce", "us-east1", shrunk)
+	assert.True(t, accepted, "the catalog should be accepted after the same rejections")
+	assert.Equal(t, shrunk, prices)
+	assert.False(t, g.Status()[0].Rejected)
+	assert.Equal(t, 0, g.Status()[0].Rejections)
+
+	_, accepted = g.CheckPrices("gce", "us-east1", map[string]Price{})
+	assert.False(t, accepted, "the new baseline should be validated against")
+	_, accepted = g.CheckPrices("gce", "us-east1", map[string]Price{"n1-standard-1": {OnDemandPrice: 0.05}, "e2-small": {OnDemandPrice: 0.02}})
+	assert.True(t, accepted)
+	_, accepted = g.CheckPrices("gce", "us-east1", map[string]Price{})
+	assert.False(t, accepted)
+	assert.Equal(t, 1, g.Status()[0].Rejections, "an accepted catalog should reset the rejections")
+}
+
+func TestGuardRules_Validate(t *testing.T) {
+	assert.Nil(t, GuardRules{MaxShrink: 0.5, RequiredAttributes: []string{Cpu, "ntwPerf"}}.Validate())
+	assert.EqualError(t, GuardRules{MaxShrink: 1}.Validate(), "the maximum shrink must be between 0 and 1")
+	assert.EqualError(t, GuardRules{RequiredAttributes: []string{"disk"}}.Validate(), "unsupported required attribute: disk")
+	assert.EqualError(t, GuardRules{RebaselineAfter: -1}.Validate(), "the number of rejections before rebaselining must not be negative")
+}
+
+func TestCachingProductInfo_renewVmsRejected(t *testing.T) {
+	infoer := &DummyProductInfoer{Vms: []VmInfo{{Type: "m5.large", Cpus: 2, Mem: 8, OnDemandPrice: 0.1}, {Type: "m5.xlarge", Cpus: 4, Mem: 16, OnDemandPrice: 0.2}}}
+	c := cache.New(5*time.Minute, 10*time.Minute)
+	productInfo, _ := NewCachingProductInfo(10*time.Second, c, map[string]ProductInfoer{"dummy": infoer})
+	productInfo.CatalogGuard().SetRules(GuardRules{MaxShrink: 0.4})
+
+	_, err := productInfo.renewVms("dummy", "dummyRegion")
+	assert.Nil(t, err, "should not get error on vm renewal")
+
+	infoer.Vms = infoer.Vms[:1]
+	_, err = productInfo.renewVms("dummy", "dummyRegion")
+	assert.EqualError(t, err, "the product catalog of dummy/dummyRegion was rejected by the sanity guard")
+	vms, _ := c.Get("/banzaicloud.com/recommender/dummy/dummyRegion/vms")
+	assert.Equal(t, 2, len(vms.([]VmInfo)), "the last accepted catalog should be kept")
+}
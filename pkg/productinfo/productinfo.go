@@ -4,10 +4,12 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"sort"
 	"strings"
 	"sync"
 	"time"
 
+	"github.com/banzaicloud/productinfo/pkg/productinfo/forecast"
 	"github.com/prometheus/client_golang/prometheus"
 	log "github.com/sirupsen/logrus"
 )
@@ -24,9 +26,111 @@ func (v AttrValues) floatValues() []float64 {
 type SpotPriceInfo map[string]float64
 
 // Price describes the on demand price and spot prices per availability zones
+// the top level prices are the prices of Linux, the prices of other operating systems are in OsPrices
 type Price struct {
-	OnDemandPrice float64       `json:"onDemandPrice"`
-	SpotPrice     SpotPriceInfo `json:"spotPrice"`
+	OnDemandPrice float64            `json:"onDemandPrice"`
+	SpotPrice     SpotPriceInfo      `json:"spotPrice"`
+	OsPrices      map[string]OsPrice `json:"osPrices,omitempty"`
+	// Commitments the prices of the Linux instances in exchange for a usage commitment
+	Commitments []CommitmentPrice `json:"commitments,omitempty"`
+	// PreemptiblePrice the legacy preemptible prices per availability zones of the providers pricing them apart from
+	// the spot prices, e.g. the preemptible VMs of Google Cloud
+	PreemptiblePrice SpotPriceInfo `json:"preemptiblePrice,omitempty"`
+}
+
+// OsPrice describes the on demand price and spot prices per availability zones of an operating system
+type OsPrice struct {
+	OnDemandPrice    float64       `json:"onDemandPrice"`
+	SpotPrice        SpotPriceInfo `json:"spotPrice,omitempty"`
+	PreemptiblePrice SpotPriceInfo `json:"preemptiblePrice,omitempty"`
+}
+
+// ForOs returns the prices of the given operating system, the prices are 0 if they are not known
+func (p Price) ForOs(os string) Price {
+	if os == "" || os == Linux {
+		return Price{OnDemandPrice: p.OnDemandPrice, SpotPrice: p.SpotPrice, Commitments: p.Commitments,
+			PreemptiblePrice: p.PreemptiblePrice}
+	}
+	op := p.OsPrices[os]
+	return Price{OnDemandPrice: op.OnDemandPrice, SpotPrice: op.SpotPrice, PreemptiblePrice: op.PreemptiblePrice}
+}
+
+// SetOsPrice sets the on demand price of an operating system, Linux prices are set on the top level
+func (p *Price) SetOsPrice(os string, onDemandPrice float64) {
+	if os == Linux {
+		p.OnDemandPrice = onDemandPrice
+		return
+	}
+	op := p.OsPrices[os]
+	op.OnDemandPrice = onDemandPrice
+	p.setOsPrice(os, op)
+}
+
+// SetOsSpotPrice sets the spot prices of an operating system, Linux prices are set on the top level
+func (p *Price) SetOsSpotPrice(os string, spotPrice SpotPriceInfo) {
+	if os == Linux {
+		p.SpotPrice = spotPrice
+		return
+	}
+	op := p.OsPrices[os]
+	op.SpotPrice = spotPrice
+	p.setOsPrice(os, op)
+}
+
+// SetOsPreemptiblePrice sets the preemptible prices of an operating system, Linux prices are set on the top level
+func (p *Price) SetOsPreemptiblePrice(os string, preemptiblePrice SpotPriceInfo) {
+	if os == Linux {
+		p.PreemptiblePrice = preemptiblePrice
+		return
+	}
+	op := p.OsPrices[os]
+	op.PreemptiblePrice = preemptiblePrice
+	p.setOsPrice(os, op)
+}
+
+// setOsPrice copies the operating system prices on write, so the copies of a price don't share the changes
+func (p *Price) setOsPrice(os string, op OsPrice) {
+	osPrices := make(map[string]OsPrice, len(p.OsPrices)+1)
+	for o, price := range p.OsPrices {
+		osPrices[o] = price
+	}
+	osPrices[os] = op
+	p.OsPrices = osPrices
+}
+
+// SpotPrices returns the spot prices of every operating system, the on demand, commitment and preemptible prices are
+// left out
+func (p Price) SpotPrices() Price {
+	spot := Price{SpotPrice: p.SpotPrice}
+	for os, op := range p.OsPrices {
+		if len(op.SpotPrice) > 0 {
+			spot.SetOsSpotPrice(os, op.SpotPrice)
+		}
+	}
+	return spot
+}
+
+// withSpotPrices returns a copy of the price with the spot prices of every operating system replaced by the ones of
+// the given spot price, the operating systems without a spot price in it are left without spot prices
+func (p Price) withSpotPrices(spot Price) Price {
+	p.SpotPrice = spot.SpotPrice
+	osPrices := make(map[string]OsPrice, len(p.OsPrices)+len(spot.OsPrices))
+	for os, op := range p.OsPrices {
+		op.SpotPrice = spot.OsPrices[os].SpotPrice
+		if op.OnDemandPrice != 0 || len(op.SpotPrice) > 0 || len(op.PreemptiblePrice) > 0 {
+			osPrices[os] = op
+		}
+	}
+	for os, op := range spot.OsPrices {
+		if _, ok := osPrices[os]; !ok && len(op.SpotPrice) > 0 {
+			osPrices[os] = OsPrice{SpotPrice: op.SpotPrice}
+		}
+	}
+	p.OsPrices = nil
+	if len(osPrices) > 0 {
+		p.OsPrices = osPrices
+	}
+	return p
 }
 
 // VmInfo representation of a virtual machine
@@ -41,6 +145,48 @@ type VmInfo struct {
 	NtwPerfCat    string        `json:"ntwPerfCategory"`
 	// CurrentGen signals whether the instance type generation is the current one. Only applies for amazon
 	CurrentGen bool `json:"currentGen"`
+	// OsPrices the prices of the operating systems other than Linux
+	OsPrices map[string]OsPrice `json:"osPrices,omitempty"`
+	// Commitments the prices of the Linux instances in exchange for a usage commitment
+	Commitments []CommitmentPrice `json:"commitments,omitempty"`
+	// Zones the availability zones of the region the instance type is available in, empty if it's not known
+	Zones []string `json:"zones,omitempty"`
+	// CpuArchitectures the processor architectures the instance type supports, e.g. x86_64 or arm64
+	CpuArchitectures []string `json:"cpuArchitectures,omitempty"`
+	// ClockSpeed the sustained clock speed of the processor in GHz
+	ClockSpeed float64 `json:"clockSpeedGhz,omitempty"`
+	// Hypervisor the hypervisor of the instance type, empty for bare metal instances
+	Hypervisor string `json:"hypervisor,omitempty"`
+	// EnaSupport signals whether the Elastic Network Adapter is unsupported, supported or required
+	EnaSupport string `json:"enaSupport,omitempty"`
+	// EbsBandwidth the baseline bandwidth of the EBS optimized instances in Mbps
+	EbsBandwidth float64 `json:"ebsBandwidthMbps,omitempty"`
+	// InstanceStorage the total size of the instance store volumes in GB
+	InstanceStorage float64 `json:"instanceStorageGb,omitempty"`
+	// InstanceStorageType the type of the instance store volumes: hdd or ssd
+	InstanceStorageType string `json:"instanceStorageType,omitempty"`
+	// GpuModel the manufacturer and the name of the GPUs
+	GpuModel string `json:"gpuModel,omitempty"`
+	// GpuMem the total memory of the GPUs in GiB
+	GpuMem float64 `json:"gpuMemPerVm,omitempty"`
+	// MaxNetworkInterfaces the maximum number of network interfaces of the instance type
+	MaxNetworkInterfaces int `json:"maxNetworkInterfaces,omitempty"`
+	// IpsPerNetworkInterface the maximum number of IPv4 addresses per network interface
+	IpsPerNetworkInterface int `json:"ipsPerNetworkInterface,omitempty"`
+	// TenancyPrices the on demand prices of the tenancies other than shared by tenancy
+	TenancyPrices map[string]TenancyPrice `json:"tenancyPrices,omitempty"`
+	// Sockets the number of processor sockets of a dedicated host
+	Sockets int `json:"socketsPerHost,omitempty"`
+	// Cores the number of physical cores of a dedicated host
+	Cores int `json:"coresPerHost,omitempty"`
+}
+
+// ForOs returns the vm with the prices of the given operating system
+// the commitment prices are only kept for Linux
+func (vm VmInfo) ForOs(os string) VmInfo {
+	p := Price{OnDemandPrice: vm.OnDemandPrice, SpotPrice: vm.SpotPrice, OsPrices: vm.OsPrices, Commitments: vm.Commitments}.ForOs(os)
+	vm.OnDemandPrice, vm.SpotPrice, vm.OsPrices, vm.Commitments = p.OnDemandPrice, p.SpotPrice, nil, p.Commitments
+	return vm
 }
 
 var (
@@ -83,9 +229,14 @@ func NewCachingProductInfo(ri time.Duration, cache ProductStorer, infoers map[st
 	}
 
 	pi := CachingProductInfo{
-		productInfoers:  infoers,
-		vmAttrStore:     cache,
-		renewalInterval: ri,
+		productInfoers:   infoers,
+		vmAttrStore:      cache,
+		renewalInterval:  ri,
+		spotHistory:      NewSpotPriceHistory(time.Hour, 14*24*time.Hour),
+		changes:          NewChangeNotifier(0.05, 10000),
+		guard:            NewCatalogGuard(GuardRules{}),
+		quality:          NewQualityTracker(),
+		spotPriceSources: make(map[string]SpotPriceSource),
 	}
 	return &pi, nil
 }
@@ -124,11 +275,18 @@ func (cpi *CachingProductInfo) renewProviderInfo(provider string, wg *sync.WaitG
 		}
 	}
 	if regions, err := pi.GetRegions(); err == nil {
+		cpi.changes.UpdateRegions(provider, regions)
 		for regionId := range regions {
 			if _, err := cpi.renewVms(provider, regionId); err != nil {
 				RegionFailuresTotalCounter.WithLabelValues(provider, regionId).Inc()
 				log.Errorf("couldn't renew attribute values in cache: %s", err.Error())
 			}
+			if err := cpi.renewHosts(provider, regionId); err != nil {
+				log.WithError(err).Warnf("couldn't renew the dedicated hosts of region %s", regionId)
+			}
+			if err := cpi.renewAddOns(provider, regionId); err != nil {
+				log.WithError(err).Warnf("couldn't renew the add-ons of region %s", regionId)
+			}
 		}
 	} else {
 		ScrapeFailuresTotalCounter.WithLabelValues(provider).Inc()
@@ -159,7 +317,7 @@ func (cpi *CachingProductInfo) Start(ctx context.Context) {
 			providerWg.Add(1)
 			go func(p string, i ProductInfoer) {
 				defer providerWg.Done()
-				if i.HasShortLivedPriceInfo() {
+				if cpi.HasShortLivedPriceInfo(p) {
 					log.Infof("renewing short lived %s product info", p)
 					var wg sync.WaitGroup
 					regions, err := i.GetRegions()
@@ -221,10 +379,23 @@ func (cpi *CachingProductInfo) Initialize(provider string) (map[string]map[strin
 	if err != nil {
 		return nil, err
 	}
+	var issues []QualityIssue
+	if src, ok := cpi.productInfoers[provider].(QualityIssueSource); ok {
+		issues = src.QualityIssues()
+	}
+	cpi.quality.initialized(provider, allPrices, issues)
 	for region, ap := range allPrices {
+		ap, accepted := cpi.guard.CheckPrices(provider, region, ap)
+		// the last accepted prices are stored again so they don't expire while the rejected ones are inspected
 		for instType, p := range ap {
 			cpi.vmAttrStore.Set(cpi.getPriceKey(provider, region, instType), p, cpi.renewalInterval)
 		}
+		if !accepted {
+			allPrices[region] = ap
+			continue
+		}
+		cpi.changes.UpdatePrices(provider, region, ap)
+		cpi.notifyPriceListeners(provider, region, ap)
 	}
 	return allPrices, nil
 }
@@ -277,22 +448,37 @@ func (cpi *CachingProductInfo) renewAttrValues(provider string, attribute string
 }
 
 // HasShortLivedPriceInfo signals if a product info provider has frequently changing price info
+// the spot prices of the providers with a spot price source are always short lived
 func (cpi *CachingProductInfo) HasShortLivedPriceInfo(provider string) bool {
+	if _, ok := cpi.spotPriceSources[provider]; ok {
+		return true
+	}
 	return cpi.productInfoers[provider].HasShortLivedPriceInfo()
 }
 
 // GetPrice returns the on demand price and zone averaged computed spot price for a given instance type in a given region
 func (cpi *CachingProductInfo) GetPrice(provider string, region string, instanceType string, zones []string) (float64, float64, error) {
-	var p Price
-	if cachedVal, ok := cpi.vmAttrStore.Get(cpi.getPriceKey(provider, region, instanceType)); ok {
-		log.Debugf("Getting price info from cache [provider=%s, region=%s, type=%s].", provider, region, instanceType)
-		p = cachedVal.(Price)
-	} else {
-		allPriceInfo, err := cpi.renewShortLivedInfo(provider, region)
-		if err != nil {
+	return cpi.GetOsPrice(provider, region, instanceType, Linux, zones)
+}
+
+// GetOsPrice returns the on demand price and the zone averaged computed spot price of an operating system for a given
+// instance type in a given region, the zones the instance type is not available in are left out of the average
+func (cpi *CachingProductInfo) GetOsPrice(provider string, region string, instanceType string, os string, zones []string) (float64, float64, error) {
+	_, hasPrice := cpi.vmAttrStore.Get(cpi.getPriceKey(provider, region, instanceType))
+	_, hasSpotPrice := cpi.vmAttrStore.Get(cpi.getSpotPriceKey(provider, region, instanceType))
+	if !hasSpotPrice && (!hasPrice || cpi.HasShortLivedPriceInfo(provider)) {
+		// the short lived spot prices expired, the on demand prices are kept from the long lived renewal
+		if _, err := cpi.renewShortLivedInfo(provider, region); err != nil {
 			return 0, 0, err
 		}
-		p = allPriceInfo[instanceType]
+	} else {
+		log.Debugf("Getting price info from cache [provider=%s, region=%s, type=%s].", provider, region, instanceType)
+	}
+	p, _ := cpi.getCachedPrice(provider, region, instanceType)
+	p = p.ForOs(os)
+	zones = cpi.availableZones(provider, region, instanceType, zones)
+	if len(zones) == 0 {
+		return p.OnDemandPrice, 0, nil
 	}
 	var sumPrice float64
 	for _, z := range zones {
@@ -305,22 +491,154 @@ func (cpi *CachingProductInfo) GetPrice(provider string, region string, instance
 	return p.OnDemandPrice, sumPrice / float64(len(zones)), nil
 }
 
+// availableZones filters the zones the instance type is available in, the zones are returned unchanged if the
+// availability of the instance type is not known
+func (cpi *CachingProductInfo) availableZones(provider string, region string, instanceType string, zones []string) []string {
+	cachedVms, ok := cpi.vmAttrStore.Get(cpi.getVmKey(provider, region))
+	if !ok {
+		return zones
+	}
+	for _, vm := range cachedVms.([]VmInfo) {
+		if vm.Type != instanceType || len(vm.Zones) == 0 {
+			continue
+		}
+		var available []string
+		for _, z := range zones {
+			for _, vz := range vm.Zones {
+				if z == vz {
+					available = append(available, z)
+					break
+				}
+			}
+		}
+		return available
+	}
+	return zones
+}
+
+// GetZoneInstanceTypes returns the instance types available in the availability zones of a region by zone, the
+// instance types without known availability are left out
+func (cpi *CachingProductInfo) GetZoneInstanceTypes(provider string, region string) map[string][]string {
+	cachedVms, ok := cpi.vmAttrStore.Get(cpi.getVmKey(provider, region))
+	if !ok {
+		return nil
+	}
+	zoneTypes := make(map[string][]string)
+	for _, vm := range cachedVms.([]VmInfo) {
+		for _, z := range vm.Zones {
+			zoneTypes[z] = append(zoneTypes[z], vm.Type)
+		}
+	}
+	for _, types := range zoneTypes {
+		sort.Strings(types)
+	}
+	return zoneTypes
+}
+
 func (cpi *CachingProductInfo) getPriceKey(provider string, region string, instanceType string) string {
 	return fmt.Sprintf(PriceKeyTemplate, provider, region, instanceType)
 }
 
-// renewAttrValues retrieves attribute values from the cloud provider and refreshes the attribute store with them
+func (cpi *CachingProductInfo) getSpotPriceKey(provider string, region string, instanceType string) string {
+	return fmt.Sprintf(SpotPriceKeyTemplate, provider, region, instanceType)
+}
+
+// getCachedPrice returns the cached prices of an instance type, the spot prices of the last short lived renewal
+// override the spot prices stored with the long lived prices
+func (cpi *CachingProductInfo) getCachedPrice(provider string, region string, instanceType string) (Price, bool) {
+	var p Price
+	cachedPrice, priceOk := cpi.vmAttrStore.Get(cpi.getPriceKey(provider, region, instanceType))
+	if priceOk {
+		p = cachedPrice.(Price)
+	}
+	cachedSpot, spotOk := cpi.vmAttrStore.Get(cpi.getSpotPriceKey(provider, region, instanceType))
+	if spotOk {
+		p = p.withSpotPrices(cachedSpot.(Price))
+	}
+	return p, priceOk || spotOk
+}
+
+// renewShortLivedInfo retrieves the current spot prices from the cloud provider and caches them for a short time,
+// the on demand and commitment prices are only refreshed by the checked long lived renewal
+// the Linux spot prices of a provider with a spot price source are taken from the source, the current prices of the
+// infoer are used as its last resort
 func (cpi *CachingProductInfo) renewShortLivedInfo(provider string, region string) (map[string]Price, error) {
-	prices, err := cpi.productInfoers[provider].GetCurrentPrices(region)
+	current, err := cpi.productInfoers[provider].GetCurrentPrices(region)
+	if src, ok := cpi.spotPriceSources[provider]; ok {
+		if current, err = withSourceSpotPrices(src, region, current, err); err != nil {
+			return nil, err
+		}
+	}
 	if err != nil {
 		return nil, err
 	}
-	for instType, p := range prices {
-		cpi.vmAttrStore.Set(cpi.getPriceKey(provider, region, instType), p, 2*time.Minute)
+	prices := make(map[string]Price, len(current))
+	for instType, p := range current {
+		prices[instType] = p.SpotPrices()
+		cpi.vmAttrStore.Set(cpi.getSpotPriceKey(provider, region, instType), prices[instType], 2*time.Minute)
+	}
+	if src, ok := cpi.productInfoers[provider].(CurrentPriceQualityIssueSource); ok {
+		cpi.quality.currentPricesRenewed(provider, region, src.CurrentPriceQualityIssues(region))
 	}
+	cpi.spotHistory.AddPrices(provider, region, prices, time.Now())
+	cpi.changes.UpdatePrices(provider, region, prices)
+	cpi.notifyPriceListeners(provider, region, prices)
 	return prices, nil
 }
 
+// withSourceSpotPrices replaces the Linux spot prices of the current prices with the ones of the spot price source,
+// the current prices retrieved from the infoer are the fallback of the source, the error of the infoer is only
+// returned if the source fails as well
+func withSourceSpotPrices(src SpotPriceSource, region string, current map[string]Price, currentErr error) (map[string]Price, error) {
+	infoerPrices := make(currentSpotPrices, len(current))
+	for instType, p := range current {
+		if len(p.SpotPrice) > 0 {
+			infoerPrices[instType] = p.SpotPrice
+		}
+	}
+	var fallback SpotPriceSource = infoerPrices
+	if currentErr != nil {
+		log.WithError(currentErr).Warnf("could not retrieve the current prices in region %s, using the spot price source only", region)
+		fallback = failingSpotPriceSource{currentErr}
+	}
+	spotPrices, err := WithFallback(src, fallback).GetSpotPrices(region)
+	if err != nil {
+		if currentErr != nil {
+			return nil, currentErr
+		}
+		return nil, err
+	}
+	prices := make(map[string]Price, len(current))
+	for instType, p := range current {
+		p.SpotPrice = spotPrices[instType]
+		prices[instType] = p
+	}
+	for instType, sp := range spotPrices {
+		if _, ok := prices[instType]; !ok {
+			prices[instType] = Price{SpotPrice: sp, OnDemandPrice: -1}
+		}
+	}
+	return prices, nil
+}
+
+// currentSpotPrices a spot price source of the spot prices already retrieved from an infoer
+type currentSpotPrices map[string]SpotPriceInfo
+
+// GetSpotPrices returns the retrieved spot prices
+func (c currentSpotPrices) GetSpotPrices(region string) (map[string]SpotPriceInfo, error) {
+	return c, nil
+}
+
+// failingSpotPriceSource a spot price source of an infoer that failed to retrieve the current prices
+type failingSpotPriceSource struct {
+	err error
+}
+
+// GetSpotPrices returns the error of the infoer
+func (f failingSpotPriceSource) GetSpotPrices(region string) (map[string]SpotPriceInfo, error) {
+	return nil, f.err
+}
+
 func (cpi *CachingProductInfo) toProviderAttribute(provider string, attr string) (string, error) {
 	switch attr {
 	case Cpu:
@@ -340,10 +658,61 @@ func (cpi *CachingProductInfo) renewVms(provider string, regionId string) ([]VmI
 	if err != nil {
 		return nil, err
 	}
-	cpi.vmAttrStore.Set(cpi.getVmKey(provider, regionId), values, cpi.renewalInterval)
+	onDemand := func(instanceType string) float64 {
+		if cachedPrice, ok := cpi.getCachedPrice(provider, regionId, instanceType); ok {
+			return cachedPrice.OnDemandPrice
+		}
+		return 0
+	}
+	var issues []QualityIssue
+	if src, ok := cpi.productInfoers[provider].(ProductQualityIssueSource); ok {
+		issues = src.ProductQualityIssues(regionId)
+	}
+	cpi.quality.productsRenewed(provider, regionId, values, issues, onDemand, func(vm VmInfo) error {
+		mapper, err := cpi.GetNetworkPerfMapper(provider)
+		if err != nil || mapper == nil {
+			return nil
+		}
+		_, err = mapper.MapNetworkPerf(vm)
+		return err
+	})
+	values, accepted := cpi.guard.CheckProducts(provider, regionId, values, onDemand)
+	if values != nil {
+		cpi.vmAttrStore.Set(cpi.getVmKey(provider, regionId), values, cpi.renewalInterval)
+	}
+	if !accepted {
+		return nil, fmt.Errorf("the product catalog of %s/%s was rejected by the sanity guard", provider, regionId)
+	}
+	cpi.changes.UpdateProducts(provider, regionId, values)
+	prices := make(map[string]Price)
+	for _, vm := range values {
+		if vm.OnDemandPrice > 0 {
+			prices[vm.Type] = Price{OnDemandPrice: vm.OnDemandPrice}
+		}
+	}
+	cpi.notifyPriceListeners(provider, regionId, prices)
 	return values, nil
 }
 
+// AddPriceListener registers a listener that is notified on every price renewal
+// listeners must be registered before the product info retrieval is started
+func (cpi *CachingProductInfo) AddPriceListener(l PriceListener) {
+	cpi.priceListeners = append(cpi.priceListeners, l)
+}
+
+// SetSpotPriceSource sets the spot price source of a provider, the Linux spot prices of the provider are taken from
+// the source and the current prices of the infoer are used as the last resort of the source
+// sources must be set before the product info retrieval is started
+func (cpi *CachingProductInfo) SetSpotPriceSource(provider string, src SpotPriceSource) {
+	cpi.spotPriceSources[provider] = src
+}
+
+func (cpi *CachingProductInfo) notifyPriceListeners(provider string, region string, prices map[string]Price) {
+	for _, l := range cpi.priceListeners {
+		l.PricesRenewed(provider, region, prices)
+	}
+}
+
 // GetZones returns the availability zones in a region
 func (cpi *CachingProductInfo) GetZones(provider string, region string) ([]string, error) {
 	zoneCacheKey := cpi.getZonesKey(provider, region)
@@ -406,26 +775,33 @@ func (cpi *CachingProductInfo) getRegionsKey(provider string) string {
 
 // GetProductDetails retrieves product details form the given provider and region
 func (cpi *CachingProductInfo) GetProductDetails(cloud string, region string) ([]ProductDetails, error) {
-	log.Debugf("getting product details for provider: %s, region: %s", cloud, region)
+	return cpi.GetProductDetailsForOs(cloud, region, Linux)
+}
 
-	cachedVms, ok := cpi.vmAttrStore.Get(cpi.getVmKey(cloud, region))
-	if !ok {
-		return nil, fmt.Errorf("vms not yet cached for the key: %s", cpi.getVmKey(cloud, region))
-	}
+// GetProductDetailsForOs retrieves product details with the prices of the given operating system form the given
+// provider and region, instance types without prices for a non Linux operating system are left out
+func (cpi *CachingProductInfo) GetProductDetailsForOs(cloud string, region string, os string) ([]ProductDetails, error) {
+	log.Debugf("getting product details for provider: %s, region: %s, os: %s", cloud, region, os)
 
-	vms := cachedVms.([]VmInfo)
-	details := make([]ProductDetails, len(vms))
+	vms, err := cpi.getCachedVms(cloud, region)
+	if err != nil {
+		return nil, err
+	}
+	details := make([]ProductDetails, 0, len(vms))
 
-	var pr Price
-	for i, vm := range vms {
-		pd := newProductDetails(vm)
+	for _, vm := range vms {
+		var pr Price
+		pd := newProductDetails(vm.ForOs(os))
 		pdWithNtwPerfCat := cpi.decorateNtwPerfCat(cloud, pd)
-		if cachedVal, ok := cpi.vmAttrStore.Get(cpi.getPriceKey(cloud, region, vm.Type)); ok {
-			pr = cachedVal.(Price)
+		if cachedPrice, ok := cpi.getCachedPrice(cloud, region, vm.Type); ok {
+			pr = cachedPrice.ForOs(os)
 			// fill the on demand price if appropriate
 			if pr.OnDemandPrice > 0 {
 				pdWithNtwPerfCat.OnDemandPrice = pr.OnDemandPrice
 			}
+			if len(pdWithNtwPerfCat.Commitments) == 0 {
+				pdWithNtwPerfCat.Commitments = pr.Commitments
+			}
 		} else {
 			log.Debugf("price info not yet cached for key: %s", cpi.getPriceKey(cloud, region, vm.Type))
 		}
@@ -433,13 +809,46 @@ func (cpi *CachingProductInfo) GetProductDetails(cloud string, region string) ([
 		for zone, price := range pr.SpotPrice {
 			pdWithNtwPerfCat.SpotInfo = append(pdWithNtwPerfCat.SpotInfo, *newZonePrice(zone, price))
 		}
+		for zone, price := range pr.PreemptiblePrice {
+			pdWithNtwPerfCat.PreemptibleInfo = append(pdWithNtwPerfCat.PreemptibleInfo, *newZonePrice(zone, price))
+		}
 
-		details[i] = *pdWithNtwPerfCat
+		if os != Linux && pdWithNtwPerfCat.OnDemandPrice <= 0 && len(pdWithNtwPerfCat.SpotInfo) == 0 &&
+			len(pdWithNtwPerfCat.PreemptibleInfo) == 0 {
+			continue
+		}
+		details = append(details, *pdWithNtwPerfCat)
 	}
 
 	return details, nil
 }
 
+// getCachedVms returns the vms of a region from the cache
+func (cpi *CachingProductInfo) getCachedVms(cloud string, region string) ([]VmInfo, error) {
+	cachedVms, ok := cpi.vmAttrStore.Get(cpi.getVmKey(cloud, region))
+	if !ok {
+		return nil, fmt.Errorf("vms not yet cached for the key: %s", cpi.getVmKey(cloud, region))
+	}
+	return cachedVms.([]VmInfo), nil
+}
+
+// EffectiveMonthlyPrice computes the on demand price of running an instance type for the given ratio of a month (0-1),
+// with the sustained use discounts of the provider applied, hourly is the on demand price of the operating system
+// the discounts apply only to the Linux price, the licensing fee of the other operating systems is charged in full
+func (cpi *CachingProductInfo) EffectiveMonthlyPrice(provider string, region string, instanceType string, os string, hourly float64, usage float64) float64 {
+	var rates []float64
+	if discounter, ok := cpi.productInfoers[provider].(SustainedUseDiscounter); ok {
+		rates = discounter.SustainedUseRates(instanceType)
+	}
+	base := hourly
+	if os != "" && os != Linux && len(rates) > 0 {
+		if p, ok := cpi.getCachedPrice(provider, region, instanceType); ok && p.OnDemandPrice > 0 && p.OnDemandPrice < hourly {
+			base = p.OnDemandPrice
+		}
+	}
+	return EffectiveMonthlyPrice(base, usage, rates) + (hourly-base)*usage*HoursPerMonth
+}
+
 // decorateNtwPerfCat returns ProductDetails with network performance category
 func (cpi *CachingProductInfo) decorateNtwPerfCat(provider string, pd *ProductDetails) *ProductDetails {
 	ntwMapper, _ := cpi.GetNetworkPerfMapper(provider)
@@ -447,6 +856,77 @@ func (cpi *CachingProductInfo) decorateNtwPerfCat(provider string, pd *ProductDe
 	return pd
 }
 
+// SpotPriceHistory returns the spot price history collected during the short lived info renewals
+func (cpi *CachingProductInfo) SpotPriceHistory() *SpotPriceHistory {
+	return cpi.spotHistory
+}
+
+// CatalogGuard returns the sanity guard validating the catalogs before they are published
+func (cpi *CachingProductInfo) CatalogGuard() *CatalogGuard {
+	return cpi.guard
+}
+
+// QualityReports returns the data quality reports of the regions of a provider
+func (cpi *CachingProductInfo) QualityReports(provider string) []QualityReport {
+	return cpi.quality.Reports(provider)
+}
+
+// Changes returns the notifier of the catalog changes
+func (cpi *CachingProductInfo) Changes() *ChangeNotifier {
+	return cpi.changes
+}
+
+// ErrNoSpotPriceHistory is returned when there's no spot price history of an instance type to forecast from
+var ErrNoSpotPriceHistory = errors.New("no spot price history")
+
+// GetSpotPriceForecast forecasts the spot prices of an instance type in every zone of a region for the given duration
+// with seasonal exponential smoothing over the collected spot price history, using a daily season
+// the duration can't be longer than the history of any of the zones, the errors other than ErrNoSpotPriceHistory are
+// caused by the parameters
+func (cpi *CachingProductInfo) GetSpotPriceForecast(provider string, region string, instanceType string, duration time.Duration,
+	confidence float64) ([]ZoneForecast, error) {
+	resolution := cpi.spotHistory.Resolution()
+	steps := int(duration / resolution)
+	if steps < 1 {
+		return nil, fmt.Errorf("the forecast duration must be at least %s", resolution)
+	}
+	seasonLength := int(24 * time.Hour / resolution)
+
+	series := cpi.spotHistory.Series(provider, region, instanceType)
+	if len(series) == 0 {
+		log.Debugf("no spot price history for provider: %s, region: %s, type: %s", provider, region, instanceType)
+		return nil, ErrNoSpotPriceHistory
+	}
+	history := -1
+	for _, s := range series {
+		if history < 0 || len(s.Values) < history {
+			history = len(s.Values)
+		}
+	}
+	if steps > history {
+		return nil, fmt.Errorf("the forecast duration can be at most the length of the spot price history: %s",
+			time.Duration(history)*resolution)
+	}
+
+	forecasts := make([]ZoneForecast, 0, len(series))
+	for zone, s := range series {
+		points, err := forecast.Fit(s.Values, seasonLength).Forecast(s.Values, steps, confidence)
+		if err != nil {
+			return nil, err
+		}
+		zf := ZoneForecast{Zone: zone, Prices: make([]ForecastPoint, len(points))}
+		last := s.Start.Add(time.Duration(len(s.Values)-1) * resolution)
+		for i, p := range points {
+			zf.Prices[i] = ForecastPoint{Time: last.Add(time.Duration(i+1) * resolution), Point: p}
+		}
+		forecasts = append(forecasts, zf)
+	}
+	sort.Slice(forecasts, func(i, j int) bool {
+		return forecasts[i].Zone < forecasts[j].Zone
+	})
+	return forecasts, nil
+}
+
 // Contains is a helper function to check if a slice contains a string
 func Contains(slice []string, s string) bool {
 	for _, e := range slice {
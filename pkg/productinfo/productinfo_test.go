@@ -464,16 +464,105 @@ func TestCachingProductInfo_renewShortLivedInfo(t *testing.T) {
 	}
 }
 
+func TestCachingProductInfo_renewShortLivedInfoKeepsLongLivedPrices(t *testing.T) {
+	c := cache.New(5*time.Minute, 10*time.Minute)
+	productInfo, _ := NewCachingProductInfo(10*time.Second, c, map[string]ProductInfoer{"dummy": &DummyProductInfoer{}})
+	committed := []CommitmentPrice{{Type: CommittedUse, Term: 1, HourlyPrice: 0.3}}
+	longLived := Price{
+		OnDemandPrice: 0.5,
+		SpotPrice:     SpotPriceInfo{"dummyZone1": 0.2},
+		OsPrices:      map[string]OsPrice{Windows: {OnDemandPrice: 0.9, SpotPrice: SpotPriceInfo{"dummyZone1": 0.4}}},
+		Commitments:   committed,
+	}
+	c.Set(productInfo.getPriceKey("dummy", "dummyRegion", "c1.xlarge"), longLived, cache.NoExpiration)
+
+	prices, err := productInfo.renewShortLivedInfo("dummy", "dummyRegion")
+	assert.Nil(t, err, "the error should be nil")
+	assert.Equal(t, Price{SpotPrice: SpotPriceInfo{"dummyZone1": 0.164}}, prices["c1.xlarge"],
+		"only the spot prices should be renewed")
+
+	cached, _ := c.Get(productInfo.getPriceKey("dummy", "dummyRegion", "c1.xlarge"))
+	assert.Equal(t, longLived, cached, "the long lived prices should not be overwritten")
+
+	p, ok := productInfo.getCachedPrice("dummy", "dummyRegion", "c1.xlarge")
+	assert.True(t, ok)
+	assert.Equal(t, 0.5, p.OnDemandPrice)
+	assert.Equal(t, SpotPriceInfo{"dummyZone1": 0.164}, p.SpotPrice)
+	assert.Equal(t, committed, p.Commitments)
+	assert.Equal(t, OsPrice{OnDemandPrice: 0.9}, p.OsPrices[Windows],
+		"the stale spot prices of the operating systems without current spot prices should be dropped")
+
+	p, ok = productInfo.getCachedPrice("dummy", "dummyRegion", "c3.large")
+	assert.True(t, ok, "the renewed spot prices should be cached without long lived prices")
+	assert.Equal(t, float64(0), p.OnDemandPrice)
+}
+
+func TestCachingProductInfo_renewShortLivedInfoWithSpotPriceSource(t *testing.T) {
+	tests := []struct {
+		name    string
+		tcId    string
+		source  *dummySpotPriceSource
+		checker func(prices map[string]Price, err error)
+	}{
+		{
+			name:   "the prices of the source replace the Linux spot prices of the infoer",
+			source: &dummySpotPriceSource{prices: map[string]SpotPriceInfo{"c1.xlarge": {"dummyZone1": 0.15}, "m5.large": {"dummyZone1": 0.03}}},
+			checker: func(prices map[string]Price, err error) {
+				assert.Nil(t, err, "the error should be nil")
+				assert.Equal(t, SpotPriceInfo{"dummyZone1": 0.15}, prices["c1.xlarge"].SpotPrice)
+				assert.Equal(t, SpotPriceInfo{"dummyZone1": 0.03}, prices["m5.large"].SpotPrice)
+			},
+		},
+		{
+			name:   "the infoer is the last resort of the source",
+			source: &dummySpotPriceSource{err: errors.New("source failed")},
+			checker: func(prices map[string]Price, err error) {
+				assert.Nil(t, err, "the error should be nil")
+				assert.Equal(t, SpotPriceInfo{"dummyZone1": 0.164}, prices["c1.xlarge"].SpotPrice)
+			},
+		},
+		{
+			name:   "the source is used if the infoer fails",
+			tcId:   GetCurrentPricesError,
+			source: &dummySpotPriceSource{prices: map[string]SpotPriceInfo{"c1.xlarge": {"dummyZone1": 0.15}}},
+			checker: func(prices map[string]Price, err error) {
+				assert.Nil(t, err, "the error should be nil")
+				assert.Equal(t, SpotPriceInfo{"dummyZone1": 0.15}, prices["c1.xlarge"].SpotPrice)
+			},
+		},
+		{
+			name:   "the error of the infoer is returned if the source fails as well",
+			tcId:   GetCurrentPricesError,
+			source: &dummySpotPriceSource{err: errors.New("source failed")},
+			checker: func(prices map[string]Price, err error) {
+				assert.Nil(t, prices, "the prices should be nil")
+				assert.EqualError(t, err, GetCurrentPricesError)
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			productInfo, _ := NewCachingProductInfo(10*time.Second, cache.New(5*time.Minute, 10*time.Minute),
+				map[string]ProductInfoer{"dummy": &DummyProductInfoer{TcId: test.tcId}})
+			productInfo.SetSpotPriceSource("dummy", test.source)
+			assert.True(t, productInfo.HasShortLivedPriceInfo("dummy"))
+			test.checker(productInfo.renewShortLivedInfo("dummy", "dummyRegion"))
+		})
+	}
+}
+
 func TestCachingProductInfo_GetPrice(t *testing.T) {
 	tests := []struct {
 		name          string
 		p             Price
 		zones         []string
+		vms           []VmInfo
 		ProductInfoer map[string]ProductInfoer
 		checker       func(i float64, f float64, err error)
 	}{
 		{
 			name:  "return on demand price and average spot price with 1 zone",
+			p:     Price{OnDemandPrice: 0.11},
 			zones: []string{"dummyZone1"},
 			ProductInfoer: map[string]ProductInfoer{
 				"dummy": &DummyProductInfoer{},
@@ -486,6 +575,7 @@ func TestCachingProductInfo_GetPrice(t *testing.T) {
 		},
 		{
 			name:  "return on demand price and average spot price with 4 zones",
+			p:     Price{OnDemandPrice: 0.11},
 			zones: []string{"dummyZone1", "dummyZone2", "dummyZone3", "dummyZone4"},
 			ProductInfoer: map[string]ProductInfoer{
 				"dummy": &DummyProductInfoer{},
@@ -498,6 +588,7 @@ func TestCachingProductInfo_GetPrice(t *testing.T) {
 		},
 		{
 			name:  "return on demand price and average spot price without expected zone",
+			p:     Price{OnDemandPrice: 0.11},
 			zones: []string{"dummyZone2", "dummyZone3", "dummyZone4"},
 			ProductInfoer: map[string]ProductInfoer{
 				"dummy": &DummyProductInfoer{},
@@ -508,6 +599,34 @@ func TestCachingProductInfo_GetPrice(t *testing.T) {
 				assert.Nil(t, err, "the error should be nil")
 			},
 		},
+		{
+			name:  "zones the instance type is not available in are left out of the average",
+			p:     Price{OnDemandPrice: 0.11},
+			zones: []string{"dummyZone1", "dummyZone2", "dummyZone3", "dummyZone4"},
+			vms:   []VmInfo{{Type: "c3.large", Zones: []string{"dummyZone1", "dummyZone2"}}},
+			ProductInfoer: map[string]ProductInfoer{
+				"dummy": &DummyProductInfoer{},
+			},
+			checker: func(ondemand float64, avg float64, err error) {
+				assert.Equal(t, float64(0.11), ondemand)
+				assert.Equal(t, float64(0.0265), avg)
+				assert.Nil(t, err, "the error should be nil")
+			},
+		},
+		{
+			name:  "instance type is not available in any of the zones",
+			p:     Price{OnDemandPrice: 0.11},
+			zones: []string{"dummyZone3"},
+			vms:   []VmInfo{{Type: "c3.large", Zones: []string{"dummyZone1"}}},
+			ProductInfoer: map[string]ProductInfoer{
+				"dummy": &DummyProductInfoer{},
+			},
+			checker: func(ondemand float64, avg float64, err error) {
+				assert.Equal(t, float64(0.11), ondemand)
+				assert.Equal(t, float64(0), avg)
+				assert.Nil(t, err, "the error should be nil")
+			},
+		},
 		{
 			name:  "could not retrieve current prices",
 			zones: []string{"dummyZone1"},
@@ -524,12 +643,34 @@ func TestCachingProductInfo_GetPrice(t *testing.T) {
 	for _, test := range tests {
 		t.Run(test.name, func(t *testing.T) {
 			productInfo, _ := NewCachingProductInfo(10*time.Second, cache.New(5*time.Minute, 10*time.Minute), test.ProductInfoer)
+			if test.p.OnDemandPrice > 0 {
+				productInfo.vmAttrStore.Set(productInfo.getPriceKey("dummy", "dummyRegion", "c3.large"), test.p, 0)
+			}
+			if test.vms != nil {
+				productInfo.vmAttrStore.Set(productInfo.getVmKey("dummy", "dummyRegion"), test.vms, 0)
+			}
 			values, value, err := productInfo.GetPrice("dummy", "dummyRegion", "c3.large", test.zones)
 			test.checker(values, value, err)
 		})
 	}
 }
 
+func TestCachingProductInfo_GetZoneInstanceTypes(t *testing.T) {
+	productInfo, _ := NewCachingProductInfo(10*time.Second, cache.New(5*time.Minute, 10*time.Minute),
+		map[string]ProductInfoer{"dummy": &DummyProductInfoer{}})
+	assert.Nil(t, productInfo.GetZoneInstanceTypes("dummy", "dummyRegion"), "nothing should be returned before the vms are cached")
+
+	productInfo.vmAttrStore.Set(productInfo.getVmKey("dummy", "dummyRegion"), []VmInfo{
+		{Type: "m5.xlarge", Zones: []string{"dummyZone1"}},
+		{Type: "c3.large", Zones: []string{"dummyZone1", "dummyZone2"}},
+		{Type: "t2.small"},
+	}, 0)
+	assert.Equal(t, map[string][]string{
+		"dummyZone1": {"c3.large", "m5.xlarge"},
+		"dummyZone2": {"c3.large"},
+	}, productInfo.GetZoneInstanceTypes("dummy", "dummyRegion"))
+}
+
 func TestCachingProductInfo_GetRegions(t *testing.T) {
 	tests := []struct {
 		name          string
@@ -620,3 +761,115 @@ func TestCachingProductInfo_GetProductDetails(t *testing.T) {
 		})
 	}
 }
+
+func TestPrice_ForOs(t *testing.T) {
+	p := Price{OnDemandPrice: 0.1, SpotPrice: SpotPriceInfo{"zone-a": 0.03}}
+	p.SetOsPrice(Windows, 0.2)
+	p.SetOsSpotPrice(Windows, SpotPriceInfo{"zone-a": 0.12})
+	shared := p
+	p.SetOsPrice(Rhel, 0.16)
+
+	assert.Equal(t, Price{OnDemandPrice: 0.1, SpotPrice: SpotPriceInfo{"zone-a": 0.03}}, p.ForOs(Linux))
+	assert.Equal(t, Price{OnDemandPrice: 0.2, SpotPrice: SpotPriceInfo{"zone-a": 0.12}}, p.ForOs(Windows))
+	assert.Equal(t, Price{OnDemandPrice: 0.16}, p.ForOs(Rhel))
+	assert.Equal(t, Price{}, p.ForOs(Suse))
+	assert.Equal(t, Price{}, shared.ForOs(Rhel), "the copies of a price should not be modified")
+}
+
+func TestCachingProductInfo_getCachedPrice(t *testing.T) {
+	tests := []struct {
+		name     string
+		provider string
+		price    Price
+		spot     Price
+		expected Price
+	}{
+		{
+			name:     "ec2 operating systems without current spot prices",
+			provider: "ec2",
+			price: Price{OnDemandPrice: 0.096, SpotPrice: SpotPriceInfo{"eu-west-1a": 0.03}, OsPrices: map[string]OsPrice{
+				Windows: {OnDemandPrice: 0.188, SpotPrice: SpotPriceInfo{"eu-west-1a": 0.12}},
+				Suse:    {OnDemandPrice: 0.196, SpotPrice: SpotPriceInfo{"eu-west-1a": 0.13}},
+				Rhel:    {SpotPrice: SpotPriceInfo{"eu-west-1a": 0.09}},
+			}},
+			spot: Price{SpotPrice: SpotPriceInfo{"eu-west-1a": 0.032, "eu-west-1b": 0.031}, OsPrices: map[string]OsPrice{
+				Windows: {SpotPrice: SpotPriceInfo{"eu-west-1a": 0.125}},
+			}},
+			expected: Price{OnDemandPrice: 0.096, SpotPrice: SpotPriceInfo{"eu-west-1a": 0.032, "eu-west-1b": 0.031}, OsPrices: map[string]OsPrice{
+				Windows: {OnDemandPrice: 0.188, SpotPrice: SpotPriceInfo{"eu-west-1a": 0.125}},
+				Suse:    {OnDemandPrice: 0.196},
+			}},
+		},
+		{
+			name:     "azure windows spot prices without a long lived windows price",
+			provider: "azure",
+			price:    Price{OnDemandPrice: 0.11, SpotPrice: SpotPriceInfo{"1": 0.02}},
+			spot: Price{SpotPrice: SpotPriceInfo{"1": 0.021, "2": 0.021}, OsPrices: map[string]OsPrice{
+				Windows: {SpotPrice: SpotPriceInfo{"1": 0.041, "2": 0.041}},
+			}},
+			expected: Price{OnDemandPrice: 0.11, SpotPrice: SpotPriceInfo{"1": 0.021, "2": 0.021}, OsPrices: map[string]OsPrice{
+				Windows: {SpotPrice: SpotPriceInfo{"1": 0.041, "2": 0.041}},
+			}},
+		},
+		{
+			name:     "gce spot prices without a spot vm rate",
+			provider: "gce",
+			price: Price{OnDemandPrice: 0.0475, SpotPrice: SpotPriceInfo{"us-central1-a": 0.0101},
+				PreemptiblePrice: SpotPriceInfo{"us-central1-a": 0.01}, OsPrices: map[string]OsPrice{
+					Rhel: {OnDemandPrice: 0.1075, SpotPrice: SpotPriceInfo{"us-central1-a": 0.0701},
+						PreemptiblePrice: SpotPriceInfo{"us-central1-a": 0.07}},
+				}},
+			spot: Price{},
+			expected: Price{OnDemandPrice: 0.0475, PreemptiblePrice: SpotPriceInfo{"us-central1-a": 0.01}, OsPrices: map[string]OsPrice{
+				Rhel: {OnDemandPrice: 0.1075, PreemptiblePrice: SpotPriceInfo{"us-central1-a": 0.07}},
+			}},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c := cache.New(5*time.Minute, 10*time.Minute)
+			productInfo, _ := NewCachingProductInfo(10*time.Second, c, map[string]ProductInfoer{test.provider: &DummyProductInfoer{}})
+			c.Set(productInfo.getPriceKey(test.provider, "region", "type"), test.price, cache.NoExpiration)
+			c.Set(productInfo.getSpotPriceKey(test.provider, "region", "type"), test.spot.SpotPrices(), cache.NoExpiration)
+
+			price, ok := productInfo.getCachedPrice(test.provider, "region", "type")
+			assert.True(t, ok)
+			assert.Equal(t, test.expected, price, "every spot price should be replaced by the current ones")
+		})
+	}
+}
+
+func TestCachingProductInfo_GetProductDetailsForOs(t *testing.T) {
+	c := cache.New(5*time.Minute, 10*time.Minute)
+	productInfo, _ := NewCachingProductInfo(10*time.Second, c, map[string]ProductInfoer{"dummy": &DummyProductInfoer{}})
+	c.Set(productInfo.getVmKey("dummy", "dummyRegion"), []VmInfo{
+		{Type: "m5.large", OnDemandPrice: 0.1, OsPrices: map[string]OsPrice{Windows: {OnDemandPrice: 0.2}}},
+		{Type: "m5.xlarge", OnDemandPrice: 0.2},
+	}, cache.NoExpiration)
+	c.Set(productInfo.getPriceKey("dummy", "dummyRegion", "m5.large"), Price{
+		OnDemandPrice: -1,
+		SpotPrice:     SpotPriceInfo{"zone-a": 0.04},
+		OsPrices: map[string]OsPrice{Windows: {SpotPrice: SpotPriceInfo{"zone-a": 0.12},
+			PreemptiblePrice: SpotPriceInfo{"zone-a": 0.13}}},
+		PreemptiblePrice: SpotPriceInfo{"zone-a": 0.05},
+	}, cache.NoExpiration)
+
+	details, err := productInfo.GetProductDetailsForOs("dummy", "dummyRegion", Windows)
+	assert.Nil(t, err, "the error should be nil")
+	assert.Equal(t, 1, len(details), "types without windows prices should be left out")
+	assert.Equal(t, 0.2, details[0].OnDemandPrice)
+	assert.Equal(t, []ZonePrice{{Zone: "zone-a", Price: 0.12}}, details[0].SpotInfo)
+	assert.Equal(t, []ZonePrice{{Zone: "zone-a", Price: 0.13}}, details[0].PreemptibleInfo)
+
+	details, err = productInfo.GetProductDetails("dummy", "dummyRegion")
+	assert.Nil(t, err, "the error should be nil")
+	assert.Equal(t, 2, len(details))
+	assert.Equal(t, 0.1, details[0].OnDemandPrice)
+	assert.Nil(t, details[0].OsPrices)
+	assert.Equal(t, []ZonePrice{{Zone: "zone-a", Price: 0.05}}, details[0].PreemptibleInfo)
+
+	onDemand, spot, err := productInfo.GetOsPrice("dummy", "dummyRegion", "m5.large", Windows, []string{"zone-a"})
+	assert.Nil(t, err, "the error should be nil")
+	assert.Equal(t, float64(0), onDemand)
+	assert.Equal(t, 0.12, spot)
+}
@@ -0,0 +1,239 @@
+package productinfo
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// QualityIssueKind the kind of a data quality issue found while scraping
+type QualityIssueKind string
+
+const (
+	// ZeroOnDemandPrice an instance type without an on demand price
+	ZeroOnDemandPrice QualityIssueKind = "zero_ondemand_price"
+	// PriceWithoutVm a price of an instance type that's not in the product catalog of the region
+	PriceWithoutVm QualityIssueKind = "price_without_vm"
+	// UnmappedRegion a provider specific region name that couldn't be mapped to a region id
+	UnmappedRegion QualityIssueKind = "unmapped_region"
+	// UnknownNetworkPerf a network performance that's not known by the network performance mapper
+	UnknownNetworkPerf QualityIssueKind = "unknown_network_performance"
+	// UnparsableProduct a product description the provider specific code couldn't parse
+	UnparsableProduct QualityIssueKind = "unparsable_product"
+)
+
+var (
+	// QualityIssuesGauge collects metrics for the prometheus
+	QualityIssuesGauge = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "productinfo",
+		Name:      "data_quality_issues",
+		Help:      "Number of data quality issues found in the last scrape, partitioned by provider, region and kind",
+	},
+		[]string{"provider", "region", "kind"},
+	)
+)
+
+// QualityIssue a data quality issue found while scraping
+type QualityIssue struct {
+	Kind QualityIssueKind `json:"kind"`
+	// Region the region of the issue, empty if it's not known
+	Region string `json:"region,omitempty"`
+	// Subject the instance type, meter or attribute value the issue refers to
+	Subject string `json:"subject"`
+	Detail  string `json:"detail,omitempty"`
+}
+
+// QualityIssueSource is implemented by the product infoers that collect data quality issues while scraping
+type QualityIssueSource interface {
+	// QualityIssues returns the issues found during the last Initialize call
+	QualityIssues() []QualityIssue
+}
+
+// ProductQualityIssueSource is implemented by the product infoers that collect data quality issues while retrieving
+// the products of a region
+type ProductQualityIssueSource interface {
+	// ProductQualityIssues returns the issues found during the last GetProducts call of the region
+	ProductQualityIssues(region string) []QualityIssue
+}
+
+// CurrentPriceQualityIssueSource is implemented by the product infoers that collect data quality issues while
+// retrieving the short lived prices of a region
+type CurrentPriceQualityIssueSource interface {
+	// CurrentPriceQualityIssues returns the issues found during the last GetCurrentPrices call of the region
+	CurrentPriceQualityIssues(region string) []QualityIssue
+}
+
+// QualityReport the data quality issues of a region
+type QualityReport struct {
+	Provider string                   `json:"provider"`
+	Region   string                   `json:"region"`
+	Updated  time.Time                `json:"updated"`
+	Counts   map[QualityIssueKind]int `json:"counts"`
+	Issues   []QualityIssue           `json:"issues"`
+}
+
+type qualityKey struct {
+	provider string
+	region   string
+}
+
+// QualityTracker collects the data quality issues per provider and region, issues are replaced on every renewal
+type QualityTracker struct {
+	mu sync.RWMutex
+	// issues found by the product infoers during Initialize
+	initIssues map[qualityKey][]QualityIssue
+	// issues found while renewing the products of a region
+	vmIssues map[qualityKey][]QualityIssue
+	// issues found while renewing the short lived prices of a region
+	currentPriceIssues map[qualityKey][]QualityIssue
+	updated            map[qualityKey]time.Time
+	priceTypes         map[qualityKey]map[string]bool
+	regions            map[string]map[string]bool
+}
+
+// NewQualityTracker creates a new data quality tracker
+func NewQualityTracker() *QualityTracker {
+	return &QualityTracker{
+		initIssues:         make(map[qualityKey][]QualityIssue),
+		vmIssues:           make(map[qualityKey][]QualityIssue),
+		currentPriceIssues: make(map[qualityKey][]QualityIssue),
+		updated:            make(map[qualityKey]time.Time),
+		priceTypes:         make(map[qualityKey]map[string]bool),
+		regions:            make(map[string]map[string]bool),
+	}
+}
+
+// initialized records the prices and the issues found during the Initialize call of a provider
+func (q *QualityTracker) initialized(provider string, allPrices map[string]map[string]Price, issues []QualityIssue) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	now := time.Now()
+	for k := range q.initIssues {
+		if k.provider == provider {
+			delete(q.initIssues, k)
+		}
+	}
+	for region, prices := range allPrices {
+		types := make(map[string]bool, len(prices))
+		for t := range prices {
+			types[t] = true
+		}
+		q.priceTypes[qualityKey{provider, region}] = types
+	}
+	for _, issue := range issues {
+		k := qualityKey{provider, issue.Region}
+		q.initIssues[k] = append(q.initIssues[k], issue)
+		q.updated[k] = now
+	}
+	q.addRegions(provider)
+	q.updateMetrics(provider)
+}
+
+// productsRenewed checks the renewed products of a region, found are the issues reported by the product infoer
+// onDemand returns the separately stored on demand price and mapNtwPerf the network performance category of a vm
+func (q *QualityTracker) productsRenewed(provider string, region string, vms []VmInfo, found []QualityIssue,
+	onDemand func(instanceType string) float64, mapNtwPerf func(vm VmInfo) error) {
+	issues := append([]QualityIssue{}, found...)
+	types := make(map[string]bool, len(vms))
+	for _, vm := range vms {
+		types[vm.Type] = true
+		if vm.OnDemandPrice <= 0 && onDemand(vm.Type) <= 0 {
+			issues = append(issues, QualityIssue{Kind: ZeroOnDemandPrice, Region: region, Subject: vm.Type})
+		}
+		if err := mapNtwPerf(vm); err != nil {
+			issues = append(issues, QualityIssue{Kind: UnknownNetworkPerf, Region: region, Subject: vm.NtwPerf,
+				Detail: "instance type " + vm.Type})
+		}
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	k := qualityKey{provider, region}
+	var orphans []string
+	for t := range q.priceTypes[k] {
+		if !types[t] {
+			orphans = append(orphans, t)
+		}
+	}
+	sort.Strings(orphans)
+	for _, t := range orphans {
+		issues = append(issues, QualityIssue{Kind: PriceWithoutVm, Region: region, Subject: t})
+	}
+	q.vmIssues[k] = issues
+	q.updated[k] = time.Now()
+	q.addRegions(provider)
+	q.updateMetrics(provider)
+}
+
+// currentPricesRenewed records the issues found while renewing the short lived prices of a region, the issues of the
+// previous renewal are replaced
+func (q *QualityTracker) currentPricesRenewed(provider string, region string, issues []QualityIssue) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	k := qualityKey{provider, region}
+	if len(issues) == 0 && len(q.currentPriceIssues[k]) == 0 {
+		return
+	}
+	q.currentPriceIssues[k] = issues
+	q.updated[k] = time.Now()
+	q.addRegions(provider)
+	q.updateMetrics(provider)
+}
+
+// Reports returns the data quality reports of the regions of a provider ordered by region
+func (q *QualityTracker) Reports(provider string) []QualityReport {
+	q.mu.RLock()
+	defer q.mu.RUnlock()
+	reports := make([]QualityReport, 0)
+	for region := range q.regions[provider] {
+		reports = append(reports, q.report(qualityKey{provider, region}))
+	}
+	sort.Slice(reports, func(i, j int) bool {
+		return reports[i].Region < reports[j].Region
+	})
+	return reports
+}
+
+func (q *QualityTracker) report(k qualityKey) QualityReport {
+	r := QualityReport{
+		Provider: k.provider,
+		Region:   k.region,
+		Updated:  q.updated[k],
+		Counts:   make(map[QualityIssueKind]int),
+		Issues:   make([]QualityIssue, 0),
+	}
+	r.Issues = append(r.Issues, q.initIssues[k]...)
+	r.Issues = append(r.Issues, q.vmIssues[k]...)
+	r.Issues = append(r.Issues, q.currentPriceIssues[k]...)
+	for _, issue := range r.Issues {
+		r.Counts[issue.Kind]++
+	}
+	return r
+}
+
+// addRegions registers the regions of a provider that have issues or were checked, must be called with the lock held
+func (q *QualityTracker) addRegions(provider string) {
+	if q.regions[provider] == nil {
+		q.regions[provider] = make(map[string]bool)
+	}
+	for _, keys := range []map[qualityKey][]QualityIssue{q.initIssues, q.vmIssues, q.currentPriceIssues} {
+		for k := range keys {
+			if k.provider == provider {
+				q.regions[provider][k.region] = true
+			}
+		}
+	}
+}
+
+// updateMetrics sets the issue counts of a provider, must be called with the lock held
+func (q *QualityTracker) updateMetrics(provider string) {
+	kinds := []QualityIssueKind{ZeroOnDemandPrice, PriceWithoutVm, UnmappedRegion, UnknownNetworkPerf, UnparsableProduct}
+	for region := range q.regions[provider] {
+		r := q.report(qualityKey{provider, region})
+		for _, kind := range kinds {
+			QualityIssuesGauge.WithLabelValues(provider, region, string(kind)).Set(float64(r.Counts[kind]))
+		}
+	}
+}
@@ -0,0 +1,93 @@
+package productinfo
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/patrickmn/go-cache"
+	"github.com/stretchr/testify/assert"
+)
+
+// qualityIssueInfoer reports the configured issues after Initialize
+type qualityIssueInfoer struct {
+	DummyProductInfoer
+	issues []QualityIssue
+}
+
+func (qi *qualityIssueInfoer) QualityIssues() []QualityIssue {
+	return qi.issues
+}
+
+func TestQualityTracker_productsRenewed(t *testing.T) {
+	q := NewQualityTracker()
+	q.initialized("ec2", map[string]map[string]Price{"eu-west-1": {"m5.large": {OnDemandPrice: 0.1}, "m3.large": {OnDemandPrice: 0.13}}}, nil)
+	vms := []VmInfo{
+		{Type: "m5.large", NtwPerf: "Up to 10 Gigabit"},
+		{Type: "m5.xlarge", NtwPerf: "Very fast"},
+	}
+	found := []QualityIssue{{Kind: UnparsableProduct, Region: "eu-west-1", Subject: "m5.2xlarge", Detail: "could not retrieve memory"}}
+	q.productsRenewed("ec2", "eu-west-1", vms, found, func(instanceType string) float64 {
+		if instanceType == "m5.large" {
+			return 0.1
+		}
+		return 0
+	}, func(vm VmInfo) error {
+		if vm.NtwPerf == "Very fast" {
+			return errors.New("unknown")
+		}
+		return nil
+	})
+
+	reports := q.Reports("ec2")
+	assert.Equal(t, 1, len(reports))
+	assert.Equal(t, "eu-west-1", reports[0].Region)
+	assert.Equal(t, map[QualityIssueKind]int{UnparsableProduct: 1, ZeroOnDemandPrice: 1, UnknownNetworkPerf: 1, PriceWithoutVm: 1}, reports[0].Counts)
+	assert.Equal(t, []QualityIssue{
+		{Kind: UnparsableProduct, Region: "eu-west-1", Subject: "m5.2xlarge", Detail: "could not retrieve memory"},
+		{Kind: ZeroOnDemandPrice, Region: "eu-west-1", Subject: "m5.xlarge"},
+		{Kind: UnknownNetworkPerf, Region: "eu-west-1", Subject: "Very fast", Detail: "instance type m5.xlarge"},
+		{Kind: PriceWithoutVm, Region: "eu-west-1", Subject: "m3.large"},
+	}, reports[0].Issues)
+	assert.Equal(t, 0, len(q.Reports("gce")))
+}
+
+func TestQualityTracker_currentPricesRenewed(t *testing.T) {
+	q := NewQualityTracker()
+	issue := QualityIssue{Kind: UnparsableProduct, Region: "westeurope", Subject: "D2s v3 Spot", Detail: "no vm size"}
+	q.currentPricesRenewed("azure", "westeurope", []QualityIssue{issue})
+
+	reports := q.Reports("azure")
+	assert.Equal(t, 1, len(reports))
+	assert.Equal(t, []QualityIssue{issue}, reports[0].Issues)
+
+	q.currentPricesRenewed("azure", "westeurope", nil)
+	reports = q.Reports("azure")
+	assert.Equal(t, 1, len(reports))
+	assert.Equal(t, 0, len(reports[0].Issues), "the issues of the previous renewal should be replaced")
+}
+
+func TestCachingProductInfo_QualityReports(t *testing.T) {
+	infoer := &qualityIssueInfoer{
+		DummyProductInfoer: DummyProductInfoer{Vms: []VmInfo{{Type: "c1.xlarge", OnDemandPrice: 0.52}}},
+		issues:             []QualityIssue{{Kind: UnmappedRegion, Subject: "US Gov TX"}},
+	}
+	productInfo, _ := NewCachingProductInfo(10*time.Second, cache.New(5*time.Minute, 10*time.Minute), map[string]ProductInfoer{"dummy": infoer})
+	_, err := productInfo.Initialize("dummy")
+	assert.Nil(t, err, "the error should be nil")
+	infoer.issues = nil
+	_, err = productInfo.renewVms("dummy", "dummyRegion")
+	assert.Nil(t, err, "the error should be nil")
+
+	reports := productInfo.QualityReports("dummy")
+	assert.Equal(t, 2, len(reports))
+	assert.Equal(t, "", reports[0].Region)
+	assert.Equal(t, map[QualityIssueKind]int{UnmappedRegion: 1}, reports[0].Counts)
+	assert.Equal(t, "dummyRegion", reports[1].Region)
+	assert.Equal(t, 0, len(reports[1].Issues))
+
+	// the issues of the previous Initialize are replaced
+	_, err = productInfo.Initialize("dummy")
+	assert.Nil(t, err, "the error should be nil")
+	assert.Equal(t, 0, len(productInfo.QualityReports("dummy")[0].Issues))
+}
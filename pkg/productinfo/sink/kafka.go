@@ -0,0 +1,67 @@
+package sink
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/banzaicloud/productinfo/pkg/productinfo"
+)
+
+const kafkaContentType = "application/vnd.kafka.json.v2+json"
+
+type kafkaRecord struct {
+	Key   string                  `json:"key"`
+	Value productinfo.ChangeEvent `json:"value"`
+}
+
+type kafkaRecords struct {
+	Records []kafkaRecord `json:"records"`
+}
+
+// KafkaSink produces the change events to a Kafka topic through the v2 API of a Kafka REST Proxy, the native Kafka
+// protocol is not supported
+// records are keyed by provider and region so the events of a region keep their order
+type KafkaSink struct {
+	address string
+	topic   string
+	client  *http.Client
+}
+
+// NewKafkaSink creates a new Kafka sink, the address is the base URL of the REST proxy
+func NewKafkaSink(address string, topic string) *KafkaSink {
+	return &KafkaSink{
+		address: strings.TrimSuffix(address, "/"),
+		topic:   topic,
+		client:  &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// Send produces the events to the topic in a single request
+func (s *KafkaSink) Send(events []productinfo.ChangeEvent) error {
+	records := kafkaRecords{Records: make([]kafkaRecord, 0, len(events))}
+	for _, e := range events {
+		records.Records = append(records.Records, kafkaRecord{Key: e.Provider + "/" + e.Region, Value: e})
+	}
+	body, err := json.Marshal(records)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest(http.MethodPost, fmt.Sprintf("%s/topics/%s", s.address, s.topic), bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", kafkaContentType)
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("kafka rest proxy responded with status %d", resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,220 @@
+package sink
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/banzaicloud/productinfo/pkg/productinfo"
+)
+
+// NatsSink publishes every change event as a JSON message to a NATS subject
+// the subject is suffixed with the provider and the event type, e.g. productinfo.changes.ec2.type_added
+// the events are delivered at least once: a batch is published again as a whole if the connection is lost before the
+// server acknowledges it, the key of the events is sent in the Nats-Msg-Id header if the server supports headers, so
+// JetStream drops the duplicates within its duplicate window, other consumers should drop them by the key of the event
+type NatsSink struct {
+	address string
+	subject string
+	timeout time.Duration
+
+	mu   sync.Mutex
+	conn *natsConn
+}
+
+// NewNatsSink creates a new NATS sink, the address is in host:port form
+func NewNatsSink(address string, subject string) *NatsSink {
+	return &NatsSink{
+		address: address,
+		subject: subject,
+		timeout: 10 * time.Second,
+	}
+}
+
+// Send publishes the events over the connection of the sink and waits for the server to acknowledge them with a PONG,
+// the connection is kept open between the batches and it's reestablished if it was lost
+func (s *NatsSink) Send(events []productinfo.ChangeEvent) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	reused := s.conn != nil
+	err := s.publish(events)
+	if err != nil && reused {
+		// the server may have dropped the connection since the previous batch, the batch is retried once over a new one
+		err = s.publish(events)
+	}
+	return err
+}
+
+// publish sends the events over the current connection, it connects first if there's no connection
+// the connection is closed and dropped on any error
+func (s *NatsSink) publish(events []productinfo.ChangeEvent) error {
+	if s.conn == nil {
+		conn, err := dialNats(s.address, s.timeout)
+		if err != nil {
+			return err
+		}
+		s.conn = conn
+	}
+	if err := s.conn.publish(s.subject, events, s.timeout); err != nil {
+		s.conn.close()
+		s.conn = nil
+		return err
+	}
+	return nil
+}
+
+// natsConn a connection to a NATS server, the PINGs of the server are answered in the background
+type natsConn struct {
+	conn net.Conn
+
+	// wMu guards the writer, the PONGs are written from the reading goroutine
+	wMu sync.Mutex
+	w   *bufio.Writer
+
+	// headers whether the server accepts messages with headers
+	headers bool
+
+	// replies receives nil for a PONG and the error for an -ERR of the server
+	replies chan error
+	done    chan struct{}
+	once    sync.Once
+}
+
+// dialNats connects to the server, reads its INFO greeting and sends the CONNECT message
+func dialNats(address string, timeout time.Duration) (*natsConn, error) {
+	conn, err := net.DialTimeout("tcp", address, timeout)
+	if err != nil {
+		return nil, err
+	}
+	conn.SetReadDeadline(time.Now().Add(timeout))
+	r := bufio.NewReader(conn)
+	line, err := r.ReadString('\n')
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	if !strings.HasPrefix(line, "INFO") {
+		conn.Close()
+		return nil, fmt.Errorf("unexpected greeting from NATS server: %q", strings.TrimSpace(line))
+	}
+	var info struct {
+		Headers bool `json:"headers"`
+	}
+	if err := json.Unmarshal([]byte(strings.TrimPrefix(line, "INFO")), &info); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("invalid INFO from NATS server: %v", err)
+	}
+	conn.SetReadDeadline(time.Time{})
+
+	c := &natsConn{
+		conn:    conn,
+		w:       bufio.NewWriter(conn),
+		headers: info.Headers,
+		replies: make(chan error),
+		done:    make(chan struct{}),
+	}
+	go c.read(r)
+	return c, c.write(timeout, func(w *bufio.Writer) error {
+		_, err := fmt.Fprintf(w, "CONNECT {\"verbose\":false,\"pedantic\":false,\"name\":\"productinfo\",\"headers\":%t}\r\n", c.headers)
+		return err
+	})
+}
+
+// publish writes a PUB (or HPUB with the Nats-Msg-Id header) message per event followed by a PING, and waits for the
+// PONG of the server
+func (c *natsConn) publish(subject string, events []productinfo.ChangeEvent, timeout time.Duration) error {
+	err := c.write(timeout, func(w *bufio.Writer) error {
+		for _, e := range events {
+			msg, err := json.Marshal(e)
+			if err != nil {
+				return err
+			}
+			if c.headers {
+				hdr := fmt.Sprintf("NATS/1.0\r\nNats-Msg-Id: %s\r\n\r\n", e.Key)
+				fmt.Fprintf(w, "HPUB %s.%s.%s %d %d\r\n%s", subject, e.Provider, e.Type, len(hdr), len(hdr)+len(msg), hdr)
+			} else {
+				fmt.Fprintf(w, "PUB %s.%s.%s %d\r\n", subject, e.Provider, e.Type, len(msg))
+			}
+			w.Write(msg)
+			w.WriteString("\r\n")
+		}
+		_, err := w.WriteString("PING\r\n")
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	var serverErr error
+	deadline := time.After(timeout)
+	for {
+		select {
+		case reply, ok := <-c.replies:
+			if !ok {
+				return errors.New("NATS connection closed")
+			}
+			if reply != nil {
+				serverErr = reply
+				continue
+			}
+			return serverErr
+		case <-deadline:
+			return errors.New("timed out waiting for the NATS server to acknowledge the events")
+		}
+	}
+}
+
+// write writes and flushes the messages written by fn while holding the writer lock
+func (c *natsConn) write(timeout time.Duration, fn func(w *bufio.Writer) error) error {
+	c.wMu.Lock()
+	defer c.wMu.Unlock()
+	c.conn.SetWriteDeadline(time.Now().Add(timeout))
+	if err := fn(c.w); err != nil {
+		return err
+	}
+	return c.w.Flush()
+}
+
+// read processes the messages of the server until the connection is closed
+func (c *natsConn) read(r *bufio.Reader) {
+	defer close(c.replies)
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		var reply error
+		switch {
+		case strings.HasPrefix(line, "PING"):
+			c.write(10*time.Second, func(w *bufio.Writer) error {
+				_, err := w.WriteString("PONG\r\n")
+				return err
+			})
+			continue
+		case strings.HasPrefix(line, "PONG"):
+		case strings.HasPrefix(line, "-ERR"):
+			reply = fmt.Errorf("NATS server error: %s", strings.TrimSpace(strings.TrimPrefix(line, "-ERR")))
+		default:
+			continue
+		}
+		select {
+		case c.replies <- reply:
+		case <-c.done:
+			return
+		}
+	}
+}
+
+// close closes the connection, it stops the reading goroutine as well
+func (c *natsConn) close() {
+	c.once.Do(func() {
+		close(c.done)
+		c.conn.Close()
+	})
+}
@@ -0,0 +1,194 @@
+package sink
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/banzaicloud/productinfo/pkg/productinfo"
+	"github.com/stretchr/testify/assert"
+)
+
+var testEvents = []productinfo.ChangeEvent{
+	{Id: 1, Key: "test-1", Type: productinfo.TypeAdded, Provider: "ec2", Region: "eu-west-1", InstanceType: "m5.large"},
+	{Id: 2, Key: "test-2", Type: productinfo.SpotPriceChanged, Provider: "ec2", Region: "eu-west-1", InstanceType: "m4.large", Zone: "eu-west-1a", OldPrice: 0.03, NewPrice: 0.04},
+}
+
+func TestWebhookSink_Send(t *testing.T) {
+	tests := []struct {
+		name     string
+		failures int
+		retries  int
+		check    func(err error, calls int, signature string, body []byte)
+	}{
+		{
+			name: "signed payload delivered",
+			check: func(err error, calls int, signature string, body []byte) {
+				assert.Nil(t, err, "the error should be nil")
+				assert.Equal(t, 1, calls)
+				assert.Equal(t, Sign([]byte("secret"), body), signature)
+				var p Payload
+				assert.Nil(t, json.Unmarshal(body, &p))
+				assert.Equal(t, 2, len(p.Events))
+			},
+		},
+		{
+			name:     "failed deliveries retried",
+			failures: 2,
+			retries:  2,
+			check: func(err error, calls int, signature string, body []byte) {
+				assert.Nil(t, err, "the error should be nil")
+				assert.Equal(t, 3, calls)
+			},
+		},
+		{
+			name:     "error after the retries are exhausted",
+			failures: 3,
+			retries:  1,
+			check: func(err error, calls int, signature string, body []byte) {
+				assert.NotNil(t, err, "the error should not be nil")
+				assert.Equal(t, 2, calls)
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var calls int
+			var signature string
+			var body []byte
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				calls++
+				signature = r.Header.Get(SignatureHeader)
+				body, _ = ioutil.ReadAll(r.Body)
+				if calls <= test.failures {
+					w.WriteHeader(http.StatusServiceUnavailable)
+				}
+			}))
+			defer server.Close()
+			err := NewWebhookSink(server.URL, "secret", test.retries, time.Millisecond).Send(testEvents)
+			test.check(err, calls, signature, body)
+		})
+	}
+}
+
+func TestNatsSink_Send(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	defer l.Close()
+
+	// the server PINGs every connection before the first batch, and closes it after the given number of batches
+	subjects := make(chan []string, 3)
+	serve := func(batches int) {
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		conn.Write([]byte("INFO {\"server_id\":\"test\"}\r\nPING\r\n"))
+		r := bufio.NewReader(conn)
+		var received []string
+		for batches > 0 {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				return
+			}
+			switch {
+			case strings.HasPrefix(line, "PUB"):
+				received = append(received, strings.Fields(line)[1])
+				r.ReadString('\n')
+			case strings.HasPrefix(line, "PING"):
+				conn.Write([]byte("PONG\r\n"))
+				subjects <- received
+				received = nil
+				batches--
+			}
+		}
+	}
+	go func() {
+		serve(2)
+		serve(1)
+	}()
+
+	s := NewNatsSink(l.Addr().String(), "productinfo.changes")
+	expected := []string{"productinfo.changes.ec2.type_added", "productinfo.changes.ec2.spot_price_changed"}
+	for i := 0; i < 3; i++ {
+		// the first connection is reused by the second batch, the third batch is sent over a new one
+		err = s.Send(testEvents)
+		assert.Nil(t, err, "the error should be nil")
+		assert.Equal(t, expected, <-subjects)
+	}
+}
+
+func TestNatsSink_SendHeaders(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	defer l.Close()
+
+	// the server supports headers, the ids of the messages are collected until the PING of the batch
+	msgIds := make(chan []string, 1)
+	go func() {
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		conn.Write([]byte("INFO {\"server_id\":\"test\",\"headers\":true}\r\n"))
+		r := bufio.NewReader(conn)
+		var ids []string
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				return
+			}
+			fields := strings.Fields(line)
+			switch {
+			case strings.HasPrefix(line, "CONNECT") && !strings.Contains(line, `"headers":true`):
+				conn.Write([]byte("-ERR 'headers not enabled'\r\n"))
+			case strings.HasPrefix(line, "HPUB"):
+				hdrLen, _ := strconv.Atoi(fields[2])
+				totalLen, _ := strconv.Atoi(fields[3])
+				msg := make([]byte, totalLen+2)
+				io.ReadFull(r, msg)
+				for _, h := range strings.Split(string(msg[:hdrLen]), "\r\n") {
+					if strings.HasPrefix(h, "Nats-Msg-Id: ") {
+						ids = append(ids, strings.TrimPrefix(h, "Nats-Msg-Id: "))
+					}
+				}
+			case strings.HasPrefix(line, "PING"):
+				conn.Write([]byte("PONG\r\n"))
+				msgIds <- ids
+				return
+			}
+		}
+	}()
+
+	err = NewNatsSink(l.Addr().String(), "productinfo.changes").Send(testEvents)
+	assert.Nil(t, err, "the error should be nil")
+	assert.Equal(t, []string{"test-1", "test-2"}, <-msgIds, "the keys of the events should be sent as message ids")
+}
+
+func TestKafkaSink_Send(t *testing.T) {
+	var path, contentType string
+	var records kafkaRecords
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path = r.URL.Path
+		contentType = r.Header.Get("Content-Type")
+		json.NewDecoder(r.Body).Decode(&records)
+	}))
+	defer server.Close()
+
+	err := NewKafkaSink(server.URL+"/", "changes").Send(testEvents)
+	assert.Nil(t, err, "the error should be nil")
+	assert.Equal(t, "/topics/changes", path)
+	assert.Equal(t, kafkaContentType, contentType)
+	assert.Equal(t, 2, len(records.Records))
+	assert.Equal(t, "ec2/eu-west-1", records.Records[0].Key)
+}
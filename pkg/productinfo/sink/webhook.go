@@ -0,0 +1,110 @@
+// Package sink contains the implementations delivering catalog change events to external systems
+package sink
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/banzaicloud/productinfo/pkg/productinfo"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// SignatureHeader the header holding the HMAC-SHA256 signature of the webhook payload
+	SignatureHeader = "X-Productinfo-Signature"
+	// EventHeader the header holding the number of events in the webhook payload
+	EventHeader = "X-Productinfo-Events"
+)
+
+// Payload the body of the webhook requests
+type Payload struct {
+	Events []productinfo.ChangeEvent `json:"events"`
+}
+
+// Webhook posts signed JSON payloads to an HTTP endpoint
+type Webhook struct {
+	url     string
+	secret  []byte
+	retries int
+	backoff time.Duration
+	client  *http.Client
+}
+
+// NewWebhook creates a new webhook, requests are signed if the secret is not empty and failed deliveries are
+// retried with exponential backoff
+func NewWebhook(url string, secret string, retries int, backoff time.Duration) *Webhook {
+	return &Webhook{
+		url:     url,
+		secret:  []byte(secret),
+		retries: retries,
+		backoff: backoff,
+		client:  &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// Sign computes the value of the signature header for the payload
+func Sign(secret []byte, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Post posts the payload in JSON, count is sent in the events header
+func (w *Webhook) Post(payload interface{}, count int) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	backoff := w.backoff
+	for attempt := 0; ; attempt++ {
+		err = w.post(body, count)
+		if err == nil || attempt >= w.retries {
+			return err
+		}
+		log.WithError(err).Warnf("webhook delivery to %s failed, retrying in %s", w.url, backoff)
+		time.Sleep(backoff)
+		backoff *= 2
+	}
+}
+
+func (w *Webhook) post(body []byte, count int) error {
+	req, err := http.NewRequest(http.MethodPost, w.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(EventHeader, fmt.Sprint(count))
+	if len(w.secret) > 0 {
+		req.Header.Set(SignatureHeader, Sign(w.secret, body))
+	}
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("webhook responded with status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// WebhookSink posts the change events to an HTTP endpoint in JSON
+type WebhookSink struct {
+	*Webhook
+}
+
+// NewWebhookSink creates a new webhook sink
+func NewWebhookSink(url string, secret string, retries int, backoff time.Duration) *WebhookSink {
+	return &WebhookSink{NewWebhook(url, secret, retries, backoff)}
+}
+
+// Send posts the events to the webhook endpoint
+func (s *WebhookSink) Send(events []productinfo.ChangeEvent) error {
+	return s.Post(Payload{Events: events}, len(events))
+}
@@ -0,0 +1,220 @@
+package productinfo
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/banzaicloud/productinfo/pkg/productinfo/forecast"
+	log "github.com/sirupsen/logrus"
+)
+
+// SpotPriceSample a spot price of an instance type in an availability zone observed at a given time
+type SpotPriceSample struct {
+	Time         time.Time
+	Provider     string
+	Region       string
+	InstanceType string
+	Zone         string
+	Price        float64
+}
+
+// SpotPriceSeries regularly spaced spot prices, Values[i] is the average price of the i-th period after Start
+type SpotPriceSeries struct {
+	Start  time.Time
+	Values []float64
+}
+
+type spotSeriesKey struct {
+	provider     string
+	region       string
+	instanceType string
+	zone         string
+}
+
+type spotBucket struct {
+	start time.Time
+	sum   float64
+	count int
+}
+
+// SpotPriceHistory keeps the spot prices collected during the short lived info renewals, aggregated into periods of
+// the given resolution. Samples can optionally be recorded in CSV format for offline analysis
+type SpotPriceHistory struct {
+	mu         sync.RWMutex
+	resolution time.Duration
+	retention  time.Duration
+	series     map[spotSeriesKey][]spotBucket
+	recorder   *csv.Writer
+}
+
+// NewSpotPriceHistory creates a new spot price history
+func NewSpotPriceHistory(resolution time.Duration, retention time.Duration) *SpotPriceHistory {
+	return &SpotPriceHistory{
+		resolution: resolution,
+		retention:  retention,
+		series:     make(map[spotSeriesKey][]spotBucket),
+	}
+}
+
+// Resolution returns the length of the periods the samples are aggregated into
+func (h *SpotPriceHistory) Resolution() time.Duration {
+	return h.resolution
+}
+
+// RecordTo sets the writer every sample is recorded to in CSV format
+func (h *SpotPriceHistory) RecordTo(w io.Writer) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.recorder = csv.NewWriter(w)
+}
+
+// AddPrices adds the spot prices of a region to the history
+func (h *SpotPriceHistory) AddPrices(provider string, region string, prices map[string]Price, t time.Time) {
+	for instType, p := range prices {
+		for zone, price := range p.SpotPrice {
+			h.Add(SpotPriceSample{Time: t, Provider: provider, Region: region, InstanceType: instType, Zone: zone, Price: price})
+		}
+	}
+}
+
+// Add adds a single sample to the history, samples older than the latest period of their series are dropped
+func (h *SpotPriceHistory) Add(s SpotPriceSample) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.recorder != nil {
+		h.recorder.Write(sampleToRecord(s))
+		h.recorder.Flush()
+		if err := h.recorder.Error(); err != nil {
+			log.WithError(err).Warn("couldn't record spot price sample")
+		}
+	}
+
+	key := spotSeriesKey{s.Provider, s.Region, s.InstanceType, s.Zone}
+	buckets := h.series[key]
+	start := s.Time.Truncate(h.resolution)
+	if n := len(buckets); n > 0 {
+		last := &buckets[n-1]
+		switch {
+		case start.Equal(last.start):
+			last.sum += s.Price
+			last.count++
+			return
+		case start.Before(last.start):
+			return
+		}
+	}
+	buckets = append(buckets, spotBucket{start: start, sum: s.Price, count: 1})
+
+	// drop the periods out of the retention window
+	cutoff := start.Add(-h.retention)
+	i := 0
+	for i < len(buckets) && !buckets[i].start.After(cutoff) {
+		i++
+	}
+	h.series[key] = buckets[i:]
+}
+
+// Series returns the regularly spaced price series of an instance type per zone
+// periods without samples are filled with the price of the previous period
+func (h *SpotPriceHistory) Series(provider string, region string, instanceType string) map[string]SpotPriceSeries {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	result := make(map[string]SpotPriceSeries)
+	for key, buckets := range h.series {
+		if key.provider != provider || key.region != region || key.instanceType != instanceType || len(buckets) == 0 {
+			continue
+		}
+		result[key.zone] = h.toSeries(buckets)
+	}
+	return result
+}
+
+// AllSeries returns every price series in the history
+func (h *SpotPriceHistory) AllSeries() map[SpotPriceSample]SpotPriceSeries {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	result := make(map[SpotPriceSample]SpotPriceSeries)
+	for key, buckets := range h.series {
+		if len(buckets) == 0 {
+			continue
+		}
+		result[SpotPriceSample{Provider: key.provider, Region: key.region, InstanceType: key.instanceType, Zone: key.zone}] = h.toSeries(buckets)
+	}
+	return result
+}
+
+func (h *SpotPriceHistory) toSeries(buckets []spotBucket) SpotPriceSeries {
+	first, last := buckets[0].start, buckets[len(buckets)-1].start
+	values := make([]float64, int(last.Sub(first)/h.resolution)+1)
+	for i, b := range buckets {
+		idx := int(b.start.Sub(first) / h.resolution)
+		values[idx] = b.sum / float64(b.count)
+		if i+1 < len(buckets) {
+			next := int(buckets[i+1].start.Sub(first) / h.resolution)
+			for j := idx + 1; j < next; j++ {
+				values[j] = values[idx]
+			}
+		}
+	}
+	return SpotPriceSeries{Start: first, Values: values}
+}
+
+func sampleToRecord(s SpotPriceSample) []string {
+	return []string{
+		s.Time.UTC().Format(time.RFC3339),
+		s.Provider,
+		s.Region,
+		s.InstanceType,
+		s.Zone,
+		strconv.FormatFloat(s.Price, 'f', -1, 64),
+	}
+}
+
+// ReadSpotPriceSamples reads spot price samples recorded in CSV format by a SpotPriceHistory
+func ReadSpotPriceSamples(r io.Reader) ([]SpotPriceSample, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = 6
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	samples := make([]SpotPriceSample, 0, len(records))
+	for i, rec := range records {
+		t, err := time.Parse(time.RFC3339, rec[0])
+		if err != nil {
+			return nil, fmt.Errorf("invalid time in record %d: %s", i+1, err.Error())
+		}
+		price, err := strconv.ParseFloat(rec[5], 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid price in record %d: %s", i+1, err.Error())
+		}
+		samples = append(samples, SpotPriceSample{
+			Time:         t,
+			Provider:     rec[1],
+			Region:       rec[2],
+			InstanceType: rec[3],
+			Zone:         rec[4],
+			Price:        price,
+		})
+	}
+	return samples, nil
+}
+
+// ForecastPoint a forecasted spot price with its confidence interval
+type ForecastPoint struct {
+	Time time.Time `json:"time"`
+	forecast.Point
+}
+
+// ZoneForecast the forecasted spot prices of an instance type in an availability zone
+type ZoneForecast struct {
+	Zone   string          `json:"zone"`
+	Prices []ForecastPoint `json:"prices"`
+}
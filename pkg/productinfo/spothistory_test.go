@@ -0,0 +1,60 @@
+package productinfo
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/patrickmn/go-cache"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSpotPriceHistory_Series(t *testing.T) {
+	start := time.Date(2018, 6, 1, 10, 0, 0, 0, time.UTC)
+	var recorded bytes.Buffer
+	h := NewSpotPriceHistory(time.Hour, 4*time.Hour)
+	h.RecordTo(&recorded)
+
+	h.AddPrices("dummy", "dummyRegion", map[string]Price{"c5.large": {SpotPrice: SpotPriceInfo{"zone1": 0.1}}}, start)
+	h.AddPrices("dummy", "dummyRegion", map[string]Price{"c5.large": {SpotPrice: SpotPriceInfo{"zone1": 0.2}}}, start.Add(30*time.Minute))
+	h.AddPrices("dummy", "dummyRegion", map[string]Price{"c5.large": {SpotPrice: SpotPriceInfo{"zone1": 0.3}}}, start.Add(3*time.Hour))
+	// older than the latest period, dropped
+	h.AddPrices("dummy", "dummyRegion", map[string]Price{"c5.large": {SpotPrice: SpotPriceInfo{"zone1": 0.9}}}, start.Add(time.Hour))
+
+	series := h.Series("dummy", "dummyRegion", "c5.large")
+	assert.Equal(t, map[string]SpotPriceSeries{"zone1": {Start: start, Values: []float64{0.15000000000000002, 0.15000000000000002, 0.15000000000000002, 0.3}}}, series)
+
+	h.AddPrices("dummy", "dummyRegion", map[string]Price{"c5.large": {SpotPrice: SpotPriceInfo{"zone1": 0.4}}}, start.Add(4*time.Hour))
+	series = h.Series("dummy", "dummyRegion", "c5.large")
+	assert.Equal(t, start.Add(3*time.Hour), series["zone1"].Start, "periods out of retention should be dropped")
+
+	samples, err := ReadSpotPriceSamples(&recorded)
+	assert.Nil(t, err, "the error should be nil")
+	assert.Equal(t, 5, len(samples))
+	assert.Equal(t, SpotPriceSample{Time: start.Add(3 * time.Hour), Provider: "dummy", Region: "dummyRegion", InstanceType: "c5.large", Zone: "zone1", Price: 0.3}, samples[2])
+}
+
+func TestCachingProductInfo_GetSpotPriceForecast(t *testing.T) {
+	productInfo, _ := NewCachingProductInfo(10*time.Second, cache.New(5*time.Minute, 10*time.Minute), map[string]ProductInfoer{"dummy": &DummyProductInfoer{}})
+	start := time.Date(2018, 6, 1, 0, 0, 0, 0, time.UTC)
+	for i := 0; i < 72; i++ {
+		productInfo.SpotPriceHistory().AddPrices("dummy", "dummyRegion", map[string]Price{
+			"c5.large": {SpotPrice: SpotPriceInfo{"zone2": 0.2, "zone1": 0.1}},
+		}, start.Add(time.Duration(i)*time.Hour))
+	}
+
+	forecasts, err := productInfo.GetSpotPriceForecast("dummy", "dummyRegion", "c5.large", 6*time.Hour, 0.9)
+	assert.Nil(t, err, "the error should be nil")
+	assert.Equal(t, 2, len(forecasts))
+	assert.Equal(t, "zone1", forecasts[0].Zone)
+	assert.Equal(t, 6, len(forecasts[0].Prices))
+	assert.Equal(t, start.Add(72*time.Hour), forecasts[0].Prices[0].Time)
+	assert.InDelta(t, 0.1, forecasts[0].Prices[5].Value, 1e-9)
+	assert.InDelta(t, 0.2, forecasts[1].Prices[0].Value, 1e-9)
+
+	_, err = productInfo.GetSpotPriceForecast("dummy", "dummyRegion", "m5.large", 6*time.Hour, 0.9)
+	assert.Equal(t, ErrNoSpotPriceHistory, err)
+
+	_, err = productInfo.GetSpotPriceForecast("dummy", "dummyRegion", "c5.large", 73*time.Hour, 0.9)
+	assert.EqualError(t, err, "the forecast duration can be at most the length of the spot price history: 72h0m0s")
+}
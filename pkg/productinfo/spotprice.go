@@ -0,0 +1,216 @@
+package productinfo
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/api"
+	"github.com/prometheus/client_golang/api/prometheus/v1"
+	"github.com/prometheus/common/model"
+	log "github.com/sirupsen/logrus"
+)
+
+// SpotFallbackRule determines when a spot price source chain turns to its next source
+type SpotFallbackRule string
+
+const (
+	// SpotFallbackOnError the next source is queried only if the previous one failed
+	SpotFallbackOnError SpotFallbackRule = "error"
+	// SpotFallbackOnEmpty the next source is queried if the previous one failed or returned no prices
+	SpotFallbackOnEmpty SpotFallbackRule = "empty"
+	// SpotFallbackMerge every source is queried, prices missing from a source are filled from the subsequent ones
+	SpotFallbackMerge SpotFallbackRule = "merge"
+)
+
+// ParseSpotFallbackRule parses the string representation of a fallback rule
+func ParseSpotFallbackRule(rule string) (SpotFallbackRule, error) {
+	switch r := SpotFallbackRule(rule); r {
+	case SpotFallbackOnError, SpotFallbackOnEmpty, SpotFallbackMerge:
+		return r, nil
+	}
+	return "", fmt.Errorf("unsupported spot price fallback rule: %s", rule)
+}
+
+// SpotPriceSourceChain queries a list of spot price sources in order, according to its fallback rule
+type SpotPriceSourceChain struct {
+	sources []SpotPriceSource
+	rule    SpotFallbackRule
+}
+
+// NewSpotPriceSourceChain creates a new chain of spot price sources, sources are queried in the given order
+func NewSpotPriceSourceChain(rule SpotFallbackRule, sources ...SpotPriceSource) *SpotPriceSourceChain {
+	return &SpotPriceSourceChain{
+		sources: sources,
+		rule:    rule,
+	}
+}
+
+// WithFallback returns a source that queries the fallback source after the given one, the fallback is appended to a
+// chain with the fallback rule of the chain, other sources fall back if they fail or return no prices
+func WithFallback(src SpotPriceSource, fallback SpotPriceSource) SpotPriceSource {
+	if chain, ok := src.(*SpotPriceSourceChain); ok {
+		sources := append(append([]SpotPriceSource{}, chain.sources...), fallback)
+		return NewSpotPriceSourceChain(chain.rule, sources...)
+	}
+	return NewSpotPriceSourceChain(SpotFallbackOnEmpty, src, fallback)
+}
+
+// GetSpotPrices retrieves the spot prices from the sources of the chain
+func (c *SpotPriceSourceChain) GetSpotPrices(region string) (map[string]SpotPriceInfo, error) {
+	var (
+		merged  map[string]SpotPriceInfo
+		lastErr error
+	)
+	succeeded := false
+	for i, src := range c.sources {
+		prices, err := src.GetSpotPrices(region)
+		if err != nil {
+			log.WithError(err).Warnf("couldn't get spot prices from source #%d, region: %s", i, region)
+			lastErr = err
+			continue
+		}
+		succeeded = true
+		switch c.rule {
+		case SpotFallbackOnError:
+			return prices, nil
+		case SpotFallbackOnEmpty:
+			if len(prices) > 0 {
+				return prices, nil
+			}
+			log.Debugf("spot price source #%d returned no prices, region: %s", i, region)
+		case SpotFallbackMerge:
+			merged = mergeSpotPrices(merged, prices)
+		}
+	}
+	if merged != nil {
+		return merged, nil
+	}
+	if !succeeded && lastErr != nil {
+		return nil, lastErr
+	}
+	return make(map[string]SpotPriceInfo), nil
+}
+
+// mergeSpotPrices adds the prices missing from dst to it from src, the prices already in dst take precedence
+func mergeSpotPrices(dst map[string]SpotPriceInfo, src map[string]SpotPriceInfo) map[string]SpotPriceInfo {
+	if dst == nil {
+		dst = make(map[string]SpotPriceInfo)
+	}
+	for instType, zonePrices := range src {
+		if dst[instType] == nil {
+			dst[instType] = make(SpotPriceInfo)
+		}
+		for zone, price := range zonePrices {
+			if _, ok := dst[instType][zone]; !ok {
+				dst[instType][zone] = price
+			}
+		}
+	}
+	return dst
+}
+
+// PrometheusLabels maps the labels of the spot price metrics to the dimensions of the spot prices
+type PrometheusLabels struct {
+	Region       string
+	InstanceType string
+	Zone         string
+}
+
+// DefaultPrometheusLabels the labels used by banzaicloud/spot-price-exporter
+var DefaultPrometheusLabels = PrometheusLabels{
+	Region:       "region",
+	InstanceType: "instance_type",
+	Zone:         "availability_zone",
+}
+
+// PrometheusSpotPriceSource retrieves spot prices through the Prometheus query API
+type PrometheusSpotPriceSource struct {
+	prometheus v1.API
+	query      string
+	labels     PrometheusLabels
+}
+
+// NewPrometheusSpotPriceSource creates a new Prometheus spot price source, the query is a format string
+// that receives the region as its only argument
+func NewPrometheusSpotPriceSource(address string, query string, labels PrometheusLabels) (*PrometheusSpotPriceSource, error) {
+	promClient, err := api.NewClient(api.Config{
+		Address: address,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &PrometheusSpotPriceSource{
+		prometheus: v1.NewAPI(promClient),
+		query:      query,
+		labels:     labels,
+	}, nil
+}
+
+// GetSpotPrices retrieves the spot prices from Prometheus
+func (p *PrometheusSpotPriceSource) GetSpotPrices(region string) (map[string]SpotPriceInfo, error) {
+	log.Debug("getting spot price averages from Prometheus API")
+	priceInfo := make(map[string]SpotPriceInfo)
+	query := fmt.Sprintf(p.query, region)
+	log.Debugf("sending prometheus query: %s", query)
+	result, err := p.prometheus.Query(context.Background(), query, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	if result.String() == "" {
+		log.Warnf("Prometheus metric is empty")
+		return priceInfo, nil
+	}
+	vector, ok := result.(model.Vector)
+	if !ok {
+		return nil, fmt.Errorf("unexpected Prometheus result type: %s", result.Type())
+	}
+	for _, value := range vector {
+		if r, ok := value.Metric[model.LabelName(p.labels.Region)]; ok && string(r) != region {
+			continue
+		}
+		instanceType := string(value.Metric[model.LabelName(p.labels.InstanceType)])
+		zone := string(value.Metric[model.LabelName(p.labels.Zone)])
+		price, err := strconv.ParseFloat(value.Value.String(), 64)
+		if err != nil {
+			return nil, err
+		}
+		if priceInfo[instanceType] == nil {
+			priceInfo[instanceType] = make(SpotPriceInfo)
+		}
+		priceInfo[instanceType][zone] = price
+	}
+	return priceInfo, nil
+}
+
+// StaticSpotPriceSource reads spot prices from a JSON file keyed by region, instance type and zone
+type StaticSpotPriceSource struct {
+	path string
+}
+
+// NewStaticSpotPriceSource creates a new spot price source backed by the file at the given path
+func NewStaticSpotPriceSource(path string) *StaticSpotPriceSource {
+	return &StaticSpotPriceSource{
+		path: path,
+	}
+}
+
+// GetSpotPrices reads the spot prices of the region from the file, the file is read on every call so it can be updated
+// without restarting the application
+func (s *StaticSpotPriceSource) GetSpotPrices(region string) (map[string]SpotPriceInfo, error) {
+	content, err := ioutil.ReadFile(s.path)
+	if err != nil {
+		return nil, err
+	}
+	var allPrices map[string]map[string]SpotPriceInfo
+	if err := json.Unmarshal(content, &allPrices); err != nil {
+		return nil, fmt.Errorf("could not parse spot price file %s: %s", s.path, err.Error())
+	}
+	if prices, ok := allPrices[region]; ok {
+		return prices, nil
+	}
+	return make(map[string]SpotPriceInfo), nil
+}
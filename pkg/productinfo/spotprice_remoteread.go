@@ -0,0 +1,124 @@
+package productinfo
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	"github.com/gogo/protobuf/proto"
+	"github.com/golang/snappy"
+	"github.com/prometheus/prometheus/prompb"
+	log "github.com/sirupsen/logrus"
+)
+
+// PrometheusRemoteReadSpotPriceSource retrieves spot prices through the Prometheus remote read API
+// the spot price of an instance type in a zone is the average of the samples in the lookback window
+type PrometheusRemoteReadSpotPriceSource struct {
+	address  string
+	metric   string
+	matchers map[string]string
+	labels   PrometheusLabels
+	lookback time.Duration
+	client   *http.Client
+}
+
+// NewPrometheusRemoteReadSpotPriceSource creates a new remote read spot price source, the address is the full
+// remote read endpoint url, matchers are additional equality label matchers of the query
+func NewPrometheusRemoteReadSpotPriceSource(address string, metric string, matchers map[string]string, labels PrometheusLabels,
+	lookback time.Duration) *PrometheusRemoteReadSpotPriceSource {
+	return &PrometheusRemoteReadSpotPriceSource{
+		address:  address,
+		metric:   metric,
+		matchers: matchers,
+		labels:   labels,
+		lookback: lookback,
+		client:   &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// GetSpotPrices retrieves the spot prices from the remote read endpoint
+func (p *PrometheusRemoteReadSpotPriceSource) GetSpotPrices(region string) (map[string]SpotPriceInfo, error) {
+	log.Debugf("getting spot price averages from Prometheus remote read API [region=%s]", region)
+	end := time.Now()
+	query := &prompb.Query{
+		StartTimestampMs: end.Add(-p.lookback).UnixNano() / int64(time.Millisecond),
+		EndTimestampMs:   end.UnixNano() / int64(time.Millisecond),
+		Matchers: []*prompb.LabelMatcher{
+			{Type: prompb.LabelMatcher_EQ, Name: "__name__", Value: p.metric},
+			{Type: prompb.LabelMatcher_EQ, Name: p.labels.Region, Value: region},
+		},
+	}
+	for name, value := range p.matchers {
+		query.Matchers = append(query.Matchers, &prompb.LabelMatcher{Type: prompb.LabelMatcher_EQ, Name: name, Value: value})
+	}
+
+	resp, err := p.read(&prompb.ReadRequest{Queries: []*prompb.Query{query}})
+	if err != nil {
+		return nil, err
+	}
+
+	priceInfo := make(map[string]SpotPriceInfo)
+	for _, result := range resp.Results {
+		for _, ts := range result.Timeseries {
+			if len(ts.Samples) == 0 {
+				continue
+			}
+			var instanceType, zone string
+			for _, l := range ts.Labels {
+				switch l.Name {
+				case p.labels.InstanceType:
+					instanceType = l.Value
+				case p.labels.Zone:
+					zone = l.Value
+				}
+			}
+			var sum float64
+			for _, s := range ts.Samples {
+				sum += s.Value
+			}
+			if priceInfo[instanceType] == nil {
+				priceInfo[instanceType] = make(SpotPriceInfo)
+			}
+			priceInfo[instanceType][zone] = sum / float64(len(ts.Samples))
+		}
+	}
+	return priceInfo, nil
+}
+
+func (p *PrometheusRemoteReadSpotPriceSource) read(readReq *prompb.ReadRequest) (*prompb.ReadResponse, error) {
+	data, err := proto.Marshal(readReq)
+	if err != nil {
+		return nil, err
+	}
+	httpReq, err := http.NewRequest(http.MethodPost, p.address, bytes.NewReader(snappy.Encode(nil, data)))
+	if err != nil {
+		return nil, err
+	}
+	httpReq.Header.Add("Content-Encoding", "snappy")
+	httpReq.Header.Set("Content-Type", "application/x-protobuf")
+	httpReq.Header.Set("X-Prometheus-Remote-Read-Version", "0.1.0")
+
+	httpResp, err := p.client.Do(httpReq)
+	if err != nil {
+		return nil, err
+	}
+	defer httpResp.Body.Close()
+	if httpResp.StatusCode/100 != 2 {
+		return nil, fmt.Errorf("remote read failed with status: %s", httpResp.Status)
+	}
+	compressed, err := ioutil.ReadAll(httpResp.Body)
+	if err != nil {
+		return nil, err
+	}
+	uncompressed, err := snappy.Decode(nil, compressed)
+	if err != nil {
+		return nil, err
+	}
+	var readResp prompb.ReadResponse
+	if err := proto.Unmarshal(uncompressed, &readResp); err != nil {
+		return nil, err
+	}
+	return &readResp, nil
+}
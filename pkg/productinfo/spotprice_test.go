@@ -0,0 +1,215 @@
+package productinfo
+
+import (
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/gogo/protobuf/proto"
+	"github.com/golang/snappy"
+	"github.com/prometheus/prometheus/prompb"
+	"github.com/stretchr/testify/assert"
+)
+
+// dummySpotPriceSource returns the configured prices or error
+type dummySpotPriceSource struct {
+	prices map[string]SpotPriceInfo
+	err    error
+	called bool
+}
+
+func (s *dummySpotPriceSource) GetSpotPrices(region string) (map[string]SpotPriceInfo, error) {
+	s.called = true
+	return s.prices, s.err
+}
+
+func TestSpotPriceSourceChain_GetSpotPrices(t *testing.T) {
+	tests := []struct {
+		name    string
+		rule    SpotFallbackRule
+		sources []*dummySpotPriceSource
+		check   func(prices map[string]SpotPriceInfo, err error, sources []*dummySpotPriceSource)
+	}{
+		{
+			name: "on error - the first successful source is used",
+			rule: SpotFallbackOnError,
+			sources: []*dummySpotPriceSource{
+				{err: errors.New("failed")},
+				{prices: map[string]SpotPriceInfo{}},
+				{prices: map[string]SpotPriceInfo{"c5.large": {"zone1": 0.04}}},
+			},
+			check: func(prices map[string]SpotPriceInfo, err error, sources []*dummySpotPriceSource) {
+				assert.Nil(t, err, "the error should be nil")
+				assert.Equal(t, 0, len(prices))
+				assert.False(t, sources[2].called, "the last source should not be called")
+			},
+		},
+		{
+			name: "on empty - empty results are skipped",
+			rule: SpotFallbackOnEmpty,
+			sources: []*dummySpotPriceSource{
+				{err: errors.New("failed")},
+				{prices: map[string]SpotPriceInfo{}},
+				{prices: map[string]SpotPriceInfo{"c5.large": {"zone1": 0.04}}},
+			},
+			check: func(prices map[string]SpotPriceInfo, err error, sources []*dummySpotPriceSource) {
+				assert.Nil(t, err, "the error should be nil")
+				assert.Equal(t, map[string]SpotPriceInfo{"c5.large": {"zone1": 0.04}}, prices)
+			},
+		},
+		{
+			name: "on empty - empty result is returned if nothing else is found",
+			rule: SpotFallbackOnEmpty,
+			sources: []*dummySpotPriceSource{
+				{prices: map[string]SpotPriceInfo{}},
+				{err: errors.New("failed")},
+			},
+			check: func(prices map[string]SpotPriceInfo, err error, sources []*dummySpotPriceSource) {
+				assert.Nil(t, err, "the error should be nil")
+				assert.Equal(t, map[string]SpotPriceInfo{}, prices)
+			},
+		},
+		{
+			name: "merge - earlier sources take precedence",
+			rule: SpotFallbackMerge,
+			sources: []*dummySpotPriceSource{
+				{prices: map[string]SpotPriceInfo{"c5.large": {"zone1": 0.04}}},
+				{err: errors.New("failed")},
+				{prices: map[string]SpotPriceInfo{"c5.large": {"zone1": 0.05, "zone2": 0.06}, "m5.large": {"zone1": 0.03}}},
+			},
+			check: func(prices map[string]SpotPriceInfo, err error, sources []*dummySpotPriceSource) {
+				assert.Nil(t, err, "the error should be nil")
+				assert.Equal(t, map[string]SpotPriceInfo{
+					"c5.large": {"zone1": 0.04, "zone2": 0.06},
+					"m5.large": {"zone1": 0.03},
+				}, prices)
+			},
+		},
+		{
+			name: "every source failed",
+			rule: SpotFallbackMerge,
+			sources: []*dummySpotPriceSource{
+				{err: errors.New("first")},
+				{err: errors.New("last")},
+			},
+			check: func(prices map[string]SpotPriceInfo, err error, sources []*dummySpotPriceSource) {
+				assert.Nil(t, prices, "the prices should be nil")
+				assert.EqualError(t, err, "last")
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var sources []SpotPriceSource
+			for _, s := range test.sources {
+				sources = append(sources, s)
+			}
+			prices, err := NewSpotPriceSourceChain(test.rule, sources...).GetSpotPrices("dummyRegion")
+			test.check(prices, err, test.sources)
+		})
+	}
+}
+
+func TestWithFallback(t *testing.T) {
+	empty := &dummySpotPriceSource{prices: map[string]SpotPriceInfo{}}
+	fallback := &dummySpotPriceSource{prices: map[string]SpotPriceInfo{"c5.large": {"zone1": 0.04}}}
+
+	prices, err := WithFallback(NewSpotPriceSourceChain(SpotFallbackOnError, empty), fallback).GetSpotPrices("dummyRegion")
+	assert.Nil(t, err, "the error should be nil")
+	assert.Equal(t, 0, len(prices))
+	assert.False(t, fallback.called, "the fallback rule of the chain should be kept")
+
+	prices, err = WithFallback(empty, fallback).GetSpotPrices("dummyRegion")
+	assert.Nil(t, err, "the error should be nil")
+	assert.Equal(t, map[string]SpotPriceInfo{"c5.large": {"zone1": 0.04}}, prices)
+}
+
+func TestStaticSpotPriceSource_GetSpotPrices(t *testing.T) {
+	f, err := ioutil.TempFile("", "spotprices")
+	if err != nil {
+		t.Fatalf("could not create temp file: %s", err.Error())
+	}
+	defer os.Remove(f.Name())
+	f.WriteString(`{"eu-west-1": {"c5.large": {"eu-west-1a": 0.035, "eu-west-1b": 0.037}}}`)
+	f.Close()
+
+	tests := []struct {
+		name   string
+		path   string
+		region string
+		check  func(prices map[string]SpotPriceInfo, err error)
+	}{
+		{
+			name:   "prices of a known region",
+			path:   f.Name(),
+			region: "eu-west-1",
+			check: func(prices map[string]SpotPriceInfo, err error) {
+				assert.Nil(t, err, "the error should be nil")
+				assert.Equal(t, map[string]SpotPriceInfo{"c5.large": {"eu-west-1a": 0.035, "eu-west-1b": 0.037}}, prices)
+			},
+		},
+		{
+			name:   "no prices for an unknown region",
+			path:   f.Name(),
+			region: "us-east-1",
+			check: func(prices map[string]SpotPriceInfo, err error) {
+				assert.Nil(t, err, "the error should be nil")
+				assert.Equal(t, 0, len(prices))
+			},
+		},
+		{
+			name:   "missing file",
+			path:   f.Name() + ".missing",
+			region: "eu-west-1",
+			check: func(prices map[string]SpotPriceInfo, err error) {
+				assert.Nil(t, prices, "the prices should be nil")
+				assert.NotNil(t, err, "the error should not be nil")
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			test.check(NewStaticSpotPriceSource(test.path).GetSpotPrices(test.region))
+		})
+	}
+}
+
+func TestPrometheusRemoteReadSpotPriceSource_GetSpotPrices(t *testing.T) {
+	var received prompb.ReadRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		data, err := snappy.Decode(nil, body)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		proto.Unmarshal(data, &received)
+		resp, _ := proto.Marshal(&prompb.ReadResponse{
+			Results: []*prompb.QueryResult{{
+				Timeseries: []*prompb.TimeSeries{{
+					Labels: []prompb.Label{
+						{Name: "instance_type", Value: "c5.large"},
+						{Name: "availability_zone", Value: "eu-west-1a"},
+					},
+					Samples: []prompb.Sample{{Value: 0.03, Timestamp: 1}, {Value: 0.05, Timestamp: 2}},
+				}},
+			}},
+		})
+		w.Write(snappy.Encode(nil, resp))
+	}))
+	defer server.Close()
+
+	src := NewPrometheusRemoteReadSpotPriceSource(server.URL, "aws_spot_current_price", map[string]string{"product_description": "Linux/UNIX"},
+		DefaultPrometheusLabels, time.Hour)
+	prices, err := src.GetSpotPrices("eu-west-1")
+
+	assert.Nil(t, err, "the error should be nil")
+	assert.InDelta(t, 0.04, prices["c5.large"]["eu-west-1a"], 1e-9)
+	assert.Equal(t, 1, len(received.Queries))
+	assert.Equal(t, 3, len(received.Queries[0].Matchers))
+	assert.Equal(t, "eu-west-1", received.Queries[0].Matchers[1].Value)
+}
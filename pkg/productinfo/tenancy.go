@@ -0,0 +1,132 @@
+package productinfo
+
+import "fmt"
+
+const (
+	// Shared the default tenancy, the instances run on hardware shared with other customers
+	Shared = "shared"
+
+	// Dedicated the tenancy of the instances running on hardware dedicated to a single customer
+	Dedicated = "dedicated"
+
+	// Host the tenancy of the physical servers dedicated to a single customer, the instances are placed on the hosts by
+	// the customer
+	Host = "host"
+
+	// HostKeyTemplate format for generating dedicated host cache keys
+	HostKeyTemplate = "/banzaicloud.com/recommender/%s/%s/hosts"
+)
+
+// Tenancies the tenancies the prices can be queried for
+var Tenancies = []string{Shared, Dedicated, Host}
+
+// TenancyPrice the on demand prices of an instance type with a tenancy other than shared
+type TenancyPrice struct {
+	OnDemandPrice float64 `json:"onDemandPrice"`
+	// OsPrices the on demand prices of the operating systems other than Linux
+	OsPrices map[string]float64 `json:"osPrices,omitempty"`
+}
+
+// ForOs returns the on demand price of the given operating system, the price is 0 if it's not known
+func (tp TenancyPrice) ForOs(os string) float64 {
+	if os == "" || os == Linux {
+		return tp.OnDemandPrice
+	}
+	return tp.OsPrices[os]
+}
+
+// DedicatedHostInfoer is implemented by the product infoers of providers offering dedicated hosts
+type DedicatedHostInfoer interface {
+	// GetDedicatedHosts returns the dedicated host families of a region, the type of a host is its family and its on
+	// demand price is the price of the whole host
+	GetDedicatedHosts(region string) ([]VmInfo, error)
+}
+
+func (cpi *CachingProductInfo) getHostKey(provider string, region string) string {
+	return fmt.Sprintf(HostKeyTemplate, provider, region)
+}
+
+// renewHosts retrieves the dedicated hosts of a region from the providers offering them and refreshes the cache with
+// them
+func (cpi *CachingProductInfo) renewHosts(provider string, region string) error {
+	hostInfoer, ok := cpi.productInfoers[provider].(DedicatedHostInfoer)
+	if !ok {
+		return nil
+	}
+	hosts, err := hostInfoer.GetDedicatedHosts(region)
+	if err != nil {
+		return err
+	}
+	cpi.vmAttrStore.Set(cpi.getHostKey(provider, region), hosts, cpi.renewalInterval)
+	return nil
+}
+
+// GetProductDetailsForTenancy retrieves product details with the prices of the given operating system and tenancy
+// form the given provider and region
+// the dedicated products are the instance types with a dedicated price without spot prices and commitments, the host
+// products are the dedicated host families priced per host regardless of the operating system
+func (cpi *CachingProductInfo) GetProductDetailsForTenancy(cloud string, region string, os string, tenancy string) ([]ProductDetails, error) {
+	switch tenancy {
+	case "", Shared:
+		return cpi.GetProductDetailsForOs(cloud, region, os)
+	case Dedicated:
+		vms, err := cpi.getCachedVms(cloud, region)
+		if err != nil {
+			return nil, err
+		}
+		details := make([]ProductDetails, 0, len(vms))
+		for _, vm := range vms {
+			price := vm.TenancyPrices[Dedicated].ForOs(os)
+			if price <= 0 {
+				continue
+			}
+			vm = vm.ForOs(os)
+			vm.OnDemandPrice, vm.SpotPrice, vm.Commitments = price, nil, nil
+			details = append(details, *cpi.decorateNtwPerfCat(cloud, newProductDetails(vm)))
+		}
+		return details, nil
+	case Host:
+		cachedHosts, ok := cpi.vmAttrStore.Get(cpi.getHostKey(cloud, region))
+		if !ok {
+			return nil, fmt.Errorf("dedicated hosts not yet cached for the key: %s", cpi.getHostKey(cloud, region))
+		}
+		hosts := cachedHosts.([]VmInfo)
+		details := make([]ProductDetails, 0, len(hosts))
+		for _, host := range hosts {
+			details = append(details, *newProductDetails(host))
+		}
+		return details, nil
+	}
+	return nil, fmt.Errorf("unsupported tenancy: %s", tenancy)
+}
+
+// GetTenancyPrice returns the on demand price and the zone averaged computed spot price of an operating system and a
+// tenancy for a given instance type in a given region, the instance type of the host tenancy is the host family
+// only shared instances have spot prices, their on demand price is taken from the products if the cached prices have
+// none, e.g. the spot prices of EC2
+func (cpi *CachingProductInfo) GetTenancyPrice(provider string, region string, instanceType string, os string, tenancy string, zones []string) (float64, float64, error) {
+	if tenancy == "" || tenancy == Shared {
+		onDemand, spot, err := cpi.GetOsPrice(provider, region, instanceType, os, zones)
+		if err != nil || onDemand > 0 {
+			return onDemand, spot, err
+		}
+		if vms, err := cpi.getCachedVms(provider, region); err == nil {
+			for _, vm := range vms {
+				if vm.Type == instanceType {
+					return vm.ForOs(os).OnDemandPrice, spot, nil
+				}
+			}
+		}
+		return 0, spot, nil
+	}
+	details, err := cpi.GetProductDetailsForTenancy(provider, region, os, tenancy)
+	if err != nil {
+		return 0, 0, err
+	}
+	for _, d := range details {
+		if d.Type == instanceType {
+			return d.OnDemandPrice, 0, nil
+		}
+	}
+	return 0, 0, nil
+}
@@ -0,0 +1,103 @@
+package productinfo
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/patrickmn/go-cache"
+	"github.com/stretchr/testify/assert"
+)
+
+// dummyHostInfoer a product infoer offering dedicated hosts
+type dummyHostInfoer struct {
+	DummyProductInfoer
+	hosts []VmInfo
+	err   error
+}
+
+func (dhi *dummyHostInfoer) GetDedicatedHosts(region string) ([]VmInfo, error) {
+	return dhi.hosts, dhi.err
+}
+
+func TestCachingProductInfo_GetProductDetailsForTenancy(t *testing.T) {
+	c := cache.New(5*time.Minute, 10*time.Minute)
+	infoer := &dummyHostInfoer{hosts: []VmInfo{{Type: "m5", OnDemandPrice: 5.069, Cpus: 96, Sockets: 2, Cores: 48}}}
+	productInfo, _ := NewCachingProductInfo(10*time.Second, c, map[string]ProductInfoer{"dummy": infoer})
+	c.Set(productInfo.getVmKey("dummy", "dummyRegion"), []VmInfo{
+		{Type: "m5.large", OnDemandPrice: 0.1, TenancyPrices: map[string]TenancyPrice{
+			Dedicated: {OnDemandPrice: 0.11, OsPrices: map[string]float64{Windows: 0.22}},
+		}},
+		{Type: "m5.xlarge", OnDemandPrice: 0.2},
+	}, cache.NoExpiration)
+	c.Set(productInfo.getPriceKey("dummy", "dummyRegion", "m5.large"), Price{
+		OnDemandPrice: -1,
+		SpotPrice:     SpotPriceInfo{"zone-a": 0.04},
+	}, cache.NoExpiration)
+
+	details, err := productInfo.GetProductDetailsForTenancy("dummy", "dummyRegion", Linux, Shared)
+	assert.Nil(t, err, "the error should be nil")
+	assert.Equal(t, 2, len(details))
+
+	details, err = productInfo.GetProductDetailsForTenancy("dummy", "dummyRegion", Windows, Dedicated)
+	assert.Nil(t, err, "the error should be nil")
+	assert.Equal(t, 1, len(details), "types without dedicated prices should be left out")
+	assert.Equal(t, 0.22, details[0].OnDemandPrice)
+	assert.Nil(t, details[0].SpotInfo, "dedicated instances should have no spot prices")
+
+	_, err = productInfo.GetProductDetailsForTenancy("dummy", "dummyRegion", Linux, Host)
+	assert.EqualError(t, err, "dedicated hosts not yet cached for the key: /banzaicloud.com/recommender/dummy/dummyRegion/hosts")
+	assert.Nil(t, productInfo.renewHosts("dummy", "dummyRegion"))
+	details, err = productInfo.GetProductDetailsForTenancy("dummy", "dummyRegion", Linux, Host)
+	assert.Nil(t, err, "the error should be nil")
+	assert.Equal(t, 1, len(details))
+	assert.Equal(t, 48, details[0].Cores)
+
+	_, err = productInfo.GetProductDetailsForTenancy("dummy", "dummyRegion", Linux, "reserved")
+	assert.EqualError(t, err, "unsupported tenancy: reserved")
+
+	infoer.err = errors.New("no hosts")
+	assert.EqualError(t, productInfo.renewHosts("dummy", "dummyRegion"), "no hosts")
+}
+
+func TestCachingProductInfo_GetTenancyPrice(t *testing.T) {
+	c := cache.New(5*time.Minute, 10*time.Minute)
+	productInfo, _ := NewCachingProductInfo(10*time.Second, c, map[string]ProductInfoer{
+		"dummy": &dummyHostInfoer{hosts: []VmInfo{{Type: "m5", OnDemandPrice: 5.069}}},
+	})
+	c.Set(productInfo.getVmKey("dummy", "dummyRegion"), []VmInfo{
+		{Type: "m5.large", TenancyPrices: map[string]TenancyPrice{Dedicated: {OnDemandPrice: 0.11}}},
+		{Type: "c5.large", OnDemandPrice: 0.085},
+	}, cache.NoExpiration)
+	c.Set(productInfo.getPriceKey("dummy", "dummyRegion", "m5.large"), Price{
+		OnDemandPrice: 0.1,
+		SpotPrice:     SpotPriceInfo{"zone-a": 0.04},
+	}, cache.NoExpiration)
+	c.Set(productInfo.getPriceKey("dummy", "dummyRegion", "c5.large"), Price{
+		OnDemandPrice: -1,
+		SpotPrice:     SpotPriceInfo{"zone-a": 0.03},
+	}, cache.NoExpiration)
+	assert.Nil(t, productInfo.renewHosts("dummy", "dummyRegion"))
+
+	tests := []struct {
+		name         string
+		instanceType string
+		tenancy      string
+		onDemand     float64
+		spot         float64
+	}{
+		{name: "shared", instanceType: "m5.large", tenancy: Shared, onDemand: 0.1, spot: 0.04},
+		{name: "shared on demand price of the products", instanceType: "c5.large", tenancy: Shared, onDemand: 0.085, spot: 0.03},
+		{name: "dedicated", instanceType: "m5.large", tenancy: Dedicated, onDemand: 0.11},
+		{name: "host", instanceType: "m5", tenancy: Host, onDemand: 5.069},
+		{name: "unknown host family", instanceType: "c5", tenancy: Host},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			onDemand, spot, err := productInfo.GetTenancyPrice("dummy", "dummyRegion", test.instanceType, Linux, test.tenancy, []string{"zone-a"})
+			assert.Nil(t, err, "the error should be nil")
+			assert.Equal(t, test.onDemand, onDemand)
+			assert.Equal(t, test.spot, spot)
+		})
+	}
+}
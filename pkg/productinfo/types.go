@@ -12,6 +12,18 @@ const (
 	// Cpu represents the cpu attribute for the recommender
 	Cpu = "cpu"
 
+	// Linux the default operating system, the top level prices are the prices of Linux
+	Linux = "linux"
+
+	// Windows the Windows Server operating system
+	Windows = "windows"
+
+	// Rhel the Red Hat Enterprise Linux operating system
+	Rhel = "rhel"
+
+	// Suse the SUSE Linux Enterprise Server operating system
+	Suse = "suse"
+
 	// VmKeyTemplate format for generating vm cache keys
 	VmKeyTemplate = "/banzaicloud.com/recommender/%s/%s/vms"
 
@@ -21,6 +33,9 @@ const (
 	// PriceKeyTemplate format for generating price cache keys
 	PriceKeyTemplate = "/banzaicloud.com/recommender/%s/%s/prices/%s"
 
+	// SpotPriceKeyTemplate format for generating the cache keys of the short lived spot prices
+	SpotPriceKeyTemplate = "/banzaicloud.com/recommender/%s/%s/spotprices/%s"
+
 	// ZoneKeyTemplate format for generating zone cache keys
 	ZoneKeyTemplate = "/banzaicloud.com/recommender/%s/%s/zones/"
 
@@ -91,16 +106,34 @@ type ProductInfo interface {
 	// GetPrice returns the on demand price and the zone averaged computed spot price for a given instance type in a given region
 	GetPrice(provider string, region string, instanceType string, zones []string) (float64, float64, error)
 
+	// GetOsPrice returns the on demand price and the zone averaged computed spot price of an operating system for a
+	// given instance type in a given region
+	GetOsPrice(provider string, region string, instanceType string, os string, zones []string) (float64, float64, error)
+
+	// GetTenancyPrice returns the on demand price and the zone averaged computed spot price of an operating system and a
+	// tenancy for a given instance type in a given region
+	GetTenancyPrice(provider string, region string, instanceType string, os string, tenancy string, zones []string) (float64, float64, error)
+
 	// GetNetworkPerfMapper retrieves the network performance mapper implementation
 	GetNetworkPerfMapper(provider string) (NetworkPerfMapper, error)
 }
 
+// OperatingSystems the operating systems the prices can be queried for
+var OperatingSystems = []string{Linux, Windows, Rhel, Suse}
+
 // CachingProductInfo is the module struct, holds configuration and cache
 // It's the entry point for the product info retrieval and management subsystem
 type CachingProductInfo struct {
 	productInfoers  map[string]ProductInfoer
 	renewalInterval time.Duration
 	vmAttrStore     ProductStorer
+	spotHistory     *SpotPriceHistory
+	changes         *ChangeNotifier
+	priceListeners  []PriceListener
+	guard           *CatalogGuard
+	quality         *QualityTracker
+	// spotPriceSources the spot price sources per provider queried before the current prices of the infoer
+	spotPriceSources map[string]SpotPriceSource
 }
 
 // AttrValue represents an attribute value
@@ -131,6 +164,19 @@ type NetworkPerfMapper interface {
 	MapNetworkPerf(vm VmInfo) (string, error)
 }
 
+// SpotPriceSource operations for retrieving frequently changing spot (preemptible) prices
+// decouples the spot price retrieval from the provider specific product infoers
+type SpotPriceSource interface {
+	// GetSpotPrices retrieves the spot prices of every instance type per availability zone in the given region
+	GetSpotPrices(region string) (map[string]SpotPriceInfo, error)
+}
+
+// PriceListener is notified of the prices of a region on every price renewal
+type PriceListener interface {
+	// PricesRenewed is called with the renewed prices of a region, prices that are not known are 0
+	PricesRenewed(provider string, region string, prices map[string]Price)
+}
+
 // ProductStorer interface collects the necessary cache operations
 type ProductStorer interface {
 	Get(k string) (interface{}, bool)
@@ -161,6 +207,12 @@ type ProductDetails struct {
 
 	// ZonePrice holds spot price information per zone
 	SpotInfo []ZonePrice `json:"spotPrice"`
+
+	// PreemptibleInfo holds the legacy preemptible price information per zone of the providers pricing it apart
+	PreemptibleInfo []ZonePrice `json:"preemptiblePrice,omitempty"`
+
+	// EffectiveMonthlyPrice the on demand price for the requested monthly usage, with the sustained use discounts applied
+	EffectiveMonthlyPrice float64 `json:"effectiveMonthlyPrice,omitempty"`
 }
 
 // ProductDetailSource product details related set of operations